@@ -4,10 +4,12 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/valkyriefinance/ai-engine/internal/server"
 	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
 )
 
 func main() {
@@ -16,17 +18,110 @@ func main() {
 	// Initialize data collector
 	dataCollector := services.NewRealDataCollector()
 
+	// Initialize feed collector for yield pools and streamed prices; its price history also
+	// drives the composite sentiment index
+	feedCollector := services.NewDataCollector()
+	dataCollector.UsePriceHistory(feedCollector.PriceHistory())
+
+	// Open the portfolio registry
+	portfolioStore, err := store.NewFilePortfolioStore(storePath("PORTFOLIO_STORE_PATH", "data/portfolios.json"))
+	if err != nil {
+		log.Fatalf("Failed to open portfolio store: %v", err)
+	}
+
+	// Open the recommendation audit trail, dropping records past the retention period
+	retention := store.DefaultRecommendationRetention
+	if value := os.Getenv("RECOMMENDATION_RETENTION"); value != "" {
+		if retention, err = services.ParseHorizon(value); err != nil {
+			log.Fatalf("Invalid RECOMMENDATION_RETENTION: %v", err)
+		}
+	}
+	recommendationStore, err := store.NewFileRecommendationStore(storePath("RECOMMENDATION_STORE_PATH", "data/recommendations.jsonl"), store.WithRetention(retention))
+	if err != nil {
+		log.Fatalf("Failed to open recommendation store: %v", err)
+	}
+	defer recommendationStore.Close()
+
+	// Score past recommendations once their horizons elapse
+	evaluatorConfig := services.DefaultOutcomeEvaluatorConfig()
+	if horizons := os.Getenv("OUTCOME_HORIZONS"); horizons != "" {
+		evaluatorConfig.Horizons = strings.Split(horizons, ",")
+		evaluatorConfig.CalibrationHorizon = "" // Shortest configured horizon
+	}
+	if horizon := os.Getenv("CALIBRATION_HORIZON"); horizon != "" {
+		evaluatorConfig.CalibrationHorizon = horizon
+	}
+	outcomeEvaluator, err := services.NewOutcomeEvaluator(recommendationStore, feedCollector, evaluatorConfig)
+	if err != nil {
+		log.Fatalf("Invalid outcome evaluator configuration: %v", err)
+	}
+
+	// Load pool reserves for simulating planned swaps; without them swaps use the cost model
+	var liquidityPools *services.LiquidityPools
+	if path := os.Getenv("LIQUIDITY_POOLS_PATH"); path != "" {
+		if liquidityPools, err = services.LoadLiquidityPools(path); err != nil {
+			log.Fatalf("Failed to load liquidity pools: %v", err)
+		}
+	}
+
+	// Load house views blended into market-implied returns
+	houseViews, err := services.LoadMarketViews(os.Getenv("HOUSE_VIEWS_PATH"))
+	if err != nil {
+		log.Fatalf("Failed to load house views: %v", err)
+	}
+	blackLitterman := services.DefaultBlackLittermanConfig()
+	blackLitterman.Views = houseViews
+
+	// Choose the benchmark behind beta, relative risk and performance attribution
+	benchmark := services.DefaultBenchmark()
+	if spec := os.Getenv("BENCHMARK"); spec != "" {
+		if benchmark, err = services.ParseBenchmark(spec); err != nil {
+			log.Fatalf("Invalid benchmark: %v", err)
+		}
+	}
+
 	// Initialize enhanced AI engine
-	aiEngine := services.NewEnhancedAIEngine()
+	aiEngine := services.NewEnhancedAIEngine(
+		services.WithYieldStore(feedCollector.YieldStore()),
+		services.WithPriceHistory(feedCollector.PriceHistory()),
+		services.WithConfidenceCalibrator(outcomeEvaluator),
+		services.WithLiquidityPools(liquidityPools),
+		services.WithBlackLitterman(blackLitterman),
+		services.WithMarketCaps(feedCollector),
+		services.WithBenchmark(benchmark),
+		services.WithSentiment(dataCollector.Sentiment()),
+	)
+
+	// Wallet-scoped endpoints trust only sessions signed with this key
+	sessionSecret := os.Getenv("SESSION_SECRET")
+	if sessionSecret == "" {
+		log.Println("SESSION_SECRET not set; portfolio, recommendation history and portfolio stream endpoints are disabled")
+	}
 
 	// Create HTTP server
-	httpServer := server.NewSimpleHTTPServer(aiEngine, dataCollector)
+	httpServer := server.NewSimpleHTTPServer(aiEngine, dataCollector,
+		server.WithYieldStore(feedCollector.YieldStore()),
+		server.WithYieldPredictor(services.NewYieldForecaster(feedCollector.YieldStore())),
+		server.WithPriceStreamer(feedCollector),
+		server.WithPortfolioStore(portfolioStore),
+		server.WithRecommendationStore(recommendationStore),
+		server.WithScorecardProvider(outcomeEvaluator),
+		server.WithPerformanceAttributor(services.NewPerformanceAnalyzer(portfolioStore, feedCollector,
+			services.WithAttributionBenchmark(benchmark, aiEngine))),
+		server.WithSessionSecret([]byte(sessionSecret)),
+	)
 
 	// Start data collection
 	log.Println("Starting data collection...")
 	if err := dataCollector.Start(); err != nil {
 		log.Fatalf("Failed to start data collector: %v", err)
 	}
+	if err := feedCollector.Start(); err != nil {
+		log.Fatalf("Failed to start feed collector: %v", err)
+	}
+	if err := outcomeEvaluator.Start(); err != nil {
+		log.Fatalf("Failed to start outcome evaluator: %v", err)
+	}
 
 	// Handle graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -36,6 +131,8 @@ func main() {
 		<-c
 		log.Println("Shutting down gracefully...")
 		dataCollector.Stop()
+		feedCollector.Stop()
+		outcomeEvaluator.Stop()
 		httpServer.Stop()
 	}()
 
@@ -46,3 +143,11 @@ func main() {
 		log.Fatalf("Failed to start HTTP server: %v", err)
 	}
 }
+
+// storePath gets a store file location from environment or default
+func storePath(envVar, fallback string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	return fallback
+}
@@ -17,12 +17,62 @@ type PriceData struct {
 
 // YieldData represents yield information from various protocols
 type YieldData struct {
+	PoolID    string    `json:"pool_id"`
+	Chain     string    `json:"chain"`
 	Protocol  string    `json:"protocol"`
 	Token     string    `json:"token"`
 	APY       float64   `json:"apy"`
 	TVL       float64   `json:"tvl"`
 	Risk      float64   `json:"risk"` // 0-1 scale
 	Timestamp time.Time `json:"timestamp"`
+
+	RiskBreakdown *RiskBreakdown `json:"risk_breakdown,omitempty"`
+}
+
+// RiskBreakdown explains how a protocol risk score was assembled
+type RiskBreakdown struct {
+	Score      float64         `json:"score"` // 0-1 scale
+	Components []RiskComponent `json:"components"`
+	Flags      []string        `json:"flags,omitempty"`
+}
+
+// RiskComponent is one weighted input of a risk score
+type RiskComponent struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"` // 0-1 scale, higher is riskier
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Value * Weight
+	Detail       string  `json:"detail"`
+}
+
+// YieldOpportunity represents a stored yield pool ranked for display
+type YieldOpportunity struct {
+	YieldData
+	RiskAdjustedAPY float64   `json:"risk_adjusted_apy"`
+	APY7dAvg        float64   `json:"apy_7d_avg"`
+	FirstSeen       time.Time `json:"first_seen"`
+	Snapshots       int       `json:"snapshots"`
+}
+
+// YieldPrediction represents a forecast APY for a yield pool
+type YieldPrediction struct {
+	Protocol     string  `json:"protocol"`
+	Token        string  `json:"token"`
+	Chain        string  `json:"chain"`
+	PoolID       string  `json:"pool_id"`
+	CurrentAPY   float64 `json:"current_apy"`
+	PredictedAPY float64 `json:"predicted_apy"`
+	LowerBound   float64 `json:"lower_bound"` // 95% confidence interval
+	UpperBound   float64 `json:"upper_bound"`
+	Confidence   float64 `json:"confidence"` // 0-1 scale
+	Timeframe    string  `json:"timeframe"`
+	Observations int     `json:"observations"`
+}
+
+// YieldPredictionResponse represents a batch of yield forecasts
+type YieldPredictionResponse struct {
+	Predictions []YieldPrediction `json:"predictions"`
+	Timestamp   time.Time         `json:"timestamp"`
 }
 
 // PortfolioPosition represents a position in the portfolio
@@ -32,6 +82,8 @@ type PortfolioPosition struct {
 	Value    float64 `json:"value"`
 	Weight   float64 `json:"weight"`
 	YieldAPY float64 `json:"yield_apy"`
+	Venue    string  `json:"venue,omitempty"` // Protocol or pool id where the position earns yield, empty when idle
+	Chain    string  `json:"chain,omitempty"`
 }
 
 // Portfolio represents the current portfolio state
@@ -42,6 +94,24 @@ type Portfolio struct {
 	LastUpdated time.Time           `json:"last_updated"`
 }
 
+// StoredPortfolio is a persisted portfolio owned by a wallet address
+type StoredPortfolio struct {
+	Portfolio
+	Owner     string    `json:"owner"`
+	Name      string    `json:"name,omitempty"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PortfolioVersion is an immutable snapshot taken on every portfolio write
+type PortfolioVersion struct {
+	Version   int       `json:"version"`
+	Portfolio Portfolio `json:"portfolio"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // RebalanceRecommendation represents AI-generated rebalancing recommendations
 type RebalanceRecommendation struct {
 	PortfolioID    string            `json:"portfolio_id"`
@@ -51,15 +121,249 @@ type RebalanceRecommendation struct {
 	Risk           float64           `json:"risk"`
 	Actions        []RebalanceAction `json:"actions"`
 	Reasoning      string            `json:"reasoning"`
+
+	ConfidenceBreakdown *ConfidenceBreakdown `json:"confidence_breakdown,omitempty"`
+	Explanation         *Explanation         `json:"explanation,omitempty"`
+
+	Current   *PortfolioProjection `json:"current,omitempty"`
+	Projected *PortfolioProjection `json:"projected,omitempty"` // Portfolio after following every action
+
+	TradePlan  *TradePlan        `json:"trade_plan,omitempty"`
+	Policy     *PolicyStatus     `json:"policy,omitempty"`
+	Allocation *AllocationReport `json:"allocation,omitempty"`
+	Regime     *MarketRegime     `json:"regime,omitempty"` // Regime whose parameters the optimizer used
+}
+
+// Rebalancing policy modes
+const (
+	PolicyModeThreshold  = "threshold"  // Rebalance tokens whose drift leaves their band
+	PolicyModeCalendar   = "calendar"   // Rebalance everything on a fixed cadence
+	PolicyModeVolatility = "volatility" // Bands scale with each token's volatility
+)
+
+// RecommendationOptions tune a single recommendation request
+type RecommendationOptions struct {
+	Policy   *RebalancePolicy `json:"policy,omitempty"`
+	Strategy string           `json:"strategy,omitempty"` // Allocation strategy; empty uses the market regime's, or risk_adjusted
+	Views    []MarketView     `json:"views,omitempty"`    // Blended with house views into expected returns
+}
+
+// Market view types
+const (
+	ViewAbsolute = "absolute" // Token returns Return per year
+	ViewRelative = "relative" // Token outperforms Versus by Return per year
+)
+
+// MarketView is an opinion on annual total price returns, blended into market-implied returns
+type MarketView struct {
+	Type       string  `json:"type"`
+	Token      string  `json:"token"`
+	Versus     string  `json:"versus,omitempty"`
+	Return     float64 `json:"return"`
+	Confidence float64 `json:"confidence"` // In (0, 1]; higher pulls returns closer to the view
+}
+
+// Allocation strategies
+const (
+	StrategyRiskAdjusted           = "risk_adjusted"      // Weights proportional to return over volatility
+	StrategyEqualWeight            = "equal_weight"       // Every token weighted equally
+	StrategyInverseVolatility      = "inverse_volatility" // Weights proportional to one over volatility
+	StrategyRiskParity             = "risk_parity"        // Every token contributes equally to portfolio risk
+	StrategyHierarchicalRiskParity = "hrp"                // Risk split recursively across correlation clusters
+)
+
+// AllocationReport describes the target allocation and how its risk is spread
+type AllocationReport struct {
+	Strategy   string            `json:"strategy"`
+	Covariance string            `json:"covariance"` // "price_history" when estimated from prices, otherwise "assumed"
+	Volatility float64           `json:"volatility"` // Annualized volatility of the target allocation
+	Assets     []AssetAllocation `json:"assets"`
+
+	// ReturnModel is "market_implied" when prior returns come from market caps, otherwise "assumed"
+	ReturnModel  string `json:"return_model"`
+	ViewsApplied int    `json:"views_applied"`
+}
+
+// AssetAllocation is one token's target weight and its share of portfolio risk
+type AssetAllocation struct {
+	Token            string  `json:"token"`
+	Weight           float64 `json:"weight"`
+	Volatility       float64 `json:"volatility"`
+	RiskContribution float64 `json:"risk_contribution"` // Share of portfolio variance; shares sum to one
+	PriorReturn      float64 `json:"prior_return"`      // Annual price return before views
+	ExpectedReturn   float64 `json:"expected_return"`   // Annual price return after views
+}
+
+// RebalancePolicy decides when and how far positions are rebalanced
+type RebalancePolicy struct {
+	Mode       string               `json:"mode"`
+	Band       DriftBand            `json:"band,omitempty"`
+	TokenBands map[string]DriftBand `json:"token_bands,omitempty"` // Per-token overrides of Band
+
+	// Cadence is the rebalance interval in calendar mode and the drift review interval otherwise ("12h", "30d")
+	Cadence        string    `json:"cadence,omitempty"`
+	LastRebalanced time.Time `json:"last_rebalanced,omitempty"`
+
+	VolatilityMultiplier float64 `json:"volatility_multiplier,omitempty"` // Band width in volatilities over the cadence
+	NoTradeZone          float64 `json:"no_trade_zone,omitempty"`         // Triggered trades stop this far from target
+}
+
+// DriftBand limits how far a weight may drift from target. Exceeding either limit triggers
+// a rebalance; zero disables a limit.
+type DriftBand struct {
+	Absolute float64 `json:"absolute,omitempty"` // Weight points, e.g. 0.02
+	Relative float64 `json:"relative,omitempty"` // Fraction of the target weight, e.g. 0.25
+}
+
+// ActionTrigger records which policy rule caused an action
+type ActionTrigger struct {
+	Mode  string  `json:"mode"`
+	Rule  string  `json:"rule"`
+	Drift float64 `json:"drift"` // Absolute weight drift, or relative drift for relative bands
+	Band  float64 `json:"band"`
+}
+
+// PolicyStatus reports the policy a recommendation was made under
+type PolicyStatus struct {
+	Mode       string    `json:"mode"`
+	Due        bool      `json:"due"` // Whether the policy allowed rebalancing trades now
+	NextReview time.Time `json:"next_review"`
+}
+
+// TradePlan is the ordered list of swaps that carries out a recommendation's trades
+type TradePlan struct {
+	QuoteToken       string   `json:"quote_token"` // Stablecoin sale proceeds are held in until buys execute
+	Swaps            []Swap   `json:"swaps"`
+	SkippedUSD       float64  `json:"skipped_usd"`   // Trades left out for being below the minimum size
+	ShortfallUSD     float64  `json:"shortfall_usd"` // Buys cut because cash and sale proceeds did not cover them
+	EstimatedCostUSD float64  `json:"estimated_cost_usd"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// Swap exchanges one token for another. Unit amounts are zero when a token's price is unknown.
+type Swap struct {
+	Step           int        `json:"step"`
+	FromToken      string     `json:"from_token"`
+	ToToken        string     `json:"to_token"`
+	Chain          string     `json:"chain"`
+	AmountIn       float64    `json:"amount_in"`
+	AmountInUSD    float64    `json:"amount_in_usd"`
+	ExpectedOut    float64    `json:"expected_out"`
+	ExpectedOutUSD float64    `json:"expected_out_usd"`
+	MinAmountOut   float64    `json:"min_amount_out"` // Expected output less the slippage tolerance
+	EstimatedCost  *TradeCost `json:"estimated_cost,omitempty"`
+
+	// Pool simulation results, set when reserves for a route are known
+	Route       []RouteHop `json:"route,omitempty"`
+	PriceImpact float64    `json:"price_impact,omitempty"` // Output lost to moving the pool price, excluding fees
+}
+
+// RouteHop is one pool a simulated swap passes through
+type RouteHop struct {
+	PoolID      string  `json:"pool_id"`
+	DEX         string  `json:"dex"`
+	TokenIn     string  `json:"token_in"`
+	TokenOut    string  `json:"token_out"`
+	AmountIn    float64 `json:"amount_in"`
+	AmountOut   float64 `json:"amount_out"`
+	PriceImpact float64 `json:"price_impact"`
+}
+
+// PortfolioProjection describes an allocation, either held now or after following a recommendation
+type PortfolioProjection struct {
+	Portfolio       Portfolio          `json:"portfolio"` // One position per token with normalized weights
+	Weights         map[string]float64 `json:"weights"`
+	ExpectedReturn  float64            `json:"expected_return"`
+	Diversification float64            `json:"diversification"` // 1 - HHI
+	RiskMetrics     RiskMetrics        `json:"risk_metrics"`
+}
+
+// Explanation is the structured rationale behind a recommendation
+type Explanation struct {
+	Before  PortfolioMetrics    `json:"before"`
+	After   PortfolioMetrics    `json:"after"` // Metrics once every action is followed
+	Actions []ActionExplanation `json:"actions"`
+	Summary string              `json:"summary"` // Rendered from Before, After and Actions
+	Locale  string              `json:"locale"`  // Locale Summary was rendered in
+}
+
+// PortfolioMetrics are the headline characteristics of an allocation
+type PortfolioMetrics struct {
+	ExpectedReturn float64 `json:"expected_return"`
+	Volatility     float64 `json:"volatility"`
+	SharpeRatio    float64 `json:"sharpe_ratio"`
+	HHI            float64 `json:"hhi"` // Herfindahl-Hirschman concentration index
+}
+
+// ActionExplanation lists why a single action was recommended
+type ActionExplanation struct {
+	Type    string         `json:"type"`
+	Token   string         `json:"token"`
+	Drivers []ActionDriver `json:"drivers"`
+}
+
+// Action driver kinds
+const (
+	DriverDrift            = "drift"
+	DriverExpectedReturn   = "expected_return"
+	DriverRiskContribution = "risk_contribution"
+	DriverConstraint       = "constraint"
+)
+
+// ActionDriver is one quantity an action changes, or a rule it satisfies.
+// For constraints Before is the limit and After the observed value.
+type ActionDriver struct {
+	Kind   string  `json:"kind"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`         // After - Before
+	Rule   string  `json:"rule,omitempty"` // Constraint that triggered or bounded the action
+}
+
+// ConfidenceBreakdown explains how a recommendation's confidence was derived
+type ConfidenceBreakdown struct {
+	Raw        float64               `json:"raw"`        // Probability of beating holding net of costs, before calibration
+	Calibrated bool                  `json:"calibrated"` // Whether past outcomes adjusted Raw
+	Components []ConfidenceComponent `json:"components"`
+}
+
+// ConfidenceComponent is one input of a confidence score
+type ConfidenceComponent struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"` // 0-1 scale, higher supports the recommendation
+	Detail string  `json:"detail"`
 }
 
 // RebalanceAction represents a single rebalancing action
 type RebalanceAction struct {
-	Type         string  `json:"type"` // "buy", "sell", "rebalance"
+	Type         string  `json:"type"` // "buy", "sell", "rebalance", "move"
 	Token        string  `json:"token"`
-	Amount       float64 `json:"amount"`
+	Amount       float64 `json:"amount"` // USD traded, or moved between venues
 	TargetWeight float64 `json:"target_weight"`
 	Priority     int     `json:"priority"`
+
+	// Yield venue details, set on "move" actions
+	FromVenue     string         `json:"from_venue,omitempty"`
+	ToVenue       string         `json:"to_venue,omitempty"`
+	Chain         string         `json:"chain,omitempty"`
+	PoolID        string         `json:"pool_id,omitempty"`
+	CurrentAPY    float64        `json:"current_apy,omitempty"`
+	TargetAPY     float64        `json:"target_apy,omitempty"`
+	RiskScore     float64        `json:"risk_score,omitempty"`
+	RiskBreakdown *RiskBreakdown `json:"risk_breakdown,omitempty"`
+
+	EstimatedCost *TradeCost     `json:"estimated_cost,omitempty"`
+	Trigger       *ActionTrigger `json:"trigger,omitempty"`
+}
+
+// TradeCost estimates what executing an action costs and what it is expected to earn, in USD
+type TradeCost struct {
+	Chain           string  `json:"chain"`
+	GasUSD          float64 `json:"gas_usd"`
+	FeeUSD          float64 `json:"fee_usd"`
+	SlippageUSD     float64 `json:"slippage_usd"`
+	TotalUSD        float64 `json:"total_usd"`
+	ExpectedBenefit float64 `json:"expected_benefit_usd"` // Risk-adjusted gain over the cost model's benefit horizon
 }
 
 // RiskMetrics represents portfolio risk metrics
@@ -72,15 +376,241 @@ type RiskMetrics struct {
 	MaxDrawdown float64   `json:"max_drawdown"`
 	Beta        float64   `json:"beta"`
 	Timestamp   time.Time `json:"timestamp"`
+
+	Attribution *RiskAttribution     `json:"attribution,omitempty"`
+	Benchmark   *BenchmarkComparison `json:"benchmark,omitempty"` // Basis of Beta
+}
+
+// RiskAttribution breaks portfolio risk down by token
+type RiskAttribution struct {
+	PortfolioID string `json:"portfolio_id"`
+	Covariance  string `json:"covariance"` // "price_history" when estimated from prices, otherwise "assumed"
+
+	Volatility float64 `json:"volatility"` // Annualized volatility under the covariance
+	VaR95      float64 `json:"var_95"`     // Daily parametric VaR as a share of value, negative for losses
+
+	DiversificationRatio float64 `json:"diversification_ratio"` // Weighted token volatility over portfolio volatility
+	EffectiveBets        float64 `json:"effective_bets"`        // Entropy-based number of independent risk sources
+
+	Positions []PositionRisk `json:"positions"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// PositionRisk is one token's contribution to portfolio risk
+type PositionRisk struct {
+	Token      string  `json:"token"`
+	Weight     float64 `json:"weight"`
+	Volatility float64 `json:"volatility"`
+
+	MarginalRisk    float64 `json:"marginal_risk"`    // Change in portfolio volatility per unit of weight
+	ComponentRisk   float64 `json:"component_risk"`   // Weight times marginal risk; components sum to volatility
+	RiskShare       float64 `json:"risk_share"`       // Component risk over volatility; shares sum to one
+	ComponentVaR95  float64 `json:"component_var_95"` // Components sum to VaR95
+	VaRContribution float64 `json:"var_contribution"` // Component VaR over VaR95
+}
+
+// EngineInfo identifies the engine build and configuration that produced a result
+type EngineInfo struct {
+	Version    string `json:"version"`
+	ConfigHash string `json:"config_hash"`
+}
+
+// MarketSnapshot captures the market state a result was computed against
+type MarketSnapshot struct {
+	Indicators *MarketIndicators  `json:"indicators,omitempty"`
+	Prices     map[string]float64 `json:"prices,omitempty"` // USD price by token symbol
+	CapturedAt time.Time          `json:"captured_at"`
+}
+
+// Recommendation record kinds
+const (
+	RecordKindRecommendation = "recommendation"
+	RecordKindRiskReport     = "risk_report"
+)
+
+// RecommendationRecord is an audited engine result stored with the inputs that produced it
+type RecommendationRecord struct {
+	ID             string                   `json:"id"`
+	Kind           string                   `json:"kind"`
+	Owner          string                   `json:"owner"`
+	PortfolioID    string                   `json:"portfolio_id"`
+	Portfolio      Portfolio                `json:"portfolio"`
+	Recommendation *RebalanceRecommendation `json:"recommendation,omitempty"`
+	RiskMetrics    *RiskMetrics             `json:"risk_metrics,omitempty"`
+	Market         MarketSnapshot           `json:"market"`
+	Engine         EngineInfo               `json:"engine"`
+	CreatedAt      time.Time                `json:"created_at"`
+
+	// Realized outcomes keyed by horizon ("1d", "7d", "30d"), filled in as horizons elapse
+	Outcomes map[string]RecommendationOutcome `json:"outcomes,omitempty"`
+}
+
+// Recommendation outcome statuses
+const (
+	OutcomeStatusEvaluated        = "evaluated"
+	OutcomeStatusInsufficientData = "insufficient_data"
+)
+
+// RecommendationOutcome compares following a recommendation with holding over one horizon
+type RecommendationOutcome struct {
+	Horizon           string    `json:"horizon"`
+	Status            string    `json:"status"`
+	HoldReturn        float64   `json:"hold_return"`
+	RecommendedReturn float64   `json:"recommended_return"`
+	ExcessReturn      float64   `json:"excess_return"` // RecommendedReturn - HoldReturn
+	Actionable        bool      `json:"actionable"`    // Whether the recommendation changed any weight or venue
+	Hit               bool      `json:"hit"`           // Actionable and beat holding
+	Coverage          float64   `json:"coverage"`      // Share of portfolio weight with price data
+	EvaluatedAt       time.Time `json:"evaluated_at"`
+}
+
+// EngineScorecard aggregates recommendation outcomes of one engine version over one horizon
+type EngineScorecard struct {
+	EngineVersion      string             `json:"engine_version"`
+	Horizon            string             `json:"horizon"`
+	Evaluated          int                `json:"evaluated"`
+	Actionable         int                `json:"actionable"`
+	Hits               int                `json:"hits"`
+	HitRate            float64            `json:"hit_rate"` // Hits / Actionable
+	MeanExcessReturn   float64            `json:"mean_excess_return"`
+	MedianExcessReturn float64            `json:"median_excess_return"`
+	MeanConfidence     float64            `json:"mean_confidence"`
+	Calibration        []ConfidenceBucket `json:"calibration"`
+}
+
+// ConfidenceBucket compares stated confidence with the realized hit rate
+type ConfidenceBucket struct {
+	MinConfidence    float64 `json:"min_confidence"`
+	MaxConfidence    float64 `json:"max_confidence"`
+	Count            int     `json:"count"`
+	MeanConfidence   float64 `json:"mean_confidence"`
+	HitRate          float64 `json:"hit_rate"`
+	MeanExcessReturn float64 `json:"mean_excess_return"`
+}
+
+// RecommendationQuery filters recommendation history
+type RecommendationQuery struct {
+	Owner       string
+	PortfolioID string
+	Kind        string
+	From        time.Time // Inclusive; zero means unbounded
+	To          time.Time // Exclusive; zero means unbounded
+	Limit       int       // Zero means no limit
+}
+
+// Named benchmarks; any other benchmark is a custom basket given by its weights
+const (
+	BenchmarkBTC       = "btc"
+	BenchmarkETH       = "eth"
+	BenchmarkMarketCap = "market_cap" // Market-cap-weighted index of the collected tokens
+)
+
+// Benchmark is a fixed-weight reference portfolio rebalanced at every holdings change
+type Benchmark struct {
+	Name    string             `json:"name"`
+	Weights map[string]float64 `json:"weights,omitempty"` // Empty for named benchmarks
+}
+
+// BenchmarkComparison measures a portfolio's returns relative to a benchmark. Returns are
+// annualized; alpha is Jensen's alpha over the risk-free rate.
+type BenchmarkComparison struct {
+	Benchmark    string             `json:"benchmark"`
+	Weights      map[string]float64 `json:"weights"`      // Benchmark weights at the time of comparison
+	Source       string             `json:"source"`       // "price_history" when estimated from daily returns, otherwise "assumed"
+	Observations int                `json:"observations"` // Daily returns behind price history estimates
+
+	PortfolioReturn  float64 `json:"portfolio_return"`
+	BenchmarkReturn  float64 `json:"benchmark_return"`
+	Beta             float64 `json:"beta"`
+	Alpha            float64 `json:"alpha"`
+	TrackingError    float64 `json:"tracking_error"`    // Volatility of the return difference
+	InformationRatio float64 `json:"information_ratio"` // Return difference over tracking error
+	Correlation      float64 `json:"correlation"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PerformanceAttribution explains a portfolio's return over a period. Periods are split at every
+// stored snapshot; returns are for the whole period, not annualized.
+type PerformanceAttribution struct {
+	PortfolioID string    `json:"portfolio_id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Periods     int       `json:"periods"`
+
+	StartValue float64 `json:"start_value"`
+	EndValue   float64 `json:"end_value"`
+	NetFlows   float64 `json:"net_flows"` // Deposits minus withdrawals implied by snapshot changes
+
+	TimeWeightedReturn  float64 `json:"time_weighted_return"`
+	MoneyWeightedReturn float64 `json:"money_weighted_return"`
+	PriceReturn         float64 `json:"price_return"`  // Share of the time-weighted return from price moves
+	IncomeReturn        float64 `json:"income_return"` // Share of the time-weighted return from yield
+
+	Benchmark       Benchmark `json:"benchmark"`
+	BenchmarkReturn float64   `json:"benchmark_return"`
+	ActiveReturn    float64   `json:"active_return"` // Time-weighted return minus benchmark return
+
+	Tokens    []TokenPerformance `json:"tokens"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// TokenPerformance is one token's part in a portfolio's return. Contributions sum to the
+// time-weighted return and Brinson effects sum to the active return.
+type TokenPerformance struct {
+	Token           string  `json:"token"`
+	AverageWeight   float64 `json:"average_weight"`
+	BenchmarkWeight float64 `json:"benchmark_weight"`
+	PriceReturn     float64 `json:"price_return"` // The token's own price return over the period
+
+	PriceContribution  float64 `json:"price_contribution"`
+	IncomeContribution float64 `json:"income_contribution"`
+	Contribution       float64 `json:"contribution"`
+
+	Allocation  float64 `json:"allocation"`  // Return from weighting the token differently to the benchmark
+	Selection   float64 `json:"selection"`   // Return from holding the token differently, i.e. its yield
+	Interaction float64 `json:"interaction"` // Joint effect of active weight and selection
 }
 
 // MarketAnalysis represents comprehensive market analysis
 type MarketAnalysis struct {
 	TokenAnalysis []TokenAnalysis `json:"token_analysis"`
 	Sentiment     MarketSentiment `json:"sentiment"`
+	Regime        *MarketRegime   `json:"regime,omitempty"`
 	Timestamp     time.Time       `json:"timestamp"`
 }
 
+// Market regimes
+const (
+	RegimeRiskOn  = "risk_on"  // Calm market trending up
+	RegimeRiskOff = "risk_off" // Calm market trending down
+	RegimeHighVol = "high_vol" // Realized volatility well above normal
+	RegimeUnknown = "unknown"  // Too little price history to classify
+)
+
+// Trends from moving-average structure
+const (
+	TrendUp    = "up"    // Price above the fast average, which is above the slow one
+	TrendDown  = "down"  // Price below the fast average, which is below the slow one
+	TrendMixed = "mixed" // Anything else
+)
+
+// MarketRegime classifies the market from the daily returns of a benchmark index
+type MarketRegime struct {
+	Regime    string `json:"regime"`
+	Trend     string `json:"trend,omitempty"`
+	Benchmark string `json:"benchmark"`
+	Reason    string `json:"reason"`
+
+	Volatility     float64 `json:"volatility"`      // Annualized realized volatility over the fast window
+	LongVolatility float64 `json:"long_volatility"` // Annualized realized volatility over the lookback
+	FastAverage    float64 `json:"fast_average"`    // Moving averages relative to the latest index level
+	SlowAverage    float64 `json:"slow_average"`
+	Observations   int     `json:"observations"` // Daily returns behind the classification
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // TokenAnalysis represents analysis for a specific token
 type TokenAnalysis struct {
 	Token           string  `json:"token"`
@@ -99,15 +629,65 @@ type MarketSentiment struct {
 	BullishSentiment float64 `json:"bullish_sentiment"`
 	BearishSentiment float64 `json:"bearish_sentiment"`
 	NeutralSentiment float64 `json:"neutral_sentiment"`
+
+	Classification string               `json:"classification,omitempty"`
+	Source         string               `json:"source,omitempty"`
+	Components     []SentimentComponent `json:"components,omitempty"` // Breakdown of the local composite index
+	History        []TimeSeriesPoint    `json:"history,omitempty"`    // Daily index values from the same source
+}
+
+// Fear & Greed classifications, using alternative.me's bands
+const (
+	SentimentExtremeFear  = "extreme_fear"  // 0-24
+	SentimentFear         = "fear"          // 25-46
+	SentimentNeutral      = "neutral"       // 47-54
+	SentimentGreed        = "greed"         // 55-75
+	SentimentExtremeGreed = "extreme_greed" // 76-100
+)
+
+// Sources of the Fear & Greed index
+const (
+	SentimentSourceAlternativeMe = "alternative.me" // Published Crypto Fear & Greed index
+	SentimentSourceComposite     = "composite"      // Computed locally from collected market data
+	SentimentSourceDefault       = "default"        // Neutral placeholder when no data is available
+)
+
+// SentimentComponent is one input to the composite Fear & Greed index
+type SentimentComponent struct {
+	Name   string  `json:"name"`   // volatility, momentum, volume or btc_dominance
+	Score  float64 `json:"score"`  // 0 (extreme fear) to 100 (extreme greed)
+	Weight float64 `json:"weight"` // Share of the composite index
+	Value  float64 `json:"value"`  // Measurement behind the score
+	Detail string  `json:"detail"`
 }
 
 // MarketIndicators represents key market indicators
 type MarketIndicators struct {
-	FearGreedIndex float64   `json:"fear_greed_index"`
-	TotalMarketCap float64   `json:"total_market_cap"`
-	BTCDominance   float64   `json:"btc_dominance"`
-	ETHDominance   float64   `json:"eth_dominance"`
-	DeFiTVL        float64   `json:"defi_tvl"`
-	Volatility     float64   `json:"volatility"`
-	Timestamp      time.Time `json:"timestamp"`
+	FearGreedIndex   float64            `json:"fear_greed_index"`
+	TotalMarketCap   float64            `json:"total_market_cap"`
+	BTCDominance     float64            `json:"btc_dominance"`
+	ETHDominance     float64            `json:"eth_dominance"`
+	DeFiTVL          float64            `json:"defi_tvl"`
+	DeFiTVLChange24h float64            `json:"defi_tvl_change_24h"` // Percent change
+	ChainTVL         map[string]float64 `json:"chain_tvl,omitempty"`
+	TopProtocols     []ProtocolTVL      `json:"top_protocols,omitempty"`
+	Volatility       float64            `json:"volatility"`
+	Timestamp        time.Time          `json:"timestamp"`
+}
+
+// ProtocolTVL represents total value locked in a single DeFi protocol
+type ProtocolTVL struct {
+	Name     string   `json:"name"`
+	Slug     string   `json:"slug"`
+	Category string   `json:"category"`
+	Chains   []string `json:"chains"`
+	TVL      float64  `json:"tvl"`
+	Change1d float64  `json:"change_1d"` // Percent change
+	Change7d float64  `json:"change_7d"` // Percent change
+}
+
+// TimeSeriesPoint represents a single timestamped observation
+type TimeSeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
 }
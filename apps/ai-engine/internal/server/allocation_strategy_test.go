@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// TestSimpleHTTPServer_AllocationStrategy tests that the requested allocation strategy is validated and reported
+func TestSimpleHTTPServer_AllocationStrategy(t *testing.T) {
+	server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+	handler := server.withMiddleware(server.optimizePortfolioHandler)
+
+	optimize := func(strategy string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"id":          "test-portfolio-123",
+			"total_value": 100000,
+			"positions": []models.PortfolioPosition{
+				{Token: "BTC", Weight: 0.6, Amount: 1.5, Value: 60000},
+				{Token: "ETH", Weight: 0.3, Amount: 12, Value: 30000},
+				{Token: "LINK", Weight: 0.1, Amount: 700, Value: 10000},
+			},
+			"strategy": strategy,
+		})
+		req := httptest.NewRequest("POST", "/api/optimize-portfolio", bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("strategy is reported with risk contributions", func(t *testing.T) {
+		rr := optimize(models.StrategyHierarchicalRiskParity)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var recommendation models.RebalanceRecommendation
+		if err := json.Unmarshal(rr.Body.Bytes(), &recommendation); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		report := recommendation.Allocation
+		if report == nil || report.Strategy != models.StrategyHierarchicalRiskParity || len(report.Assets) != 3 {
+			t.Fatalf("Expected an hrp allocation over 3 tokens, got %+v", report)
+		}
+		total := 0.0
+		for _, asset := range report.Assets {
+			total += asset.RiskContribution
+		}
+		if total < 0.999 || total > 1.001 {
+			t.Errorf("Expected risk contributions summing to 1, got %f", total)
+		}
+	})
+
+	t.Run("unknown strategy is rejected", func(t *testing.T) {
+		rr := optimize("max_sharpe")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "strategy") {
+			t.Errorf("Expected the error to name the strategy, got %q", rr.Body.String())
+		}
+	})
+}
+
+// TestSimpleHTTPServer_MarketViews tests that market views are validated and applied
+func TestSimpleHTTPServer_MarketViews(t *testing.T) {
+	server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+	handler := server.withMiddleware(server.optimizePortfolioHandler)
+
+	optimize := func(views []models.MarketView) *httptest.ResponseRecorder {
+		portfolio := createTestPortfolio()
+		portfolio.Positions = append(portfolio.Positions, models.PortfolioPosition{Token: "ETH", Weight: 0.4, Value: 40000})
+		body, _ := json.Marshal(map[string]interface{}{
+			"id":        portfolio.ID,
+			"positions": portfolio.Positions,
+			"views":     views,
+		})
+		req := httptest.NewRequest("POST", "/api/optimize-portfolio", bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("views are applied", func(t *testing.T) {
+		rr := optimize([]models.MarketView{{Type: models.ViewRelative, Token: "ETH", Versus: "BTC", Return: 0.05, Confidence: 0.6}})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var recommendation models.RebalanceRecommendation
+		if err := json.Unmarshal(rr.Body.Bytes(), &recommendation); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if recommendation.Allocation == nil || recommendation.Allocation.ViewsApplied != 1 {
+			t.Errorf("Expected one view applied, got %+v", recommendation.Allocation)
+		}
+	})
+
+	t.Run("invalid views are rejected", func(t *testing.T) {
+		rr := optimize([]models.MarketView{{Type: models.ViewRelative, Token: "ETH", Return: 0.05, Confidence: 0.6}})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "views") {
+			t.Errorf("Expected the error to name the views, got %q", rr.Body.String())
+		}
+	})
+}
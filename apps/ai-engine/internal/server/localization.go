@@ -0,0 +1,48 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// requestLocale picks the summary locale from the locale query parameter, then Accept-Language.
+// An unsupported locale parameter is a validation error; unsupported languages fall back to the default.
+func requestLocale(r *http.Request) (string, error) {
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		locale = strings.ToLower(locale)
+		if !services.SupportedLocale(locale) {
+			return "", ValidationError{Field: "locale", Message: "unsupported locale"}
+		}
+		return locale, nil
+	}
+
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		language, _, _ := strings.Cut(strings.TrimSpace(tag), ";")
+		language, _, _ = strings.Cut(language, "-")
+		if language = strings.ToLower(language); services.SupportedLocale(language) {
+			return language, nil
+		}
+	}
+	return services.DefaultLocale, nil
+}
+
+// localizeRecommendation re-renders the recommendation summary in locale when it was rendered in another
+func localizeRecommendation(recommendation *models.RebalanceRecommendation, locale string) {
+	explanation := recommendation.Explanation
+	if explanation == nil || explanation.Locale == locale {
+		return
+	}
+
+	summary, err := services.RenderExplanationSummary(explanation, locale)
+	if err != nil {
+		log.Printf("failed to localize recommendation summary: %v", err)
+		return
+	}
+	explanation.Summary = summary
+	explanation.Locale = locale
+	recommendation.Reasoning = summary
+}
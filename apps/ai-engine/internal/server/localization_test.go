@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// TestSimpleHTTPServer_LocalizedRecommendation tests that recommendation summaries follow the requested locale
+func TestSimpleHTTPServer_LocalizedRecommendation(t *testing.T) {
+	server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+	handler := server.withMiddleware(server.optimizePortfolioHandler)
+
+	portfolio := createTestPortfolio()
+	portfolio.Positions = append(portfolio.Positions, models.PortfolioPosition{Token: "ETH", Weight: 0.4, Value: 40000})
+	body, _ := json.Marshal(portfolio)
+
+	optimize := func(path, acceptLanguage string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+		setAuthHeaders(req)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name, path, acceptLanguage, locale string
+	}{
+		{"default locale", "/api/optimize-portfolio", "", "en"},
+		{"Accept-Language", "/api/optimize-portfolio", "fr-FR;q=0.9, es-MX;q=0.8", "es"},
+		{"locale parameter wins", "/api/optimize-portfolio?locale=en", "es", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := optimize(tt.path, tt.acceptLanguage)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+			}
+			var recommendation models.RebalanceRecommendation
+			if err := json.Unmarshal(rr.Body.Bytes(), &recommendation); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			if recommendation.Explanation == nil || recommendation.Explanation.Locale != tt.locale {
+				t.Fatalf("Expected a %s explanation, got %+v", tt.locale, recommendation.Explanation)
+			}
+			if recommendation.Reasoning != recommendation.Explanation.Summary {
+				t.Errorf("Expected reasoning to match the summary, got %q", recommendation.Reasoning)
+			}
+		})
+	}
+
+	t.Run("unsupported locale parameter", func(t *testing.T) {
+		rr := optimize("/api/optimize-portfolio?locale=xx", "")
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "locale") {
+			t.Errorf("Expected status code %d for locale, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	})
+}
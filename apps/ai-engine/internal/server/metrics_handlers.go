@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// providerMetricsHandler reports outcome counters, breaker state and budget use of the
+// providers behind the market data collector and the price feed
+//
+//	GET /api/provider-metrics
+func (s *SimpleHTTPServer) providerMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	providers := make(map[string]interface{})
+	if reporter, ok := s.dataCollector.(services.ProviderMetricsReporter); ok {
+		providers["market_data"] = reporter.ProviderMetrics()
+	}
+	if reporter, ok := s.priceStreamer.(services.ProviderMetricsReporter); ok {
+		providers["price_feed"] = reporter.ProviderMetrics()
+	}
+	if len(providers) == 0 {
+		http.Error(w, "Provider metrics not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"providers": providers,
+		"timestamp": time.Now(),
+	})
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// TestSimpleHTTPServer_ProviderMetrics tests that provider metrics of both collectors are served
+// and that request-path lookups may spend the reserved budget
+func TestSimpleHTTPServer_ProviderMetrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	config := services.DefaultProviderClientConfig()
+	config.RequestsPerMinute = map[string]int{host: 2}
+	config.ReservedRequestsPerMinute = map[string]int{host: 1}
+	client := services.NewProviderClient(config)
+
+	server := NewSimpleHTTPServer(NewMockAIEngine(), services.NewRealDataCollectorWithClient(client),
+		WithPriceStreamer(services.NewDataCollector()))
+
+	if _, err := client.Get(context.Background(), upstream.URL); err != nil {
+		t.Fatalf("Expected a background request within budget, got: %v", err)
+	}
+	lookup := server.withMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := client.Get(r.Context(), upstream.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+	})
+	req := httptest.NewRequest("GET", "/api/lookup", nil)
+	setAuthHeaders(req)
+	rr := httptest.NewRecorder()
+	lookup.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected the request-path lookup to use the reserve, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/provider-metrics", nil)
+	setAuthHeaders(req)
+	rr = httptest.NewRecorder()
+	server.withMiddleware(server.providerMetricsHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Providers map[string]struct {
+			Hosts map[string]struct {
+				BudgetUsed     int `json:"budget_used"`
+				BudgetReserved int `json:"budget_reserved"`
+			} `json:"hosts"`
+		} `json:"providers"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if _, ok := response.Providers["price_feed"]; !ok {
+		t.Errorf("Expected price feed metrics, got %s", rr.Body.String())
+	}
+	if metrics := response.Providers["market_data"].Hosts[host]; metrics.BudgetUsed != 2 || metrics.BudgetReserved != 1 {
+		t.Errorf("Expected 2 requests used with 1 reserved, got %+v", metrics)
+	}
+
+	t.Run("not available", func(t *testing.T) {
+		bare := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector())
+		req := httptest.NewRequest("GET", "/api/provider-metrics", nil)
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		bare.withMiddleware(bare.providerMetricsHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
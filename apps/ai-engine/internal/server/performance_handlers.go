@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// defaultPerformancePeriod is the lookback used when neither period nor from is given
+const defaultPerformancePeriod = "30d"
+
+// WithPerformanceAttributor enables the portfolio performance endpoint
+func WithPerformanceAttributor(attributor services.PerformanceAttributor) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.performance = attributor
+	}
+}
+
+// portfolioPerformance attributes a stored portfolio's returns over a period
+//
+//	GET /api/portfolios/{id}/performance?period=30d
+//	GET /api/portfolios/{id}/performance?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z
+func (s *SimpleHTTPServer) portfolioPerformance(w http.ResponseWriter, r *http.Request, owner, id string) {
+	if s.performance == nil {
+		http.Error(w, "Performance attribution not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	start, end, err := performancePeriod(r, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	attribution, err := s.performance.AttributePerformance(r.Context(), owner, id, start, end)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, attribution)
+	case errors.Is(err, store.ErrNotFound):
+		s.writeStoreError(w, "attribute performance", err)
+	case errors.Is(err, services.ErrInsufficientPerformanceData):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		log.Printf("failed to attribute performance of portfolio %s: %v", id, err)
+		http.Error(w, "Failed to attribute performance", http.StatusInternalServerError)
+	}
+}
+
+// performancePeriod reads the period from the query: from and to as RFC 3339 times, or a
+// period such as 7d ending at to (default now)
+func performancePeriod(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	end := now
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, ValidationError{Field: "to", Message: "must be an RFC 3339 time"}
+		}
+		end = parsed
+	}
+
+	if from := query.Get("from"); from != "" {
+		if query.Get("period") != "" {
+			return time.Time{}, time.Time{}, ValidationError{Field: "period", Message: "cannot be combined with from"}
+		}
+		start, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, ValidationError{Field: "from", Message: "must be an RFC 3339 time"}
+		}
+		if !end.After(start) {
+			return time.Time{}, time.Time{}, ValidationError{Field: "from", Message: "must be before to"}
+		}
+		return start, end, nil
+	}
+
+	period := query.Get("period")
+	if period == "" {
+		period = defaultPerformancePeriod
+	}
+	duration, err := services.ParseHorizon(period)
+	if err != nil {
+		return time.Time{}, time.Time{}, ValidationError{Field: "period", Message: err.Error()}
+	}
+	return end.Add(-duration), end, nil
+}
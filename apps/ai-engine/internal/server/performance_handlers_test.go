@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// mockPerformanceAttributor records the requested period and attributes known portfolios
+type mockPerformanceAttributor struct {
+	start, end time.Time
+}
+
+func (m *mockPerformanceAttributor) AttributePerformance(ctx context.Context, owner, portfolioID string, start, end time.Time) (*models.PerformanceAttribution, error) {
+	m.start, m.end = start, end
+	switch portfolioID {
+	case "pf-known":
+		return &models.PerformanceAttribution{PortfolioID: portfolioID, Start: start, End: end, TimeWeightedReturn: 0.05}, nil
+	case "pf-new":
+		return nil, fmt.Errorf("%w: no snapshots before %s", services.ErrInsufficientPerformanceData, end)
+	}
+	return nil, fmt.Errorf("portfolio %s: %w", portfolioID, store.ErrNotFound)
+}
+
+// TestSimpleHTTPServer_PortfolioPerformance tests the portfolio performance endpoint
+func TestSimpleHTTPServer_PortfolioPerformance(t *testing.T) {
+	portfolioStore, _ := store.NewFilePortfolioStore("")
+	attributor := &mockPerformanceAttributor{}
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+		WithPortfolioStore(portfolioStore), WithPerformanceAttributor(attributor), WithSessionSecret(testSessionSecret))
+	handler := server.withMiddleware(server.portfoliosHandler)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("default period", func(t *testing.T) {
+		rr := get("/api/portfolios/pf-known/performance")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var attribution models.PerformanceAttribution
+		if err := json.Unmarshal(rr.Body.Bytes(), &attribution); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if attribution.TimeWeightedReturn != 0.05 || attributor.end.Sub(attributor.start) != 30*24*time.Hour {
+			t.Errorf("Expected 30 days attributed, got %+v", attribution)
+		}
+	})
+
+	t.Run("explicit range", func(t *testing.T) {
+		rr := get("/api/portfolios/pf-known/performance?from=2024-01-01T00:00:00Z&to=2024-03-01T00:00:00Z")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if !attributor.start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !attributor.end.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected January to March, got %s to %s", attributor.start, attributor.end)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := map[string]int{
+			"/api/portfolios/pf-known/performance?period=2w":                                         http.StatusBadRequest,
+			"/api/portfolios/pf-known/performance?from=yesterday":                                    http.StatusBadRequest,
+			"/api/portfolios/pf-known/performance?from=2024-03-01T00:00:00Z&to=2024-01-01T00:00:00Z": http.StatusBadRequest,
+			"/api/portfolios/pf-known/performance?from=2024-01-01T00:00:00Z&period=7d":               http.StatusBadRequest,
+			"/api/portfolios/pf-missing/performance":                                                 http.StatusNotFound,
+			"/api/portfolios/pf-new/performance":                                                     http.StatusUnprocessableEntity,
+		}
+		for path, status := range tests {
+			if rr := get(path); rr.Code != status {
+				t.Errorf("Expected status code %d for %s, got %d", status, path, rr.Code)
+			}
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		unconfigured := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+			WithPortfolioStore(portfolioStore), WithSessionSecret(testSessionSecret))
+		req := httptest.NewRequest("GET", "/api/portfolios/pf-known/performance", nil)
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		unconfigured.withMiddleware(unconfigured.portfoliosHandler).ServeHTTP(rr, req)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
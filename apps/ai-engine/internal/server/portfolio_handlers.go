@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// WithPortfolioStore enables the /api/portfolios endpoints and portfolio_id lookups
+func WithPortfolioStore(portfolioStore store.PortfolioStore) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.portfolioStore = portfolioStore
+		s.portfolios = storeResolver{portfolioStore}
+	}
+}
+
+// storeResolver adapts a PortfolioStore to PortfolioResolver
+type storeResolver struct {
+	store store.PortfolioStore
+}
+
+// ResolvePortfolio returns the current version of a stored portfolio
+func (r storeResolver) ResolvePortfolio(ctx context.Context, owner, portfolioID string) (models.Portfolio, error) {
+	stored, err := r.store.Get(ctx, owner, portfolioID)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.Portfolio{}, ErrPortfolioNotFound
+	}
+	if err != nil {
+		return models.Portfolio{}, err
+	}
+	return stored.Portfolio, nil
+}
+
+// portfolioWriteRequest is the body of portfolio create and update requests
+type portfolioWriteRequest struct {
+	models.Portfolio
+	Name string `json:"name"`
+}
+
+// portfoliosHandler routes the portfolio registry endpoints
+//
+//	GET    /api/portfolios
+//	POST   /api/portfolios
+//	GET    /api/portfolios/{id}
+//	PUT    /api/portfolios/{id}
+//	DELETE /api/portfolios/{id}
+//	GET    /api/portfolios/{id}/versions
+//	GET    /api/portfolios/{id}/versions/{version}
+//	GET    /api/portfolios/{id}/performance
+func (s *SimpleHTTPServer) portfoliosHandler(w http.ResponseWriter, r *http.Request) {
+	if s.portfolioStore == nil {
+		http.Error(w, "Portfolio storage not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	owner, ok := requireWallet(w, r)
+	if !ok {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/portfolios"), "/"), "/")
+	if parts[0] == "" {
+		parts = nil
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		s.listPortfolios(w, r, owner)
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		s.createPortfolio(w, r, owner)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.getPortfolio(w, r, owner, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		s.updatePortfolio(w, r, owner, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.deletePortfolio(w, r, owner, parts[0])
+	case len(parts) == 2 && parts[1] == "versions" && r.Method == http.MethodGet:
+		s.listPortfolioVersions(w, r, owner, parts[0])
+	case len(parts) == 3 && parts[1] == "versions" && r.Method == http.MethodGet:
+		s.getPortfolioVersion(w, r, owner, parts[0], parts[2])
+	case len(parts) == 2 && parts[1] == "performance" && r.Method == http.MethodGet:
+		s.portfolioPerformance(w, r, owner, parts[0])
+	case len(parts) <= 3:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *SimpleHTTPServer) listPortfolios(w http.ResponseWriter, r *http.Request, owner string) {
+	portfolios, err := s.portfolioStore.List(r.Context(), owner)
+	if err != nil {
+		s.writeStoreError(w, "list portfolios", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"portfolios": portfolios,
+		"count":      len(portfolios),
+		"timestamp":  time.Now(),
+	})
+}
+
+func (s *SimpleHTTPServer) createPortfolio(w http.ResponseWriter, r *http.Request, owner string) {
+	request, ok := s.decodePortfolioWrite(w, r)
+	if !ok {
+		return
+	}
+
+	stored, err := s.portfolioStore.Create(r.Context(), owner, request.Name, request.Portfolio)
+	if err != nil {
+		s.writeStoreError(w, "create portfolio", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, stored)
+}
+
+func (s *SimpleHTTPServer) getPortfolio(w http.ResponseWriter, r *http.Request, owner, id string) {
+	stored, err := s.portfolioStore.Get(r.Context(), owner, id)
+	if err != nil {
+		s.writeStoreError(w, "get portfolio", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *SimpleHTTPServer) updatePortfolio(w http.ResponseWriter, r *http.Request, owner, id string) {
+	request, ok := s.decodePortfolioWrite(w, r)
+	if !ok {
+		return
+	}
+	if request.ID != "" && request.ID != id {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "id", Message: "does not match the URL"}), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.portfolioStore.Update(r.Context(), owner, id, request.Name, request.Portfolio)
+	if err != nil {
+		s.writeStoreError(w, "update portfolio", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *SimpleHTTPServer) deletePortfolio(w http.ResponseWriter, r *http.Request, owner, id string) {
+	if err := s.portfolioStore.Delete(r.Context(), owner, id); err != nil {
+		s.writeStoreError(w, "delete portfolio", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SimpleHTTPServer) listPortfolioVersions(w http.ResponseWriter, r *http.Request, owner, id string) {
+	versions, err := s.portfolioStore.Versions(r.Context(), owner, id)
+	if err != nil {
+		s.writeStoreError(w, "list portfolio versions", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"portfolio_id": id,
+		"versions":     versions,
+		"count":        len(versions),
+	})
+}
+
+func (s *SimpleHTTPServer) getPortfolioVersion(w http.ResponseWriter, r *http.Request, owner, id, versionParam string) {
+	version, err := strconv.Atoi(versionParam)
+	if err != nil || version <= 0 {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "version", Message: "must be a positive integer"}), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := s.portfolioStore.Version(r.Context(), owner, id, version)
+	if err != nil {
+		s.writeStoreError(w, "get portfolio version", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// decodePortfolioWrite decodes and validates a create or update body, writing errors itself.
+// Creates may omit the portfolio ID, which the store then generates
+func (s *SimpleHTTPServer) decodePortfolioWrite(w http.ResponseWriter, r *http.Request) (portfolioWriteRequest, bool) {
+	// Set max body size for security
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var request portfolioWriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("failed to decode portfolio request: %v", err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return request, false
+	}
+
+	// Creates without an ID get one from the store, and updates keep the URL's
+	validated := request.Portfolio
+	if validated.ID == "" {
+		validated.ID = "pending"
+	}
+	if err := s.validatePortfolio(validated); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return request, false
+	}
+	if len(request.Name) > 100 {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "name", Message: "must be at most 100 characters"}), http.StatusBadRequest)
+		return request, false
+	}
+	return request, true
+}
+
+// writeStoreError maps store errors to HTTP responses
+func (s *SimpleHTTPServer) writeStoreError(w http.ResponseWriter, operation string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "Portfolio already exists", http.StatusConflict)
+	default:
+		log.Printf("failed to %s: %v", operation, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes v as the response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// portfolioFromRequest decodes a posted portfolio, or loads the stored one named by
+// portfolio_id in the body or query string. It writes the error response itself.
+func (s *SimpleHTTPServer) portfolioFromRequest(w http.ResponseWriter, r *http.Request) (models.Portfolio, bool) {
+	portfolio, _, ok := s.recommendationRequest(w, r)
+	return portfolio, ok
+}
+
+// recommendationRequest decodes a portfolio request together with its recommendation options.
+// It writes the error response itself.
+func (s *SimpleHTTPServer) recommendationRequest(w http.ResponseWriter, r *http.Request) (models.Portfolio, models.RecommendationOptions, bool) {
+	// Set max body size for security
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var request struct {
+		models.Portfolio
+		PortfolioID string                  `json:"portfolio_id"`
+		Policy      *models.RebalancePolicy `json:"policy"`
+		Strategy    string                  `json:"strategy"`
+		Views       []models.MarketView     `json:"views"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("failed to decode portfolio request: %v", err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return models.Portfolio{}, models.RecommendationOptions{}, false
+	}
+
+	portfolioID := request.PortfolioID
+	if portfolioID == "" {
+		portfolioID = r.URL.Query().Get("portfolio_id")
+	}
+
+	portfolio := request.Portfolio
+	if portfolioID != "" && len(portfolio.Positions) == 0 {
+		resolved, status, err := s.resolvePortfolio(r, portfolioID)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return models.Portfolio{}, models.RecommendationOptions{}, false
+		}
+		portfolio = resolved
+	}
+
+	// Validate portfolio data
+	if err := s.validatePortfolio(portfolio); err != nil {
+		log.Printf("portfolio validation failed: %v", err)
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return models.Portfolio{}, models.RecommendationOptions{}, false
+	}
+
+	options := models.RecommendationOptions{Policy: request.Policy, Strategy: request.Strategy, Views: request.Views}
+	if options.Policy != nil {
+		if err := services.ValidateRebalancePolicy(*options.Policy); err != nil {
+			http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "policy", Message: err.Error()}), http.StatusBadRequest)
+			return models.Portfolio{}, models.RecommendationOptions{}, false
+		}
+	}
+	if _, err := services.NewAllocationStrategy(options.Strategy); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "strategy", Message: err.Error()}), http.StatusBadRequest)
+		return models.Portfolio{}, models.RecommendationOptions{}, false
+	}
+	if err := services.ValidateMarketViews(options.Views); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "views", Message: err.Error()}), http.StatusBadRequest)
+		return models.Portfolio{}, models.RecommendationOptions{}, false
+	}
+	return portfolio, options, true
+}
+
+// errOptionsUnsupported is returned when options are requested from an engine that ignores them
+var errOptionsUnsupported = errors.New("recommendation options are not supported by the configured engine")
+
+// recommend asks the engine for a recommendation, passing options to engines that accept them
+func (s *SimpleHTTPServer) recommend(ctx context.Context, portfolio models.Portfolio, options models.RecommendationOptions) (*models.RebalanceRecommendation, error) {
+	if recommender, ok := s.aiEngine.(services.OptionsRecommender); ok {
+		return recommender.RecommendWithOptions(ctx, portfolio, options)
+	}
+	if options.Policy != nil || options.Strategy != "" || len(options.Views) > 0 {
+		return nil, errOptionsUnsupported
+	}
+	return s.aiEngine.GetRebalanceRecommendation(ctx, portfolio)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// TestSimpleHTTPServer_PortfoliosHandler tests the portfolio registry endpoints
+func TestSimpleHTTPServer_PortfoliosHandler(t *testing.T) {
+	portfolioStore, err := store.NewFilePortfolioStore("")
+	if err != nil {
+		t.Fatalf("Failed to create portfolio store: %v", err)
+	}
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+		WithPortfolioStore(portfolioStore), WithSessionSecret(testSessionSecret))
+	handler := server.withMiddleware(server.portfoliosHandler)
+
+	do := func(method, path string, body interface{}, wallet string) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		setWalletSession(req, wallet)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	portfolio := createTestPortfolio()
+	portfolio.ID = ""
+	created := do("POST", "/api/portfolios", portfolioWriteRequest{Portfolio: portfolio, Name: "core"}, "0xtest")
+	if created.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	var stored models.StoredPortfolio
+	if err := json.Unmarshal(created.Body.Bytes(), &stored); err != nil {
+		t.Fatalf("Failed to parse JSON response: %v", err)
+	}
+	if stored.ID == "" || stored.Name != "core" || stored.Version != 1 {
+		t.Fatalf("Expected version 1 of a named portfolio with an ID, got %+v", stored)
+	}
+
+	t.Run("GET /api/portfolios lists owned portfolios", func(t *testing.T) {
+		rr := do("GET", "/api/portfolios", nil, "0xtest")
+		var response struct {
+			Count int `json:"count"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if rr.Code != http.StatusOK || response.Count != 1 {
+			t.Errorf("Expected 1 portfolio, got status %d count %d", rr.Code, response.Count)
+		}
+
+		rr = do("GET", "/api/portfolios", nil, "0xother")
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Count != 0 {
+			t.Errorf("Expected no portfolios for another wallet, got %d", response.Count)
+		}
+	})
+
+	t.Run("GET /api/portfolios/{id} enforces ownership", func(t *testing.T) {
+		if rr := do("GET", "/api/portfolios/"+stored.ID, nil, "0xtest"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if rr := do("GET", "/api/portfolios/"+stored.ID, nil, "0xother"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("PUT /api/portfolios/{id} creates a version", func(t *testing.T) {
+		update := createTestPortfolio()
+		update.ID = ""
+		update.Positions[0].Weight = 0.4
+		rr := do("PUT", "/api/portfolios/"+stored.ID, portfolioWriteRequest{Portfolio: update}, "0xtest")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		rr = do("GET", "/api/portfolios/"+stored.ID+"/versions", nil, "0xtest")
+		var response struct {
+			Versions []models.PortfolioVersion `json:"versions"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if len(response.Versions) != 2 {
+			t.Fatalf("Expected 2 versions, got %d", len(response.Versions))
+		}
+
+		rr = do("GET", "/api/portfolios/"+stored.ID+"/versions/1", nil, "0xtest")
+		var version models.PortfolioVersion
+		json.Unmarshal(rr.Body.Bytes(), &version)
+		if rr.Code != http.StatusOK || version.Portfolio.Positions[0].Weight != 0.6 {
+			t.Errorf("Expected version 1 with weight 0.6, got status %d %+v", rr.Code, version)
+		}
+	})
+
+	t.Run("invalid writes are rejected", func(t *testing.T) {
+		invalid := createTestPortfolio()
+		invalid.Positions = nil
+		if rr := do("POST", "/api/portfolios", portfolioWriteRequest{Portfolio: invalid}, "0xtest"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		duplicate := createTestPortfolio()
+		duplicate.ID = stored.ID
+		if rr := do("POST", "/api/portfolios", portfolioWriteRequest{Portfolio: duplicate}, "0xtest"); rr.Code != http.StatusConflict {
+			t.Errorf("Expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+
+		if rr := do("GET", "/api/portfolios/"+stored.ID+"/versions/zero", nil, "0xtest"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("engine endpoints accept portfolio_id", func(t *testing.T) {
+		optimize := server.withMiddleware(server.optimizePortfolioHandler)
+		req := httptest.NewRequest("POST", "/api/optimize-portfolio", bytes.NewBufferString(`{"portfolio_id":"`+stored.ID+`"}`))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		optimize.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		risk := server.withMiddleware(server.riskMetricsHandler)
+		req = httptest.NewRequest("POST", "/api/risk-metrics?portfolio_id=missing", bytes.NewBufferString(`{}`))
+		setAuthHeaders(req)
+		rr = httptest.NewRecorder()
+		risk.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("DELETE /api/portfolios/{id}", func(t *testing.T) {
+		if rr := do("DELETE", "/api/portfolios/"+stored.ID, nil, "0xtest"); rr.Code != http.StatusNoContent {
+			t.Errorf("Expected status code %d, got %d", http.StatusNoContent, rr.Code)
+		}
+		if rr := do("GET", "/api/portfolios/"+stored.ID, nil, "0xtest"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// TestSimpleHTTPServer_RebalancePolicy tests that a requested rebalancing policy is validated and applied
+func TestSimpleHTTPServer_RebalancePolicy(t *testing.T) {
+	optimize := func(server *SimpleHTTPServer, policy map[string]interface{}) *httptest.ResponseRecorder {
+		request := map[string]interface{}{
+			"id":          "test-portfolio-123",
+			"total_value": 100000,
+			"positions": []models.PortfolioPosition{
+				{Token: "BTC", Weight: 0.8, Amount: 2, Value: 80000},
+				{Token: "ETH", Weight: 0.2, Amount: 8, Value: 20000},
+			},
+			"policy": policy,
+		}
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest("POST", "/api/optimize-portfolio", bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.optimizePortfolioHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("valid policy is reported", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		rr := optimize(server, map[string]interface{}{"mode": "volatility", "cadence": "7d"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var recommendation models.RebalanceRecommendation
+		if err := json.Unmarshal(rr.Body.Bytes(), &recommendation); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if recommendation.Policy == nil || recommendation.Policy.Mode != models.PolicyModeVolatility || !recommendation.Policy.Due {
+			t.Errorf("Expected a due volatility policy, got %+v", recommendation.Policy)
+		}
+	})
+
+	t.Run("invalid policy is rejected", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		rr := optimize(server, map[string]interface{}{"mode": "weekly"})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "policy") {
+			t.Errorf("Expected the error to name the policy, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("engines without policy support reject policies", func(t *testing.T) {
+		server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector())
+		rr := optimize(server, map[string]interface{}{"mode": "calendar"})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// WithRecommendationStore records every recommendation and risk report and enables /api/recommendations
+func WithRecommendationStore(recommendationStore store.RecommendationStore) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.recommendations = recommendationStore
+	}
+}
+
+// WithScorecardProvider enables the recommendation scorecard endpoint
+func WithScorecardProvider(provider services.ScorecardProvider) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.scorecards = provider
+	}
+}
+
+// recordResult stores an engine result with its inputs and returns the record ID.
+// Failures are logged rather than surfaced so auditing never blocks a response.
+func (s *SimpleHTTPServer) recordResult(ctx context.Context, owner string, portfolio models.Portfolio, recommendation *models.RebalanceRecommendation, riskMetrics *models.RiskMetrics) string {
+	if s.recommendations == nil {
+		return ""
+	}
+
+	record := models.RecommendationRecord{
+		Owner:          owner,
+		PortfolioID:    portfolio.ID,
+		Portfolio:      portfolio,
+		Recommendation: recommendation,
+		RiskMetrics:    riskMetrics,
+		Market:         s.marketSnapshot(portfolio),
+	}
+	if recommendation != nil {
+		record.Kind = models.RecordKindRecommendation
+	} else {
+		record.Kind = models.RecordKindRiskReport
+	}
+	if describer, ok := s.aiEngine.(services.EngineDescriber); ok {
+		record.Engine = describer.EngineInfo()
+	}
+
+	saved, err := s.recommendations.Save(ctx, record)
+	if err != nil {
+		log.Printf("failed to record %s for portfolio %s: %v", record.Kind, portfolio.ID, err)
+		return ""
+	}
+	return saved.ID
+}
+
+// marketSnapshot captures current indicators and the cached prices of the portfolio's tokens;
+// it never fetches, so recording stays off the provider budget and inside the request deadline
+func (s *SimpleHTTPServer) marketSnapshot(portfolio models.Portfolio) models.MarketSnapshot {
+	snapshot := models.MarketSnapshot{CapturedAt: time.Now()}
+
+	if indicators, err := s.dataCollector.GetMarketIndicators(); err == nil {
+		snapshot.Indicators = indicators
+	}
+
+	cache, ok := s.priceStreamer.(services.CachedPriceProvider)
+	if !ok {
+		return snapshot
+	}
+	cached := cache.CachedPrices()
+	for _, position := range portfolio.Positions {
+		token := strings.ToUpper(position.Token)
+		price, ok := cached[token]
+		if !ok {
+			continue
+		}
+		if snapshot.Prices == nil {
+			snapshot.Prices = make(map[string]float64)
+		}
+		snapshot.Prices[token] = price.Price
+	}
+	return snapshot
+}
+
+// recommendationsHandler serves the recommendation audit trail of the authenticated wallet
+//
+//	GET /api/recommendations?portfolio_id=...&kind=recommendation&from=RFC3339&to=RFC3339&limit=50
+//	GET /api/recommendations/{id}
+func (s *SimpleHTTPServer) recommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.recommendations == nil {
+		http.Error(w, "Recommendation history not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	owner, ok := requireWallet(w, r)
+	if !ok {
+		return
+	}
+	if id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/recommendations"), "/"); id != "" {
+		if strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		record, err := s.recommendations.Get(r.Context(), owner, id)
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Recommendation not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to get recommendation %s: %v", id, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, record)
+		return
+	}
+
+	query, err := parseRecommendationQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+	query.Owner = owner
+
+	records, err := s.recommendations.Query(r.Context(), query)
+	if err != nil {
+		log.Printf("failed to query recommendations: %v", err)
+		http.Error(w, "Failed to load recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recommendations": records,
+		"count":           len(records),
+		"timestamp":       time.Now(),
+	})
+}
+
+// parseRecommendationQuery builds a history query from query parameters
+func parseRecommendationQuery(values url.Values) (models.RecommendationQuery, error) {
+	query := models.RecommendationQuery{
+		PortfolioID: values.Get("portfolio_id"),
+		Kind:        values.Get("kind"),
+		Limit:       50,
+	}
+
+	switch query.Kind {
+	case "", models.RecordKindRecommendation, models.RecordKindRiskReport:
+	default:
+		return query, ValidationError{Field: "kind", Message: "must be recommendation or risk_report"}
+	}
+
+	for _, bound := range []struct {
+		field  string
+		target *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		value := values.Get(bound.field)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, ValidationError{Field: bound.field, Message: "must be an RFC 3339 timestamp"}
+		}
+		*bound.target = parsed
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, ValidationError{Field: "from", Message: "must be before to"}
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 500 {
+			return query, ValidationError{Field: "limit", Message: "must be between 1 and 500"}
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// scorecardHandler reports hit rate and excess return of past recommendations per engine version
+//
+//	GET /api/scorecard?engine_version=1.1.0&horizon=7d
+func (s *SimpleHTTPServer) scorecardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.scorecards == nil {
+		http.Error(w, "Recommendation scoring not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	horizon := r.URL.Query().Get("horizon")
+	if horizon != "" {
+		if _, err := services.ParseHorizon(horizon); err != nil {
+			http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "horizon", Message: err.Error()}), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scorecards, err := s.scorecards.Scorecards(r.Context(), r.URL.Query().Get("engine_version"), horizon)
+	if err != nil {
+		log.Printf("failed to build scorecards: %v", err)
+		http.Error(w, "Failed to build scorecards", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scorecards": scorecards,
+		"timestamp":  time.Now(),
+	})
+}
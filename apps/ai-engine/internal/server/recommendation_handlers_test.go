@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// TestSimpleHTTPServer_RecommendationHistory tests that results are recorded and queryable
+func TestSimpleHTTPServer_RecommendationHistory(t *testing.T) {
+	recommendationStore, err := store.NewFileRecommendationStore("")
+	if err != nil {
+		t.Fatalf("Failed to create recommendation store: %v", err)
+	}
+	hub := services.NewHub[models.PriceData](1)
+	defer hub.Close()
+
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+		WithRecommendationStore(recommendationStore),
+		WithSessionSecret(testSessionSecret),
+		WithPriceStreamer(&mockPriceStreamer{hub: hub}),
+	)
+
+	portfolio := createTestPortfolio()
+	portfolio.Positions = append(portfolio.Positions, models.PortfolioPosition{Token: "ETH", Weight: 0.4, Value: 40000})
+	body, _ := json.Marshal(portfolio)
+
+	post := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		server.withMiddleware(handler).ServeHTTP(rr, req)
+		return rr
+	}
+	get := func(path, wallet string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		setWalletSession(req, wallet)
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.recommendationsHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	optimized := post(server.optimizePortfolioHandler, "/api/optimize-portfolio")
+	if optimized.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, optimized.Code)
+	}
+	recommendationID := optimized.Header().Get("X-Recommendation-ID")
+	if recommendationID == "" {
+		t.Fatal("Expected X-Recommendation-ID header")
+	}
+	if rr := post(server.riskMetricsHandler, "/api/risk-metrics"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	t.Run("GET /api/recommendations/{id}", func(t *testing.T) {
+		rr := get("/api/recommendations/"+recommendationID, "0xtest")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var record models.RecommendationRecord
+		if err := json.Unmarshal(rr.Body.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if record.Kind != models.RecordKindRecommendation || record.Recommendation == nil {
+			t.Errorf("Expected a stored recommendation, got %+v", record)
+		}
+		if len(record.Portfolio.Positions) != 2 {
+			t.Errorf("Expected the input portfolio to be stored, got %+v", record.Portfolio)
+		}
+		if record.Market.Indicators == nil || record.Market.Prices["ETH"] != 2500 {
+			t.Errorf("Expected indicators and ETH price in the market snapshot, got %+v", record.Market)
+		}
+	})
+
+	t.Run("GET /api/recommendations filters by portfolio and kind", func(t *testing.T) {
+		rr := get("/api/recommendations?portfolio_id="+portfolio.ID+"&kind=risk_report", "0xtest")
+		var response struct {
+			Recommendations []models.RecommendationRecord `json:"recommendations"`
+			Count           int                           `json:"count"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Count != 1 || response.Recommendations[0].RiskMetrics == nil {
+			t.Errorf("Expected one risk report, got %+v", response.Recommendations)
+		}
+
+		rr = get("/api/recommendations", "0xother")
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Count != 0 {
+			t.Errorf("Expected no records for another wallet, got %d", response.Count)
+		}
+	})
+
+	t.Run("other wallets cannot read records", func(t *testing.T) {
+		if rr := get("/api/recommendations/"+recommendationID, "0xother"); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("invalid queries are rejected", func(t *testing.T) {
+		for _, query := range []string{"kind=trade", "from=yesterday", "limit=0", "from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z"} {
+			if rr := get("/api/recommendations?"+query, "0xtest"); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d for %s, got %d", http.StatusBadRequest, query, rr.Code)
+			}
+		}
+	})
+}
+
+// mockScorecardProvider returns a fixed scorecard and records the filters it was called with
+type mockScorecardProvider struct {
+	engineVersion, horizon string
+}
+
+func (m *mockScorecardProvider) Scorecards(ctx context.Context, engineVersion, horizon string) ([]models.EngineScorecard, error) {
+	m.engineVersion, m.horizon = engineVersion, horizon
+	return []models.EngineScorecard{{EngineVersion: "1.1.0", Horizon: "7d", Actionable: 4, Hits: 3, HitRate: 0.75}}, nil
+}
+
+// TestSimpleHTTPServer_ScorecardHandler tests the recommendation scorecard endpoint
+func TestSimpleHTTPServer_ScorecardHandler(t *testing.T) {
+	provider := &mockScorecardProvider{}
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(), WithScorecardProvider(provider))
+	handler := server.withMiddleware(server.scorecardHandler)
+
+	t.Run("GET /api/scorecard passes filters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/scorecard?engine_version=1.1.0&horizon=7d", nil)
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		var response struct {
+			Scorecards []models.EngineScorecard `json:"scorecards"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if len(response.Scorecards) != 1 || response.Scorecards[0].HitRate != 0.75 {
+			t.Errorf("Expected the provider's scorecard, got %+v", response.Scorecards)
+		}
+		if provider.engineVersion != "1.1.0" || provider.horizon != "7d" {
+			t.Errorf("Expected filters 1.1.0 and 7d, got %s and %s", provider.engineVersion, provider.horizon)
+		}
+	})
+
+	t.Run("GET /api/scorecard with invalid horizon", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/scorecard?horizon=soon", nil)
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+// fetchFailingPriceStreamer fails the test when prices are fetched instead of read from the cache
+type fetchFailingPriceStreamer struct {
+	*mockPriceStreamer
+	t *testing.T
+}
+
+func (f fetchFailingPriceStreamer) GetLatestPrice(token string) (*models.PriceData, error) {
+	f.t.Errorf("Expected no price fetch, got one for %s", token)
+	return nil, fmt.Errorf("no price data for token %s", token)
+}
+
+// TestSimpleHTTPServer_MarketSnapshotCached tests that snapshots read cached prices only
+func TestSimpleHTTPServer_MarketSnapshotCached(t *testing.T) {
+	streamer := fetchFailingPriceStreamer{mockPriceStreamer: &mockPriceStreamer{}, t: t}
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(), WithPriceStreamer(streamer))
+
+	snapshot := server.marketSnapshot(models.Portfolio{Positions: []models.PortfolioPosition{
+		{Token: "eth", Weight: 0.5},
+		{Token: "USDC", Weight: 0.5},
+	}})
+	if len(snapshot.Prices) != 1 || snapshot.Prices["ETH"] != 2500 {
+		t.Errorf("Expected only the cached ETH price, got %v", snapshot.Prices)
+	}
+}
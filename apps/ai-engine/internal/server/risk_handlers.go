@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// riskAttributionHandler breaks a portfolio's risk down by token
+//
+//	POST /api/risk-attribution
+func (s *SimpleHTTPServer) riskAttributionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	attributor, ok := s.aiEngine.(services.RiskAttributor)
+	if !ok {
+		http.Error(w, "Risk attribution not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	portfolio, ok := s.portfolioFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	attribution, err := attributor.RiskAttribution(r.Context(), portfolio)
+	if err != nil {
+		log.Printf("failed to attribute risk: %v", err)
+		http.Error(w, "Failed to attribute risk", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, attribution)
+}
+
+// benchmarkComparisonHandler measures a portfolio against a named benchmark (btc, eth,
+// market_cap) or a basket of token weights, defaulting to the engine's benchmark
+//
+//	POST /api/benchmark-comparison?benchmark=BTC:0.6,ETH:0.4
+func (s *SimpleHTTPServer) benchmarkComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	comparer, ok := s.aiEngine.(services.BenchmarkComparer)
+	if !ok {
+		http.Error(w, "Benchmark comparison not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var benchmark models.Benchmark
+	if spec := r.URL.Query().Get("benchmark"); spec != "" {
+		parsed, err := services.ParseBenchmark(spec)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "benchmark", Message: err.Error()}), http.StatusBadRequest)
+			return
+		}
+		benchmark = parsed
+	}
+
+	portfolio, ok := s.portfolioFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	comparison, err := comparer.CompareToBenchmark(r.Context(), portfolio, benchmark)
+	if err != nil {
+		log.Printf("failed to compare to benchmark: %v", err)
+		http.Error(w, "Failed to compare to benchmark", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, comparison)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// TestSimpleHTTPServer_RiskAttributionHandler tests the risk attribution endpoint
+func TestSimpleHTTPServer_RiskAttributionHandler(t *testing.T) {
+	body, _ := json.Marshal(createTestPortfolio())
+	attribute := func(server *SimpleHTTPServer, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/risk-attribution", bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.riskAttributionHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("attribution by token", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		rr := attribute(server, http.MethodPost)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var attribution models.RiskAttribution
+		if err := json.Unmarshal(rr.Body.Bytes(), &attribution); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if attribution.PortfolioID != "test-portfolio-123" || len(attribution.Positions) == 0 || attribution.Volatility <= 0 {
+			t.Errorf("Expected an attribution of the portfolio, got %+v", attribution)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		if rr := attribute(server, http.MethodGet); rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+		}
+	})
+
+	t.Run("engines without attribution", func(t *testing.T) {
+		server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector())
+		if rr := attribute(server, http.MethodPost); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
+
+// TestSimpleHTTPServer_BenchmarkComparisonHandler tests the benchmark comparison endpoint
+func TestSimpleHTTPServer_BenchmarkComparisonHandler(t *testing.T) {
+	body, _ := json.Marshal(createTestPortfolio())
+	compare := func(server *SimpleHTTPServer, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/benchmark-comparison"+query, bytes.NewReader(body))
+		setAuthHeaders(req)
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.benchmarkComparisonHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("comparison against the requested benchmark", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		rr := compare(server, "?benchmark=BTC:0.5,ETH:0.5")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var comparison models.BenchmarkComparison
+		if err := json.Unmarshal(rr.Body.Bytes(), &comparison); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if comparison.Benchmark != "custom" || comparison.Weights["ETH"] != 0.5 || comparison.Beta <= 0 {
+			t.Errorf("Expected a comparison against the basket, got %+v", comparison)
+		}
+	})
+
+	t.Run("invalid benchmark", func(t *testing.T) {
+		server := NewSimpleHTTPServer(services.NewEnhancedAIEngine(), NewMockMarketDataCollector())
+		if rr := compare(server, "?benchmark=nasdaq"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("engines without comparison", func(t *testing.T) {
+		server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector())
+		if rr := compare(server, ""); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// walletContextKey holds the wallet proven by a verified session
+type walletContextKey struct{}
+
+// WithSessionSecret enables wallet-scoped endpoints for sessions signed with secret.
+// Without it portfolios, recommendation history and portfolio streams are unavailable,
+// because an unsigned X-Wallet-Address header proves nothing about ownership.
+func WithSessionSecret(secret []byte) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.sessionSecret = secret
+	}
+}
+
+// SignSession returns a session token proving wallet until expires. Tokens have the form
+// "<wallet>.<unix expiry>.<hex HMAC-SHA256 of wallet and expiry>", with the wallet lowercased,
+// and are issued by whatever verified the wallet signature at sign-in.
+func SignSession(secret []byte, wallet string, expires time.Time) string {
+	payload := strings.ToLower(wallet) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + sessionSignature(secret, payload)
+}
+
+// sessionSignature returns the hex HMAC of a session payload
+func sessionSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySession returns the wallet proven by an unexpired token signed with secret
+func verifySession(secret []byte, token string, now time.Time) (string, bool) {
+	if len(secret) == 0 {
+		return "", false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sessionSignature(secret, payload))) {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// withWallet returns r carrying the wallet proven by its session
+func withWallet(r *http.Request, wallet string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), walletContextKey{}, wallet))
+}
+
+// sessionWallet returns the wallet proven by the request's session, or "" when none was verified
+func sessionWallet(r *http.Request) string {
+	wallet, _ := r.Context().Value(walletContextKey{}).(string)
+	return wallet
+}
+
+// requireWallet returns the verified wallet of the request, answering 401 when there is none
+func requireWallet(w http.ResponseWriter, r *http.Request) (string, bool) {
+	wallet := sessionWallet(r)
+	if wallet == "" {
+		http.Error(w, "Signed wallet session required", http.StatusUnauthorized)
+		return "", false
+	}
+	return wallet, true
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+func TestVerifySession(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	token := SignSession(testSessionSecret, "0xABC", now.Add(time.Hour))
+
+	if wallet, ok := verifySession(testSessionSecret, token, now); !ok || wallet != "0xabc" {
+		t.Errorf("Expected the lowercased wallet 0xabc, got %q (%v)", wallet, ok)
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		at     time.Time
+	}{
+		{"expired", testSessionSecret, token, now.Add(time.Hour)},
+		{"other secret", []byte("other"), token, now},
+		{"no secret", nil, token, now},
+		{"forged wallet", testSessionSecret, strings.Replace(token, "0xabc", "0xdef", 1), now},
+		{"unsigned", testSessionSecret, "test-session", now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wallet, ok := verifySession(tt.secret, tt.token, tt.at); ok {
+				t.Errorf("Expected the session to be rejected, got wallet %q", wallet)
+			}
+		})
+	}
+}
+
+// TestSimpleHTTPServer_WalletSessions tests that wallet-scoped endpoints trust only signed sessions
+func TestSimpleHTTPServer_WalletSessions(t *testing.T) {
+	portfolioStore, _ := store.NewFilePortfolioStore("")
+	signed := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+		WithPortfolioStore(portfolioStore), WithSessionSecret(testSessionSecret))
+	unsigned := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(), WithPortfolioStore(portfolioStore))
+
+	tests := []struct {
+		name           string
+		server         *SimpleHTTPServer
+		session        string
+		wallet         string
+		expectedStatus int
+	}{
+		{"signed session", signed, SignSession(testSessionSecret, "0xtest", time.Now().Add(time.Hour)), "0xtest", http.StatusOK},
+		{"session proves the wallet", signed, SignSession(testSessionSecret, "0xtest", time.Now().Add(time.Hour)), "", http.StatusOK},
+		{"unsigned session", signed, "test-session", "0xtest", http.StatusUnauthorized},
+		{"session of another wallet", signed, SignSession(testSessionSecret, "0xother", time.Now().Add(time.Hour)), "0xtest", http.StatusUnauthorized},
+		{"expired session", signed, SignSession(testSessionSecret, "0xtest", time.Now().Add(-time.Minute)), "0xtest", http.StatusUnauthorized},
+		{"sessions not configured", unsigned, SignSession(testSessionSecret, "0xtest", time.Now().Add(time.Hour)), "0xtest", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/portfolios", nil)
+			req.Header.Set("X-Session-ID", tt.session)
+			req.Header.Set("X-Wallet-Address", tt.wallet)
+			rr := httptest.NewRecorder()
+			tt.server.withMiddleware(tt.server.portfoliosHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("streams take no wallet from the query", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/portfolios?session_id=test-session&wallet_address=0xtest", nil)
+		rr := httptest.NewRecorder()
+		signed.withStreamMiddleware(signed.portfoliosHandler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+}
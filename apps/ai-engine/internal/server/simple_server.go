@@ -3,14 +3,17 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/valkyriefinance/ai-engine/internal/health"
 	"github.com/valkyriefinance/ai-engine/internal/models"
 	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
 )
 
 // ValidationError represents a validation error with structured information
@@ -25,17 +28,61 @@ func (e ValidationError) Error() string {
 
 // SimpleHTTPServer is a basic HTTP server for the AI engine
 type SimpleHTTPServer struct {
-	aiEngine      services.AIEngine
-	dataCollector services.MarketDataCollector
-	server        *http.Server
+	aiEngine        services.AIEngine
+	dataCollector   services.MarketDataCollector
+	yieldStore      *services.YieldStore
+	yieldPredictor  services.YieldPredictor
+	priceStreamer   services.PriceStreamer
+	portfolios      PortfolioResolver
+	portfolioStore  store.PortfolioStore
+	recommendations store.RecommendationStore
+	scorecards      services.ScorecardProvider
+	performance     services.PerformanceAttributor
+	sessionSecret   []byte
+	server          *http.Server
+}
+
+// ServerOption configures optional dependencies of the HTTP server
+type ServerOption func(*SimpleHTTPServer)
+
+// WithYieldStore enables the yield opportunity endpoints
+func WithYieldStore(store *services.YieldStore) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.yieldStore = store
+	}
+}
+
+// WithYieldPredictor enables the yield prediction endpoint
+func WithYieldPredictor(predictor services.YieldPredictor) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.yieldPredictor = predictor
+	}
+}
+
+// WithPriceStreamer enables the price and recommendation streaming endpoints
+func WithPriceStreamer(streamer services.PriceStreamer) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.priceStreamer = streamer
+	}
+}
+
+// WithPortfolioResolver lets endpoints look up portfolios by portfolio_id
+func WithPortfolioResolver(resolver PortfolioResolver) ServerOption {
+	return func(s *SimpleHTTPServer) {
+		s.portfolios = resolver
+	}
 }
 
 // NewSimpleHTTPServer creates a new HTTP server
-func NewSimpleHTTPServer(aiEngine services.AIEngine, dataCollector services.MarketDataCollector) *SimpleHTTPServer {
-	return &SimpleHTTPServer{
+func NewSimpleHTTPServer(aiEngine services.AIEngine, dataCollector services.MarketDataCollector, opts ...ServerOption) *SimpleHTTPServer {
+	s := &SimpleHTTPServer{
 		aiEngine:      aiEngine,
 		dataCollector: dataCollector,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Start starts the HTTP server
@@ -62,9 +109,21 @@ func (s *SimpleHTTPServer) startServer(port int, healthChecker *health.HealthChe
 	}
 
 	mux.HandleFunc("/api/market-indicators", s.withMiddleware(s.marketIndicatorsHandler))
+	mux.HandleFunc("/api/provider-metrics", s.withMiddleware(s.providerMetricsHandler))
 	mux.HandleFunc("/api/optimize-portfolio", s.withMiddleware(s.optimizePortfolioHandler))
 	mux.HandleFunc("/api/risk-metrics", s.withMiddleware(s.riskMetricsHandler))
+	mux.HandleFunc("/api/risk-attribution", s.withMiddleware(s.riskAttributionHandler))
+	mux.HandleFunc("/api/benchmark-comparison", s.withMiddleware(s.benchmarkComparisonHandler))
 	mux.HandleFunc("/api/market-analysis", s.withMiddleware(s.marketAnalysisHandler))
+	mux.HandleFunc("/api/yields", s.withMiddleware(s.yieldsHandler))
+	mux.HandleFunc("/api/predict-yields", s.withMiddleware(s.predictYieldsHandler))
+	mux.HandleFunc("/api/portfolios", s.withMiddleware(s.portfoliosHandler))
+	mux.HandleFunc("/api/portfolios/", s.withMiddleware(s.portfoliosHandler))
+	mux.HandleFunc("/api/recommendations", s.withMiddleware(s.recommendationsHandler))
+	mux.HandleFunc("/api/recommendations/", s.withMiddleware(s.recommendationsHandler))
+	mux.HandleFunc("/api/scorecard", s.withMiddleware(s.scorecardHandler))
+	mux.HandleFunc("/api/stream/prices", s.withStreamMiddleware(s.streamPricesHandler))
+	mux.HandleFunc("/api/stream/recommendations", s.withStreamMiddleware(s.streamRecommendationsHandler))
 
 	s.server = &http.Server{
 		Addr:           fmt.Sprintf(":%d", port),
@@ -82,29 +141,51 @@ func (s *SimpleHTTPServer) startServer(port int, healthChecker *health.HealthChe
 	return nil
 }
 
+// allowedOrigins restricts CORS and WebSocket upgrades to known origins
+var allowedOrigins = []string{
+	"https://valkyriefinance-web.vercel.app",
+	"https://valkyrie.finance",
+	"http://localhost:3001", // Dev only
+}
+
+// isAllowedOrigin reports whether origin may access the API
+func isAllowedOrigin(origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // withMiddleware wraps handlers with common middleware
 func (s *SimpleHTTPServer) withMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return s.middleware(next, 30*time.Second, false)
+}
+
+// withStreamMiddleware wraps long-lived streaming handlers: no request timeout, and
+// credentials may come from query parameters because EventSource cannot set headers
+func (s *SimpleHTTPServer) withStreamMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return s.middleware(next, 0, true)
+}
+
+// middleware applies CORS, authentication, security headers and logging
+func (s *SimpleHTTPServer) middleware(next http.HandlerFunc, timeout time.Duration, queryAuth bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Add request timeout
-		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-		defer cancel()
-		r = r.WithContext(ctx)
-
-		// Restricted CORS headers - only allow known origins
-		allowedOrigins := []string{
-			"https://valkyriefinance-web.vercel.app",
-			"https://valkyrie.finance",
-			"http://localhost:3001", // Dev only
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
 		}
 
+		// Provider lookups made for a waiting client may spend the budget reserved for them
+		r = r.WithContext(services.WithInteractivePriority(r.Context()))
+
+		// Restricted CORS headers - only allow known origins
 		origin := r.Header.Get("Origin")
-		if origin != "" {
-			for _, allowed := range allowedOrigins {
-				if origin == allowed {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					break
-				}
-			}
+		if origin != "" && isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
@@ -120,6 +201,21 @@ func (s *SimpleHTTPServer) withMiddleware(next http.HandlerFunc) http.HandlerFun
 		// Basic authentication check - require session headers
 		sessionID := r.Header.Get("X-Session-ID")
 		walletAddress := r.Header.Get("X-Wallet-Address")
+		if queryAuth && sessionID == "" {
+			// Only a signed session proves a wallet, so no wallet address is taken from the query
+			sessionID = r.URL.Query().Get("session_id")
+			r.Header.Set("X-Session-ID", sessionID)
+		}
+
+		// A signed session proves its wallet, which must match any claimed address
+		if wallet, ok := verifySession(s.sessionSecret, sessionID, time.Now()); ok {
+			if walletAddress != "" && !strings.EqualFold(walletAddress, wallet) {
+				http.Error(w, "Session does not match wallet", http.StatusUnauthorized)
+				return
+			}
+			walletAddress = wallet
+			r = withWallet(r, wallet)
+		}
 
 		// Skip auth for health endpoint
 		if r.URL.Path != "/health" && r.URL.Path != "/api/health" {
@@ -236,29 +332,32 @@ func (s *SimpleHTTPServer) optimizePortfolioHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Set max body size for security
-	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
-
-	var portfolio models.Portfolio
-	if err := json.NewDecoder(r.Body).Decode(&portfolio); err != nil {
-		log.Printf("failed to decode portfolio request: %v", err)
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+	locale, err := requestLocale(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Validate portfolio data
-	if err := s.validatePortfolio(portfolio); err != nil {
-		log.Printf("portfolio validation failed: %v", err)
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+	portfolio, options, ok := s.recommendationRequest(w, r)
+	if !ok {
 		return
 	}
 
-	recommendation, err := s.aiEngine.GetRebalanceRecommendation(r.Context(), portfolio)
+	recommendation, err := s.recommend(r.Context(), portfolio, options)
+	if errors.Is(err, errOptionsUnsupported) {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "policy", Message: err.Error()}), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("failed to get rebalance recommendation: %v", err)
 		http.Error(w, "Failed to generate recommendation", http.StatusInternalServerError)
 		return
 	}
+	localizeRecommendation(recommendation, locale)
+
+	if id := s.recordResult(r.Context(), sessionWallet(r), portfolio, recommendation, nil); id != "" {
+		w.Header().Set("X-Recommendation-ID", id)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(recommendation); err != nil {
@@ -275,20 +374,8 @@ func (s *SimpleHTTPServer) riskMetricsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Set max body size for security
-	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
-
-	var portfolio models.Portfolio
-	if err := json.NewDecoder(r.Body).Decode(&portfolio); err != nil {
-		log.Printf("failed to decode portfolio request: %v", err)
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-		return
-	}
-
-	// Validate portfolio data
-	if err := s.validatePortfolio(portfolio); err != nil {
-		log.Printf("portfolio validation failed: %v", err)
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+	portfolio, ok := s.portfolioFromRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -299,6 +386,10 @@ func (s *SimpleHTTPServer) riskMetricsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if id := s.recordResult(r.Context(), sessionWallet(r), portfolio, nil, riskMetrics); id != "" {
+		w.Header().Set("X-Recommendation-ID", id)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(riskMetrics); err != nil {
 		log.Printf("failed to encode risk metrics response: %v", err)
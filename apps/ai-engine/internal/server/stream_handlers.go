@@ -0,0 +1,516 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// streamHeartbeatInterval keeps idle streams alive through proxies
+const streamHeartbeatInterval = 15 * time.Second
+
+// Bounds of the interval_ms parameter, mirroring update_interval_ms in the proto
+const (
+	minPriceInterval          = 250 * time.Millisecond
+	maxPriceInterval          = time.Minute
+	minRecommendationInterval = 5 * time.Second
+	maxRecommendationInterval = time.Hour
+	defaultRecommendationRate = time.Minute
+	maxStreamTokens           = 20
+)
+
+// ErrPortfolioNotFound is returned by resolvers for unknown or foreign portfolios
+var ErrPortfolioNotFound = errors.New("portfolio not found")
+
+// PortfolioResolver looks up a portfolio owned by a wallet address
+type PortfolioResolver interface {
+	ResolvePortfolio(ctx context.Context, owner, portfolioID string) (models.Portfolio, error)
+}
+
+// eventStream is a transport-neutral sink for streamed events
+type eventStream interface {
+	Send(event string, payload interface{}) error
+	Ping() error
+}
+
+// sseStream writes Server-Sent Events
+type sseStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// newSSEStream prepares the response for Server-Sent Events without writing anything,
+// so that failures can still be answered with an error status
+func newSSEStream(w http.ResponseWriter) (*sseStream, error) {
+	if !canFlush(w) {
+		return nil, fmt.Errorf("streaming not supported: %w", http.ErrNotSupported)
+	}
+	controller := http.NewResponseController(w)
+
+	// Streams outlive the server write timeout
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, fmt.Errorf("failed to clear write deadline: %w", err)
+	}
+	return &sseStream{w: w, controller: controller}, nil
+}
+
+// canFlush reports whether w, or a writer it wraps, can flush
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch writer := w.(type) {
+		case http.Flusher:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// start sends the event stream headers
+func (s *sseStream) start() error {
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	return s.controller.Flush()
+}
+
+// Send writes one named event with a JSON payload
+func (s *sseStream) Send(event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// Ping writes an SSE comment line
+func (s *sseStream) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// wsStream sends events as {"event": ..., "data": ...} text messages
+type wsStream struct {
+	conn     *wsConn
+	messages chan []byte
+	closed   chan struct{}
+}
+
+// newWSStream starts reading client messages so pings and close frames are answered
+func newWSStream(conn *wsConn) *wsStream {
+	stream := &wsStream{
+		conn:     conn,
+		messages: make(chan []byte, 1),
+		closed:   make(chan struct{}),
+	}
+	go func() {
+		defer close(stream.closed)
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case stream.messages <- message:
+			default: // Clients are not expected to send more than one message
+			}
+		}
+	}()
+	return stream
+}
+
+// Receive waits for the next client message
+func (s *wsStream) Receive(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case message := <-s.messages:
+		return message, nil
+	case <-s.closed:
+		return nil, errWebSocketClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, errors.New("timed out waiting for client message")
+	}
+}
+
+// Send writes one event envelope
+func (s *wsStream) Send(event string, payload interface{}) error {
+	data, err := json.Marshal(struct {
+		Event string      `json:"event"`
+		Data  interface{} `json:"data"`
+	}{event, payload})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+	return s.conn.WriteText(data)
+}
+
+// Ping sends a WebSocket ping
+func (s *wsStream) Ping() error {
+	return s.conn.Ping()
+}
+
+// streamPricesHandler pushes PriceData updates, mirroring the StreamPriceData RPC
+//
+//	GET /api/stream/prices?tokens=ETH,BTC&interval_ms=1000
+//
+// Without interval_ms every update is pushed as it arrives; with it, the latest price of
+// each changed token is pushed once per interval.
+func (s *SimpleHTTPServer) streamPricesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.priceStreamer == nil {
+		http.Error(w, "Price streaming not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	tokens, interval, err := parsePriceStreamRequest(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.serveStream(w, r, func(ctx context.Context, stream eventStream) error {
+		return s.streamPrices(ctx, stream, tokens, interval)
+	})
+}
+
+// parsePriceStreamRequest reads the tokens and interval_ms parameters
+func parsePriceStreamRequest(r *http.Request) ([]string, time.Duration, error) {
+	query := r.URL.Query()
+
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, token := range strings.Split(query.Get("tokens"), ",") {
+		token = strings.ToUpper(strings.TrimSpace(token))
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, 0, ValidationError{Field: "tokens", Message: "at least one token is required"}
+	}
+	if len(tokens) > maxStreamTokens {
+		return nil, 0, ValidationError{Field: "tokens", Message: fmt.Sprintf("maximum %d tokens allowed", maxStreamTokens)}
+	}
+
+	interval, err := parseStreamInterval(query.Get("interval_ms"), 0, minPriceInterval, maxPriceInterval)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tokens, interval, nil
+}
+
+// parseStreamInterval parses interval_ms, applying a default and bounds
+func parseStreamInterval(value string, fallback, min, max time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	ms, err := strconv.Atoi(value)
+	interval := time.Duration(ms) * time.Millisecond
+	if err != nil || interval < min || interval > max {
+		return 0, ValidationError{Field: "interval_ms", Message: fmt.Sprintf("must be between %d and %d", min.Milliseconds(), max.Milliseconds())}
+	}
+	return interval, nil
+}
+
+// streamPrices sends a snapshot of current prices, then updates until ctx ends or the feed closes
+func (s *SimpleHTTPServer) streamPrices(ctx context.Context, stream eventStream, tokens []string, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan models.PriceData, len(tokens)*4)
+	feedsClosed := make(chan struct{}, len(tokens))
+	for _, token := range tokens {
+		sub := s.priceStreamer.Subscribe(ctx, token)
+		go func() {
+			defer func() { feedsClosed <- struct{}{} }()
+			for data := range sub.C {
+				select {
+				case updates <- data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	for _, token := range tokens {
+		if data, err := s.priceStreamer.GetLatestPrice(token); err == nil {
+			if err := stream.Send("price", data); err != nil {
+				return err
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// A nil channel never fires, so without an interval updates are sent immediately
+	var flush <-chan time.Time
+	pending := make(map[string]models.PriceData)
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	open := len(tokens)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-feedsClosed:
+			if open--; open == 0 {
+				return stream.Send("end", map[string]string{"reason": "price feed closed"})
+			}
+		case data := <-updates:
+			if interval == 0 {
+				if err := stream.Send("price", data); err != nil {
+					return err
+				}
+				continue
+			}
+			pending[data.Symbol] = data
+		case <-flush:
+			symbols := make([]string, 0, len(pending))
+			for symbol := range pending {
+				symbols = append(symbols, symbol)
+			}
+			sort.Strings(symbols)
+			for _, symbol := range symbols {
+				if err := stream.Send("price", pending[symbol]); err != nil {
+					return err
+				}
+				delete(pending, symbol)
+			}
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamRecommendationsHandler pushes re-computed recommendations, mirroring the StreamRecommendations RPC
+//
+//	GET  /api/stream/recommendations?portfolio_id=...&interval_ms=60000&locale=es
+//	POST /api/stream/recommendations?interval_ms=60000 with a Portfolio body
+//
+// WebSocket clients without a portfolio_id send the Portfolio as their first message.
+func (s *SimpleHTTPServer) streamRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	interval, err := parseStreamInterval(r.URL.Query().Get("interval_ms"), defaultRecommendationRate, minRecommendationInterval, maxRecommendationInterval)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := requestLocale(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var portfolio *models.Portfolio
+	switch {
+	case r.URL.Query().Get("portfolio_id") != "":
+		resolved, status, err := s.resolvePortfolio(r, r.URL.Query().Get("portfolio_id"))
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		portfolio = &resolved
+	case r.Method == http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+		var posted models.Portfolio
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			log.Printf("failed to decode portfolio request: %v", err)
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+		portfolio = &posted
+	case !isWebSocketUpgrade(r):
+		http.Error(w, "Validation error: portfolio_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if portfolio != nil {
+		if err := s.validatePortfolio(*portfolio); err != nil {
+			http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.serveStream(w, r, func(ctx context.Context, stream eventStream) error {
+		if portfolio == nil {
+			message, err := stream.(*wsStream).Receive(ctx, 30*time.Second)
+			if err != nil {
+				return err
+			}
+			var received models.Portfolio
+			if err := json.Unmarshal(message, &received); err != nil {
+				return stream.Send("error", map[string]string{"error": "Invalid JSON format"})
+			}
+			if err := s.validatePortfolio(received); err != nil {
+				return stream.Send("error", map[string]string{"error": fmt.Sprintf("Validation error: %v", err)})
+			}
+			portfolio = &received
+		}
+		return s.streamRecommendations(ctx, stream, sessionWallet(r), *portfolio, locale, interval)
+	})
+}
+
+// resolvePortfolio looks up a portfolio owned by the wallet proven by the request's session
+func (s *SimpleHTTPServer) resolvePortfolio(r *http.Request, portfolioID string) (models.Portfolio, int, error) {
+	if s.portfolios == nil {
+		return models.Portfolio{}, http.StatusServiceUnavailable, errors.New("Portfolio lookup not available")
+	}
+	owner := sessionWallet(r)
+	if owner == "" {
+		return models.Portfolio{}, http.StatusUnauthorized, errors.New("Signed wallet session required")
+	}
+
+	portfolio, err := s.portfolios.ResolvePortfolio(r.Context(), owner, portfolioID)
+	if errors.Is(err, ErrPortfolioNotFound) {
+		return models.Portfolio{}, http.StatusNotFound, errors.New("Portfolio not found")
+	}
+	if err != nil {
+		log.Printf("failed to resolve portfolio %s: %v", portfolioID, err)
+		return models.Portfolio{}, http.StatusInternalServerError, errors.New("Failed to load portfolio")
+	}
+	return portfolio, http.StatusOK, nil
+}
+
+// streamRecommendations sends a recommendation, summarized in locale, immediately and then once per interval.
+// Only recommendations whose actions differ from the last recorded one are added to the audit trail.
+func (s *SimpleHTTPServer) streamRecommendations(ctx context.Context, stream eventStream, owner string, portfolio models.Portfolio, locale string, interval time.Duration) error {
+	recorded, saved := "", false
+	send := func() error {
+		recommendation, err := s.aiEngine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			log.Printf("failed to get streamed rebalance recommendation: %v", err)
+			return stream.Send("error", map[string]string{"error": "Failed to generate recommendation"})
+		}
+		localizeRecommendation(recommendation, locale)
+		if key := actionsKey(recommendation.Actions); !saved || key != recorded {
+			if s.recordResult(ctx, owner, portfolio, recommendation, nil) != "" {
+				recorded, saved = key, true
+			}
+		}
+		return stream.Send("recommendation", recommendation)
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := send(); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// actionsKey identifies what a set of actions trades, ignoring amounts that move with prices
+func actionsKey(actions []models.RebalanceAction) string {
+	var key strings.Builder
+	for _, action := range actions {
+		fmt.Fprintf(&key, "%s:%s:%s:%s:%s:%.3f;", action.Type, action.Token, action.FromVenue, action.ToVenue, action.PoolID, action.TargetWeight)
+	}
+	return key.String()
+}
+
+// serveStream runs fn over SSE, or over a WebSocket when the client asks for an upgrade
+func (s *SimpleHTTPServer) serveStream(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, stream eventStream) error) {
+	if !isWebSocketUpgrade(r) {
+		stream, err := newSSEStream(w)
+		if err != nil {
+			log.Printf("failed to start event stream: %v", err)
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		if err := stream.start(); err != nil {
+			// The headers are sent, so the client only sees the stream end
+			log.Printf("failed to start event stream: %v", err)
+			return
+		}
+		if err := fn(r.Context(), stream); err != nil {
+			log.Printf("event stream %s ended: %v", r.URL.Path, err)
+		}
+		return
+	}
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("websocket upgrade failed: %v", err)
+		return
+	}
+
+	// The hijacked connection is no longer tied to the request context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := newWSStream(conn)
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx, stream) }()
+
+	var streamErr error
+	select {
+	case streamErr = <-done:
+	case <-stream.closed:
+		cancel()
+		streamErr = <-done
+	}
+
+	switch {
+	case streamErr == nil, errors.Is(streamErr, errWebSocketClosed), errors.Is(streamErr, context.Canceled):
+		conn.Close(wsCloseNormal, "")
+	default:
+		log.Printf("websocket stream %s ended: %v", r.URL.Path, streamErr)
+		conn.Close(wsCloseInternalErr, "stream error")
+	}
+}
@@ -0,0 +1,523 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// mockPriceStreamer serves prices from an in-memory hub
+type mockPriceStreamer struct {
+	hub *services.Hub[models.PriceData]
+}
+
+func (m *mockPriceStreamer) Subscribe(ctx context.Context, token string) *services.Subscription[models.PriceData] {
+	return m.hub.Subscribe(ctx, token)
+}
+
+func (m *mockPriceStreamer) CachedPrices() map[string]models.PriceData {
+	return map[string]models.PriceData{"ETH": {Symbol: "ETH", Price: 2500}}
+}
+
+func (m *mockPriceStreamer) GetLatestPrice(token string) (*models.PriceData, error) {
+	if token != "ETH" {
+		return nil, fmt.Errorf("no price data for token %s", token)
+	}
+	return &models.PriceData{Symbol: "ETH", Price: 2500}, nil
+}
+
+// waitForSubscribers blocks until the hub has n subscribers
+func waitForSubscribers(t *testing.T, hub *services.Hub[models.PriceData], n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for hub.Stats().Subscribers < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d subscribers", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// mockPortfolioResolver resolves a single portfolio owned by 0xtest
+type mockPortfolioResolver struct {
+	portfolio models.Portfolio
+}
+
+func (m *mockPortfolioResolver) ResolvePortfolio(ctx context.Context, owner, portfolioID string) (models.Portfolio, error) {
+	if owner != "0xtest" || portfolioID != m.portfolio.ID {
+		return models.Portfolio{}, ErrPortfolioNotFound
+	}
+	return m.portfolio, nil
+}
+
+func newStreamTestServer(t *testing.T, opts ...ServerOption) *httptest.Server {
+	t.Helper()
+
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(), append([]ServerOption{WithSessionSecret(testSessionSecret)}, opts...)...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/stream/prices", server.withStreamMiddleware(server.streamPricesHandler))
+	mux.HandleFunc("/api/stream/recommendations", server.withStreamMiddleware(server.streamRecommendationsHandler))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readSSEEvent reads the next named event, skipping comments
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// TestSimpleHTTPServer_StreamPricesSSE tests price streaming over Server-Sent Events
+func TestSimpleHTTPServer_StreamPricesSSE(t *testing.T) {
+	hub := services.NewHub[models.PriceData](4)
+	srv := newStreamTestServer(t, WithPriceStreamer(&mockPriceStreamer{hub: hub}))
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, query := range []string{"", "tokens=ETH&interval_ms=10"} {
+			req, _ := http.NewRequest("GET", srv.URL+"/api/stream/prices?"+query, nil)
+			setAuthHeaders(req)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Query %q: expected status %d, got %d", query, http.StatusBadRequest, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("QueryAuthAndUpdates", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// EventSource cannot set headers, so the signed session travels as a query parameter
+		session := url.QueryEscape(SignSession(testSessionSecret, "0xtest", time.Now().Add(time.Hour)))
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/stream/prices?tokens=eth,BTC&session_id="+session, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("Expected text/event-stream, got %s", contentType)
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		event, data := readSSEEvent(t, reader)
+		if event != "price" || !strings.Contains(data, `"price":2500`) {
+			t.Errorf("Expected ETH snapshot, got %s %s", event, data)
+		}
+
+		waitForSubscribers(t, hub, 2)
+		hub.Publish("BTC", models.PriceData{Symbol: "BTC", Price: 61000})
+
+		event, data = readSSEEvent(t, reader)
+		var price models.PriceData
+		if err := json.Unmarshal([]byte(data), &price); err != nil {
+			t.Fatalf("Failed to decode price event: %v", err)
+		}
+		if event != "price" || price.Symbol != "BTC" || price.Price != 61000 {
+			t.Errorf("Expected BTC update, got %s %+v", event, price)
+		}
+
+		// Disconnecting releases the subscriptions
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for hub.Stats().Subscribers != 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := hub.Stats().Subscribers; n != 0 {
+			t.Errorf("Expected subscriptions to be released, got %d", n)
+		}
+	})
+
+	t.Run("FeedClosed", func(t *testing.T) {
+		closing := services.NewHub[models.PriceData](4)
+		closedSrv := newStreamTestServer(t, WithPriceStreamer(&mockPriceStreamer{hub: closing}))
+
+		req, _ := http.NewRequest("GET", closedSrv.URL+"/api/stream/prices?tokens=BTC", nil)
+		setAuthHeaders(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		waitForSubscribers(t, closing, 1)
+		closing.Close()
+
+		if event, _ := readSSEEvent(t, bufio.NewReader(resp.Body)); event != "end" {
+			t.Errorf("Expected end event, got %s", event)
+		}
+	})
+}
+
+// TestSimpleHTTPServer_StreamRecommendations tests recommendation streaming
+func TestSimpleHTTPServer_StreamRecommendations(t *testing.T) {
+	portfolio := createTestPortfolio()
+
+	t.Run("PostedPortfolio", func(t *testing.T) {
+		srv := newStreamTestServer(t)
+		body, _ := json.Marshal(portfolio)
+
+		req, _ := http.NewRequest("POST", srv.URL+"/api/stream/recommendations?interval_ms=5000", bytes.NewReader(body))
+		setAuthHeaders(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		event, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+		if event != "recommendation" || !strings.Contains(data, `"portfolio_id":"test-portfolio"`) {
+			t.Errorf("Expected recommendation event, got %s %s", event, data)
+		}
+	})
+
+	t.Run("PortfolioID", func(t *testing.T) {
+		srv := newStreamTestServer(t, WithPortfolioResolver(&mockPortfolioResolver{portfolio: portfolio}))
+
+		tests := []struct {
+			query          string
+			expectedStatus int
+		}{
+			{"", http.StatusBadRequest},
+			{"portfolio_id=unknown", http.StatusNotFound},
+			{"portfolio_id=" + portfolio.ID + "&interval_ms=100", http.StatusBadRequest},
+			{"portfolio_id=" + portfolio.ID, http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			req, _ := http.NewRequest("GET", srv.URL+"/api/stream/recommendations?"+tt.query, nil)
+			setAuthHeaders(req)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Query %q: expected status %d, got %d", tt.query, tt.expectedStatus, resp.StatusCode)
+			}
+			if resp.StatusCode == http.StatusOK {
+				if event, _ := readSSEEvent(t, bufio.NewReader(resp.Body)); event != "recommendation" {
+					t.Errorf("Expected recommendation event, got %s", event)
+				}
+			}
+			resp.Body.Close()
+		}
+	})
+
+	t.Run("NoResolver", func(t *testing.T) {
+		srv := newStreamTestServer(t)
+		req, _ := http.NewRequest("GET", srv.URL+"/api/stream/recommendations?portfolio_id=abc", nil)
+		setAuthHeaders(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+		}
+	})
+}
+
+// testWSClient is a minimal WebSocket client for tests
+type testWSClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialTestWebSocket(t *testing.T, rawURL string) *testWSClient {
+	t.Helper()
+
+	target := strings.TrimPrefix(rawURL, "http://")
+	host, path, _ := strings.Cut(target, "/")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	fmt.Fprintf(conn, "GET /%s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\nX-Session-ID: s\r\nX-Wallet-Address: 0xtest\r\n\r\n", path, host, key)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") == "" {
+		t.Fatal("Expected Sec-WebSocket-Accept header")
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &testWSClient{conn: conn, reader: reader}
+}
+
+// readFrame reads one unmasked server frame
+func (c *testWSClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("Failed to read payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// readEvent reads the next event envelope, skipping control frames
+func (c *testWSClient) readEvent(t *testing.T) (string, json.RawMessage) {
+	t.Helper()
+	for {
+		opcode, payload := c.readFrame(t)
+		if opcode != wsOpText {
+			continue
+		}
+		var envelope struct {
+			Event string          `json:"event"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &envelope); err != nil {
+			t.Fatalf("Failed to decode envelope: %v", err)
+		}
+		return envelope.Event, envelope.Data
+	}
+}
+
+// writeFrame writes one masked client frame
+func (c *testWSClient) writeFrame(opcode byte, payload []byte) {
+	frame := []byte{0x80 | opcode}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+// TestSimpleHTTPServer_StreamWebSocket tests the WebSocket variant of both streams
+func TestSimpleHTTPServer_StreamWebSocket(t *testing.T) {
+	hub := services.NewHub[models.PriceData](4)
+	srv := newStreamTestServer(t, WithPriceStreamer(&mockPriceStreamer{hub: hub}))
+
+	t.Run("Prices", func(t *testing.T) {
+		client := dialTestWebSocket(t, srv.URL+"/api/stream/prices?tokens=ETH")
+
+		event, data := client.readEvent(t)
+		if event != "price" || !strings.Contains(string(data), `"price":2500`) {
+			t.Errorf("Expected ETH snapshot, got %s %s", event, data)
+		}
+
+		waitForSubscribers(t, hub, 1)
+		hub.Publish("ETH", models.PriceData{Symbol: "ETH", Price: 2600})
+		if event, data := client.readEvent(t); event != "price" || !strings.Contains(string(data), `"price":2600`) {
+			t.Errorf("Expected ETH update, got %s %s", event, data)
+		}
+
+		// Ping is answered with a pong carrying the same payload
+		client.writeFrame(wsOpPing, []byte("hi"))
+		if opcode, payload := client.readFrame(t); opcode != wsOpPong || string(payload) != "hi" {
+			t.Errorf("Expected pong, got opcode %d payload %q", opcode, payload)
+		}
+
+		client.writeFrame(wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseNormal))
+		if opcode, _ := client.readFrame(t); opcode != wsOpClose {
+			t.Errorf("Expected close frame, got opcode %d", opcode)
+		}
+	})
+
+	t.Run("RecommendationsFirstMessage", func(t *testing.T) {
+		client := dialTestWebSocket(t, srv.URL+"/api/stream/recommendations?interval_ms=5000")
+
+		body, _ := json.Marshal(createTestPortfolio())
+		client.writeFrame(wsOpText, body)
+
+		if event, data := client.readEvent(t); event != "recommendation" || !strings.Contains(string(data), `"confidence":0.85`) {
+			t.Errorf("Expected recommendation, got %s %s", event, data)
+		}
+	})
+
+	t.Run("RejectsUnknownOrigin", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/stream/prices?tokens=ETH", nil)
+		setAuthHeaders(req)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Origin", "https://evil.example")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+	})
+}
+
+// recordingStream collects sent events and runs onSend after each one
+type recordingStream struct {
+	events []string
+	onSend func(sent int)
+}
+
+func (r *recordingStream) Send(event string, payload interface{}) error {
+	r.events = append(r.events, event)
+	r.onSend(len(r.events))
+	return nil
+}
+
+func (r *recordingStream) Ping() error { return nil }
+
+// TestSimpleHTTPServer_StreamRecordsChanges tests that streams audit only changed recommendations
+func TestSimpleHTTPServer_StreamRecordsChanges(t *testing.T) {
+	recommendationStore, _ := store.NewFileRecommendationStore("")
+	engine := NewMockAIEngine()
+	server := NewSimpleHTTPServer(engine, NewMockMarketDataCollector(), WithRecommendationStore(recommendationStore))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &recordingStream{onSend: func(sent int) {
+		switch sent {
+		case 2:
+			// Amounts move with prices without changing the trade
+			changed := *engine.rebalanceRecommendation
+			changed.Actions = append([]models.RebalanceAction{}, changed.Actions...)
+			changed.Actions[0].Amount *= 2
+			engine.rebalanceRecommendation = &changed
+		case 3:
+			changed := *engine.rebalanceRecommendation
+			changed.Actions = append([]models.RebalanceAction{}, changed.Actions...)
+			changed.Actions[0].TargetWeight += 0.1
+			engine.rebalanceRecommendation = &changed
+		case 5:
+			cancel()
+		}
+	}}
+
+	if err := server.streamRecommendations(ctx, stream, "0xabc", createTestPortfolio(), "en", time.Millisecond); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(stream.events) != 5 {
+		t.Fatalf("Expected 5 recommendations sent, got %v", stream.events)
+	}
+
+	records, _ := recommendationStore.Query(context.Background(), models.RecommendationQuery{Owner: "0xabc"})
+	if len(records) != 2 {
+		t.Errorf("Expected the first and the changed recommendation recorded, got %d records", len(records))
+	}
+}
+
+// plainResponseWriter is a ResponseWriter that cannot flush
+type plainResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *plainResponseWriter) Header() http.Header         { return w.header }
+func (w *plainResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainResponseWriter) WriteHeader(code int)        { w.code = code }
+
+// TestSimpleHTTPServer_StreamWithoutFlush tests that unflushable responses fail before any stream headers
+func TestSimpleHTTPServer_StreamWithoutFlush(t *testing.T) {
+	server := createTestServer()
+	w := &plainResponseWriter{header: make(http.Header)}
+	req := httptest.NewRequest("GET", "/api/stream/prices", nil)
+
+	server.serveStream(w, req, func(ctx context.Context, stream eventStream) error {
+		t.Error("Expected the stream not to start")
+		return nil
+	})
+
+	if w.code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.code)
+	}
+	if contentType := w.header.Get("Content-Type"); contentType == "text/event-stream" {
+		t.Errorf("Expected no event stream headers, got %s", contentType)
+	}
+
+	wrapped := &unwrappingResponseWriter{ResponseWriter: w, inner: httptest.NewRecorder()}
+	if !canFlush(wrapped) || canFlush(w) {
+		t.Error("Expected only the writer wrapping a recorder to flush")
+	}
+}
+
+// unwrappingResponseWriter hides the flusher it wraps behind Unwrap
+type unwrappingResponseWriter struct {
+	http.ResponseWriter
+	inner http.ResponseWriter
+}
+
+func (w *unwrappingResponseWriter) Unwrap() http.ResponseWriter { return w.inner }
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// yieldsHandler lists stored yield opportunities
+//
+//	GET /api/yields?chain=&protocol=&token=&min_tvl=&max_risk=&sort=&limit=
+func (s *SimpleHTTPServer) yieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.yieldStore == nil {
+		http.Error(w, "Yield data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := parseYieldFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	yields := s.yieldStore.Query(filter)
+
+	response := map[string]interface{}{
+		"yields":      yields,
+		"count":       len(yields),
+		"last_update": s.yieldStore.LastIngest(),
+		"timestamp":   time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("failed to encode yields response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseYieldFilter builds a yield filter from query parameters
+func parseYieldFilter(query url.Values) (services.YieldFilter, error) {
+	filter := services.YieldFilter{
+		Chain:    query.Get("chain"),
+		Protocol: query.Get("protocol"),
+		Token:    query.Get("token"),
+		SortBy:   query.Get("sort"),
+		Limit:    100,
+	}
+
+	switch filter.SortBy {
+	case "", services.YieldSortRiskAdjustedAPY, services.YieldSortAPY, services.YieldSortTVL:
+	default:
+		return filter, ValidationError{Field: "sort", Message: "must be one of risk_adjusted_apy, apy, tvl"}
+	}
+
+	if value := query.Get("min_tvl"); value != "" {
+		minTVL, err := strconv.ParseFloat(value, 64)
+		if err != nil || minTVL < 0 {
+			return filter, ValidationError{Field: "min_tvl", Message: "must be a non-negative number"}
+		}
+		filter.MinTVL = minTVL
+	}
+
+	if value := query.Get("max_risk"); value != "" {
+		maxRisk, err := strconv.ParseFloat(value, 64)
+		if err != nil || maxRisk < 0 || maxRisk > 1 {
+			return filter, ValidationError{Field: "max_risk", Message: "must be between 0 and 1"}
+		}
+		filter.MaxRisk = maxRisk
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return filter, ValidationError{Field: "limit", Message: "must be between 1 and 1000"}
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// predictYieldsHandler forecasts pool APYs, mirroring the PredictYields RPC. The service has
+// no gRPC server, so this endpoint is the only way the RPC is served.
+//
+//	POST /api/predict-yields {"protocols": [...], "tokens": [...], "prediction_period": "7d"}
+func (s *SimpleHTTPServer) predictYieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.yieldPredictor == nil {
+		http.Error(w, "Yield predictions not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Set max body size for security
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var request struct {
+		Protocols        []string `json:"protocols"`
+		Tokens           []string `json:"tokens"`
+		PredictionPeriod string   `json:"prediction_period"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("failed to decode yield prediction request: %v", err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if request.PredictionPeriod == "" {
+		request.PredictionPeriod = "7d" // Default period
+	}
+	if _, err := services.ParsePredictionPeriod(request.PredictionPeriod); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", ValidationError{Field: "prediction_period", Message: "must be one of 1d, 7d, 30d"}), http.StatusBadRequest)
+		return
+	}
+	if len(request.Protocols) > 20 || len(request.Tokens) > 20 {
+		http.Error(w, "Maximum 20 protocols and 20 tokens allowed", http.StatusBadRequest)
+		return
+	}
+
+	predictions, err := s.yieldPredictor.PredictYields(r.Context(), request.Protocols, request.Tokens, request.PredictionPeriod)
+	if err != nil {
+		log.Printf("failed to predict yields: %v", err)
+		http.Error(w, "Failed to predict yields", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(predictions); err != nil {
+		log.Printf("failed to encode yield prediction response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/services"
+)
+
+// setAuthHeaders adds the session headers required by the middleware
+func setAuthHeaders(req *http.Request) {
+	setWalletSession(req, "0xtest")
+}
+
+// testSessionSecret signs the sessions of test requests
+var testSessionSecret = []byte("test-session-secret")
+
+// setWalletSession authenticates req as wallet with a signed session
+func setWalletSession(req *http.Request, wallet string) {
+	req.Header.Set("X-Session-ID", SignSession(testSessionSecret, wallet, time.Now().Add(time.Hour)))
+	req.Header.Set("X-Wallet-Address", wallet)
+}
+
+// TestSimpleHTTPServer_YieldsHandler tests the yield opportunities endpoint
+func TestSimpleHTTPServer_YieldsHandler(t *testing.T) {
+	store := services.NewYieldStore()
+	store.Ingest([]models.YieldData{
+		{PoolID: "a", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.05, TVL: 500e6, Risk: 0.2},
+		{PoolID: "b", Chain: "Arbitrum", Protocol: "gmx", Token: "WETH", APY: 0.30, TVL: 5e6, Risk: 0.9},
+	})
+
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(), WithYieldStore(store))
+	handler := server.withMiddleware(server.yieldsHandler)
+
+	t.Run("GET /api/yields with filters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/yields?max_risk=0.5&min_tvl=1000000", nil)
+		setAuthHeaders(req)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var response struct {
+			Yields []models.YieldOpportunity `json:"yields"`
+			Count  int                       `json:"count"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if response.Count != 1 || response.Yields[0].PoolID != "a" {
+			t.Errorf("Expected only pool a, got %+v", response.Yields)
+		}
+	})
+
+	t.Run("GET /api/yields with invalid max_risk", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/yields?max_risk=2", nil)
+		setAuthHeaders(req)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("GET /api/yields without store", func(t *testing.T) {
+		server := createTestServer()
+		req := httptest.NewRequest("GET", "/api/yields", nil)
+		setAuthHeaders(req)
+
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.yieldsHandler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
+
+// TestSimpleHTTPServer_PredictYieldsHandler tests the yield prediction endpoint
+func TestSimpleHTTPServer_PredictYieldsHandler(t *testing.T) {
+	store := services.NewYieldStore()
+	store.Ingest([]models.YieldData{
+		{PoolID: "a", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.05, TVL: 500e6, Risk: 0.2},
+		{PoolID: "b", Chain: "Arbitrum", Protocol: "gmx", Token: "WETH", APY: 0.30, TVL: 5e6, Risk: 0.9},
+	})
+
+	server := NewSimpleHTTPServer(NewMockAIEngine(), NewMockMarketDataCollector(),
+		WithYieldPredictor(services.NewYieldForecaster(store)))
+	post := func(server *SimpleHTTPServer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/predict-yields", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		setAuthHeaders(req)
+
+		rr := httptest.NewRecorder()
+		server.withMiddleware(server.predictYieldsHandler).ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(t *testing.T, rr *httptest.ResponseRecorder) models.YieldPredictionResponse {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var response models.YieldPredictionResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("POST /api/predict-yields with filters", func(t *testing.T) {
+		response := decode(t, post(server, `{"protocols": ["aave-v3"], "tokens": ["usdc"], "prediction_period": "30d"}`))
+		if len(response.Predictions) != 1 {
+			t.Fatalf("Expected a prediction for pool a, got %+v", response.Predictions)
+		}
+		prediction := response.Predictions[0]
+		if prediction.PoolID != "a" || prediction.Timeframe != "30d" || prediction.CurrentAPY != 0.05 {
+			t.Errorf("Expected a 30d forecast of pool a from 5%%, got %+v", prediction)
+		}
+		if prediction.LowerBound > prediction.PredictedAPY || prediction.UpperBound < prediction.PredictedAPY {
+			t.Errorf("Expected the forecast inside its interval, got %+v", prediction)
+		}
+	})
+
+	t.Run("POST /api/predict-yields with empty filters", func(t *testing.T) {
+		response := decode(t, post(server, `{}`))
+		if len(response.Predictions) != 2 {
+			t.Fatalf("Expected predictions for every pool, got %+v", response.Predictions)
+		}
+		for _, prediction := range response.Predictions {
+			if prediction.Timeframe != "7d" {
+				t.Errorf("Expected the default 7d period, got %+v", prediction)
+			}
+		}
+	})
+
+	t.Run("POST /api/predict-yields with invalid period", func(t *testing.T) {
+		if rr := post(server, `{"prediction_period": "1y"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("POST /api/predict-yields with invalid JSON", func(t *testing.T) {
+		if rr := post(server, `{"protocols": "aave-v3"`); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("POST /api/predict-yields without predictor", func(t *testing.T) {
+		if rr := post(createTestServer(), `{}`); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	assumedCorrelation = 0.3 // Correlation between tokens without price history
+	minVolatility      = 1e-4
+	riskParityMaxIter  = 1000
+	riskParityTol      = 1e-12
+)
+
+// Covariance sources reported on allocations
+const (
+	covarianceHistory = "price_history"
+	covarianceAssumed = "assumed"
+)
+
+// AllocationUniverse is what a strategy allocates across. Slices are indexed like Tokens and
+// Covariance holds annualized covariances.
+type AllocationUniverse struct {
+	Tokens          []string
+	ExpectedReturns []float64 // Annual price return plus achievable yield
+	Covariance      [][]float64
+}
+
+// volatility returns the annualized volatility of token i
+func (u AllocationUniverse) volatility(i int) float64 {
+	return math.Sqrt(math.Max(u.Covariance[i][i], 0))
+}
+
+// AllocationStrategy turns a universe into target weights summing to one
+type AllocationStrategy interface {
+	Name() string
+	Allocate(universe AllocationUniverse) []float64
+}
+
+// NewAllocationStrategy returns the named strategy; an empty name selects risk_adjusted
+func NewAllocationStrategy(name string) (AllocationStrategy, error) {
+	switch name {
+	case "", models.StrategyRiskAdjusted:
+		return RiskAdjustedStrategy{}, nil
+	case models.StrategyEqualWeight:
+		return EqualWeightStrategy{}, nil
+	case models.StrategyInverseVolatility:
+		return InverseVolatilityStrategy{}, nil
+	case models.StrategyRiskParity:
+		return RiskParityStrategy{}, nil
+	case models.StrategyHierarchicalRiskParity:
+		return HierarchicalRiskParityStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unknown allocation strategy %q (use risk_adjusted, equal_weight, inverse_volatility, risk_parity or hrp)", name)
+}
+
+// RiskAdjustedStrategy weights tokens by expected return over volatility
+type RiskAdjustedStrategy struct{}
+
+// Name returns the strategy name
+func (RiskAdjustedStrategy) Name() string { return models.StrategyRiskAdjusted }
+
+// Allocate weights tokens by a Sharpe-like score
+func (RiskAdjustedStrategy) Allocate(universe AllocationUniverse) []float64 {
+	scores := make([]float64, len(universe.Tokens))
+	for i := range universe.Tokens {
+		scores[i] = universe.ExpectedReturns[i] / (universe.volatility(i) + 0.01) // Epsilon avoids division by zero
+	}
+	return normalizeSlice(scores)
+}
+
+// EqualWeightStrategy gives every token the same weight
+type EqualWeightStrategy struct{}
+
+// Name returns the strategy name
+func (EqualWeightStrategy) Name() string { return models.StrategyEqualWeight }
+
+// Allocate weights every token equally
+func (EqualWeightStrategy) Allocate(universe AllocationUniverse) []float64 {
+	weights := make([]float64, len(universe.Tokens))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return normalizeSlice(weights)
+}
+
+// InverseVolatilityStrategy weights tokens by the inverse of their volatility
+type InverseVolatilityStrategy struct{}
+
+// Name returns the strategy name
+func (InverseVolatilityStrategy) Name() string { return models.StrategyInverseVolatility }
+
+// Allocate weights tokens by one over volatility, ignoring correlations
+func (InverseVolatilityStrategy) Allocate(universe AllocationUniverse) []float64 {
+	weights := make([]float64, len(universe.Tokens))
+	for i := range weights {
+		weights[i] = 1 / math.Max(universe.volatility(i), minVolatility)
+	}
+	return normalizeSlice(weights)
+}
+
+// RiskParityStrategy finds the long-only weights at which every token contributes equally to
+// portfolio variance (equal risk contribution)
+type RiskParityStrategy struct{}
+
+// Name returns the strategy name
+func (RiskParityStrategy) Name() string { return models.StrategyRiskParity }
+
+// Allocate solves for equal risk contributions by cyclical coordinate descent, starting from
+// inverse volatility weights. Each step solves w_i (Σw)_i = 1/n exactly for w_i.
+func (RiskParityStrategy) Allocate(universe AllocationUniverse) []float64 {
+	n := len(universe.Tokens)
+	if n == 0 {
+		return nil
+	}
+	weights := InverseVolatilityStrategy{}.Allocate(universe)
+	budget := 1 / float64(n)
+
+	for iter := 0; iter < riskParityMaxIter; iter++ {
+		change := 0.0
+		for i := 0; i < n; i++ {
+			a := math.Max(universe.Covariance[i][i], minVolatility*minVolatility)
+			b := 0.0
+			for j := 0; j < n; j++ {
+				if j != i {
+					b += universe.Covariance[i][j] * weights[j]
+				}
+			}
+			next := (-b + math.Sqrt(b*b+4*a*budget)) / (2 * a)
+			change = math.Max(change, math.Abs(next-weights[i]))
+			weights[i] = next
+		}
+		if change < riskParityTol {
+			break
+		}
+	}
+	return normalizeSlice(weights)
+}
+
+// HierarchicalRiskParityStrategy clusters tokens on their correlations and splits risk
+// recursively between clusters (López de Prado, 2016)
+type HierarchicalRiskParityStrategy struct{}
+
+// Name returns the strategy name
+func (HierarchicalRiskParityStrategy) Name() string { return models.StrategyHierarchicalRiskParity }
+
+// Allocate orders tokens by single-linkage clustering of correlation distances, then bisects
+// the order, giving each half weight in inverse proportion to its inverse-variance variance
+func (HierarchicalRiskParityStrategy) Allocate(universe AllocationUniverse) []float64 {
+	n := len(universe.Tokens)
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	clusters := [][]int{hrpOrder(universe.Covariance)}
+	for len(clusters) > 0 {
+		var next [][]int
+		for _, cluster := range clusters {
+			if len(cluster) < 2 {
+				continue
+			}
+			left, right := cluster[:len(cluster)/2], cluster[len(cluster)/2:]
+			leftVar, rightVar := clusterVariance(universe.Covariance, left), clusterVariance(universe.Covariance, right)
+			alpha := 0.5
+			if leftVar+rightVar > 0 {
+				alpha = 1 - leftVar/(leftVar+rightVar)
+			}
+			for _, i := range left {
+				weights[i] *= alpha
+			}
+			for _, i := range right {
+				weights[i] *= 1 - alpha
+			}
+			next = append(next, left, right)
+		}
+		clusters = next
+	}
+	return normalizeSlice(weights)
+}
+
+// hrpOrder returns the leaf order of a single-linkage tree over correlation distances, so
+// similar tokens sit next to each other. Ties merge the lowest indices first.
+func hrpOrder(covariance [][]float64) []int {
+	n := len(covariance)
+	// Distance between tokens is sqrt((1-ρ)/2); clusters are joined on the Euclidean distance
+	// between their rows of that matrix, which accounts for how each relates to all others
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+		for j := range dist[i] {
+			dist[i][j] = math.Sqrt(math.Max(0, (1-correlationAt(covariance, i, j))/2))
+		}
+	}
+	between := make([][]float64, n)
+	for i := range between {
+		between[i] = make([]float64, n)
+		for j := range between[i] {
+			sum := 0.0
+			for k := 0; k < n; k++ {
+				d := dist[k][i] - dist[k][j]
+				sum += d * d
+			}
+			between[i][j] = math.Sqrt(sum)
+		}
+	}
+
+	clusters := make([][]int, n)
+	for i := range clusters {
+		clusters[i] = []int{i}
+	}
+	for len(clusters) > 1 {
+		bestA, bestB, best := 0, 1, math.Inf(1)
+		for a := 0; a < len(clusters); a++ {
+			for b := a + 1; b < len(clusters); b++ {
+				if d := linkage(between, clusters[a], clusters[b]); d < best {
+					bestA, bestB, best = a, b, d
+				}
+			}
+		}
+		merged := append(append([]int{}, clusters[bestA]...), clusters[bestB]...)
+		clusters[bestA] = merged
+		clusters = append(clusters[:bestB], clusters[bestB+1:]...)
+	}
+	if n == 0 {
+		return nil
+	}
+	return clusters[0]
+}
+
+// linkage returns the single-linkage distance between two clusters
+func linkage(dist [][]float64, a, b []int) float64 {
+	best := math.Inf(1)
+	for _, i := range a {
+		for _, j := range b {
+			best = math.Min(best, dist[i][j])
+		}
+	}
+	return best
+}
+
+// correlationAt returns the correlation of tokens i and j implied by the covariance
+func correlationAt(covariance [][]float64, i, j int) float64 {
+	if i == j {
+		return 1
+	}
+	denominator := math.Sqrt(covariance[i][i] * covariance[j][j])
+	if denominator <= 0 {
+		return 0
+	}
+	return math.Max(-1, math.Min(1, covariance[i][j]/denominator))
+}
+
+// clusterVariance returns the variance of a cluster held at inverse-variance weights
+func clusterVariance(covariance [][]float64, cluster []int) float64 {
+	weights := make([]float64, len(cluster))
+	for k, i := range cluster {
+		weights[k] = 1 / math.Max(covariance[i][i], minVolatility*minVolatility)
+	}
+	weights = normalizeSlice(weights)
+
+	variance := 0.0
+	for a, i := range cluster {
+		for b, j := range cluster {
+			variance += weights[a] * weights[b] * covariance[i][j]
+		}
+	}
+	return variance
+}
+
+// normalizeSlice scales weights to sum to one, or returns them unchanged when they sum to zero
+func normalizeSlice(weights []float64) []float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return weights
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
+
+// allocationUniverse collects the tokens of the positions with a covariance estimated from
+// daily price history, or assumed from token volatilities, and expected returns that blend
+// prior returns with the return model's views. The report describes the estimates without weights.
+func (e *EnhancedAIEngine) allocationUniverse(positions []models.PortfolioPosition, returnModel BlackLittermanConfig) (AllocationUniverse, *models.AllocationReport) {
+	yields := make(map[string]float64)
+	for _, position := range positions {
+		if yield, ok := yields[position.Token]; !ok || e.achievableYield(position) > yield {
+			yields[position.Token] = e.achievableYield(position)
+		}
+	}
+
+	universe := AllocationUniverse{Tokens: sortedTokens(yields)}
+	report := &models.AllocationReport{}
+	universe.Covariance, report.Covariance = e.covariance(universe.Tokens, yields)
+
+	prior, source := e.priorReturns(universe.Tokens, universe.Covariance, returnModel.RiskAversion)
+	posterior, applied := blendViews(universe.Tokens, universe.Covariance, prior, returnModel.Views, returnModel.Tau)
+	report.ReturnModel, report.ViewsApplied = source, applied
+
+	for i, token := range universe.Tokens {
+		universe.ExpectedReturns = append(universe.ExpectedReturns, posterior[i]+yields[token])
+		report.Assets = append(report.Assets, models.AssetAllocation{
+			Token:          token,
+			Volatility:     universe.volatility(i),
+			PriorReturn:    prior[i],
+			ExpectedReturn: posterior[i],
+		})
+	}
+	return universe, report
+}
+
+// covariance estimates the covariance of tokens, the keys of set, from daily price history,
+// or assumes it from token volatilities, and reports which it used
+func (e *EnhancedAIEngine) covariance(tokens []string, set map[string]float64) ([][]float64, string) {
+	if covariance, ok := e.historicalCovariance(tokens, set); ok {
+		return covariance, covarianceHistory
+	}
+	return e.assumedCovariance(tokens), covarianceAssumed
+}
+
+// assumedCovariance builds covariances from token volatilities and a common correlation
+func (e *EnhancedAIEngine) assumedCovariance(tokens []string) [][]float64 {
+	covariance := make([][]float64, len(tokens))
+	for i, a := range tokens {
+		covariance[i] = make([]float64, len(tokens))
+		for j, b := range tokens {
+			covariance[i][j] = e.getTokenRisk(a) * e.getTokenRisk(b)
+			if i != j {
+				covariance[i][j] *= assumedCorrelation
+			}
+		}
+	}
+	return covariance
+}
+
+// historicalCovariance estimates annualized covariances from aligned daily log returns
+func (e *EnhancedAIEngine) historicalCovariance(tokens []string, set map[string]float64) ([][]float64, bool) {
+	returns, count := e.alignedDailyReturns(set)
+	if count < minBootstrapReturns {
+		return nil, false
+	}
+
+	means := make([]float64, len(tokens))
+	for i, token := range tokens {
+		for _, r := range returns[token] {
+			means[i] += r
+		}
+		means[i] /= float64(count)
+	}
+	covariance := make([][]float64, len(tokens))
+	for i, a := range tokens {
+		covariance[i] = make([]float64, len(tokens))
+		for j, b := range tokens {
+			sum := 0.0
+			for k := 0; k < count; k++ {
+				sum += (returns[a][k] - means[i]) * (returns[b][k] - means[j])
+			}
+			covariance[i][j] = sum / float64(count-1) * 365
+		}
+	}
+	return covariance, true
+}
+
+// allocate runs a strategy over the positions and reports the risk of the resulting weights
+func (e *EnhancedAIEngine) allocate(positions []models.PortfolioPosition, strategy AllocationStrategy, returnModel BlackLittermanConfig) (map[string]float64, *models.AllocationReport) {
+	universe, report := e.allocationUniverse(positions, returnModel)
+	weights := strategy.Allocate(universe)
+	contributions, variance := riskBudget(universe.Covariance, weights)
+
+	allocations := make(map[string]float64, len(universe.Tokens))
+	for i, token := range universe.Tokens {
+		allocations[token] = weights[i]
+		report.Assets[i].Weight = weights[i]
+		report.Assets[i].RiskContribution = contributions[i]
+	}
+	report.Strategy = strategy.Name()
+	report.Volatility = math.Sqrt(math.Max(variance, 0))
+	sort.SliceStable(report.Assets, func(i, j int) bool {
+		return report.Assets[i].Weight > report.Assets[j].Weight
+	})
+	return allocations, report
+}
+
+// riskBudget returns each weight's share of portfolio variance, w_i (Σw)_i / w'Σw, and the variance
+func riskBudget(covariance [][]float64, weights []float64) ([]float64, float64) {
+	contributions := make([]float64, len(weights))
+	variance := 0.0
+	for i := range weights {
+		marginal := 0.0
+		for j := range weights {
+			marginal += covariance[i][j] * weights[j]
+		}
+		contributions[i] = weights[i] * marginal
+		variance += contributions[i]
+	}
+	if variance > 0 {
+		for i := range contributions {
+			contributions[i] /= variance
+		}
+	}
+	return contributions, variance
+}
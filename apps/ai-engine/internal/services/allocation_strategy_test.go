@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// clusteredUniverse has two pairs of highly correlated tokens that barely move together
+func clusteredUniverse() AllocationUniverse {
+	vols := []float64{0.2, 0.4, 0.3, 0.6}
+	correlations := [][]float64{
+		{1, 0.9, 0.1, 0.1},
+		{0.9, 1, 0.1, 0.1},
+		{0.1, 0.1, 1, 0.8},
+		{0.1, 0.1, 0.8, 1},
+	}
+	universe := AllocationUniverse{
+		Tokens:          []string{"A", "B", "C", "D"},
+		ExpectedReturns: []float64{0.05, 0.1, 0.08, 0.2},
+	}
+	for i := range vols {
+		row := make([]float64, len(vols))
+		for j := range vols {
+			row[j] = vols[i] * vols[j] * correlations[i][j]
+		}
+		universe.Covariance = append(universe.Covariance, row)
+	}
+	return universe
+}
+
+func TestAllocationStrategies(t *testing.T) {
+	universe := clusteredUniverse()
+
+	for _, name := range []string{"", models.StrategyRiskAdjusted, models.StrategyEqualWeight, models.StrategyInverseVolatility, models.StrategyRiskParity, models.StrategyHierarchicalRiskParity} {
+		t.Run("weights sum to one: "+name, func(t *testing.T) {
+			strategy, err := NewAllocationStrategy(name)
+			if err != nil {
+				t.Fatalf("Expected strategy %q, got %v", name, err)
+			}
+			total := 0.0
+			for _, w := range strategy.Allocate(universe) {
+				if w < 0 {
+					t.Errorf("Expected long-only weights, got %f", w)
+				}
+				total += w
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Errorf("Expected weights summing to 1, got %f", total)
+			}
+		})
+	}
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		if _, err := NewAllocationStrategy("max_sharpe"); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("inverse volatility", func(t *testing.T) {
+		weights := InverseVolatilityStrategy{}.Allocate(universe)
+		if math.Abs(weights[0]/weights[1]-2) > 1e-9 {
+			t.Errorf("Expected A at twice the weight of B, got %v", weights)
+		}
+	})
+
+	t.Run("risk parity equalizes risk contributions", func(t *testing.T) {
+		weights := RiskParityStrategy{}.Allocate(universe)
+		contributions, _ := riskBudget(universe.Covariance, weights)
+		for i, c := range contributions {
+			if math.Abs(c-0.25) > 1e-6 {
+				t.Errorf("Expected %s to contribute 25%% of risk, got %f", universe.Tokens[i], c)
+			}
+		}
+	})
+
+	t.Run("hrp keeps correlated tokens together", func(t *testing.T) {
+		order := hrpOrder(universe.Covariance)
+		position := make(map[int]int)
+		for k, i := range order {
+			position[i] = k
+		}
+		if math.Abs(float64(position[0]-position[1])) != 1 || math.Abs(float64(position[2]-position[3])) != 1 {
+			t.Errorf("Expected A next to B and C next to D, got %v", order)
+		}
+
+		weights := HierarchicalRiskParityStrategy{}.Allocate(universe)
+		if weights[0] <= weights[1] || weights[2] <= weights[3] {
+			t.Errorf("Expected the calmer token of each pair to weigh more, got %v", weights)
+		}
+		// The low-volatility cluster gets more weight than the high-volatility one
+		if weights[0]+weights[1] <= weights[2]+weights[3] {
+			t.Errorf("Expected more weight in the A/B cluster, got %v", weights)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_AllocationStrategy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	portfolio := models.Portfolio{ID: "pf-strategy", TotalValue: 100000, Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 0.5, Amount: 1.2, Value: 50000},
+		{Token: "ETH", Weight: 0.5, Amount: 20, Value: 50000},
+	}}
+
+	t.Run("risk parity report", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		recommendation, err := engine.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Strategy: models.StrategyRiskParity})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		report := recommendation.Allocation
+		if report == nil || report.Strategy != models.StrategyRiskParity || report.Covariance != covarianceAssumed || len(report.Assets) != 2 {
+			t.Fatalf("Expected a risk parity report on assumed covariance, got %+v", report)
+		}
+		for _, asset := range report.Assets {
+			if math.Abs(asset.RiskContribution-0.5) > 1e-6 {
+				t.Errorf("Expected %s to carry half the risk, got %f", asset.Token, asset.RiskContribution)
+			}
+		}
+		// ETH is the less volatile token, so it is bought
+		if report.Assets[0].Token != "ETH" || report.Assets[0].Weight <= 0.5 {
+			t.Errorf("Expected ETH overweighted, got %+v", report.Assets)
+		}
+	})
+
+	t.Run("default strategy is risk adjusted", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Allocation == nil || recommendation.Allocation.Strategy != models.StrategyRiskAdjusted {
+			t.Errorf("Expected the risk adjusted strategy, got %+v", recommendation.Allocation)
+		}
+	})
+
+	t.Run("covariance comes from price history when available", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now, 30)))
+		engine.now = func() time.Time { return now }
+		_, report := engine.calculateOptimalAllocations(portfolio.Positions, RiskParityStrategy{}, DefaultBlackLittermanConfig())
+		if report.Covariance != covarianceHistory {
+			t.Errorf("Expected covariance from price history, got %s", report.Covariance)
+		}
+	})
+
+	t.Run("unknown strategy is rejected", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		if _, err := engine.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Strategy: "kelly"}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	riskFreeRate        = 0.02 // Annual rate behind Sharpe ratios and alpha
+	customBenchmarkName = "custom"
+)
+
+// marketIndexTokens are the tokens the data collector tracks, weighted by market cap in the
+// market_cap benchmark
+var marketIndexTokens = []string{"BTC", "ETH", "LINK", "UNI"}
+
+// WithBenchmark sets the benchmark that portfolio beta and relative risk are measured against
+func WithBenchmark(benchmark models.Benchmark) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.benchmark = benchmark
+	}
+}
+
+// ParseBenchmark reads a named benchmark (btc, eth, market_cap) or a custom basket of
+// token weights such as "BTC:0.6,ETH:0.4"
+func ParseBenchmark(spec string) (models.Benchmark, error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.Contains(spec, ":") {
+		benchmark := models.Benchmark{Name: strings.ToLower(spec)}
+		return benchmark, ValidateBenchmark(benchmark)
+	}
+
+	benchmark := models.Benchmark{Name: customBenchmarkName, Weights: make(map[string]float64)}
+	for _, part := range strings.Split(spec, ",") {
+		token, weight, _ := strings.Cut(part, ":")
+		value, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil {
+			return models.Benchmark{}, fmt.Errorf("invalid benchmark weight %q for %q", weight, strings.TrimSpace(token))
+		}
+		benchmark.Weights[strings.ToUpper(strings.TrimSpace(token))] += value
+	}
+	return benchmark, ValidateBenchmark(benchmark)
+}
+
+// ValidateBenchmark checks that a benchmark is named or has positive token weights
+func ValidateBenchmark(benchmark models.Benchmark) error {
+	if len(benchmark.Weights) == 0 {
+		switch benchmark.Name {
+		case models.BenchmarkBTC, models.BenchmarkETH, models.BenchmarkMarketCap:
+			return nil
+		}
+		return fmt.Errorf("unknown benchmark %q (use btc, eth, market_cap or token weights)", benchmark.Name)
+	}
+	for token, weight := range benchmark.Weights {
+		if strings.TrimSpace(token) == "" {
+			return fmt.Errorf("benchmark weights need a token")
+		}
+		if !(weight > 0) || math.IsInf(weight, 0) {
+			return fmt.Errorf("benchmark weight for %s must be positive", token)
+		}
+	}
+	return nil
+}
+
+// CompareToBenchmark measures a portfolio against a benchmark, or against the engine's
+// benchmark when none is given
+func (e *EnhancedAIEngine) CompareToBenchmark(ctx context.Context, portfolio models.Portfolio, benchmark models.Benchmark) (*models.BenchmarkComparison, error) {
+	if len(portfolio.Positions) == 0 {
+		return nil, fmt.Errorf("failed to compare to benchmark: portfolio %s has no positions", portfolio.ID)
+	}
+	if benchmark.Name == "" && len(benchmark.Weights) == 0 {
+		benchmark = e.benchmark
+	}
+	if err := ValidateBenchmark(benchmark); err != nil {
+		return nil, fmt.Errorf("invalid benchmark: %w", err)
+	}
+	return e.compareToBenchmark(portfolio, benchmark)
+}
+
+// compareToBenchmark estimates relative risk from daily returns when price history covers
+// every priced token, and otherwise from assumed volatilities and expected returns
+func (e *EnhancedAIEngine) compareToBenchmark(portfolio models.Portfolio, benchmark models.Benchmark) (*models.BenchmarkComparison, error) {
+	benchmarkWeights, err := e.benchmarkWeights(benchmark)
+	if err != nil {
+		return nil, err
+	}
+	weights, _ := positionWeights(portfolio)
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("portfolio %s has no weighted positions", portfolio.ID)
+	}
+	for token := range weights {
+		weights[token] /= total
+	}
+
+	comparison := &models.BenchmarkComparison{
+		Benchmark: benchmark.Name,
+		Weights:   benchmarkWeights,
+		Timestamp: e.now(),
+	}
+	if comparison.Benchmark == "" {
+		comparison.Benchmark = customBenchmarkName
+	}
+	if !e.historicalComparison(comparison, weights, benchmarkWeights) {
+		e.assumedComparison(comparison, weights, benchmarkWeights)
+	}
+	return comparison, nil
+}
+
+// BenchmarkWeights resolves a valid benchmark to token weights summing to one
+func (e *EnhancedAIEngine) BenchmarkWeights(benchmark models.Benchmark) (map[string]float64, error) {
+	if err := ValidateBenchmark(benchmark); err != nil {
+		return nil, fmt.Errorf("invalid benchmark: %w", err)
+	}
+	return e.benchmarkWeights(benchmark)
+}
+
+// benchmarkWeights resolves a benchmark to token weights summing to one
+func (e *EnhancedAIEngine) benchmarkWeights(benchmark models.Benchmark) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	switch {
+	case len(benchmark.Weights) > 0:
+		for token, weight := range benchmark.Weights {
+			weights[strings.ToUpper(token)] += weight
+		}
+	case benchmark.Name == models.BenchmarkBTC:
+		weights["BTC"] = 1
+	case benchmark.Name == models.BenchmarkETH:
+		weights["ETH"] = 1
+	case benchmark.Name == models.BenchmarkMarketCap:
+		caps, ok := e.marketWeights(marketIndexTokens)
+		if !ok {
+			return nil, fmt.Errorf("market cap benchmark requires market cap data")
+		}
+		for i, token := range marketIndexTokens {
+			if caps[i] > 0 {
+				weights[token] = caps[i]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown benchmark %q", benchmark.Name)
+	}
+
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	for token := range weights {
+		weights[token] /= total
+	}
+	return weights, nil
+}
+
+// historicalComparison fills in the comparison from the simple daily returns of the portfolio
+// and benchmark held at fixed weights, assuming pegged tokens return nothing. It reports false
+// when price history is too short.
+func (e *EnhancedAIEngine) historicalComparison(comparison *models.BenchmarkComparison, weights, benchmarkWeights map[string]float64) bool {
+	priced := make(map[string]float64)
+	for _, set := range []map[string]float64{weights, benchmarkWeights} {
+		for token := range set {
+			if !peggedTokens[token] {
+				priced[token] = 1
+			}
+		}
+	}
+	if len(priced) == 0 {
+		return false
+	}
+	returns, days := e.alignedDailyReturns(priced)
+	if days < minBootstrapReturns {
+		return false
+	}
+
+	portfolio, benchmark := make([]float64, days), make([]float64, days)
+	for token, series := range returns {
+		for t, logReturn := range series {
+			simple := math.Exp(logReturn) - 1
+			portfolio[t] += weights[token] * simple
+			benchmark[t] += benchmarkWeights[token] * simple
+		}
+	}
+
+	portfolioMean, benchmarkMean := 0.0, 0.0
+	for t := range portfolio {
+		portfolioMean += portfolio[t] / float64(days)
+		benchmarkMean += benchmark[t] / float64(days)
+	}
+	portfolioVariance, benchmarkVariance, covariance := 0.0, 0.0, 0.0
+	for t := range portfolio {
+		p, b := portfolio[t]-portfolioMean, benchmark[t]-benchmarkMean
+		portfolioVariance += p * p
+		benchmarkVariance += b * b
+		covariance += p * b
+	}
+
+	// Annualize daily moments
+	scale := 365 / float64(days-1)
+	comparison.Source = covarianceHistory
+	comparison.Observations = days
+	setRelativeMetrics(comparison, portfolioMean*365, benchmarkMean*365, portfolioVariance*scale, benchmarkVariance*scale, covariance*scale)
+	return true
+}
+
+// assumedComparison fills in the comparison from assumed token volatilities, correlations and
+// expected returns
+func (e *EnhancedAIEngine) assumedComparison(comparison *models.BenchmarkComparison, weights, benchmarkWeights map[string]float64) {
+	union := make(map[string]float64)
+	for _, set := range []map[string]float64{weights, benchmarkWeights} {
+		for token := range set {
+			union[token] = 1
+		}
+	}
+	tokens := sortedTokens(union)
+	covariance := e.assumedCovariance(tokens)
+
+	portfolioReturn, benchmarkReturn := 0.0, 0.0
+	portfolioVariance, benchmarkVariance, crossCovariance := 0.0, 0.0, 0.0
+	for i, a := range tokens {
+		expected := e.getTokenExpectedReturn(a)
+		portfolioReturn += weights[a] * expected
+		benchmarkReturn += benchmarkWeights[a] * expected
+		for j, b := range tokens {
+			portfolioVariance += weights[a] * covariance[i][j] * weights[b]
+			benchmarkVariance += benchmarkWeights[a] * covariance[i][j] * benchmarkWeights[b]
+			crossCovariance += weights[a] * covariance[i][j] * benchmarkWeights[b]
+		}
+	}
+
+	comparison.Source = covarianceAssumed
+	setRelativeMetrics(comparison, portfolioReturn, benchmarkReturn, portfolioVariance, benchmarkVariance, crossCovariance)
+}
+
+// setRelativeMetrics derives beta, alpha, tracking error, information ratio and correlation
+// from annualized returns, variances and the portfolio-benchmark covariance
+func setRelativeMetrics(comparison *models.BenchmarkComparison, portfolioReturn, benchmarkReturn, portfolioVariance, benchmarkVariance, covariance float64) {
+	comparison.PortfolioReturn = portfolioReturn
+	comparison.BenchmarkReturn = benchmarkReturn
+	if benchmarkVariance > 0 {
+		comparison.Beta = covariance / benchmarkVariance
+	}
+	comparison.Alpha = portfolioReturn - riskFreeRate - comparison.Beta*(benchmarkReturn-riskFreeRate)
+	comparison.TrackingError = math.Sqrt(math.Max(portfolioVariance+benchmarkVariance-2*covariance, 0))
+	if comparison.TrackingError > 1e-9 {
+		comparison.InformationRatio = (portfolioReturn - benchmarkReturn) / comparison.TrackingError
+	}
+	if portfolioVariance > 0 && benchmarkVariance > 0 {
+		comparison.Correlation = covariance / math.Sqrt(portfolioVariance*benchmarkVariance)
+	}
+}
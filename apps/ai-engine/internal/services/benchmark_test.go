@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestParseBenchmark(t *testing.T) {
+	t.Run("named benchmarks", func(t *testing.T) {
+		for _, spec := range []string{"btc", "ETH", " market_cap "} {
+			if _, err := ParseBenchmark(spec); err != nil {
+				t.Errorf("Expected %q to parse, got %v", spec, err)
+			}
+		}
+	})
+
+	t.Run("custom basket", func(t *testing.T) {
+		benchmark, err := ParseBenchmark("btc:0.5, SOL:0.25,sol:0.25")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if benchmark.Name != "custom" || benchmark.Weights["BTC"] != 0.5 || benchmark.Weights["SOL"] != 0.5 {
+			t.Errorf("Expected half BTC and half SOL, got %+v", benchmark)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, spec := range []string{"", "nasdaq", "BTC:x", "BTC:0.5,", ":1", "BTC:-1", "BTC:0"} {
+			if _, err := ParseBenchmark(spec); err == nil {
+				t.Errorf("Expected an error for %q", spec)
+			}
+		}
+	})
+}
+
+func TestEnhancedAIEngine_CompareToBenchmark(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	history := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now, 20)))
+	history.now = func() time.Time { return now }
+
+	portfolio := func(positions ...models.PortfolioPosition) models.Portfolio {
+		return models.Portfolio{ID: "pf-bench", Positions: positions}
+	}
+	btc := models.Benchmark{Name: models.BenchmarkBTC}
+
+	t.Run("portfolio matching the benchmark", func(t *testing.T) {
+		comparison, err := history.CompareToBenchmark(ctx, portfolio(
+			models.PortfolioPosition{Token: "BTC", Weight: 0.6},
+			models.PortfolioPosition{Token: "ETH", Weight: 0.4},
+		), models.Benchmark{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if comparison.Source != covarianceHistory || comparison.Observations != 20 || comparison.Benchmark != DefaultBenchmark().Name {
+			t.Errorf("Expected 20 days of history against the default benchmark, got %+v", comparison)
+		}
+		if math.Abs(comparison.Beta-1) > 1e-9 || math.Abs(comparison.Correlation-1) > 1e-9 {
+			t.Errorf("Expected beta and correlation of 1, got %f and %f", comparison.Beta, comparison.Correlation)
+		}
+		if comparison.TrackingError > 1e-6 || math.Abs(comparison.Alpha) > 1e-9 || comparison.InformationRatio != 0 {
+			t.Errorf("Expected no active risk or return, got %+v", comparison)
+		}
+	})
+
+	t.Run("stablecoins halve beta", func(t *testing.T) {
+		comparison, err := history.CompareToBenchmark(ctx, portfolio(
+			models.PortfolioPosition{Token: "BTC", Value: 5000},
+			models.PortfolioPosition{Token: "USDC", Value: 5000},
+		), btc)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if math.Abs(comparison.Beta-0.5) > 1e-9 || math.Abs(comparison.Correlation-1) > 1e-9 {
+			t.Errorf("Expected beta 0.5 with full correlation, got %f and %f", comparison.Beta, comparison.Correlation)
+		}
+		// Idle stablecoins fall short of the risk-free rate
+		if want := -0.5 * riskFreeRate; math.Abs(comparison.Alpha-want) > 1e-9 {
+			t.Errorf("Expected alpha %f, got %f", want, comparison.Alpha)
+		}
+		if math.Abs(comparison.InformationRatio*comparison.TrackingError-(comparison.PortfolioReturn-comparison.BenchmarkReturn)) > 1e-9 {
+			t.Errorf("Expected the information ratio to scale the return difference, got %+v", comparison)
+		}
+	})
+
+	t.Run("assumed without price history", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		comparison, err := engine.CompareToBenchmark(ctx, portfolio(models.PortfolioPosition{Token: "ETH", Weight: 1}), btc)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := assumedCorrelation * engine.getTokenRisk("ETH") / engine.getTokenRisk("BTC")
+		if comparison.Source != covarianceAssumed || math.Abs(comparison.Beta-want) > 1e-9 {
+			t.Errorf("Expected assumed beta %f, got %+v", want, comparison)
+		}
+		if math.Abs(comparison.Correlation-assumedCorrelation) > 1e-9 {
+			t.Errorf("Expected correlation %f, got %f", assumedCorrelation, comparison.Correlation)
+		}
+	})
+
+	t.Run("market cap index", func(t *testing.T) {
+		market := models.Benchmark{Name: models.BenchmarkMarketCap}
+		if _, err := NewEnhancedAIEngine().CompareToBenchmark(ctx, portfolio(models.PortfolioPosition{Token: "ETH", Weight: 1}), market); err == nil {
+			t.Error("Expected an error without market caps")
+		}
+
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 750, "ETH": 250}))
+		comparison, err := engine.CompareToBenchmark(ctx, portfolio(models.PortfolioPosition{Token: "ETH", Weight: 1}), market)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if comparison.Weights["BTC"] != 0.75 || comparison.Weights["ETH"] != 0.25 || len(comparison.Weights) != 2 {
+			t.Errorf("Expected weights from market caps, got %v", comparison.Weights)
+		}
+	})
+
+	t.Run("invalid benchmark", func(t *testing.T) {
+		_, err := history.CompareToBenchmark(ctx, portfolio(models.PortfolioPosition{Token: "ETH", Weight: 1}), models.Benchmark{Name: "nasdaq"})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestEnhancedAIEngine_RiskMetricsBeta(t *testing.T) {
+	engine := NewEnhancedAIEngine(WithBenchmark(models.Benchmark{Name: models.BenchmarkETH}))
+	metrics, err := engine.CalculateRiskMetrics(context.Background(), models.Portfolio{ID: "pf-beta", Positions: []models.PortfolioPosition{
+		{Token: "ETH", Weight: 1},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if metrics.Benchmark == nil || metrics.Benchmark.Benchmark != models.BenchmarkETH {
+		t.Fatalf("Expected a comparison against ETH, got %+v", metrics.Benchmark)
+	}
+	if math.Abs(metrics.Beta-1) > 1e-9 || metrics.Beta != metrics.Benchmark.Beta {
+		t.Errorf("Expected beta 1 from the comparison, got %f", metrics.Beta)
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// Return models reported on allocations
+const (
+	returnModelImplied = "market_implied"
+	returnModelAssumed = "assumed"
+)
+
+// minViewVariance keeps fully confident views from making the view covariance singular
+const minViewVariance = 1e-10
+
+// BlackLittermanConfig controls how expected returns are derived from market caps and views
+type BlackLittermanConfig struct {
+	RiskAversion float64             `json:"risk_aversion"`   // Market excess return per unit of variance
+	Tau          float64             `json:"tau"`             // Uncertainty of the prior relative to return covariance
+	Views        []models.MarketView `json:"views,omitempty"` // House views applied to every recommendation
+}
+
+// DefaultBlackLittermanConfig returns the conventional risk aversion of 2.5 and tau of 0.05, with no house views
+func DefaultBlackLittermanConfig() BlackLittermanConfig {
+	return BlackLittermanConfig{RiskAversion: 2.5, Tau: 0.05}
+}
+
+// WithBlackLitterman overrides the Black-Litterman parameters and house views
+func WithBlackLitterman(config BlackLittermanConfig) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.blackLitterman = config
+	}
+}
+
+// WithMarketCaps derives prior returns from the cached market caps of the latest prices.
+// Without it the engine falls back to its assumed token returns.
+func WithMarketCaps(caps MarketCapProvider) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.marketCaps = caps
+	}
+}
+
+// LoadMarketViews reads house views from a JSON file of the form {"views": [...]}. An empty
+// path returns no views.
+func LoadMarketViews(path string) ([]models.MarketView, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market views: %w", err)
+	}
+
+	var file struct {
+		Views []models.MarketView `json:"views"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse market views: %w", err)
+	}
+	if err := ValidateMarketViews(file.Views); err != nil {
+		return nil, fmt.Errorf("invalid market views: %w", err)
+	}
+	return file.Views, nil
+}
+
+// ValidateMarketViews checks that views are well formed
+func ValidateMarketViews(views []models.MarketView) error {
+	for i, view := range views {
+		switch {
+		case view.Token == "":
+			return fmt.Errorf("view %d: token is required", i)
+		case view.Type == models.ViewAbsolute && view.Versus != "":
+			return fmt.Errorf("view %d: absolute views take no versus token", i)
+		case view.Type == models.ViewRelative && (view.Versus == "" || strings.EqualFold(view.Versus, view.Token)):
+			return fmt.Errorf("view %d: relative views need a versus token other than %s", i, view.Token)
+		case view.Type != models.ViewAbsolute && view.Type != models.ViewRelative:
+			return fmt.Errorf("view %d: unknown type %q (use absolute or relative)", i, view.Type)
+		case view.Confidence <= 0 || view.Confidence > 1:
+			return fmt.Errorf("view %d: confidence must be within (0, 1]", i)
+		}
+	}
+	return nil
+}
+
+// priorReturns returns the market-implied equilibrium returns r + δΣw of the tokens, with r the
+// risk-free rate, δ the risk aversion and w their market cap weights. δΣw is an excess return,
+// so r is added to make the prior, like views and assumed returns, a total return. Pegged
+// tokens are left out of the market portfolio. Unless every other token has a market cap it
+// returns the engine's assumed returns.
+func (e *EnhancedAIEngine) priorReturns(tokens []string, covariance [][]float64, riskAversion float64) ([]float64, string) {
+	prior := make([]float64, len(tokens))
+	if weights, ok := e.completeMarketWeights(tokens); ok {
+		for i := range tokens {
+			prior[i] = riskFreeRate
+			for j := range tokens {
+				prior[i] += riskAversion * covariance[i][j] * weights[j]
+			}
+		}
+		return prior, returnModelImplied
+	}
+
+	for i, token := range tokens {
+		prior[i] = e.getTokenExpectedReturn(token)
+	}
+	return prior, returnModelAssumed
+}
+
+// completeMarketWeights returns the market cap weights of the tokens, reporting false unless
+// every token that is not pegged has a cached cap, since a missing cap would imply a zero weight
+func (e *EnhancedAIEngine) completeMarketWeights(tokens []string) ([]float64, bool) {
+	weights, ok := e.marketWeights(tokens)
+	if !ok {
+		return nil, false
+	}
+	for i, token := range tokens {
+		if weights[i] <= 0 && !peggedTokens[strings.ToUpper(token)] {
+			return nil, false
+		}
+	}
+	return weights, true
+}
+
+// marketWeights returns the market cap weights of the tokens, reporting false when no cap is known
+func (e *EnhancedAIEngine) marketWeights(tokens []string) ([]float64, bool) {
+	if e.marketCaps == nil {
+		return nil, false
+	}
+
+	cached := e.marketCaps.CachedMarketCaps()
+	caps := make([]float64, len(tokens))
+	for i, token := range tokens {
+		if symbol := strings.ToUpper(token); !peggedTokens[symbol] {
+			caps[i] = cached[symbol]
+		}
+	}
+	caps = normalizeSlice(caps)
+	for _, weight := range caps {
+		if weight > 0 {
+			return caps, true
+		}
+	}
+	return nil, false
+}
+
+// blendViews returns the Black-Litterman posterior returns
+//
+//	μ = π + τΣPᵀ(PτΣPᵀ + Ω)⁻¹(Q − Pπ)
+//
+// where each view is a row of P with expected return Q. A view's variance in Ω is its variance
+// under the prior scaled by (1−c)/c for confidence c, so a lone view at confidence c moves the
+// return a share c of the way from the prior to the view. Views on tokens outside the universe
+// are skipped; the number applied is returned.
+func blendViews(tokens []string, covariance [][]float64, prior []float64, views []models.MarketView, tau float64) ([]float64, int) {
+	index := make(map[string]int, len(tokens))
+	for i, token := range tokens {
+		index[strings.ToUpper(token)] = i
+	}
+
+	var picks [][]float64
+	var targets, confidences []float64
+	for _, view := range views {
+		i, ok := index[strings.ToUpper(view.Token)]
+		if !ok {
+			continue
+		}
+		pick := make([]float64, len(tokens))
+		pick[i] = 1
+		if view.Type == models.ViewRelative {
+			j, ok := index[strings.ToUpper(view.Versus)]
+			if !ok {
+				continue
+			}
+			pick[j] = -1
+		}
+		picks = append(picks, pick)
+		targets = append(targets, view.Return)
+		confidences = append(confidences, view.Confidence)
+	}
+
+	posterior := append([]float64{}, prior...)
+	if len(picks) == 0 {
+		return posterior, 0
+	}
+
+	// τΣPᵀ, one column per view
+	scaled := make([][]float64, len(tokens))
+	for i := range tokens {
+		scaled[i] = make([]float64, len(picks))
+		for k, pick := range picks {
+			for j := range tokens {
+				scaled[i][k] += tau * covariance[i][j] * pick[j]
+			}
+		}
+	}
+
+	// PτΣPᵀ + Ω and the surprise Q − Pπ
+	system := make([][]float64, len(picks))
+	surprise := make([]float64, len(picks))
+	for k, pick := range picks {
+		system[k] = make([]float64, len(picks))
+		for l := range picks {
+			for i := range tokens {
+				system[k][l] += pick[i] * scaled[i][l]
+			}
+		}
+		system[k][k] += math.Max(system[k][k]*(1-confidences[k])/confidences[k], minViewVariance)
+
+		surprise[k] = targets[k]
+		for i := range tokens {
+			surprise[k] -= pick[i] * prior[i]
+		}
+	}
+
+	solution, ok := solveLinear(system, surprise)
+	if !ok {
+		return posterior, 0
+	}
+	for i := range tokens {
+		for k := range picks {
+			posterior[i] += scaled[i][k] * solution[k]
+		}
+	}
+	return posterior, len(picks)
+}
+
+// solveLinear solves a·x = b by Gaussian elimination with partial pivoting, reporting false
+// when a is singular
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append([]float64{}, a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-15 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, true
+}
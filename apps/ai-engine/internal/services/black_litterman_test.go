@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// mockMarketCaps serves fixed market caps
+type mockMarketCaps map[string]float64
+
+func (m mockMarketCaps) CachedMarketCaps() map[string]float64 {
+	return m
+}
+
+func TestValidateMarketViews(t *testing.T) {
+	invalid := map[string]models.MarketView{
+		"missing token":           {Type: models.ViewAbsolute, Return: 0.1, Confidence: 0.5},
+		"unknown type":            {Type: "bullish", Token: "ETH", Confidence: 0.5},
+		"absolute with versus":    {Type: models.ViewAbsolute, Token: "ETH", Versus: "BTC", Confidence: 0.5},
+		"relative without pair":   {Type: models.ViewRelative, Token: "ETH", Confidence: 0.5},
+		"relative against itself": {Type: models.ViewRelative, Token: "ETH", Versus: "eth", Confidence: 0.5},
+		"zero confidence":         {Type: models.ViewAbsolute, Token: "ETH"},
+		"confidence above one":    {Type: models.ViewAbsolute, Token: "ETH", Confidence: 1.5},
+	}
+	for name, view := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateMarketViews([]models.MarketView{view}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestBlendViews(t *testing.T) {
+	tokens := []string{"BTC", "ETH"}
+	covariance := [][]float64{
+		{0.09, 0.3 * 0.3 * 0.25},
+		{0.3 * 0.3 * 0.25, 0.0625},
+	}
+	prior := []float64{0.12, 0.15}
+
+	t.Run("no views keep the prior", func(t *testing.T) {
+		posterior, applied := blendViews(tokens, covariance, prior, nil, 0.05)
+		if applied != 0 || posterior[0] != prior[0] || posterior[1] != prior[1] {
+			t.Errorf("Expected the prior unchanged, got %v", posterior)
+		}
+	})
+
+	t.Run("confidence sets how far a lone view moves the return", func(t *testing.T) {
+		view := models.MarketView{Type: models.ViewAbsolute, Token: "eth", Return: 0.35, Confidence: 0.5}
+		posterior, applied := blendViews(tokens, covariance, prior, []models.MarketView{view}, 0.05)
+		if applied != 1 || math.Abs(posterior[1]-0.25) > 1e-9 {
+			t.Errorf("Expected ETH halfway to the view at 0.25, got %f", posterior[1])
+		}
+		// Correlation carries part of the view over to BTC
+		if posterior[0] <= prior[0] {
+			t.Errorf("Expected BTC to rise with ETH, got %f", posterior[0])
+		}
+	})
+
+	t.Run("fully confident relative view sets the spread", func(t *testing.T) {
+		view := models.MarketView{Type: models.ViewRelative, Token: "ETH", Versus: "BTC", Return: 0.05, Confidence: 1}
+		posterior, _ := blendViews(tokens, covariance, prior, []models.MarketView{view}, 0.05)
+		if math.Abs(posterior[1]-posterior[0]-0.05) > 1e-6 {
+			t.Errorf("Expected ETH to outperform BTC by 5%%, got %v", posterior)
+		}
+	})
+
+	t.Run("views outside the universe are skipped", func(t *testing.T) {
+		views := []models.MarketView{
+			{Type: models.ViewAbsolute, Token: "SOL", Return: 0.5, Confidence: 1},
+			{Type: models.ViewRelative, Token: "ETH", Versus: "SOL", Return: 0.1, Confidence: 1},
+		}
+		if _, applied := blendViews(tokens, covariance, prior, views, 0.05); applied != 0 {
+			t.Errorf("Expected no views applied, got %d", applied)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_PriorReturns(t *testing.T) {
+	tokens := []string{"BTC", "ETH", "USDC"}
+
+	t.Run("market caps imply equilibrium returns", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 600, "ETH": 300, "USDC": 100}))
+		covariance := engine.assumedCovariance(tokens)
+		prior, model := engine.priorReturns(tokens, covariance, 2.5)
+		if model != returnModelImplied {
+			t.Fatalf("Expected market implied returns, got %s", model)
+		}
+		// USDC is pegged, so the market portfolio is two thirds BTC and one third ETH
+		want := riskFreeRate + 2.5*(covariance[0][0]*2/3+covariance[0][1]/3)
+		if math.Abs(prior[0]-want) > 1e-12 {
+			t.Errorf("Expected BTC prior %f, got %f", want, prior[0])
+		}
+		if prior[2] >= prior[1] {
+			t.Errorf("Expected USDC to imply less return than ETH, got %v", prior)
+		}
+	})
+
+	t.Run("assumed returns without market caps", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{}))
+		prior, model := engine.priorReturns(tokens, engine.assumedCovariance(tokens), 2.5)
+		if model != returnModelAssumed || prior[1] != engine.getTokenExpectedReturn("ETH") {
+			t.Errorf("Expected assumed returns, got %s %v", model, prior)
+		}
+	})
+
+	t.Run("assumed returns unless every unpegged token has a cap", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 600, "USDC": 100}))
+		prior, model := engine.priorReturns(tokens, engine.assumedCovariance(tokens), 2.5)
+		if model != returnModelAssumed || prior[0] != engine.getTokenExpectedReturn("BTC") {
+			t.Errorf("Expected assumed returns without an ETH cap, got %s %v", model, prior)
+		}
+	})
+
+	t.Run("pegged tokens imply the risk-free rate", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 600}))
+		pegged := []string{"BTC", "USDC"}
+		covariance := [][]float64{{0.36, 0}, {0, 0}}
+		prior, model := engine.priorReturns(pegged, covariance, 2.5)
+		if model != returnModelImplied || prior[1] != riskFreeRate || math.Abs(prior[0]-(riskFreeRate+2.5*0.36)) > 1e-12 {
+			t.Errorf("Expected BTC at r + δσ² and USDC at r, got %s %v", model, prior)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_RecommendWithViews(t *testing.T) {
+	ctx := context.Background()
+	portfolio := models.Portfolio{ID: "pf-views", TotalValue: 100000, Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 0.5, Amount: 1.2, Value: 50000},
+		{Token: "ETH", Weight: 0.5, Amount: 20, Value: 50000},
+	}}
+	bearishETH := models.MarketView{Type: models.ViewRelative, Token: "BTC", Versus: "ETH", Return: 0.2, Confidence: 0.9}
+
+	weightOf := func(report *models.AllocationReport, token string) float64 {
+		for _, asset := range report.Assets {
+			if asset.Token == token {
+				return asset.Weight
+			}
+		}
+		return 0
+	}
+
+	engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 800, "ETH": 300}))
+	base, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if base.Allocation.ReturnModel != returnModelImplied || base.Allocation.ViewsApplied != 0 {
+		t.Errorf("Expected implied returns without views, got %+v", base.Allocation)
+	}
+
+	t.Run("request views shift the allocation", func(t *testing.T) {
+		viewed, err := engine.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Views: []models.MarketView{bearishETH}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if viewed.Allocation.ViewsApplied != 1 {
+			t.Errorf("Expected one view applied, got %d", viewed.Allocation.ViewsApplied)
+		}
+		if weightOf(viewed.Allocation, "BTC") <= weightOf(base.Allocation, "BTC") {
+			t.Errorf("Expected more BTC after the view, got %+v", viewed.Allocation.Assets)
+		}
+	})
+
+	t.Run("house views apply to every request", func(t *testing.T) {
+		config := DefaultBlackLittermanConfig()
+		config.Views = []models.MarketView{bearishETH}
+		house := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 800, "ETH": 300}), WithBlackLitterman(config))
+		recommendation, err := house.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Views: []models.MarketView{bearishETH}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Allocation.ViewsApplied != 2 {
+			t.Errorf("Expected house and request views applied, got %d", recommendation.Allocation.ViewsApplied)
+		}
+	})
+
+	t.Run("invalid views are rejected", func(t *testing.T) {
+		views := []models.MarketView{{Type: models.ViewAbsolute, Token: "ETH", Return: 0.1}}
+		if _, err := engine.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Views: views}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestLoadMarketViews(t *testing.T) {
+	if views, err := LoadMarketViews(""); err != nil || views != nil {
+		t.Errorf("Expected no views without a path, got %v, %v", views, err)
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "views.json")
+	os.WriteFile(valid, []byte(`{"views": [{"type": "relative", "token": "ETH", "versus": "BTC", "return": 0.05, "confidence": 0.6}]}`), 0o644)
+	views, err := LoadMarketViews(valid)
+	if err != nil || len(views) != 1 || views[0].Versus != "BTC" {
+		t.Errorf("Expected one relative view, got %v, %v", views, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"views": [{"type": "absolute", "token": "ETH", "return": 0.05}]}`), 0o644)
+	if _, err := LoadMarketViews(invalid); err == nil {
+		t.Error("Expected an error for a view without confidence")
+	}
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	confidenceLookback  = 30 * 24 * time.Hour // Price history used to estimate return uncertainty
+	bootstrapSamples    = 500
+	bootstrapSeed       = 1     // Fixed so identical inputs give identical confidence
+	minBootstrapReturns = 14    // Daily returns required before bootstrapping instead of assuming volatility
+	estimatedTradeCost  = 0.003 // DEX fee plus slippage per unit of traded weight, when actions carry no cost estimates
+	freshPriceAge       = 15 * time.Minute
+	stalePriceAge       = 24 * time.Hour
+)
+
+// WithPriceHistory lets the engine measure return uncertainty and data freshness from sampled prices
+func WithPriceHistory(history *TimeSeriesStore) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.priceHistory = history
+	}
+}
+
+// WithConfidenceCalibrator maps raw confidence onto the hit rate of past recommendations
+func WithConfidenceCalibrator(calibrator ConfidenceCalibrator) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.calibrator = calibrator
+	}
+}
+
+// confidenceInputs are the weights compared when scoring a recommendation
+type confidenceInputs struct {
+	current, target             map[string]float64
+	currentYields, targetYields map[string]float64
+	movedWeight                 float64 // Weight moved between yield venues
+	trading                     bool    // False when the recommendation is to hold; target is then the unexecuted optimum
+}
+
+// scoreConfidence estimates the probability that the recommendation beats the alternative
+// (holding, or for a hold recommendation the optimal allocation) net of trading costs.
+// Estimation uncertainty comes from bootstrapping historical returns, and the probability
+// is pulled towards a coin flip when price data is stale or sparse.
+func (e *EnhancedAIEngine) scoreConfidence(ctx context.Context, portfolio models.Portfolio, optimalAllocations map[string]float64, actions []models.RebalanceAction, plan *models.TradePlan) (float64, *models.ConfidenceBreakdown) {
+	inputs := e.confidenceInputs(portfolio, optimalAllocations, actions)
+
+	// Tokens are visited in order so floating point sums are reproducible
+	improvement, traded := 0.0, inputs.movedWeight
+	deltas := make(map[string]float64)
+	for _, token := range sortedTokens(inputs.current, inputs.target) {
+		current, target := inputs.current[token], inputs.target[token]
+		expected := e.getTokenExpectedReturn(token)
+		improvement += target*(expected+inputs.targetYields[token]) - current*(expected+inputs.currentYields[token])
+		traded += math.Abs(target - current)
+		if target != current && !peggedTokens[token] {
+			deltas[token] = target - current
+		}
+	}
+	cost := estimatedTradeCost * traded
+	if estimated, ok := estimatedCostShare(portfolio, actions, plan); ok {
+		cost = estimated
+	}
+
+	pGross, pNet, estimationDetail := e.improvementProbability(deltas, improvement, cost)
+	quality, qualityDetail := e.dataQuality(inputs, deltas)
+
+	p, estimation := pNet, pGross
+	coverage := 0.0 // Share of the expected improvement left after costs
+	if improvement > 0 {
+		coverage = clamp01(1 - cost/improvement)
+	}
+	if !inputs.trading {
+		p, estimation = 1-pNet, 1-pGross
+		coverage = 1
+		if improvement > cost {
+			coverage = cost / improvement
+		}
+	}
+	raw := 0.5 + (p-0.5)*quality
+
+	breakdown := &models.ConfidenceBreakdown{
+		Raw: raw,
+		Components: []models.ConfidenceComponent{
+			{Name: "estimation", Value: estimation, Detail: estimationDetail},
+			{Name: "improvement_vs_cost", Value: coverage, Detail: fmt.Sprintf("expected improvement %.2f%%/yr vs estimated cost %.2f%%", improvement*100, cost*100)},
+			{Name: "data_quality", Value: quality, Detail: qualityDetail},
+		},
+	}
+
+	confidence := raw
+	calibrationDetail := "no confidence calibration configured"
+	switch {
+	case !inputs.trading:
+		calibrationDetail = "calibration applies to trade recommendations only"
+	case e.calibrator != nil:
+		if calibrated, samples, ok := e.calibrator.CalibrateConfidence(ctx, EngineVersion, raw); ok {
+			confidence = calibrated
+			breakdown.Calibrated = true
+			calibrationDetail = fmt.Sprintf("fitted on %d evaluated recommendations of engine %s", samples, EngineVersion)
+		} else {
+			calibrationDetail = fmt.Sprintf("too few evaluated recommendations of engine %s (%d)", EngineVersion, samples)
+		}
+	}
+	breakdown.Components = append(breakdown.Components, models.ConfidenceComponent{
+		Name: "calibration", Value: confidence, Detail: calibrationDetail,
+	})
+
+	return confidence, breakdown
+}
+
+// confidenceInputs derives current and target weights from the portfolio and actions
+func (e *EnhancedAIEngine) confidenceInputs(portfolio models.Portfolio, optimalAllocations map[string]float64, actions []models.RebalanceAction) confidenceInputs {
+	current, currentYields := positionWeights(portfolio)
+	recommendation := &models.RebalanceRecommendation{Actions: actions}
+	target, targetYields := applyRecommendation(recommendation, current, currentYields)
+
+	inputs := confidenceInputs{
+		current:       current,
+		target:        target,
+		currentYields: currentYields,
+		targetYields:  targetYields,
+		trading:       len(actions) > 0,
+	}
+	for _, action := range actions {
+		if action.Type == actionTypeMove {
+			inputs.movedWeight += current[strings.ToUpper(action.Token)]
+		}
+	}
+
+	if !inputs.trading {
+		optimal := make(map[string]float64, len(optimalAllocations))
+		for token, weight := range optimalAllocations {
+			optimal[strings.ToUpper(token)] += weight
+		}
+		inputs.target = normalizeWeights(optimal)
+	}
+	return inputs
+}
+
+// improvementProbability returns the probabilities that the expected improvement is positive
+// and that it exceeds cost, given estimation noise in the expected returns of changed weights
+func (e *EnhancedAIEngine) improvementProbability(deltas map[string]float64, improvement, cost float64) (float64, float64, string) {
+	if len(deltas) == 0 {
+		return step(improvement), step(improvement - cost), "no change in price exposure"
+	}
+
+	if returns, days := e.alignedDailyReturns(deltas); days >= minBootstrapReturns {
+		rng := rand.New(rand.NewSource(bootstrapSeed))
+		means := make(map[string]float64, len(returns))
+		for token, series := range returns {
+			means[token] = mean(series)
+		}
+
+		positive, profitable := 0, 0
+		for b := 0; b < bootstrapSamples; b++ {
+			sampled := make(map[string]float64, len(returns))
+			for i := 0; i < days; i++ {
+				day := rng.Intn(days)
+				for token, series := range returns {
+					sampled[token] += series[day]
+				}
+			}
+			noise := 0.0
+			for _, token := range sortedTokens(deltas) {
+				noise += deltas[token] * (sampled[token]/float64(days) - means[token]) * 365
+			}
+			if improvement+noise > 0 {
+				positive++
+			}
+			if improvement+noise > cost {
+				profitable++
+			}
+		}
+		detail := fmt.Sprintf("%d bootstrap resamples of %d daily returns", bootstrapSamples, days)
+		return float64(positive) / bootstrapSamples, float64(profitable) / bootstrapSamples, detail
+	}
+
+	// Without enough history, treat the assumed volatility as one year of observations
+	variance := 0.0
+	tokens := sortedTokens(deltas)
+	for _, token1 := range tokens {
+		for _, token2 := range tokens {
+			correlation := 0.3
+			if token1 == token2 {
+				correlation = 1
+			}
+			variance += deltas[token1] * deltas[token2] * e.getTokenRisk(token1) * e.getTokenRisk(token2) * correlation
+		}
+	}
+	sd := math.Sqrt(variance)
+	if sd == 0 {
+		return step(improvement), step(improvement - cost), "no change in price exposure"
+	}
+	return normalCDF(improvement / sd), normalCDF((improvement - cost) / sd), "normal approximation from assumed token volatility; insufficient price history"
+}
+
+// alignedDailyReturns returns daily log returns of the tokens over the days all of them have prices
+func (e *EnhancedAIEngine) alignedDailyReturns(tokens map[string]float64) (map[string][]float64, int) {
+	closes, days := e.alignedDays(tokens)
+	return dailyReturns(closes, days)
+}
+
+// alignedDays returns the daily closes of the tokens and, in order, the days all of them have prices
+func (e *EnhancedAIEngine) alignedDays(tokens map[string]float64) (map[string]map[int64]float64, []int64) {
+	if e.priceHistory == nil {
+		return nil, nil
+	}
+
+	now := e.now()
+	closes := make(map[string]map[int64]float64, len(tokens))
+	for token := range tokens {
+		daily := make(map[int64]float64)
+		for _, point := range e.priceHistory.Range(PriceSeriesKey(token), now.Add(-confidenceLookback), now) {
+			if point.Value > 0 {
+				daily[point.Timestamp.Unix()/86400] = point.Value // Points are ordered, so the last one per day wins
+			}
+		}
+		closes[token] = daily
+	}
+
+	var days []int64
+	for day := range closes[anyKey(tokens)] {
+		shared := true
+		for token := range tokens {
+			if _, ok := closes[token][day]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	return closes, days
+}
+
+// dailyReturns returns the daily log returns between consecutive days of the closes
+func dailyReturns(closes map[string]map[int64]float64, days []int64) (map[string][]float64, int) {
+	returns := make(map[string][]float64, len(closes))
+	count := 0
+	for i := 1; i < len(days); i++ {
+		if days[i]-days[i-1] != 1 {
+			continue // Skip gaps so every return covers one day
+		}
+		for token, daily := range closes {
+			returns[token] = append(returns[token], math.Log(daily[days[i]]/daily[days[i-1]]))
+		}
+		count++
+	}
+	return returns, count
+}
+
+// dataQuality scores freshness and depth of the price data behind the changed weights,
+// or behind the whole portfolio when nothing changes
+func (e *EnhancedAIEngine) dataQuality(inputs confidenceInputs, deltas map[string]float64) (float64, string) {
+	if e.priceHistory == nil {
+		return 0.5, "no price history available"
+	}
+
+	weights := make(map[string]float64, len(deltas))
+	for token, delta := range deltas {
+		weights[token] = math.Abs(delta)
+	}
+	if len(weights) == 0 {
+		for token, weight := range inputs.current {
+			weights[token] = weight
+		}
+	}
+
+	now := e.now()
+	quality, total, stale := 0.0, 0.0, 0
+	for _, token := range sortedTokens(weights) {
+		weight := weights[token]
+		total += weight
+		if peggedTokens[token] {
+			quality += weight
+			continue
+		}
+
+		freshness := 0.0
+		if latest, ok := e.priceHistory.Latest(PriceSeriesKey(token)); ok {
+			age := now.Sub(latest.Timestamp)
+			freshness = clamp01(1 - float64(age-freshPriceAge)/float64(stalePriceAge-freshPriceAge))
+		}
+		if freshness < 1 {
+			stale++
+		}
+		_, days := e.alignedDailyReturns(map[string]float64{token: 1})
+		depth := math.Min(1, float64(days)/(confidenceLookback.Hours()/24))
+		quality += weight * (freshness + depth) / 2
+	}
+	if total == 0 {
+		return 1, "no price-exposed holdings"
+	}
+	return quality / total, fmt.Sprintf("%d of %d tokens have stale prices", stale, len(weights))
+}
+
+// estimatedCostShare sums the estimated costs of the actions as a share of the portfolio value,
+// taking trade costs from the trade plan when there is one. It reports false without estimates.
+func estimatedCostShare(portfolio models.Portfolio, actions []models.RebalanceAction, plan *models.TradePlan) (float64, bool) {
+	totalValue := portfolioValue(portfolio)
+	if totalValue <= 0 {
+		return 0, false
+	}
+	total, estimated := 0.0, false
+	if plan != nil {
+		total, estimated = plan.EstimatedCostUSD, true
+	}
+	for _, action := range actions {
+		if plan != nil && action.Type != actionTypeMove {
+			continue // Already costed as swaps
+		}
+		if action.EstimatedCost != nil {
+			total += action.EstimatedCost.TotalUSD
+			estimated = true
+		}
+	}
+	return total / totalValue, estimated
+}
+
+// sortedTokens returns the tokens present in any of the allocations, in order
+func sortedTokens(allocations ...map[string]float64) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, allocation := range allocations {
+		for token := range allocation {
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// anyKey returns an arbitrary token of a non-empty allocation
+func anyKey(weights map[string]float64) string {
+	for token := range weights {
+		return token
+	}
+	return ""
+}
+
+// normalCDF returns the standard normal cumulative probability of x
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// step returns 1 for positive x and 0 otherwise
+func step(x float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	minCalibrationSamples  = 20 // Evaluated recommendations required before calibrating
+	calibrationPriorWeight = 20 // Pseudo-observations keeping the raw score until outcomes outweigh it
+)
+
+// calibrationPoint maps a mean raw confidence to the hit rate observed around it
+type calibrationPoint struct {
+	raw, hitRate float64
+}
+
+// confidenceFit is an isotonic fit of hit rate against raw confidence
+type confidenceFit struct {
+	points  []calibrationPoint // Ordered by raw confidence, hit rate non-decreasing
+	samples int
+}
+
+// CalibrateConfidence maps a raw confidence score onto the hit rate of past recommendations
+// with similar scores at the calibration horizon. It reports false until enough outcomes exist.
+func (e *OutcomeEvaluator) CalibrateConfidence(ctx context.Context, engineVersion string, raw float64) (float64, int, bool) {
+	fit, err := e.calibration(ctx, engineVersion)
+	if err != nil {
+		e.logger.Error("confidence calibration failed", "engine_version", engineVersion, "error", err)
+		return raw, 0, false
+	}
+	if fit.samples < minCalibrationSamples {
+		return raw, fit.samples, false
+	}
+
+	// Shrink towards the raw score so a handful of outcomes cannot dominate it
+	weight := float64(fit.samples) / float64(fit.samples+calibrationPriorWeight)
+	return clamp01(weight*fit.at(raw) + (1-weight)*raw), fit.samples, true
+}
+
+// calibration returns the cached fit for an engine version, fitting it when missing
+func (e *OutcomeEvaluator) calibration(ctx context.Context, engineVersion string) (*confidenceFit, error) {
+	e.fitMu.Lock()
+	defer e.fitMu.Unlock()
+
+	if fit, ok := e.fits[engineVersion]; ok {
+		return fit, nil
+	}
+
+	records, err := e.records.Query(ctx, models.RecommendationQuery{Kind: models.RecordKindRecommendation})
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []calibrationPoint
+	for _, record := range records {
+		if record.Engine.Version != engineVersion || record.Recommendation == nil || record.Recommendation.ConfidenceBreakdown == nil {
+			continue
+		}
+		outcome, ok := record.Outcomes[e.config.CalibrationHorizon]
+		if !ok || outcome.Status != models.OutcomeStatusEvaluated || !outcome.Actionable {
+			continue
+		}
+		hit := 0.0
+		if outcome.Hit {
+			hit = 1
+		}
+		samples = append(samples, calibrationPoint{raw: record.Recommendation.ConfidenceBreakdown.Raw, hitRate: hit})
+	}
+
+	fit := fitIsotonic(samples)
+	if e.fits == nil {
+		e.fits = make(map[string]*confidenceFit)
+	}
+	e.fits[engineVersion] = fit
+	return fit, nil
+}
+
+// resetCalibration drops cached fits so new outcomes are taken into account
+func (e *OutcomeEvaluator) resetCalibration() {
+	e.fitMu.Lock()
+	defer e.fitMu.Unlock()
+	e.fits = nil
+}
+
+// fitIsotonic fits a non-decreasing hit rate curve with the pool adjacent violators algorithm
+func fitIsotonic(samples []calibrationPoint) *confidenceFit {
+	sort.Slice(samples, func(i, j int) bool { return samples[i].raw < samples[j].raw })
+
+	type block struct {
+		rawSum, hitSum float64
+		count          int
+	}
+	var blocks []block
+	for i, sample := range samples {
+		if i > 0 && sample.raw == samples[i-1].raw {
+			// Equal scores must map to one rate, so they start in the same block
+			last := &blocks[len(blocks)-1]
+			last.rawSum += sample.raw
+			last.hitSum += sample.hitRate
+			last.count++
+		} else {
+			blocks = append(blocks, block{rawSum: sample.raw, hitSum: sample.hitRate, count: 1})
+		}
+		for len(blocks) > 1 {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if prev.hitSum/float64(prev.count) < last.hitSum/float64(last.count) {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{
+				rawSum: prev.rawSum + last.rawSum,
+				hitSum: prev.hitSum + last.hitSum,
+				count:  prev.count + last.count,
+			}
+		}
+	}
+
+	fit := &confidenceFit{samples: len(samples)}
+	for _, b := range blocks {
+		n := float64(b.count)
+		fit.points = append(fit.points, calibrationPoint{raw: b.rawSum / n, hitRate: b.hitSum / n})
+	}
+	return fit
+}
+
+// at interpolates the fitted hit rate at a raw confidence, flat beyond the fitted range
+func (f *confidenceFit) at(raw float64) float64 {
+	points := f.points
+	if len(points) == 0 {
+		return raw
+	}
+	if raw <= points[0].raw {
+		return points[0].hitRate
+	}
+	for i := 1; i < len(points); i++ {
+		if raw <= points[i].raw {
+			lo, hi := points[i-1], points[i]
+			return lo.hitRate + (hi.hitRate-lo.hitRate)*(raw-lo.raw)/(hi.raw-lo.raw)
+		}
+	}
+	return points[len(points)-1].hitRate
+}
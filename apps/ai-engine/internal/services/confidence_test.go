@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// mockCalibrator maps every raw score to a fixed confidence
+type mockCalibrator struct {
+	calibrated float64
+	samples    int
+}
+
+func (m *mockCalibrator) CalibrateConfidence(ctx context.Context, engineVersion string, raw float64) (float64, int, bool) {
+	return m.calibrated, m.samples, m.samples >= minCalibrationSamples
+}
+
+// dailyPriceHistory stores days of daily ETH and BTC prices ending at end
+func dailyPriceHistory(end time.Time, days int) *TimeSeriesStore {
+	history := NewTimeSeriesStore(1000)
+	for i := days; i >= 0; i-- {
+		at := end.Add(-time.Duration(i) * 24 * time.Hour)
+		history.Append(PriceSeriesKey("ETH"), at, 2500*(1+0.03*math.Sin(float64(i))))
+		history.Append(PriceSeriesKey("BTC"), at, 42000*(1+0.02*math.Cos(float64(i)*1.3)))
+	}
+	return history
+}
+
+func TestEnhancedAIEngine_ScoreConfidence(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	portfolio := models.Portfolio{ID: "pf-1", Positions: []models.PortfolioPosition{
+		{Token: "ETH", Weight: 0.5},
+		{Token: "BTC", Weight: 0.5},
+	}}
+	// ETH has the higher expected return, so shifting into it is the better trade
+	intoETH := []models.RebalanceAction{
+		{Type: "buy", Token: "ETH", TargetWeight: 0.7},
+		{Type: "sell", Token: "BTC", TargetWeight: 0.3},
+	}
+	intoBTC := []models.RebalanceAction{
+		{Type: "sell", Token: "ETH", TargetWeight: 0.3},
+		{Type: "buy", Token: "BTC", TargetWeight: 0.7},
+	}
+
+	component := func(breakdown *models.ConfidenceBreakdown, name string) models.ConfidenceComponent {
+		for _, c := range breakdown.Components {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("Expected a %s component, got %+v", name, breakdown.Components)
+		return models.ConfidenceComponent{}
+	}
+
+	t.Run("components are reported", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		confidence, breakdown := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+		if len(breakdown.Components) != 4 {
+			t.Fatalf("Expected 4 components, got %+v", breakdown.Components)
+		}
+		if confidence != breakdown.Raw || breakdown.Calibrated {
+			t.Errorf("Expected uncalibrated confidence to equal raw %f, got %f", breakdown.Raw, confidence)
+		}
+		if !strings.Contains(component(breakdown, "estimation").Detail, "normal approximation") {
+			t.Errorf("Expected the normal approximation without history, got %+v", component(breakdown, "estimation"))
+		}
+		if quality := component(breakdown, "data_quality").Value; quality != 0.5 {
+			t.Errorf("Expected data quality 0.5 without history, got %f", quality)
+		}
+	})
+
+	t.Run("better expected returns raise confidence above worse ones", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		better, _ := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+		worse, _ := engine.scoreConfidence(ctx, portfolio, nil, intoBTC, nil)
+		if better <= 0.5 || worse >= 0.5 {
+			t.Errorf("Expected confidence above 0.5 for the better trade and below for the worse, got %f and %f", better, worse)
+		}
+	})
+
+	t.Run("fresh history bootstraps and improves data quality", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now, 30)))
+		engine.now = func() time.Time { return now }
+		confidence, breakdown := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+
+		if !strings.Contains(component(breakdown, "estimation").Detail, "bootstrap") {
+			t.Errorf("Expected a bootstrap estimate, got %+v", component(breakdown, "estimation"))
+		}
+		if quality := component(breakdown, "data_quality").Value; quality < 0.9 {
+			t.Errorf("Expected high data quality with fresh history, got %f", quality)
+		}
+		if again, _ := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil); again != confidence {
+			t.Errorf("Expected a deterministic bootstrap, got %f and %f", confidence, again)
+		}
+	})
+
+	t.Run("stale prices pull confidence towards a coin flip", func(t *testing.T) {
+		fresh := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now, 30)))
+		fresh.now = func() time.Time { return now }
+		stale := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now.Add(-72*time.Hour), 30)))
+		stale.now = func() time.Time { return now }
+
+		_, freshBreakdown := fresh.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+		_, staleBreakdown := stale.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+		if component(staleBreakdown, "data_quality").Value >= component(freshBreakdown, "data_quality").Value {
+			t.Errorf("Expected stale data to lower quality, got %+v", staleBreakdown.Components)
+		}
+	})
+
+	t.Run("costs above the improvement lower confidence", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		// Shifting 10% into a token earning 0.4% more yield gains less than the fees
+		marginal := models.Portfolio{ID: "pf-2", Positions: []models.PortfolioPosition{
+			{Token: "FOO", Weight: 0.5},
+			{Token: "BAR", Weight: 0.5, YieldAPY: 0.004},
+		}}
+		confidence, breakdown := engine.scoreConfidence(ctx, marginal, nil, []models.RebalanceAction{
+			{Type: "sell", Token: "FOO", TargetWeight: 0.4},
+			{Type: "buy", Token: "BAR", TargetWeight: 0.6},
+		}, nil)
+		if value := component(breakdown, "improvement_vs_cost").Value; value != 0 {
+			t.Errorf("Expected no improvement left after costs, got %f", value)
+		}
+		if confidence >= 0.5 {
+			t.Errorf("Expected confidence below 0.5, got %f", confidence)
+		}
+	})
+
+	t.Run("hold recommendations score the unexecuted optimum", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		confidence, breakdown := engine.scoreConfidence(ctx, portfolio, map[string]float64{"ETH": 0.5, "BTC": 0.5}, nil, nil)
+		if confidence <= 0.5 {
+			t.Errorf("Expected holding an optimal portfolio to be confident, got %f", confidence)
+		}
+		if component(breakdown, "calibration").Detail != "calibration applies to trade recommendations only" {
+			t.Errorf("Expected hold recommendations to skip calibration, got %+v", component(breakdown, "calibration"))
+		}
+	})
+
+	t.Run("calibrator replaces the raw score", func(t *testing.T) {
+		engine := NewEnhancedAIEngine(WithConfidenceCalibrator(&mockCalibrator{calibrated: 0.62, samples: 40}))
+		confidence, breakdown := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil)
+		if confidence != 0.62 || !breakdown.Calibrated {
+			t.Errorf("Expected calibrated confidence 0.62, got %f (%+v)", confidence, breakdown)
+		}
+
+		engine = NewEnhancedAIEngine(WithConfidenceCalibrator(&mockCalibrator{calibrated: 0.62, samples: 3}))
+		if confidence, breakdown := engine.scoreConfidence(ctx, portfolio, nil, intoETH, nil); breakdown.Calibrated || confidence != breakdown.Raw {
+			t.Errorf("Expected raw confidence with too few outcomes, got %f (%+v)", confidence, breakdown)
+		}
+	})
+}
+
+func TestFitIsotonic(t *testing.T) {
+	fit := fitIsotonic([]calibrationPoint{
+		{raw: 0.2, hitRate: 0}, {raw: 0.3, hitRate: 1}, {raw: 0.4, hitRate: 0},
+		{raw: 0.7, hitRate: 1}, {raw: 0.8, hitRate: 1},
+	})
+	for i := 1; i < len(fit.points); i++ {
+		if fit.points[i].hitRate < fit.points[i-1].hitRate {
+			t.Errorf("Expected a non-decreasing fit, got %+v", fit.points)
+		}
+	}
+	if got := fit.at(0.1); got != 0 {
+		t.Errorf("Expected 0 below the fitted range, got %f", got)
+	}
+	if got := fit.at(0.9); got != 1 {
+		t.Errorf("Expected 1 above the fitted range, got %f", got)
+	}
+}
+
+func TestOutcomeEvaluator_CalibrateConfidence(t *testing.T) {
+	ctx := context.Background()
+	records, _ := store.NewFileRecommendationStore("")
+	evaluator, err := NewOutcomeEvaluator(records, &mockPriceHistory{}, DefaultOutcomeEvaluatorConfig())
+	if err != nil {
+		t.Fatalf("Failed to create evaluator: %v", err)
+	}
+
+	// Raw scores of 0.8 were right only half the time
+	for i := 0; i < 40; i++ {
+		saved, _ := records.Save(ctx, models.RecommendationRecord{
+			Kind: models.RecordKindRecommendation,
+			Recommendation: &models.RebalanceRecommendation{
+				Actions:             []models.RebalanceAction{{Type: "buy", Token: "ETH", TargetWeight: 1}},
+				ConfidenceBreakdown: &models.ConfidenceBreakdown{Raw: 0.8},
+			},
+			Engine: models.EngineInfo{Version: EngineVersion},
+		})
+		records.SaveOutcome(ctx, saved.ID, models.RecommendationOutcome{
+			Horizon: "7d", Status: models.OutcomeStatusEvaluated, Actionable: true, Hit: i%2 == 0,
+		})
+	}
+
+	if _, samples, ok := evaluator.CalibrateConfidence(ctx, "0.0.1", 0.8); ok || samples != 0 {
+		t.Errorf("Expected no calibration for an unseen version, got %d samples", samples)
+	}
+
+	calibrated, samples, ok := evaluator.CalibrateConfidence(ctx, EngineVersion, 0.8)
+	if !ok || samples != 40 {
+		t.Fatalf("Expected calibration from 40 samples, got %d (%v)", samples, ok)
+	}
+	// 40 outcomes against 20 prior pseudo-observations: 2/3 * 0.5 + 1/3 * 0.8
+	if math.Abs(calibrated-0.6) > 1e-9 {
+		t.Errorf("Expected calibrated confidence 0.6, got %f", calibrated)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"math"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// defaultChain is assumed for positions that do not name a chain
+const defaultChain = "ethereum"
+
+// CostModel estimates the cost of executing rebalancing actions
+type CostModel struct {
+	GasUSD           map[string]float64 // Gas for one DEX swap by chain, in USD
+	DefaultGasUSD    float64            // Gas for one swap on chains without an estimate
+	DEXFee           float64            // Pool fee as a fraction of the traded amount
+	Liquidity        map[string]float64 // Pool liquidity a token trades against, in USD
+	DefaultLiquidity float64            // Liquidity assumed for tokens without an estimate
+	BenefitHorizon   float64            // Years of expected gain weighed against the one-off cost of a trade
+}
+
+// DefaultCostModel returns cost estimates for typical mainnet and L2 conditions
+func DefaultCostModel() CostModel {
+	return CostModel{
+		GasUSD: map[string]float64{
+			"ethereum": 15,
+			"arbitrum": 0.3,
+			"optimism": 0.3,
+			"base":     0.2,
+			"polygon":  0.05,
+			"bsc":      0.3,
+		},
+		DefaultGasUSD: 15,
+		DEXFee:        0.003,
+		Liquidity: map[string]float64{
+			"BTC":  200000000,
+			"WBTC": 200000000,
+			"ETH":  300000000,
+			"WETH": 300000000,
+			"USDC": 500000000,
+			"USDT": 500000000,
+			"DAI":  200000000,
+		},
+		DefaultLiquidity: 5000000,
+		BenefitHorizon:   1,
+	}
+}
+
+// WithCostModel overrides the default transaction cost model
+func WithCostModel(model CostModel) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.costModel = model
+	}
+}
+
+// gas returns the gas cost of one transaction on chain
+func (m CostModel) gas(chain string) float64 {
+	if gas, ok := m.GasUSD[strings.ToLower(chain)]; ok {
+		return gas
+	}
+	return m.DefaultGasUSD
+}
+
+// liquidity returns the pool liquidity a token trades against, in USD
+func (m CostModel) liquidity(token string) float64 {
+	if liquidity, ok := m.Liquidity[strings.ToUpper(token)]; ok {
+		return liquidity
+	}
+	return m.DefaultLiquidity
+}
+
+// thinnerToken returns whichever of two tokens trades against less liquidity
+func (m CostModel) thinnerToken(a, b string) string {
+	if m.liquidity(b) < m.liquidity(a) {
+		return b
+	}
+	return a
+}
+
+// slippage returns the price impact of trading notional USD against a constant-product pool
+func (m CostModel) slippage(token string, notional float64) float64 {
+	reserve := m.liquidity(token) / 2 // Each side of the pool holds half its liquidity
+	if reserve <= 0 {
+		return notional
+	}
+	return notional * notional / (reserve + notional)
+}
+
+// SwapCost estimates the cost of buying or selling notional USD of token on chain
+func (m CostModel) SwapCost(chain, token string, notional float64) models.TradeCost {
+	cost := models.TradeCost{
+		Chain:       strings.ToLower(chain),
+		GasUSD:      m.gas(chain),
+		FeeUSD:      notional * m.DEXFee,
+		SlippageUSD: m.slippage(token, notional),
+	}
+	cost.TotalUSD = cost.GasUSD + cost.FeeUSD + cost.SlippageUSD
+	return cost
+}
+
+// MoveCost estimates the cost of withdrawing from one venue and depositing into another on chain
+func (m CostModel) MoveCost(chain string) models.TradeCost {
+	cost := models.TradeCost{Chain: strings.ToLower(chain), GasUSD: 2 * m.gas(chain)}
+	cost.TotalUSD = cost.GasUSD
+	return cost
+}
+
+// applyTradeCosts attaches estimated costs to buy, sell and rebalance actions and drops those whose
+// share of the expected risk-adjusted gain does not cover their cost. Dropping a trade changes the
+// target portfolio, so the remaining trades are re-evaluated until every one pays for itself.
+// Portfolios without a known value are returned unchanged since costs cannot be priced.
+func (e *EnhancedAIEngine) applyTradeCosts(portfolio models.Portfolio, actions []models.RebalanceAction) []models.RebalanceAction {
+	totalValue := portfolioValue(portfolio)
+	if totalValue <= 0 || len(actions) == 0 {
+		return actions
+	}
+
+	weights, yields := positionWeights(portfolio)
+	chains := positionChains(portfolio)
+	quote := e.quoteToken(weights)
+	prices := e.tokenPrices(portfolio, weights)
+
+	for {
+		benefits := e.tradeBenefits(portfolio, actions, weights, yields, totalValue)
+
+		kept := make([]models.RebalanceAction, 0, len(actions))
+		for i, action := range actions {
+			token := strings.ToUpper(action.Token)
+			notional := math.Abs(action.TargetWeight-weights[token]) * totalValue
+
+			// Sales settle into the quote token and purchases are paid from it
+			from, to := token, quote
+			if action.TargetWeight > weights[token] {
+				from, to = quote, token
+			}
+			cost, _ := e.estimateSwap(tokenChain(chains, token), from, to, notional, prices)
+			cost.ExpectedBenefit = benefits[i]
+			action.EstimatedCost = &cost
+			if cost.ExpectedBenefit > cost.TotalUSD {
+				kept = append(kept, action)
+			}
+		}
+
+		if len(kept) == len(actions) {
+			return kept
+		}
+		actions = kept
+		if len(actions) == 0 {
+			return nil
+		}
+	}
+}
+
+// tradeBenefits splits the risk-adjusted gain of executing all actions over the benefit horizon
+// across the actions in proportion to the weight each one trades. The gain is the Sharpe ratio
+// improvement earned at the current volatility, or the expected return change for riskless portfolios.
+func (e *EnhancedAIEngine) tradeBenefits(portfolio models.Portfolio, actions []models.RebalanceAction, weights, yields map[string]float64, totalValue float64) []float64 {
+	target, targetYields := applyRecommendation(&models.RebalanceRecommendation{Actions: actions}, weights, yields)
+	current := e.projection(portfolio, weights, yields, nil)
+	projected := e.projection(portfolio, target, targetYields, nil)
+
+	gain := projected.ExpectedReturn - current.ExpectedReturn
+	if volatility := current.RiskMetrics.Volatility; volatility > 0 {
+		gain = (projected.RiskMetrics.SharpeRatio - current.RiskMetrics.SharpeRatio) * volatility
+	}
+	gainUSD := gain * totalValue * e.costModel.BenefitHorizon
+
+	traded := make([]float64, len(actions))
+	totalTraded := 0.0
+	for i, action := range actions {
+		traded[i] = math.Abs(action.TargetWeight - weights[strings.ToUpper(action.Token)])
+		totalTraded += traded[i]
+	}
+
+	benefits := make([]float64, len(actions))
+	for i := range actions {
+		if totalTraded > 0 {
+			benefits[i] = gainUSD * traded[i] / totalTraded
+		}
+	}
+	return benefits
+}
+
+// tradeTargets returns the target weight of every token a buy, sell or rebalance action trades
+func tradeTargets(actions []models.RebalanceAction) map[string]float64 {
+	targets := make(map[string]float64)
+	for _, action := range actions {
+		if action.Type != actionTypeMove {
+			targets[action.Token] = action.TargetWeight
+		}
+	}
+	return targets
+}
+
+// positionChains returns the chain each token is held on, keyed by upper-case token
+func positionChains(portfolio models.Portfolio) map[string]string {
+	chains := make(map[string]string)
+	for _, position := range portfolio.Positions {
+		token := strings.ToUpper(position.Token)
+		if _, seen := chains[token]; !seen && position.Chain != "" {
+			chains[token] = position.Chain
+		}
+	}
+	return chains
+}
+
+// tokenChain returns the chain a token is held on, or the default chain
+func tokenChain(chains map[string]string, token string) string {
+	if chain, ok := chains[token]; ok {
+		return chain
+	}
+	return defaultChain
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// scaledTestPortfolio returns the test portfolio worth value, held on chain
+func scaledTestPortfolio(value float64, chain string) models.Portfolio {
+	portfolio := createTestPortfolio()
+	portfolio.TotalValue = value
+	for i := range portfolio.Positions {
+		portfolio.Positions[i].Value = portfolio.Positions[i].Weight * value
+		portfolio.Positions[i].Chain = chain
+	}
+	return portfolio
+}
+
+func TestCostModel_SwapCost(t *testing.T) {
+	model := DefaultCostModel()
+
+	t.Run("gas depends on the chain", func(t *testing.T) {
+		mainnet := model.SwapCost("Ethereum", "ETH", 1000)
+		l2 := model.SwapCost("arbitrum", "ETH", 1000)
+		if mainnet.GasUSD <= l2.GasUSD {
+			t.Errorf("Expected mainnet gas above arbitrum gas, got %f and %f", mainnet.GasUSD, l2.GasUSD)
+		}
+		if unknown := model.SwapCost("unknown-chain", "ETH", 1000); unknown.GasUSD != model.DefaultGasUSD {
+			t.Errorf("Expected default gas %f for unknown chains, got %f", model.DefaultGasUSD, unknown.GasUSD)
+		}
+	})
+
+	t.Run("slippage grows faster than trade size", func(t *testing.T) {
+		small := model.SwapCost("ethereum", "LINK", 10000)
+		large := model.SwapCost("ethereum", "LINK", 100000)
+		if large.SlippageUSD/100000 <= small.SlippageUSD/10000 {
+			t.Errorf("Expected larger trades to slip more per dollar, got %f and %f", small.SlippageUSD, large.SlippageUSD)
+		}
+		deep := model.SwapCost("ethereum", "ETH", 100000)
+		if deep.SlippageUSD >= large.SlippageUSD {
+			t.Errorf("Expected deeper liquidity to slip less, got %f for ETH and %f for LINK", deep.SlippageUSD, large.SlippageUSD)
+		}
+	})
+
+	t.Run("total adds gas, fees and slippage", func(t *testing.T) {
+		cost := model.SwapCost("ethereum", "ETH", 50000)
+		if math.Abs(cost.TotalUSD-(cost.GasUSD+cost.FeeUSD+cost.SlippageUSD)) > 1e-9 {
+			t.Errorf("Expected total to sum its parts, got %+v", cost)
+		}
+		if cost.FeeUSD != 150 {
+			t.Errorf("Expected a 0.3%% fee of 150, got %f", cost.FeeUSD)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_TradeCosts(t *testing.T) {
+	ctx := context.Background()
+	engine := NewEnhancedAIEngine()
+
+	t.Run("large portfolios trade with costs attached", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, scaledTestPortfolio(100000, "ethereum"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(recommendation.Actions) == 0 {
+			t.Fatal("Expected trades for a $100k portfolio")
+		}
+		for _, action := range recommendation.Actions {
+			if action.EstimatedCost == nil {
+				t.Fatalf("Expected an estimated cost on %s %s", action.Type, action.Token)
+			}
+			if action.EstimatedCost.ExpectedBenefit <= action.EstimatedCost.TotalUSD {
+				t.Errorf("Expected only trades whose benefit exceeds cost, got %+v", action.EstimatedCost)
+			}
+		}
+	})
+
+	t.Run("mainnet gas outweighs the gain of small portfolios", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, scaledTestPortfolio(200, "ethereum"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(recommendation.Actions) != 0 {
+			t.Errorf("Expected no trades for a $200 mainnet portfolio, got %+v", recommendation.Actions)
+		}
+	})
+
+	t.Run("cheap gas lets small portfolios trade", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, scaledTestPortfolio(200, "arbitrum"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(recommendation.Actions) == 0 {
+			t.Fatal("Expected trades for a $200 arbitrum portfolio")
+		}
+		if chain := recommendation.Actions[0].EstimatedCost.Chain; chain != "arbitrum" {
+			t.Errorf("Expected costs priced on arbitrum, got %s", chain)
+		}
+	})
+
+	t.Run("portfolios without a value are not filtered", func(t *testing.T) {
+		portfolio := scaledTestPortfolio(0, "ethereum")
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(recommendation.Actions) == 0 || recommendation.Actions[0].EstimatedCost != nil {
+			t.Errorf("Expected unpriced trades, got %+v", recommendation.Actions)
+		}
+	})
+}
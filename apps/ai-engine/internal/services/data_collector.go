@@ -2,26 +2,45 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/valkyriefinance/ai-engine/internal/models"
 )
 
+// Price history sampling: one point per interval keeps 30 days within the series bound
+const (
+	priceHistoryInterval  = 5 * time.Minute
+	priceHistoryPoints    = 10000
+	priceHistoryTolerance = time.Hour // Maximum gap between a requested time and the price used
+	priceBackfillPoints   = 100000    // Bound on each token's on-demand backfill, about 11 years hourly
+)
+
+// ErrTokenNotTracked is returned for prices of tokens the collector does not poll
+var ErrTokenNotTracked = errors.New("token not tracked")
+
 // DataCollector handles real-time market data collection from multiple sources
 type DataCollector struct {
-	priceFeeds  map[string]chan models.PriceData
-	yieldFeeds  map[string]chan models.YieldData
-	subscribers map[string][]chan models.PriceData
-	mu          sync.RWMutex
-	httpClient  *http.Client
-	ctx         context.Context
-	cancel      context.CancelFunc
+	prices        *Hub[models.PriceData]
+	indicators    *Hub[models.MarketIndicators]
+	latestPrices  map[string]*models.PriceData
+	pricesFetched time.Time // When latestPrices was last refreshed from CoinGecko
+	priceHistory  *TimeSeriesStore
+	backfills     *TimeSeriesStore      // Prices fetched on demand, kept apart from the trimmed live samples
+	backfilled    map[string][]timeSpan // Spans fetched into backfills by CoinGecko id, sorted and disjoint
+	trackedTokens map[string]string     // CoinGecko id to symbol
+	priceBaseURL  string
+	mu            sync.RWMutex
+	provider      *ProviderClient
+	yieldStore    *YieldStore
+	riskModel     *ProtocolRiskModel
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
 // CoinGeckoResponse represents the response from CoinGecko API
@@ -46,14 +65,34 @@ type DeFiLlamaResponse struct {
 // NewDataCollector creates a new data collector instance
 func NewDataCollector() *DataCollector {
 	ctx, cancel := context.WithCancel(context.Background())
+	yieldStore := NewYieldStore()
+
+	riskModel, err := NewDefaultProtocolRiskModel(yieldStore)
+	if err != nil {
+		log.Printf("Failed to load protocol registry, using built-in registry: %v", err)
+		registry, _ := LoadProtocolRegistry("")
+		riskModel = NewProtocolRiskModel(registry, yieldStore)
+	}
 
 	return &DataCollector{
-		priceFeeds:  make(map[string]chan models.PriceData),
-		yieldFeeds:  make(map[string]chan models.YieldData),
-		subscribers: make(map[string][]chan models.PriceData),
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
-		ctx:         ctx,
-		cancel:      cancel,
+		prices:       NewHub[models.PriceData](defaultSubscriberBuffer),
+		indicators:   NewHub[models.MarketIndicators](defaultSubscriberBuffer),
+		latestPrices: make(map[string]*models.PriceData),
+		priceHistory: NewTimeSeriesStore(priceHistoryPoints),
+		backfills:    NewTimeSeriesStore(priceBackfillPoints),
+		backfilled:   make(map[string][]timeSpan),
+		trackedTokens: map[string]string{
+			"ethereum":  "ETH",
+			"bitcoin":   "BTC",
+			"chainlink": "LINK",
+			"uniswap":   "UNI",
+		},
+		priceBaseURL: "https://api.coingecko.com/api/v3",
+		provider:     DefaultProviderClient(),
+		yieldStore:   yieldStore,
+		riskModel:    riskModel,
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -61,11 +100,9 @@ func NewDataCollector() *DataCollector {
 func (dc *DataCollector) Start() error {
 	log.Println("Starting data collector...")
 
-	// Start price data collection for major tokens
-	tokens := []string{"ethereum", "bitcoin", "chainlink", "uniswap"}
-	for _, token := range tokens {
-		go dc.collectPriceData(token)
-	}
+	// Start price data collection for major tokens, with the history return estimates look back on
+	go dc.collectPriceData()
+	go dc.backfillHistory()
 
 	// Start yield data collection
 	go dc.collectYieldData()
@@ -80,6 +117,10 @@ func (dc *DataCollector) Start() error {
 func (dc *DataCollector) Stop() error {
 	log.Println("Stopping data collector...")
 	dc.cancel()
+
+	// Release every subscriber so stream consumers can finish
+	dc.prices.Close()
+	dc.indicators.Close()
 	return nil
 }
 
@@ -97,57 +138,340 @@ func (dc *DataCollector) GetMarketIndicators() (*models.MarketIndicators, error)
 	}, nil
 }
 
-// Subscribe subscribes to price updates for a token
-func (dc *DataCollector) Subscribe(token string) chan models.PriceData {
-	dc.mu.Lock()
-	defer dc.mu.Unlock()
+// Subscribe subscribes to price updates for a token symbol or CoinGecko id.
+// The subscription ends when ctx is done, Unsubscribe is called or the collector stops.
+func (dc *DataCollector) Subscribe(ctx context.Context, token string) *Subscription[models.PriceData] {
+	return dc.prices.Subscribe(ctx, dc.priceSymbol(token))
+}
 
-	// Create a new subscriber channel
-	ch := make(chan models.PriceData, 10)
+// SubscribeIndicators subscribes to market indicator updates
+func (dc *DataCollector) SubscribeIndicators(ctx context.Context) *Subscription[models.MarketIndicators] {
+	return dc.indicators.Subscribe(ctx, "")
+}
 
-	if dc.subscribers[token] == nil {
-		dc.subscribers[token] = make([]chan models.PriceData, 0)
+// HubStats returns subscriber and drop counters of the price and indicator hubs
+func (dc *DataCollector) HubStats() map[string]HubStats {
+	return map[string]HubStats{
+		"prices":     dc.prices.Stats(),
+		"indicators": dc.indicators.Stats(),
 	}
-
-	dc.subscribers[token] = append(dc.subscribers[token], ch)
-	return ch
 }
 
-// GetLatestPrice returns the latest price for a token (mock implementation)
+// GetLatestPrice returns the latest price for a token symbol or CoinGecko id, refreshing
+// tracked prices when the last fetch is more than a minute old
 func (dc *DataCollector) GetLatestPrice(token string) (*models.PriceData, error) {
-	// Return mock data to avoid API parsing issues
-	// TODO: Fix CoinGecko API parsing
-	priceData := &models.PriceData{
-		Symbol:    token,
-		Price:     2000.0, // Mock price
-		Volume24h: 1000000.0,
-		Change24h: 0.05,          // 5% change
-		MarketCap: 50000000000.0, // 50B market cap
-		Timestamp: time.Now(),
-		Source:    "mock",
+	symbol := dc.priceSymbol(token)
+	if dc.coinID(symbol) == "" {
+		return nil, fmt.Errorf("%w: %s", ErrTokenNotTracked, token)
+	}
+
+	dc.mu.RLock()
+	cached, exists := dc.latestPrices[symbol]
+	fresh := time.Since(dc.pricesFetched) < time.Minute
+	dc.mu.RUnlock()
+
+	if !fresh {
+		if err := dc.refreshPrices(); err != nil && !exists {
+			return nil, err
+		}
+		// Serve stale data rather than nothing when the refresh fails
+		dc.mu.RLock()
+		if latest, ok := dc.latestPrices[symbol]; ok {
+			cached, exists = latest, true
+		}
+		dc.mu.RUnlock()
 	}
 
-	return priceData, nil
+	if !exists {
+		return nil, fmt.Errorf("no price data for token %s", token)
+	}
+	data := *cached
+	return &data, nil
 }
 
-// GetYieldData fetches yield data from DeFiLlama
-func (dc *DataCollector) GetYieldData() ([]models.YieldData, error) {
-	url := "https://yields.llama.fi/pools"
+// CachedPrices returns the latest polled prices without refreshing them
+func (dc *DataCollector) CachedPrices() map[string]models.PriceData {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
 
-	resp, err := dc.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch yield data: %w", err)
+	prices := make(map[string]models.PriceData, len(dc.latestPrices))
+	for symbol, data := range dc.latestPrices {
+		prices[symbol] = *data
 	}
-	defer resp.Body.Close()
+	return prices
+}
 
-	body, err := io.ReadAll(resp.Body)
+// CachedMarketCaps returns the market caps of the latest fetched prices, skipping unknown caps
+func (dc *DataCollector) CachedMarketCaps() map[string]float64 {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	caps := make(map[string]float64, len(dc.latestPrices))
+	for symbol, data := range dc.latestPrices {
+		if data.MarketCap > 0 {
+			caps[symbol] = data.MarketCap
+		}
+	}
+	return caps
+}
+
+// priceSymbol normalises a CoinGecko id or symbol to the upper-case symbol used as hub topic
+func (dc *DataCollector) priceSymbol(token string) string {
+	if symbol, ok := dc.trackedTokens[strings.ToLower(token)]; ok {
+		return symbol
+	}
+	return strings.ToUpper(token)
+}
+
+// fetchPrices fetches current prices of all tracked tokens from CoinGecko in one request
+func (dc *DataCollector) fetchPrices(ctx context.Context) ([]models.PriceData, error) {
+	ids := make([]string, 0, len(dc.trackedTokens))
+	for id := range dc.trackedTokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=usd&include_24hr_change=true&include_24hr_vol=true&include_market_cap=true&include_last_updated_at=true",
+		dc.priceBaseURL, strings.Join(ids, ","))
+
+	var result map[string]CoinPriceData
+	if err := dc.provider.GetJSON(ctx, url, &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+
+	now := time.Now()
+	prices := make([]models.PriceData, 0, len(result))
+	for _, id := range ids {
+		coin, ok := result[id]
+		if !ok || coin.USD <= 0 {
+			continue
+		}
+		timestamp := now
+		if coin.LastUpdated > 0 {
+			timestamp = time.Unix(coin.LastUpdated, 0)
+		}
+		prices = append(prices, models.PriceData{
+			Symbol:    dc.trackedTokens[id],
+			Price:     coin.USD,
+			Volume24h: coin.USDVolume24h,
+			Change24h: coin.USDChange24h,
+			MarketCap: coin.MarketCap,
+			Timestamp: timestamp,
+			Source:    "coingecko",
+		})
+	}
+	return prices, nil
+}
+
+// refreshPrices fetches prices, caches them and publishes them to subscribers
+func (dc *DataCollector) refreshPrices() error {
+	ctx, cancel := context.WithTimeout(dc.ctx, 15*time.Second)
+	defer cancel()
+
+	prices, err := dc.fetchPrices(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return err
+	}
+
+	dc.mu.Lock()
+	for i := range prices {
+		data := prices[i]
+		dc.latestPrices[data.Symbol] = &data
+	}
+	dc.pricesFetched = time.Now()
+	dc.mu.Unlock()
+
+	for _, data := range prices {
+		key := PriceSeriesKey(data.Symbol)
+		if latest, ok := dc.priceHistory.Latest(key); !ok || data.Timestamp.Sub(latest.Timestamp) >= priceHistoryInterval {
+			dc.priceHistory.Append(key, data.Timestamp, data.Price)
+			if data.Volume24h > 0 {
+				dc.priceHistory.Append(VolumeSeriesKey(data.Symbol), data.Timestamp, data.Volume24h)
+			}
+		}
 	}
 
+	for _, data := range prices {
+		dc.prices.Publish(data.Symbol, data)
+	}
+	return nil
+}
+
+// PriceHistory returns the sampled price and volume history of the tracked tokens
+func (dc *DataCollector) PriceHistory() *TimeSeriesStore {
+	return dc.priceHistory
+}
+
+// ProviderMetrics returns outcome counters for outbound provider requests
+func (dc *DataCollector) ProviderMetrics() map[string]interface{} {
+	return dc.provider.Metrics()
+}
+
+// PriceAt returns the USD price of a token closest to at, backfilling from CoinGecko
+// when neither the sampled nor the backfilled history covers that time
+func (dc *DataCollector) PriceAt(ctx context.Context, token string, at time.Time) (float64, error) {
+	symbol := dc.priceSymbol(token)
+	key := PriceSeriesKey(symbol)
+	for _, history := range []*TimeSeriesStore{dc.priceHistory, dc.backfills} {
+		if point, ok := history.Nearest(key, at, priceHistoryTolerance); ok {
+			return point.Value, nil
+		}
+	}
+
+	id := dc.coinID(symbol)
+	if id == "" {
+		return 0, fmt.Errorf("no price history for token %s", token)
+	}
+	if err := dc.loadBackfill(ctx, id, at.Add(-priceHistoryTolerance), at.Add(priceHistoryTolerance)); err != nil {
+		return 0, err
+	}
+
+	if point, ok := dc.backfills.Nearest(key, at, priceHistoryTolerance); ok {
+		return point.Value, nil
+	}
+	return 0, fmt.Errorf("no price for token %s near %s", token, at.Format(time.RFC3339))
+}
+
+// PriceRange returns the prices of a tracked token between from and to, fetching only the parts
+// of the range not backfilled before, in one request each
+func (dc *DataCollector) PriceRange(ctx context.Context, token string, from, to time.Time) ([]models.TimeSeriesPoint, error) {
+	symbol := dc.priceSymbol(token)
+	id := dc.coinID(symbol)
+	if id == "" {
+		return nil, fmt.Errorf("%w: %s", ErrTokenNotTracked, token)
+	}
+	if err := dc.loadBackfill(ctx, id, from, to); err != nil {
+		return nil, err
+	}
+
+	key := PriceSeriesKey(symbol)
+	points := append(dc.backfills.Range(key, from, to), dc.priceHistory.Range(key, from, to)...)
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	return points, nil
+}
+
+// coinID returns the CoinGecko id of a tracked symbol
+func (dc *DataCollector) coinID(symbol string) string {
+	for id, tracked := range dc.trackedTokens {
+		if tracked == symbol {
+			return id
+		}
+	}
+	return ""
+}
+
+// loadBackfill fetches the parts of from to to that have not been backfilled for a coin into the
+// backfill store, which live samples cannot trim
+func (dc *DataCollector) loadBackfill(ctx context.Context, id string, from, to time.Time) error {
+	if now := time.Now(); to.After(now) {
+		to = now // Later prices do not exist yet, so the span is fetched again once they do
+	}
+	key := PriceSeriesKey(dc.trackedTokens[id])
+
+	dc.mu.RLock()
+	missing := missingSpans(dc.backfilled[id], timeSpan{from: from, to: to})
+	dc.mu.RUnlock()
+
+	for _, span := range missing {
+		points, err := dc.fetchPriceRange(ctx, id, span.from, span.to)
+		if err != nil {
+			return err
+		}
+		dc.backfills.AppendPoints(key, points)
+
+		dc.mu.Lock()
+		dc.backfilled[id] = addSpan(dc.backfilled[id], span)
+		dc.mu.Unlock()
+	}
+	return nil
+}
+
+// fetchPriceRange fetches CoinGecko prices of a coin between from and to
+func (dc *DataCollector) fetchPriceRange(ctx context.Context, id string, from, to time.Time) ([]models.TimeSeriesPoint, error) {
+	url := fmt.Sprintf("%s/coins/%s/market_chart/range?vs_currency=usd&from=%d&to=%d",
+		dc.priceBaseURL, id, from.Unix(), to.Unix())
+
+	var chart CoinGeckoResponse
+	if err := dc.provider.GetJSON(ctx, url, &chart); err != nil {
+		return nil, fmt.Errorf("failed to fetch price history for %s: %w", id, err)
+	}
+
+	points := make([]models.TimeSeriesPoint, 0, len(chart.Prices))
+	for _, entry := range chart.Prices {
+		if len(entry) < 2 || entry[1] <= 0 {
+			continue
+		}
+		points = append(points, models.TimeSeriesPoint{
+			Timestamp: time.UnixMilli(int64(entry[0])),
+			Value:     entry[1],
+		})
+	}
+	return points, nil
+}
+
+// timeSpan is a closed interval of time
+type timeSpan struct {
+	from, to time.Time
+}
+
+// missingSpans returns the parts of want not covered by spans, which are sorted and disjoint
+func missingSpans(spans []timeSpan, want timeSpan) []timeSpan {
+	var missing []timeSpan
+	from := want.from
+	for _, span := range spans {
+		if !from.Before(want.to) {
+			break
+		}
+		if !span.to.After(from) {
+			continue
+		}
+		if span.from.After(from) {
+			end := span.from
+			if end.After(want.to) {
+				end = want.to
+			}
+			missing = append(missing, timeSpan{from: from, to: end})
+		}
+		from = span.to
+	}
+	if from.Before(want.to) {
+		missing = append(missing, timeSpan{from: from, to: want.to})
+	}
+	return missing
+}
+
+// addSpan merges span into sorted, disjoint spans
+func addSpan(spans []timeSpan, span timeSpan) []timeSpan {
+	merged := make([]timeSpan, 0, len(spans)+1)
+	for _, existing := range spans {
+		switch {
+		case existing.to.Before(span.from):
+			merged = append(merged, existing)
+		case span.to.Before(existing.from):
+			merged = append(merged, span)
+			span = existing
+		default:
+			if existing.from.Before(span.from) {
+				span.from = existing.from
+			}
+			if existing.to.After(span.to) {
+				span.to = existing.to
+			}
+		}
+	}
+	return append(merged, span)
+}
+
+// GetYieldData fetches yield data from DeFiLlama
+func (dc *DataCollector) GetYieldData() ([]models.YieldData, error) {
+	url := "https://yields.llama.fi/pools"
+
+	ctx, cancel := context.WithTimeout(dc.ctx, 30*time.Second)
+	defer cancel()
+
 	var result DeFiLlamaResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := dc.provider.GetJSON(ctx, url, &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch yield data: %w", err)
 	}
 
 	var yieldData []models.YieldData
@@ -155,38 +479,64 @@ func (dc *DataCollector) GetYieldData() ([]models.YieldData, error) {
 		// Filter for relevant protocols and minimum TVL
 		if pool.TVL > 1000000 && pool.APY > 0 { // Min $1M TVL
 			yieldData = append(yieldData, models.YieldData{
+				PoolID:    pool.Pool,
+				Chain:     pool.Chain,
 				Protocol:  pool.Project,
 				Token:     pool.Symbol,
 				APY:       pool.APY / 100, // Convert percentage to decimal
 				TVL:       pool.TVL,
-				Risk:      dc.calculateRiskScore(pool.Project, pool.TVL),
 				Timestamp: time.Now(),
 			})
 		}
 	}
 
+	// Score the batch together so APYs are compared against peer pools
+	dc.riskModel.ScorePools(yieldData)
+
 	return yieldData, nil
 }
 
-// collectPriceData continuously collects price data for a specific token
-func (dc *DataCollector) collectPriceData(token string) {
-	ticker := time.NewTicker(5 * time.Second) // Update every 5 seconds
+// collectPriceData continuously collects prices of the tracked tokens
+func (dc *DataCollector) collectPriceData() {
+	// CoinGecko's public tier is shared with other collectors, so poll every 15 seconds
+	ticker := time.NewTicker(15 * time.Second)
 	defer ticker.Stop()
 
 	for {
+		if err := dc.refreshPrices(); err != nil && dc.ctx.Err() == nil {
+			log.Printf("Error fetching price data: %v", err)
+		}
+
 		select {
 		case <-dc.ctx.Done():
 			return
 		case <-ticker.C:
-			priceData, err := dc.GetLatestPrice(token)
-			if err != nil {
-				log.Printf("Error fetching price data for %s: %v", token, err)
-				continue
-			}
+		}
+	}
+}
+
+// backfillHistory loads the confidence lookback of prices for every tracked token, one request
+// per token, so return estimates and regime detection need not wait for samples to accumulate
+func (dc *DataCollector) backfillHistory() {
+	ctx, cancel := context.WithTimeout(dc.ctx, time.Minute)
+	defer cancel()
 
-			// Broadcast to subscribers
-			dc.broadcastPriceData(token, *priceData)
+	ids := make([]string, 0, len(dc.trackedTokens))
+	for id := range dc.trackedTokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	to := time.Now()
+	for _, id := range ids {
+		points, err := dc.fetchPriceRange(ctx, id, to.Add(-confidenceLookback), to)
+		if err != nil {
+			if dc.ctx.Err() == nil {
+				log.Printf("Error backfilling price history: %v", err)
+			}
+			continue
 		}
+		dc.priceHistory.AppendPoints(PriceSeriesKey(dc.trackedTokens[id]), points)
 	}
 }
 
@@ -195,23 +545,31 @@ func (dc *DataCollector) collectYieldData() {
 	ticker := time.NewTicker(1 * time.Minute) // Update every minute
 	defer ticker.Stop()
 
+	// Populate the store immediately rather than after the first tick
+	dc.refreshYieldData()
+
 	for {
 		select {
 		case <-dc.ctx.Done():
 			return
 		case <-ticker.C:
-			yieldData, err := dc.GetYieldData()
-			if err != nil {
-				log.Printf("Error fetching yield data: %v", err)
-				continue
-			}
-
-			// Process and store yield data
-			dc.processYieldData(yieldData)
+			dc.refreshYieldData()
 		}
 	}
 }
 
+// refreshYieldData fetches and stores one round of yield data
+func (dc *DataCollector) refreshYieldData() {
+	yieldData, err := dc.GetYieldData()
+	if err != nil {
+		log.Printf("Error fetching yield data: %v", err)
+		return
+	}
+
+	// Process and store yield data
+	dc.processYieldData(yieldData)
+}
+
 // collectMarketIndicators collects broader market indicators
 func (dc *DataCollector) collectMarketIndicators() {
 	ticker := time.NewTicker(30 * time.Second) // Update every 30 seconds
@@ -235,26 +593,15 @@ func (dc *DataCollector) collectMarketIndicators() {
 	}
 }
 
-// broadcastPriceData sends price data to all subscribers
-func (dc *DataCollector) broadcastPriceData(token string, data models.PriceData) {
-	dc.mu.RLock()
-	subscribers := dc.subscribers[token]
-	dc.mu.RUnlock()
-
-	for _, ch := range subscribers {
-		select {
-		case ch <- data:
-		default:
-			// Channel is full, skip this update
-		}
-	}
+// processYieldData stores yield snapshots and their APY history
+func (dc *DataCollector) processYieldData(yieldData []models.YieldData) {
+	stored := dc.yieldStore.Ingest(yieldData)
+	log.Printf("Processed %d yield data points (%d pools tracked)", stored, dc.yieldStore.Len())
 }
 
-// processYieldData processes and stores yield data
-func (dc *DataCollector) processYieldData(yieldData []models.YieldData) {
-	// TODO: Store in time-series database (InfluxDB)
-	// For now, just log the data
-	log.Printf("Processed %d yield data points", len(yieldData))
+// YieldStore returns the store of collected yield pools
+func (dc *DataCollector) YieldStore() *YieldStore {
+	return dc.yieldStore
 }
 
 // getMarketIndicators fetches market indicators
@@ -274,40 +621,9 @@ func (dc *DataCollector) getMarketIndicators() (*models.MarketIndicators, error)
 
 // processMarketIndicators processes market indicators
 func (dc *DataCollector) processMarketIndicators(indicators *models.MarketIndicators) {
+	dc.indicators.Publish("", *indicators)
+
 	// TODO: Store in database and trigger AI analysis
 	log.Printf("Market cap: $%.2fB, BTC dominance: %.2f%%, ETH dominance: %.2f%%",
 		indicators.TotalMarketCap/1e9, indicators.BTCDominance, indicators.ETHDominance)
 }
-
-// calculateRiskScore calculates a risk score for a protocol
-func (dc *DataCollector) calculateRiskScore(protocol string, tvl float64) float64 {
-	// Simple risk scoring based on protocol reputation and TVL
-	// TODO: Implement more sophisticated risk analysis
-
-	highRepProtocols := map[string]bool{
-		"aave": true, "compound": true, "uniswap": true, "curve": true,
-		"lido": true, "convex": true, "yearn": true,
-	}
-
-	risk := 0.5 // Base risk
-
-	if highRepProtocols[protocol] {
-		risk -= 0.2 // Lower risk for established protocols
-	}
-
-	if tvl > 100000000 { // $100M+
-		risk -= 0.1 // Lower risk for high TVL protocols
-	} else if tvl < 10000000 { // $10M-
-		risk += 0.2 // Higher risk for low TVL protocols
-	}
-
-	// Ensure risk is between 0 and 1
-	if risk < 0 {
-		risk = 0
-	}
-	if risk > 1 {
-		risk = 1
-	}
-
-	return risk
-}
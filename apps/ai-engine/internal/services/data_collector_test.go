@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// newPriceFixtureCollector creates a collector reading prices from a fake CoinGecko server
+func newPriceFixtureCollector(t *testing.T, handler http.HandlerFunc) *DataCollector {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	config := DefaultProviderClientConfig()
+	config.BaseBackoff = time.Millisecond
+	config.MaxBackoff = time.Millisecond
+	config.MaxRetries = 0
+
+	collector := NewDataCollector()
+	collector.provider = NewProviderClient(config)
+	collector.priceBaseURL = srv.URL
+	t.Cleanup(func() { collector.Stop() })
+	return collector
+}
+
+// TestDataCollector_PriceSubscription tests that fetched prices reach subscribers
+func TestDataCollector_PriceSubscription(t *testing.T) {
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/price" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ethereum": {"usd": 2500, "usd_24h_change": 1.5, "usd_24h_vol": 8e9}, "bitcoin": {"usd": 60000}}`))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribing by CoinGecko id and by symbol share a topic
+	byID := collector.Subscribe(ctx, "ethereum")
+	bySymbol := collector.Subscribe(ctx, "ETH")
+
+	if err := collector.refreshPrices(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, sub := range []*Subscription[models.PriceData]{byID, bySymbol} {
+		data, _ := receiveWithin(t, sub.C)
+		if data.Symbol != "ETH" || data.Price != 2500 || data.Source != "coingecko" {
+			t.Errorf("Unexpected price update: %+v", data)
+		}
+	}
+
+	latest, err := collector.GetLatestPrice("BTC")
+	if err != nil || latest.Price != 60000 {
+		t.Errorf("Expected cached BTC price 60000, got %+v (%v)", latest, err)
+	}
+
+	if _, err := collector.GetLatestPrice("DOGE"); !errors.Is(err, ErrTokenNotTracked) {
+		t.Errorf("Expected ErrTokenNotTracked for untracked token, got %v", err)
+	}
+
+	if volume, ok := collector.priceHistory.Latest(VolumeSeriesKey("ETH")); !ok || volume.Value != 8e9 {
+		t.Errorf("Expected ETH volume sampled with its price, got %+v", volume)
+	}
+
+	// Pending updates are still delivered before the channel closes
+	collector.Stop()
+	for {
+		if _, ok := receiveWithin(t, byID.C); !ok {
+			break
+		}
+	}
+}
+
+// TestDataCollector_LatestPriceRefresh tests that cache misses fetch at most once a minute
+func TestDataCollector_LatestPriceRefresh(t *testing.T) {
+	requests := 0
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 60000, "last_updated_at": 1}}`))
+	})
+
+	for _, token := range []string{"USDC", "AAVE", "usdc"} {
+		if _, err := collector.GetLatestPrice(token); !errors.Is(err, ErrTokenNotTracked) {
+			t.Errorf("Expected ErrTokenNotTracked for %s, got %v", token, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected untracked tokens not to fetch, got %d requests", requests)
+	}
+
+	// A stale provider timestamp does not force a refresh after our own fetch
+	for i := 0; i < 3; i++ {
+		if latest, err := collector.GetLatestPrice("BTC"); err != nil || latest.Price != 60000 {
+			t.Errorf("Expected BTC at 60000, got %+v (%v)", latest, err)
+		}
+	}
+	if _, err := collector.GetLatestPrice("ETH"); err == nil {
+		t.Error("Expected an error for a tracked token missing from the response")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request within a minute, got %d", requests)
+	}
+}
+
+// TestDataCollector_CachedMarketCaps tests that market cap weights come from cached prices only
+func TestDataCollector_CachedMarketCaps(t *testing.T) {
+	requests := 0
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 60000, "usd_market_cap": 750}, "ethereum": {"usd": 2500, "usd_market_cap": 250}}`))
+	})
+	engine := NewEnhancedAIEngine(WithMarketCaps(collector))
+
+	if _, ok := engine.marketWeights([]string{"BTC", "ETH"}); ok {
+		t.Error("Expected no market weights before prices are cached")
+	}
+	if requests != 0 {
+		t.Errorf("Expected market weights not to fetch, got %d requests", requests)
+	}
+
+	if err := collector.refreshPrices(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	weights, ok := engine.marketWeights([]string{"BTC", "ETH", "USDC"})
+	if !ok || weights[0] != 0.75 || weights[1] != 0.25 || weights[2] != 0 {
+		t.Errorf("Expected cached weights [0.75 0.25 0], got %v", weights)
+	}
+	if requests != 1 {
+		t.Errorf("Expected only the refresh to fetch, got %d requests", requests)
+	}
+}
+
+// TestDataCollector_BackfillHistory tests that startup loads the confidence lookback once per tracked token
+func TestDataCollector_BackfillHistory(t *testing.T) {
+	requests := make(map[string]int)
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		if time.Duration(to-from)*time.Second != confidenceLookback {
+			t.Errorf("Expected a %s range, got %s", confidenceLookback, time.Duration(to-from)*time.Second)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"prices": [[%d, 100], [%d, 101]]}`,
+			time.Unix(from, 0).UnixMilli(), time.Unix(to, 0).UnixMilli())
+	})
+
+	collector.backfillHistory()
+
+	if len(requests) != len(collector.trackedTokens) {
+		t.Errorf("Expected a request per tracked token, got %v", requests)
+	}
+	for id, symbol := range collector.trackedTokens {
+		if count := requests["/coins/"+id+"/market_chart/range"]; count != 1 {
+			t.Errorf("Expected one range request for %s, got %d", id, count)
+		}
+		if points := collector.priceHistory.Len(PriceSeriesKey(symbol)); points != 2 {
+			t.Errorf("Expected 2 backfilled %s prices, got %d", symbol, points)
+		}
+	}
+}
+
+// TestDataCollector_PriceAt tests price lookups from sampled history and CoinGecko backfill
+func TestDataCollector_PriceAt(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	backfills := 0
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/coins/bitcoin/market_chart/range" {
+			http.NotFound(w, r)
+			return
+		}
+		backfills++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"prices": [[%d, 61000], [%d, 62000]]}`,
+			at.Add(-50*time.Minute).UnixMilli(), at.Add(10*time.Minute).UnixMilli())
+	})
+
+	collector.priceHistory.Append(PriceSeriesKey("ETH"), at.Add(-20*time.Minute), 2500)
+
+	if price, err := collector.PriceAt(context.Background(), "ethereum", at); err != nil || price != 2500 {
+		t.Errorf("Expected sampled ETH price 2500, got %f (%v)", price, err)
+	}
+	if price, err := collector.PriceAt(context.Background(), "BTC", at); err != nil || price != 62000 {
+		t.Errorf("Expected nearest backfilled BTC price 62000, got %f (%v)", price, err)
+	}
+	if _, err := collector.PriceAt(context.Background(), "BTC", at.Add(5*time.Minute)); err != nil || backfills != 1 {
+		t.Errorf("Expected backfilled history to be reused, got %d backfills (%v)", backfills, err)
+	}
+	if _, err := collector.PriceAt(context.Background(), "DOGE", at); err == nil {
+		t.Error("Expected error for untracked token")
+	}
+}
+
+// TestDataCollector_PriceRange tests that backfilled ranges outlive the trimmed live samples and are not refetched
+func TestDataCollector_PriceRange(t *testing.T) {
+	var fetched []string
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		fetched = append(fetched, r.URL.Query().Get("from")+"-"+r.URL.Query().Get("to"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"prices": [[%d, 40000], [%d, 41000]]}`, from*1000, to*1000)
+	})
+
+	now := time.Now().Truncate(time.Second)
+	live := make([]models.TimeSeriesPoint, priceHistoryPoints)
+	for i := range live {
+		live[i] = models.TimeSeriesPoint{Timestamp: now.Add(-time.Duration(i) * priceHistoryInterval), Value: 60000}
+	}
+	collector.priceHistory.AppendPoints(PriceSeriesKey("BTC"), live)
+
+	from := now.Add(-60 * 24 * time.Hour)
+	to := from.Add(7 * 24 * time.Hour)
+	points, err := collector.PriceRange(context.Background(), "BTC", from, to)
+	if err != nil || len(points) != 2 {
+		t.Fatalf("Expected 2 backfilled prices 60 days back, got %d (%v)", len(points), err)
+	}
+	collector.priceHistory.Append(PriceSeriesKey("BTC"), now.Add(time.Minute), 60000)
+
+	if points, err := collector.PriceRange(context.Background(), "BTC", from, to); err != nil || len(points) != 2 || len(fetched) != 1 {
+		t.Errorf("Expected the stored range reused, got %d prices after %v (%v)", len(points), fetched, err)
+	}
+	if price, err := collector.PriceAt(context.Background(), "BTC", to); err != nil || price != 41000 || len(fetched) != 1 {
+		t.Errorf("Expected stored price 41000 without fetching, got %f after %v (%v)", price, fetched, err)
+	}
+
+	if _, err := collector.PriceRange(context.Background(), "BTC", from.Add(-24*time.Hour), to.Add(24*time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []string{
+		strconv.FormatInt(from.Add(-24*time.Hour).Unix(), 10) + "-" + strconv.FormatInt(from.Unix(), 10),
+		strconv.FormatInt(to.Unix(), 10) + "-" + strconv.FormatInt(to.Add(24*time.Hour).Unix(), 10),
+	}
+	if len(fetched) != 3 || fetched[1] != want[0] || fetched[2] != want[1] {
+		t.Errorf("Expected only the uncovered edges %v fetched, got %v", want, fetched[1:])
+	}
+}
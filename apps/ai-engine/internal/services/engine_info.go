@@ -0,0 +1,60 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// EngineVersion identifies the recommendation logic; bump it when results change for the same inputs
+const EngineVersion = "1.10.0"
+
+// engineConfig is the configuration that influences engine results
+type engineConfig struct {
+	YieldVenues  bool             `json:"yield_venues"`
+	YieldPolicy  YieldVenuePolicy `json:"yield_policy"`
+	CostModel    CostModel        `json:"cost_model"`
+	TradePlan    TradePlanConfig  `json:"trade_plan"`
+	Liquidity    bool             `json:"liquidity_pools"`
+	PriceHistory bool             `json:"price_history"`
+	Calibrated   bool             `json:"calibrated"`
+
+	BlackLitterman BlackLittermanConfig `json:"black_litterman"`
+	MarketCaps     bool                 `json:"market_caps"`
+	Benchmark      models.Benchmark     `json:"benchmark"`
+	Regime         RegimeConfig         `json:"regime"`
+}
+
+// EngineInfo returns the engine version and a hash of its configuration
+func (e *EnhancedAIEngine) EngineInfo() models.EngineInfo {
+	config := engineConfig{
+		YieldVenues:  e.yieldStore != nil,
+		YieldPolicy:  e.yieldPolicy,
+		CostModel:    e.costModel,
+		TradePlan:    e.tradePlan,
+		Liquidity:    e.pools != nil,
+		PriceHistory: e.priceHistory != nil,
+		Calibrated:   e.calibrator != nil,
+
+		BlackLitterman: e.blackLitterman,
+		MarketCaps:     e.marketCaps != nil,
+		Benchmark:      e.benchmark,
+		Regime:         e.regime,
+	}
+	return models.EngineInfo{
+		Version:    EngineVersion,
+		ConfigHash: configHash(config),
+	}
+}
+
+// configHash returns a short stable hash of a JSON-encodable configuration
+func configHash(config interface{}) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,19 @@
+package services
+
+import "testing"
+
+func TestEnhancedAIEngine_EngineInfo(t *testing.T) {
+	info := NewEnhancedAIEngine().EngineInfo()
+	if info.Version != EngineVersion {
+		t.Errorf("Expected version %s, got %s", EngineVersion, info.Version)
+	}
+	if info.ConfigHash == "" || info.ConfigHash != NewEnhancedAIEngine().EngineInfo().ConfigHash {
+		t.Errorf("Expected a stable config hash, got %q", info.ConfigHash)
+	}
+
+	policy := DefaultYieldVenuePolicy()
+	policy.MaxPoolRisk = 0.3
+	if changed := NewEnhancedAIEngine(WithYieldVenuePolicy(policy)).EngineInfo(); changed.ConfigHash == info.ConfigHash {
+		t.Error("Expected the config hash to change with the yield venue policy")
+	}
+}
@@ -13,36 +13,115 @@ import (
 
 // EnhancedAIEngine provides improved AI capabilities
 type EnhancedAIEngine struct {
-	running bool
-	logger  *slog.Logger
+	running     bool
+	logger      *slog.Logger
+	yieldStore  *YieldStore
+	yieldPolicy YieldVenuePolicy
+	costModel   CostModel
+	tradePlan   TradePlanConfig
+	pools       *LiquidityPools
+
+	blackLitterman BlackLittermanConfig
+	marketCaps     MarketCapProvider
+	benchmark      models.Benchmark
+	regime         RegimeConfig
+	sentiment      SentimentProvider
+
+	priceHistory *TimeSeriesStore
+	calibrator   ConfidenceCalibrator
+	now          func() time.Time
 }
 
+// EngineOption configures optional engine dependencies
+type EngineOption func(*EnhancedAIEngine)
+
 // NewEnhancedAIEngine creates a new enhanced AI engine
-func NewEnhancedAIEngine() *EnhancedAIEngine {
-	return &EnhancedAIEngine{
-		logger: slog.Default().With("component", "ai-engine"),
-	}
+func NewEnhancedAIEngine(opts ...EngineOption) *EnhancedAIEngine {
+	engine := &EnhancedAIEngine{
+		logger:      slog.Default().With("component", "ai-engine"),
+		yieldPolicy: DefaultYieldVenuePolicy(),
+		costModel:   DefaultCostModel(),
+		tradePlan:   DefaultTradePlanConfig(),
+		now:         time.Now,
+
+		blackLitterman: DefaultBlackLittermanConfig(),
+		benchmark:      DefaultBenchmark(),
+		regime:         DefaultRegimeConfig(),
+	}
+	for _, opt := range opts {
+		opt(engine)
+	}
+	return engine
 }
 
 // GetRebalanceRecommendation provides intelligent portfolio rebalancing
 func (e *EnhancedAIEngine) GetRebalanceRecommendation(ctx context.Context, portfolio models.Portfolio) (*models.RebalanceRecommendation, error) {
+	return e.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{})
+}
+
+// RecommendWithOptions provides portfolio rebalancing under the requested policy
+func (e *EnhancedAIEngine) RecommendWithOptions(ctx context.Context, portfolio models.Portfolio, options models.RecommendationOptions) (*models.RebalanceRecommendation, error) {
 	start := time.Now()
 	e.logger.Info("starting portfolio rebalance recommendation",
 		"portfolio_id", portfolio.ID,
 		"positions_count", len(portfolio.Positions),
 	)
 
+	schedule, err := newPolicySchedule(options.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Switch optimizer parameters with the market regime, keeping a requested strategy
+	regime := e.detectRegime()
+	parameters, switched := e.regimeParameters(regime)
+	strategyName := options.Strategy
+	if strategyName == "" && switched {
+		strategyName = parameters.Strategy
+	}
+	strategy, err := NewAllocationStrategy(strategyName)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateMarketViews(options.Views); err != nil {
+		return nil, fmt.Errorf("invalid market views: %w", err)
+	}
+	returnModel := e.blackLitterman
+	returnModel.Views = append(append([]models.MarketView{}, e.blackLitterman.Views...), options.Views...)
+	if switched && parameters.RiskAversion > 0 {
+		returnModel.RiskAversion = parameters.RiskAversion
+	}
+	now := e.now()
+
 	// Enhanced portfolio analysis
 	analysis := e.analyzePortfolio(portfolio)
 
-	// Calculate optimal allocations using simplified Modern Portfolio Theory
-	optimalAllocations := e.calculateOptimalAllocations(portfolio.Positions)
+	// Calculate optimal allocations with the requested strategy
+	optimalAllocations, allocation := e.calculateOptimalAllocations(portfolio.Positions, strategy, returnModel)
+
+	// Generate rebalancing actions for the positions the policy triggers
+	var actions []models.RebalanceAction
+	if schedule.due(now) {
+		actions = e.generateRebalanceActions(portfolio, optimalAllocations, schedule)
+	}
+
+	// Keep only trades whose expected benefit exceeds gas, fees and slippage
+	actions = e.applyTradeCosts(portfolio, actions)
+
+	// Move under-earning holdings to better yield venues, sized by the trades that remain
+	actions = e.appendYieldMoveActions(actions, portfolio, tradeTargets(actions))
+
+	// Turn the trades into ordered swaps, simulated against pool liquidity when available
+	plan := e.planTrades(portfolio, actions)
 
-	// Generate rebalancing actions
-	actions := e.generateRebalanceActions(portfolio.Positions, optimalAllocations)
+	// Estimate the probability that following the actions beats holding
+	confidence, breakdown := e.scoreConfidence(ctx, portfolio, optimalAllocations, actions, plan)
 
-	// Calculate confidence based on portfolio quality
-	confidence := e.calculateConfidence(portfolio, analysis)
+	// Project the portfolio held after following the actions
+	current, projected := e.projectRecommendation(portfolio, actions)
+
+	// Explain each action and the portfolio change
+	explanation := e.explainRecommendation(portfolio, actions, current, projected)
 
 	recommendation := &models.RebalanceRecommendation{
 		PortfolioID:    portfolio.ID,
@@ -51,7 +130,16 @@ func (e *EnhancedAIEngine) GetRebalanceRecommendation(ctx context.Context, portf
 		ExpectedReturn: analysis.ExpectedReturn,
 		Risk:           analysis.Risk,
 		Actions:        actions,
-		Reasoning:      e.generateReasoning(analysis, actions),
+		Reasoning:      explanation.Summary,
+
+		ConfidenceBreakdown: breakdown,
+		Explanation:         explanation,
+		Current:             current,
+		Projected:           projected,
+		TradePlan:           plan,
+		Policy:              schedule.status(now),
+		Allocation:          allocation,
+		Regime:              regime,
 	}
 
 	duration := time.Since(start)
@@ -83,8 +171,25 @@ func (e *EnhancedAIEngine) CalculateRiskMetrics(ctx context.Context, portfolio m
 		return nil, fmt.Errorf("failed to calculate risk metrics: %w", err)
 	}
 
-	// Volatility based on token types and market conditions
-	volatility := e.calculatePortfolioVolatility(portfolio.Positions)
+	attribution := e.riskAttribution(portfolio)
+	metrics := e.riskMetrics(portfolio, attribution)
+	metrics.Attribution = attribution
+
+	duration := time.Since(start)
+	e.logger.Info("completed risk metrics calculation",
+		"portfolio_id", portfolio.ID,
+		"volatility", metrics.Volatility,
+		"sharpe_ratio", metrics.SharpeRatio,
+		"duration_ms", duration.Milliseconds(),
+	)
+
+	return metrics, nil
+}
+
+// riskMetrics computes the risk metrics of a portfolio from the volatility of its risk attribution
+func (e *EnhancedAIEngine) riskMetrics(portfolio models.Portfolio, attribution *models.RiskAttribution) *models.RiskMetrics {
+	// Volatility from the same covariance as the attribution
+	volatility := attribution.Volatility
 
 	// Enhanced VaR calculations
 	var95 := e.calculateVaR(portfolio, 0.95, volatility)
@@ -96,9 +201,6 @@ func (e *EnhancedAIEngine) CalculateRiskMetrics(ctx context.Context, portfolio m
 	// Maximum drawdown estimation
 	maxDrawdown := e.estimateMaxDrawdown(portfolio.Positions, volatility)
 
-	// Beta calculation relative to crypto market
-	beta := e.calculateBeta(portfolio.Positions)
-
 	metrics := &models.RiskMetrics{
 		PortfolioID: portfolio.ID,
 		VaR95:       var95,
@@ -106,19 +208,21 @@ func (e *EnhancedAIEngine) CalculateRiskMetrics(ctx context.Context, portfolio m
 		Volatility:  volatility,
 		SharpeRatio: sharpeRatio,
 		MaxDrawdown: maxDrawdown,
-		Beta:        beta,
 		Timestamp:   time.Now(),
 	}
 
-	duration := time.Since(start)
-	e.logger.Info("completed risk metrics calculation",
-		"portfolio_id", portfolio.ID,
-		"volatility", volatility,
-		"sharpe_ratio", sharpeRatio,
-		"duration_ms", duration.Milliseconds(),
-	)
-
-	return metrics, nil
+	// Beta relative to the configured benchmark
+	comparison, err := e.compareToBenchmark(portfolio, e.benchmark)
+	if err != nil {
+		e.logger.Error("benchmark comparison failed",
+			"portfolio_id", portfolio.ID,
+			"error", err,
+		)
+		return metrics
+	}
+	metrics.Beta = comparison.Beta
+	metrics.Benchmark = comparison
+	return metrics
 }
 
 // GetMarketAnalysis provides enhanced market analysis
@@ -148,11 +252,12 @@ func (e *EnhancedAIEngine) GetMarketAnalysis(ctx context.Context, tokens []strin
 	}
 
 	// Market sentiment analysis
-	sentiment := e.analyzeMarketSentiment(tokens)
+	sentiment := e.analyzeMarketSentiment()
 
 	analysis := &models.MarketAnalysis{
 		TokenAnalysis: tokenAnalysis,
 		Sentiment:     sentiment,
+		Regime:        e.detectRegime(),
 		Timestamp:     time.Now(),
 	}
 
@@ -189,7 +294,7 @@ func (e *EnhancedAIEngine) analyzePortfolio(portfolio models.Portfolio) portfoli
 	totalRisk := 0.0
 
 	for _, position := range portfolio.Positions {
-		tokenReturn := e.getTokenExpectedReturn(position.Token)
+		tokenReturn := e.positionExpectedReturn(position)
 		tokenRisk := e.getTokenRisk(position.Token)
 
 		expectedReturn += position.Weight * tokenReturn
@@ -207,42 +312,24 @@ func (e *EnhancedAIEngine) analyzePortfolio(portfolio models.Portfolio) portfoli
 	}
 }
 
-func (e *EnhancedAIEngine) calculateOptimalAllocations(positions []models.PortfolioPosition) map[string]float64 {
-	// Simplified Modern Portfolio Theory implementation
-	allocations := make(map[string]float64)
-
-	// Risk-adjusted scoring for each token
-	scores := make(map[string]float64)
-	totalScore := 0.0
-
-	for _, position := range positions {
-		expectedReturn := e.getTokenExpectedReturn(position.Token)
-		risk := e.getTokenRisk(position.Token)
-
-		// Risk-adjusted score (Sharpe-like ratio)
-		score := expectedReturn / (risk + 0.01) // Add small epsilon to avoid division by zero
-		scores[position.Token] = score
-		totalScore += score
-	}
-
-	// Normalize to get optimal allocations
-	for token, score := range scores {
-		allocations[token] = score / totalScore
-	}
-
-	return allocations
+// calculateOptimalAllocations returns the target weights of the strategy and their risk breakdown
+func (e *EnhancedAIEngine) calculateOptimalAllocations(positions []models.PortfolioPosition, strategy AllocationStrategy, returnModel BlackLittermanConfig) (map[string]float64, *models.AllocationReport) {
+	return e.allocate(positions, strategy, returnModel)
 }
 
-func (e *EnhancedAIEngine) generateRebalanceActions(positions []models.PortfolioPosition, optimalAllocations map[string]float64) []models.RebalanceAction {
+func (e *EnhancedAIEngine) generateRebalanceActions(portfolio models.Portfolio, optimalAllocations map[string]float64, schedule policySchedule) []models.RebalanceAction {
 	var actions []models.RebalanceAction
+	totalValue := portfolioValue(portfolio)
 
-	for _, position := range positions {
+	for _, position := range portfolio.Positions {
 		optimalWeight := optimalAllocations[position.Token]
 		currentWeight := position.Weight
 
-		weightDiff := optimalWeight - currentWeight
+		// Only rebalance positions whose drift the policy does not tolerate
+		if trigger, ok := e.policyTrigger(schedule, position.Token, currentWeight, optimalWeight); ok {
+			targetWeight := schedule.tradeTarget(currentWeight, optimalWeight)
+			weightDiff := targetWeight - currentWeight
 
-		if math.Abs(weightDiff) > 0.02 { // Only rebalance if difference > 2%
 			actionType := "rebalance"
 			if weightDiff > 0.1 {
 				actionType = "buy"
@@ -251,7 +338,7 @@ func (e *EnhancedAIEngine) generateRebalanceActions(positions []models.Portfolio
 			}
 
 			// Calculate amount based on total portfolio value
-			amount := math.Abs(weightDiff) * position.Value / position.Weight
+			amount := math.Abs(weightDiff) * totalValue
 
 			priority := int(math.Abs(weightDiff) * 100) // Higher priority for larger differences
 
@@ -259,8 +346,9 @@ func (e *EnhancedAIEngine) generateRebalanceActions(positions []models.Portfolio
 				Type:         actionType,
 				Token:        position.Token,
 				Amount:       amount,
-				TargetWeight: optimalWeight,
+				TargetWeight: targetWeight,
 				Priority:     priority,
+				Trigger:      trigger,
 			})
 		}
 	}
@@ -273,57 +361,6 @@ func (e *EnhancedAIEngine) generateRebalanceActions(positions []models.Portfolio
 	return actions
 }
 
-func (e *EnhancedAIEngine) calculateConfidence(portfolio models.Portfolio, analysis portfolioAnalysis) float64 {
-	confidence := 0.7 // Base confidence
-
-	// Increase confidence with better diversification
-	confidence += analysis.Diversification * 0.2
-
-	// Decrease confidence with high concentration
-	if analysis.Concentration > 0.5 {
-		confidence -= (analysis.Concentration - 0.5) * 0.3
-	}
-
-	// Increase confidence with better risk-return profile
-	if analysis.ExpectedReturn/analysis.Risk > 0.5 {
-		confidence += 0.1
-	}
-
-	// Ensure confidence is between 0 and 1
-	if confidence < 0 {
-		confidence = 0
-	}
-	if confidence > 1 {
-		confidence = 1
-	}
-
-	return confidence
-}
-
-func (e *EnhancedAIEngine) generateReasoning(analysis portfolioAnalysis, actions []models.RebalanceAction) string {
-	if len(actions) == 0 {
-		return "Portfolio is well-balanced. No rebalancing needed at this time."
-	}
-
-	reasoning := "Portfolio analysis suggests rebalancing to improve risk-adjusted returns. "
-
-	if analysis.Concentration > 0.6 {
-		reasoning += "High concentration detected - diversification recommended. "
-	}
-
-	if analysis.Diversification > 0.8 {
-		reasoning += "Good diversification maintained. "
-	}
-
-	if len(actions) > 3 {
-		reasoning += "Multiple adjustments needed for optimal allocation."
-	} else {
-		reasoning += "Minor adjustments will optimize performance."
-	}
-
-	return reasoning
-}
-
 // Risk Calculation Helper Functions
 
 func (e *EnhancedAIEngine) calculateConcentrationRisk(positions []models.PortfolioPosition) float64 {
@@ -335,29 +372,6 @@ func (e *EnhancedAIEngine) calculateConcentrationRisk(positions []models.Portfol
 	return hhi
 }
 
-func (e *EnhancedAIEngine) calculatePortfolioVolatility(positions []models.PortfolioPosition) float64 {
-	totalVariance := 0.0
-
-	for _, position := range positions {
-		tokenVolatility := e.getTokenRisk(position.Token)
-		totalVariance += position.Weight * position.Weight * tokenVolatility * tokenVolatility
-	}
-
-	// Add correlation effects (simplified assumption)
-	correlationFactor := 0.3 // Assume 30% correlation between crypto assets
-	for i, pos1 := range positions {
-		for j, pos2 := range positions {
-			if i != j {
-				vol1 := e.getTokenRisk(pos1.Token)
-				vol2 := e.getTokenRisk(pos2.Token)
-				totalVariance += 2 * pos1.Weight * pos2.Weight * vol1 * vol2 * correlationFactor
-			}
-		}
-	}
-
-	return math.Sqrt(totalVariance)
-}
-
 func (e *EnhancedAIEngine) calculateVaR(portfolio models.Portfolio, confidence float64, volatility float64) float64 {
 	// Calculate VaR using normal distribution assumption
 	var zScore float64
@@ -378,11 +392,10 @@ func (e *EnhancedAIEngine) calculateVaR(portfolio models.Portfolio, confidence f
 func (e *EnhancedAIEngine) calculateSharpeRatio(positions []models.PortfolioPosition, volatility float64) float64 {
 	portfolioReturn := 0.0
 	for _, position := range positions {
-		tokenReturn := e.getTokenExpectedReturn(position.Token)
+		tokenReturn := e.positionExpectedReturn(position)
 		portfolioReturn += position.Weight * tokenReturn
 	}
 
-	riskFreeRate := 0.02 // Assume 2% risk-free rate
 	excessReturn := portfolioReturn - riskFreeRate
 
 	if volatility == 0 {
@@ -398,15 +411,6 @@ func (e *EnhancedAIEngine) estimateMaxDrawdown(positions []models.PortfolioPosit
 	return -volatility * 2.5 // Rough estimation: 2.5x volatility as negative (loss)
 }
 
-func (e *EnhancedAIEngine) calculateBeta(positions []models.PortfolioPosition) float64 {
-	totalBeta := 0.0
-	for _, position := range positions {
-		tokenBeta := e.getTokenBeta(position.Token)
-		totalBeta += position.Weight * tokenBeta
-	}
-	return totalBeta
-}
-
 // Market Analysis Helper Functions
 
 func (e *EnhancedAIEngine) performTechnicalAnalysis(token string, timeframe string) models.TokenAnalysis {
@@ -418,8 +422,8 @@ func (e *EnhancedAIEngine) performTechnicalAnalysis(token string, timeframe stri
 	supportLevel := basePrice * (1.0 - volatility*0.1)
 	resistanceLevel := basePrice * (1.0 + volatility*0.1)
 
-	// Determine trend based on momentum (simplified)
-	trend := e.determineTrend(token, volatility)
+	// Determine trend from the moving averages of the token's price history
+	trend := e.tokenTrend(token)
 
 	// Calculate 24h change with some randomness but realistic bounds
 	change24h := (math.Sin(float64(time.Now().Unix()%86400)/86400*2*math.Pi) * volatility * 0.1)
@@ -436,33 +440,6 @@ func (e *EnhancedAIEngine) performTechnicalAnalysis(token string, timeframe stri
 	}
 }
 
-func (e *EnhancedAIEngine) analyzeMarketSentiment(tokens []string) models.MarketSentiment {
-	// Enhanced sentiment analysis
-	fearGreedIndex := 50.0 + math.Sin(float64(time.Now().Unix())/86400)*20 // Oscillates between 30-70
-
-	// Calculate sentiment distribution
-	bullishSentiment := 60.0
-	bearishSentiment := 25.0
-	neutralSentiment := 15.0
-
-	// Adjust based on market conditions
-	if fearGreedIndex > 60 {
-		bullishSentiment += 10
-		bearishSentiment -= 5
-		neutralSentiment -= 5
-	} else if fearGreedIndex < 40 {
-		bullishSentiment -= 10
-		bearishSentiment += 10
-	}
-
-	return models.MarketSentiment{
-		FearGreedIndex:   fearGreedIndex,
-		BullishSentiment: bullishSentiment,
-		BearishSentiment: bearishSentiment,
-		NeutralSentiment: neutralSentiment,
-	}
-}
-
 // Token Data Helper Functions (Enhanced with realistic values)
 
 func (e *EnhancedAIEngine) getTokenExpectedReturn(token string) float64 {
@@ -497,22 +474,6 @@ func (e *EnhancedAIEngine) getTokenRisk(token string) float64 {
 	return 0.35 // Default 35% for unknown tokens
 }
 
-func (e *EnhancedAIEngine) getTokenBeta(token string) float64 {
-	betas := map[string]float64{
-		"ETH":  1.0, // Market beta
-		"BTC":  0.8, // Slightly less correlated
-		"USDC": 0.1, // Low correlation (stablecoin)
-		"LINK": 1.2, // Higher correlation
-		"UNI":  1.3, // Higher correlation
-		"AAVE": 1.1, // Slightly higher correlation
-	}
-
-	if val, exists := betas[token]; exists {
-		return val
-	}
-	return 1.0 // Default market beta
-}
-
 func (e *EnhancedAIEngine) getTokenBasePrice(token string) float64 {
 	prices := map[string]float64{
 		"ETH":  2500.0,
@@ -545,15 +506,3 @@ func (e *EnhancedAIEngine) estimateVolume(token string, price float64) float64 {
 	}
 	return 50000000 // Default $50M volume
 }
-
-func (e *EnhancedAIEngine) determineTrend(token string, volatility float64) string {
-	// Simple trend determination based on time and volatility
-	timeBasedTrend := math.Sin(float64(time.Now().Unix()) / 3600) // Hourly oscillation
-
-	if timeBasedTrend > 0.3 {
-		return "bullish"
-	} else if timeBasedTrend < -0.3 {
-		return "bearish"
-	}
-	return "neutral"
-}
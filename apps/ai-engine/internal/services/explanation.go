@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// rebalanceBand is the weight drift a position may have before it is rebalanced
+const rebalanceBand = 0.02
+
+// Constraint rules reported on action drivers
+const (
+	ruleRebalanceBand     = "rebalance_band"
+	ruleMinAPYImprovement = "min_apy_improvement"
+	ruleMaxPoolRisk       = "max_pool_risk"
+	ruleCostBenefit       = "cost_benefit"
+)
+
+// DefaultLocale is the locale summaries are rendered in unless another is requested
+const DefaultLocale = "en"
+
+// summaryLocale holds the wording of explanation summaries in one language
+type summaryLocale struct {
+	verbs    map[string]string // Action type to imperative verb
+	template string
+}
+
+// summaryLocales are the supported summary translations. Templates receive an *models.Explanation.
+var summaryLocales = map[string]summaryLocale{
+	"en": {
+		verbs: map[string]string{"buy": "Buy", "sell": "Sell", "rebalance": "Rebalance", "move": "Move"},
+		template: `{{if not .Actions}}Portfolio is well-balanced. No rebalancing needed at this time.{{else -}}
+{{len .Actions}} adjustment(s) change expected return from {{pct .Before.ExpectedReturn}} to {{pct .After.ExpectedReturn}} and volatility from {{pct .Before.Volatility}} to {{pct .After.Volatility}}.
+{{- if gt .Before.HHI 0.6}} High concentration detected - diversification recommended.{{end}}
+{{- if lt .Before.HHI 0.2}} Good diversification maintained.{{end}}
+{{- range .Actions}} {{verb .Type}} {{.Token}}
+{{- if eq .Type "move"}} to a higher-yield venue{{else}}{{with driver . "drift"}} from {{pct .Before}} to {{pct .After}} of the portfolio{{end}}{{end}}
+{{- with driver . "expected_return"}}, changing its expected return contribution by {{signedPct .Change}}{{end}}.
+{{- end}}{{end}}`,
+	},
+	"es": {
+		verbs: map[string]string{"buy": "Comprar", "sell": "Vender", "rebalance": "Reequilibrar", "move": "Mover"},
+		template: `{{if not .Actions}}La cartera está bien equilibrada. No es necesario reequilibrar por ahora.{{else -}}
+{{len .Actions}} ajuste(s) cambian el rendimiento esperado de {{pct .Before.ExpectedReturn}} a {{pct .After.ExpectedReturn}} y la volatilidad de {{pct .Before.Volatility}} a {{pct .After.Volatility}}.
+{{- if gt .Before.HHI 0.6}} Alta concentración detectada: se recomienda diversificar.{{end}}
+{{- if lt .Before.HHI 0.2}} La diversificación es buena.{{end}}
+{{- range .Actions}} {{verb .Type}} {{.Token}}
+{{- if eq .Type "move"}} a un destino con mayor rendimiento{{else}}{{with driver . "drift"}} de {{pct .Before}} a {{pct .After}} de la cartera{{end}}{{end}}
+{{- with driver . "expected_return"}}, con un cambio de {{signedPct .Change}} en su contribución al rendimiento esperado{{end}}.
+{{- end}}{{end}}`,
+	},
+}
+
+var (
+	summaryTemplatesOnce sync.Once
+	summaryTemplates     map[string]*template.Template
+)
+
+// SupportedLocale reports whether summaries can be rendered in locale
+func SupportedLocale(locale string) bool {
+	_, ok := summaryLocales[locale]
+	return ok
+}
+
+// RenderExplanationSummary renders the human summary of an explanation in locale
+func RenderExplanationSummary(explanation *models.Explanation, locale string) (string, error) {
+	summaryTemplatesOnce.Do(parseSummaryTemplates)
+
+	tmpl, ok := summaryTemplates[locale]
+	if !ok {
+		return "", fmt.Errorf("unsupported locale %q", locale)
+	}
+	var summary strings.Builder
+	if err := tmpl.Execute(&summary, explanation); err != nil {
+		return "", fmt.Errorf("failed to render %s summary: %w", locale, err)
+	}
+	return summary.String(), nil
+}
+
+// parseSummaryTemplates compiles the templates of every locale; they are fixed at build time
+func parseSummaryTemplates() {
+	summaryTemplates = make(map[string]*template.Template, len(summaryLocales))
+	for locale, wording := range summaryLocales {
+		verbs := wording.verbs
+		funcs := template.FuncMap{
+			"pct":       func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+			"signedPct": func(v float64) string { return fmt.Sprintf("%+.2f%%", v*100) },
+			"verb":      func(actionType string) string { return verbs[actionType] },
+			"driver":    findDriver,
+		}
+		summaryTemplates[locale] = template.Must(template.New(locale).Funcs(funcs).Parse(wording.template))
+	}
+}
+
+// findDriver returns the action's driver of the given kind, or nil
+func findDriver(action models.ActionExplanation, kind string) *models.ActionDriver {
+	for i := range action.Drivers {
+		if action.Drivers[i].Kind == kind {
+			return &action.Drivers[i]
+		}
+	}
+	return nil
+}
+
+// explainRecommendation compares the current and projected portfolios and lists each action's drivers
+func (e *EnhancedAIEngine) explainRecommendation(portfolio models.Portfolio, actions []models.RebalanceAction, current, projected *models.PortfolioProjection) *models.Explanation {
+	weights, yields := positionWeights(portfolio)
+	target, targetYields := applyRecommendation(&models.RebalanceRecommendation{Actions: actions}, weights, yields)
+	tokens := sortedTokens(weights, target)
+	held := make(map[string]float64, len(tokens))
+	for _, token := range tokens {
+		held[token] = 1
+	}
+	covariance, _ := e.covariance(tokens, held)
+	currentRisk := riskContributions(tokens, covariance, weights)
+	targetRisk := riskContributions(tokens, covariance, target)
+
+	explanation := &models.Explanation{
+		Before:  projectionMetrics(current),
+		After:   projectionMetrics(projected),
+		Actions: make([]models.ActionExplanation, 0, len(actions)),
+	}
+
+	for _, action := range actions {
+		token := strings.ToUpper(action.Token)
+		expected := e.getTokenExpectedReturn(token)
+		explained := models.ActionExplanation{Type: action.Type, Token: action.Token}
+
+		if action.Type != actionTypeMove {
+			explained.Drivers = append(explained.Drivers,
+				newDriver(models.DriverDrift, weights[token], target[token]),
+				newDriver(models.DriverRiskContribution, currentRisk[token], targetRisk[token]),
+			)
+		}
+		explained.Drivers = append(explained.Drivers, newDriver(models.DriverExpectedReturn,
+			weights[token]*(expected+yields[token]),
+			target[token]*(expected+targetYields[token]),
+		))
+
+		// Constraints report the limit as Before and the observed value as After
+		if action.Type == actionTypeMove {
+			explained.Drivers = append(explained.Drivers,
+				constraintDriver(ruleMinAPYImprovement, e.yieldPolicy.MinAPYImprovement, action.TargetAPY-action.CurrentAPY),
+				constraintDriver(ruleMaxPoolRisk, e.yieldPolicy.MaxPoolRisk, action.RiskScore),
+			)
+		} else {
+			rule, band, drift := ruleRebalanceBand, rebalanceBand, math.Abs(target[token]-weights[token])
+			if trigger := action.Trigger; trigger != nil {
+				rule, band, drift = trigger.Rule, trigger.Band, trigger.Drift
+			}
+			explained.Drivers = append(explained.Drivers, constraintDriver(rule, band, drift))
+		}
+		if cost := action.EstimatedCost; cost != nil {
+			explained.Drivers = append(explained.Drivers, constraintDriver(ruleCostBenefit, cost.TotalUSD, cost.ExpectedBenefit))
+		}
+		explanation.Actions = append(explanation.Actions, explained)
+	}
+
+	explanation.Locale = DefaultLocale
+	explanation.Summary, _ = RenderExplanationSummary(explanation, DefaultLocale) // Templates are fixed, so rendering cannot fail
+	return explanation
+}
+
+// projectionMetrics extracts the headline metrics of a projected portfolio
+func projectionMetrics(projection *models.PortfolioProjection) models.PortfolioMetrics {
+	return models.PortfolioMetrics{
+		ExpectedReturn: projection.ExpectedReturn,
+		Volatility:     projection.RiskMetrics.Volatility,
+		SharpeRatio:    projection.RiskMetrics.SharpeRatio,
+		HHI:            1 - projection.Diversification,
+	}
+}
+
+// riskContributions splits portfolio variance across tokens, as shares summing to one, using
+// the covariance and risk budget behind the allocation report
+func riskContributions(tokens []string, covariance [][]float64, weights map[string]float64) map[string]float64 {
+	w := make([]float64, len(tokens))
+	for i, token := range tokens {
+		w[i] = weights[token]
+	}
+	shares, _ := riskBudget(covariance, w)
+
+	contributions := make(map[string]float64, len(tokens))
+	for i, token := range tokens {
+		contributions[token] = shares[i]
+	}
+	return contributions
+}
+
+func newDriver(kind string, before, after float64) models.ActionDriver {
+	return models.ActionDriver{Kind: kind, Before: before, After: after, Change: after - before}
+}
+
+func constraintDriver(rule string, limit, observed float64) models.ActionDriver {
+	driver := newDriver(models.DriverConstraint, limit, observed)
+	driver.Rule = rule
+	return driver
+}
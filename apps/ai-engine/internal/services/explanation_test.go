@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestEnhancedAIEngine_Explanation(t *testing.T) {
+	engine := NewEnhancedAIEngine()
+	portfolio := models.Portfolio{ID: "pf-1", Positions: []models.PortfolioPosition{
+		{Token: "ETH", Weight: 0.8},
+		{Token: "BTC", Weight: 0.1},
+		{Token: "USDC", Weight: 0.1},
+	}}
+
+	recommendation, err := engine.GetRebalanceRecommendation(context.Background(), portfolio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	explanation := recommendation.Explanation
+	if explanation == nil {
+		t.Fatal("Expected an explanation")
+	}
+
+	t.Run("before and after metrics", func(t *testing.T) {
+		if math.Abs(explanation.Before.HHI-0.66) > 1e-9 {
+			t.Errorf("Expected HHI 0.66 before, got %f", explanation.Before.HHI)
+		}
+		if explanation.After.HHI >= explanation.Before.HHI || explanation.After.Volatility >= explanation.Before.Volatility {
+			t.Errorf("Expected diversifying to lower HHI and volatility, got %+v -> %+v", explanation.Before, explanation.After)
+		}
+	})
+
+	t.Run("every action has drivers", func(t *testing.T) {
+		if len(explanation.Actions) != len(recommendation.Actions) {
+			t.Fatalf("Expected %d action explanations, got %d", len(recommendation.Actions), len(explanation.Actions))
+		}
+		for _, action := range explanation.Actions {
+			for _, kind := range []string{models.DriverDrift, models.DriverRiskContribution, models.DriverExpectedReturn, models.DriverConstraint} {
+				if findDriver(action, kind) == nil {
+					t.Errorf("Expected a %s driver for %s, got %+v", kind, action.Token, action.Drivers)
+				}
+			}
+		}
+
+		eth := explanation.Actions[0]
+		if eth.Token != "ETH" || eth.Type != "sell" {
+			t.Fatalf("Expected selling ETH first, got %+v", eth)
+		}
+		if drift := findDriver(eth, models.DriverDrift); drift.Before != 0.8 || drift.Change >= 0 {
+			t.Errorf("Expected ETH to drift down from 0.8, got %+v", drift)
+		}
+		if risk := findDriver(eth, models.DriverRiskContribution); risk.After >= risk.Before {
+			t.Errorf("Expected ETH's risk contribution to fall, got %+v", risk)
+		}
+	})
+
+	t.Run("summary is rendered from the explanation", func(t *testing.T) {
+		if recommendation.Reasoning != explanation.Summary || explanation.Locale != DefaultLocale {
+			t.Errorf("Expected reasoning to be the %s summary, got %q", DefaultLocale, recommendation.Reasoning)
+		}
+		if !strings.Contains(explanation.Summary, "High concentration detected") || !strings.Contains(explanation.Summary, "Sell ETH from 80.0%") {
+			t.Errorf("Expected concentration and ETH sale in summary, got %q", explanation.Summary)
+		}
+
+		spanish, err := RenderExplanationSummary(explanation, "es")
+		if err != nil || !strings.Contains(spanish, "Vender ETH de 80.0%") {
+			t.Errorf("Expected a Spanish summary, got %q (%v)", spanish, err)
+		}
+		if _, err := RenderExplanationSummary(explanation, "fr"); err == nil {
+			t.Error("Expected an error for an unsupported locale")
+		}
+	})
+
+	t.Run("hold recommendations", func(t *testing.T) {
+		summary, _ := RenderExplanationSummary(&models.Explanation{}, "en")
+		if summary != "Portfolio is well-balanced. No rebalancing needed at this time." {
+			t.Errorf("Expected the hold summary, got %q", summary)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_ExplanationRiskMatchesAttribution(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	engine := NewEnhancedAIEngine(WithPriceHistory(dailyPriceHistory(now, 30)))
+	engine.now = func() time.Time { return now }
+	portfolio := models.Portfolio{ID: "pf-1", Positions: []models.PortfolioPosition{
+		{Token: "ETH", Weight: 0.9},
+		{Token: "BTC", Weight: 0.1},
+	}}
+
+	recommendation, err := engine.GetRebalanceRecommendation(context.Background(), portfolio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(recommendation.Explanation.Actions) == 0 {
+		t.Fatal("Expected rebalancing actions")
+	}
+
+	attribution := engine.riskAttribution(portfolio)
+	if attribution.Covariance != covarianceHistory {
+		t.Fatalf("Expected historical covariance, got %s", attribution.Covariance)
+	}
+	shares := make(map[string]float64)
+	for _, position := range attribution.Positions {
+		shares[position.Token] = position.RiskShare
+	}
+	compared := 0
+	for _, action := range recommendation.Explanation.Actions {
+		risk := findDriver(action, models.DriverRiskContribution)
+		if risk == nil {
+			continue
+		}
+		compared++
+		if want := shares[action.Token]; math.Abs(risk.Before-want) > 1e-9 {
+			t.Errorf("Expected %s risk contribution %f as in the risk attribution, got %f", action.Token, want, risk.Before)
+		}
+	}
+	if compared == 0 {
+		t.Error("Expected a risk contribution driver on at least one action")
+	}
+}
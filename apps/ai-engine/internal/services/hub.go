@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultSubscriberBuffer is the number of pending updates kept per subscriber
+const defaultSubscriberBuffer = 16
+
+// Hub fans out published values to per-topic subscribers.
+// Slow subscribers never block publishers: when a subscriber's buffer is full the
+// oldest pending value is dropped so the newest one is always delivered.
+type Hub[T any] struct {
+	mu        sync.RWMutex
+	topics    map[string]map[uint64]*Subscription[T]
+	nextID    uint64
+	buffer    int
+	closed    bool
+	published atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// Subscription is a single subscriber's view of a hub topic
+type Subscription[T any] struct {
+	C <-chan T
+
+	hub     *Hub[T]
+	topic   string
+	id      uint64
+	ch      chan T
+	mu      sync.Mutex // Serialises sends and drops on ch
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// HubStats summarises hub activity
+type HubStats struct {
+	Topics      int    `json:"topics"`
+	Subscribers int    `json:"subscribers"`
+	Published   uint64 `json:"published"`
+	Dropped     uint64 `json:"dropped"`
+}
+
+// NewHub creates a hub whose subscribers buffer up to buffer pending values
+func NewHub[T any](buffer int) *Hub[T] {
+	if buffer <= 0 {
+		buffer = defaultSubscriberBuffer
+	}
+	return &Hub[T]{
+		topics: make(map[string]map[uint64]*Subscription[T]),
+		buffer: buffer,
+	}
+}
+
+// Subscribe registers a subscriber to topic until ctx is done or Unsubscribe is called.
+// Subscribing to a closed hub returns a subscription whose channel is already closed.
+func (h *Hub[T]) Subscribe(ctx context.Context, topic string) *Subscription[T] {
+	ch := make(chan T, h.buffer)
+	sub := &Subscription[T]{
+		C:     ch,
+		hub:   h,
+		topic: topic,
+		ch:    ch,
+		done:  make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		sub.once.Do(func() {
+			close(sub.done)
+			close(sub.ch)
+		})
+		return sub
+	}
+	h.nextID++
+	sub.id = h.nextID
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[uint64]*Subscription[T])
+	}
+	h.topics[topic][sub.id] = sub
+	h.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Unsubscribe()
+		case <-sub.done:
+		}
+	}()
+
+	return sub
+}
+
+// Publish delivers value to every subscriber of topic without blocking
+func (h *Hub[T]) Publish(topic string, value T) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.closed {
+		return
+	}
+	h.published.Add(1)
+
+	for _, sub := range h.topics[topic] {
+		if sub.deliver(value) {
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// Close unsubscribes everyone and closes all subscriber channels
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for _, subscribers := range h.topics {
+		for _, sub := range subscribers {
+			sub.close()
+		}
+	}
+	h.topics = make(map[string]map[uint64]*Subscription[T])
+}
+
+// Stats returns current subscriber counts and lifetime publish and drop totals
+func (h *Hub[T]) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := HubStats{
+		Topics:    len(h.topics),
+		Published: h.published.Load(),
+		Dropped:   h.dropped.Load(),
+	}
+	for _, subscribers := range h.topics {
+		stats.Subscribers += len(subscribers)
+	}
+	return stats
+}
+
+// Topic returns the topic this subscription listens to
+func (s *Subscription[T]) Topic() string {
+	return s.topic
+}
+
+// Dropped returns how many values were discarded because this subscriber fell behind
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Done is closed once the subscription ends
+func (s *Subscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// Unsubscribe removes the subscription and closes its channel; it is safe to call more than once
+func (s *Subscription[T]) Unsubscribe() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subscribers, ok := h.topics[s.topic]; ok {
+		delete(subscribers, s.id)
+		if len(subscribers) == 0 {
+			delete(h.topics, s.topic)
+		}
+	}
+	s.close()
+}
+
+// deliver sends value, replacing the oldest pending value when the buffer is full.
+// It reports whether a value was dropped.
+func (s *Subscription[T]) deliver(value T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case s.ch <- value:
+		return false
+	default:
+	}
+
+	// Coalesce: discard the oldest pending value to make room for the latest
+	select {
+	case <-s.ch:
+	default:
+	}
+	s.dropped.Add(1)
+
+	select {
+	case s.ch <- value:
+	default:
+	}
+	return true
+}
+
+// close closes the subscriber channel once; callers hold the hub write lock
+func (s *Subscription[T]) close() {
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.done)
+		close(s.ch)
+	})
+}
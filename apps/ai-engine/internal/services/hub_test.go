@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func receiveWithin[T any](t *testing.T, ch <-chan T) (T, bool) {
+	t.Helper()
+	select {
+	case value, ok := <-ch:
+		return value, ok
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for channel")
+		var zero T
+		return zero, false
+	}
+}
+
+// TestHub_FanOut tests delivery to every subscriber of a topic only
+func TestHub_FanOut(t *testing.T) {
+	hub := NewHub[int](4)
+	ctx := context.Background()
+
+	first := hub.Subscribe(ctx, "ETH")
+	second := hub.Subscribe(ctx, "ETH")
+	other := hub.Subscribe(ctx, "BTC")
+
+	hub.Publish("ETH", 42)
+
+	for _, sub := range []*Subscription[int]{first, second} {
+		if value, _ := receiveWithin(t, sub.C); value != 42 {
+			t.Errorf("Expected 42, got %d", value)
+		}
+	}
+	select {
+	case value := <-other.C:
+		t.Errorf("Expected no value on other topic, got %d", value)
+	default:
+	}
+
+	stats := hub.Stats()
+	if stats.Topics != 2 || stats.Subscribers != 3 || stats.Published != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestHub_SlowSubscriberCoalesces tests that slow subscribers keep the latest values
+func TestHub_SlowSubscriberCoalesces(t *testing.T) {
+	hub := NewHub[int](2)
+	sub := hub.Subscribe(context.Background(), "ETH")
+
+	for i := 1; i <= 5; i++ {
+		hub.Publish("ETH", i)
+	}
+
+	if sub.Dropped() != 3 {
+		t.Errorf("Expected 3 dropped values, got %d", sub.Dropped())
+	}
+	if hub.Stats().Dropped != 3 {
+		t.Errorf("Expected hub drop total 3, got %d", hub.Stats().Dropped)
+	}
+
+	// The two newest values survive, in order
+	for _, expected := range []int{4, 5} {
+		if value, _ := receiveWithin(t, sub.C); value != expected {
+			t.Errorf("Expected %d, got %d", expected, value)
+		}
+	}
+}
+
+// TestHub_Unsubscribe tests explicit and context-bound unsubscription
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := NewHub[int](4)
+
+	t.Run("Explicit", func(t *testing.T) {
+		sub := hub.Subscribe(context.Background(), "ETH")
+		sub.Unsubscribe()
+		sub.Unsubscribe() // Safe to repeat
+
+		if _, ok := receiveWithin(t, sub.C); ok {
+			t.Error("Expected channel to be closed")
+		}
+		hub.Publish("ETH", 1) // Must not panic on the closed channel
+	})
+
+	t.Run("ContextCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub := hub.Subscribe(ctx, "ETH")
+		cancel()
+
+		if _, ok := receiveWithin(t, sub.C); ok {
+			t.Error("Expected channel to be closed after cancel")
+		}
+		<-sub.Done()
+	})
+
+	if stats := hub.Stats(); stats.Subscribers != 0 || stats.Topics != 0 {
+		t.Errorf("Expected no subscribers left, got %+v", stats)
+	}
+}
+
+// TestHub_Close tests that closing releases all subscribers
+func TestHub_Close(t *testing.T) {
+	hub := NewHub[int](4)
+	subs := []*Subscription[int]{
+		hub.Subscribe(context.Background(), "ETH"),
+		hub.Subscribe(context.Background(), "BTC"),
+	}
+
+	hub.Close()
+	hub.Close()
+
+	for _, sub := range subs {
+		if _, ok := receiveWithin(t, sub.C); ok {
+			t.Errorf("Expected %s channel to be closed", sub.Topic())
+		}
+	}
+
+	late := hub.Subscribe(context.Background(), "ETH")
+	if _, ok := receiveWithin(t, late.C); ok {
+		t.Error("Expected subscription on closed hub to be closed")
+	}
+	hub.Publish("ETH", 1)
+}
+
+// TestHub_ConcurrentPublish tests publishing while subscribers come and go
+func TestHub_ConcurrentPublish(t *testing.T) {
+	hub := NewHub[int](1)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				hub.Publish("ETH", j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				sub := hub.Subscribe(ctx, "ETH")
+				cancel()
+				for range sub.C {
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	hub.Close()
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/valkyriefinance/ai-engine/internal/models"
 )
@@ -30,6 +31,107 @@ type MarketDataCollector interface {
 	Stop() error
 }
 
+// YieldPredictor defines the interface behind the PredictYields RPC, served over HTTP only
+type YieldPredictor interface {
+	// PredictYields forecasts APYs for pools matching protocols and tokens over period ("1d", "7d", "30d")
+	PredictYields(ctx context.Context, protocols, tokens []string, period string) (*models.YieldPredictionResponse, error)
+}
+
+// PriceStreamer defines the interface behind the StreamPriceData RPC
+type PriceStreamer interface {
+	// Subscribe delivers price updates for token until ctx is done
+	Subscribe(ctx context.Context, token string) *Subscription[models.PriceData]
+
+	// GetLatestPrice returns the most recent price for token
+	GetLatestPrice(token string) (*models.PriceData, error)
+}
+
+// CachedPriceProvider serves the most recently fetched prices without contacting providers
+type CachedPriceProvider interface {
+	// CachedPrices returns the latest cached price data keyed by upper-case symbol
+	CachedPrices() map[string]models.PriceData
+}
+
+// ProviderMetricsReporter reports the health of the providers a collector fetches from
+type ProviderMetricsReporter interface {
+	// ProviderMetrics returns per-host outcome counters, breaker state and budget use
+	ProviderMetrics() map[string]interface{}
+}
+
+// MarketCapProvider serves the most recently fetched market caps without contacting providers
+type MarketCapProvider interface {
+	// CachedMarketCaps returns the latest cached market caps keyed by upper-case symbol
+	CachedMarketCaps() map[string]float64
+}
+
+// PriceHistory provides historical token prices
+type PriceHistory interface {
+	// PriceAt returns the USD price of token closest to at
+	PriceAt(ctx context.Context, token string, at time.Time) (float64, error)
+}
+
+// PriceRangeHistory loads historical token prices over a period in one request per token
+type PriceRangeHistory interface {
+	// PriceRange returns the USD prices of token between from and to
+	PriceRange(ctx context.Context, token string, from, to time.Time) ([]models.TimeSeriesPoint, error)
+}
+
+// BenchmarkResolver resolves benchmarks to the token weights they hold
+type BenchmarkResolver interface {
+	// BenchmarkWeights returns the token weights of benchmark, summing to one
+	BenchmarkWeights(benchmark models.Benchmark) (map[string]float64, error)
+}
+
+// ScorecardProvider reports how past recommendations performed
+type ScorecardProvider interface {
+	// Scorecards aggregates recommendation outcomes; empty filters match everything
+	Scorecards(ctx context.Context, engineVersion, horizon string) ([]models.EngineScorecard, error)
+}
+
+// ConfidenceCalibrator maps raw confidence scores onto realized hit rates
+type ConfidenceCalibrator interface {
+	// CalibrateConfidence returns the calibrated confidence and the number of outcomes behind it;
+	// ok is false while too few outcomes have been evaluated
+	CalibrateConfidence(ctx context.Context, engineVersion string, raw float64) (calibrated float64, samples int, ok bool)
+}
+
+// EngineDescriber is implemented by engines that can identify their version and configuration
+type EngineDescriber interface {
+	// EngineInfo returns the engine version and configuration hash
+	EngineInfo() models.EngineInfo
+}
+
+// OptionsRecommender is implemented by engines that accept per-request recommendation options
+type OptionsRecommender interface {
+	// RecommendWithOptions provides rebalancing recommendations under the requested options
+	RecommendWithOptions(ctx context.Context, portfolio models.Portfolio, options models.RecommendationOptions) (*models.RebalanceRecommendation, error)
+}
+
+// RiskAttributor is implemented by engines that can break portfolio risk down by token
+type RiskAttributor interface {
+	// RiskAttribution returns per-token risk contributions and portfolio diversification measures
+	RiskAttribution(ctx context.Context, portfolio models.Portfolio) (*models.RiskAttribution, error)
+}
+
+// BenchmarkComparer is implemented by engines that can measure portfolios against a benchmark
+type BenchmarkComparer interface {
+	// CompareToBenchmark returns beta, alpha, tracking error, information ratio and correlation
+	// against benchmark, or against the engine's default benchmark when it is empty
+	CompareToBenchmark(ctx context.Context, portfolio models.Portfolio, benchmark models.Benchmark) (*models.BenchmarkComparison, error)
+}
+
+// SentimentProvider reports the market Fear & Greed index
+type SentimentProvider interface {
+	// MarketSentiment returns the current index with its classification, breakdown and history
+	MarketSentiment() (*models.MarketSentiment, error)
+}
+
+// PerformanceAttributor explains the realized performance of stored portfolios
+type PerformanceAttributor interface {
+	// AttributePerformance returns the returns of a portfolio between start and end, broken down by token
+	AttributePerformance(ctx context.Context, owner, portfolioID string, start, end time.Time) (*models.PerformanceAttribution, error)
+}
+
 // PortfolioValidator defines the interface for portfolio validation
 type PortfolioValidator interface {
 	// ValidatePortfolio validates portfolio data and returns validation errors
@@ -50,6 +152,30 @@ type MarketDataProvider interface {
 
 // Ensure our concrete types implement the interfaces
 var (
-	_ AIEngine            = (*EnhancedAIEngine)(nil)
-	_ MarketDataCollector = (*RealDataCollector)(nil)
+	_ AIEngine             = (*EnhancedAIEngine)(nil)
+	_ MarketDataCollector  = (*RealDataCollector)(nil)
+	_ YieldPredictor       = (*YieldForecaster)(nil)
+	_ PriceStreamer        = (*DataCollector)(nil)
+	_ EngineDescriber      = (*EnhancedAIEngine)(nil)
+	_ PriceHistory         = (*DataCollector)(nil)
+	_ PriceRangeHistory    = (*DataCollector)(nil)
+	_ MarketCapProvider    = (*DataCollector)(nil)
+	_ CachedPriceProvider  = (*DataCollector)(nil)
+	_ ScorecardProvider    = (*OutcomeEvaluator)(nil)
+	_ ConfidenceCalibrator = (*OutcomeEvaluator)(nil)
+	_ OptionsRecommender   = (*EnhancedAIEngine)(nil)
+	_ RiskAttributor       = (*EnhancedAIEngine)(nil)
+	_ BenchmarkComparer    = (*EnhancedAIEngine)(nil)
+	_ BenchmarkResolver    = (*EnhancedAIEngine)(nil)
+
+	_ PerformanceAttributor   = (*PerformanceAnalyzer)(nil)
+	_ SentimentProvider       = (*SentimentAnalyzer)(nil)
+	_ ProviderMetricsReporter = (*RealDataCollector)(nil)
+	_ ProviderMetricsReporter = (*DataCollector)(nil)
+
+	_ AllocationStrategy = (*RiskAdjustedStrategy)(nil)
+	_ AllocationStrategy = (*EqualWeightStrategy)(nil)
+	_ AllocationStrategy = (*InverseVolatilityStrategy)(nil)
+	_ AllocationStrategy = (*RiskParityStrategy)(nil)
+	_ AllocationStrategy = (*HierarchicalRiskParityStrategy)(nil)
 )
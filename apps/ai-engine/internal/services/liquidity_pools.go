@@ -0,0 +1,267 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+//go:embed data/liquidity_pools.json
+var defaultLiquidityPools []byte
+
+// Pool kinds supported by the swap simulation
+const (
+	PoolKindConstantProduct = "constant_product"
+	PoolKindStableSwap      = "stable_swap"
+)
+
+// stableSwapIterations bounds the Newton iterations of the stable swap invariant
+const stableSwapIterations = 255
+
+// LiquidityPool is a two-token DEX pool with reserves in token units
+type LiquidityPool struct {
+	ID            string  `json:"id"`
+	DEX           string  `json:"dex"`
+	Chain         string  `json:"chain"`
+	Kind          string  `json:"kind"`
+	TokenA        string  `json:"token_a"`
+	TokenB        string  `json:"token_b"`
+	ReserveA      float64 `json:"reserve_a"`
+	ReserveB      float64 `json:"reserve_b"`
+	Fee           float64 `json:"fee"`                     // Fraction of the input kept by the pool
+	Amplification float64 `json:"amplification,omitempty"` // Stable swap amplification coefficient
+}
+
+// SwapRoute is the simulated result of swapping through one or more pools
+type SwapRoute struct {
+	Hops        []models.RouteHop
+	AmountIn    float64
+	AmountOut   float64
+	FeeShare    float64 // Share of the input paid in pool fees across all hops
+	PriceImpact float64 // Share of the post-fee output lost to price movement
+}
+
+// LiquidityPools holds pool reserves for swap simulation. A provider may refresh them with Replace.
+type LiquidityPools struct {
+	mu    sync.RWMutex
+	pools []LiquidityPool
+}
+
+// LoadLiquidityPools reads a pool file, or the embedded fixture when path is empty
+func LoadLiquidityPools(path string) (*LiquidityPools, error) {
+	data := defaultLiquidityPools
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read liquidity pools: %w", err)
+		}
+	}
+	return ParseLiquidityPools(data)
+}
+
+// ParseLiquidityPools decodes and validates pool JSON
+func ParseLiquidityPools(data []byte) (*LiquidityPools, error) {
+	var file struct {
+		Pools []LiquidityPool `json:"pools"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse liquidity pools: %w", err)
+	}
+
+	pools := &LiquidityPools{}
+	if err := pools.Replace(file.Pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// Replace validates and swaps in a new set of pools
+func (p *LiquidityPools) Replace(pools []LiquidityPool) error {
+	normalized := make([]LiquidityPool, 0, len(pools))
+	for _, pool := range pools {
+		switch {
+		case pool.Kind != PoolKindConstantProduct && pool.Kind != PoolKindStableSwap:
+			return fmt.Errorf("pool %s: unknown kind %q", pool.ID, pool.Kind)
+		case pool.ReserveA <= 0 || pool.ReserveB <= 0:
+			return fmt.Errorf("pool %s: reserves must be positive", pool.ID)
+		case pool.Fee < 0 || pool.Fee >= 1:
+			return fmt.Errorf("pool %s: fee %.4f out of range [0, 1)", pool.ID, pool.Fee)
+		case pool.Kind == PoolKindStableSwap && pool.Amplification <= 0:
+			return fmt.Errorf("pool %s: stable swap pools need a positive amplification", pool.ID)
+		case strings.EqualFold(pool.TokenA, pool.TokenB):
+			return fmt.Errorf("pool %s: tokens must differ", pool.ID)
+		}
+		pool.Chain = strings.ToLower(pool.Chain)
+		pool.TokenA = strings.ToUpper(pool.TokenA)
+		pool.TokenB = strings.ToUpper(pool.TokenB)
+		normalized = append(normalized, pool)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pools = normalized
+	return nil
+}
+
+// Len returns the number of pools
+func (p *LiquidityPools) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.pools)
+}
+
+// BestRoute simulates swapping amountIn units of from into to on chain, directly or through one
+// intermediate token, and returns the route with the largest output
+func (p *LiquidityPools) BestRoute(chain, from, to string, amountIn float64) (SwapRoute, bool) {
+	chain, from, to = strings.ToLower(chain), strings.ToUpper(from), strings.ToUpper(to)
+	if amountIn <= 0 || from == to {
+		return SwapRoute{}, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var best SwapRoute
+	found := false
+	consider := func(path ...LiquidityPool) {
+		if route, ok := simulateRoute(path, from, amountIn); ok && (!found || route.AmountOut > best.AmountOut) {
+			best, found = route, true
+		}
+	}
+
+	for _, first := range p.pools {
+		if first.Chain != chain || !first.has(from) {
+			continue
+		}
+		mid := first.other(from)
+		if mid == to {
+			consider(first)
+			continue
+		}
+		for _, second := range p.pools {
+			if second.Chain == chain && second.ID != first.ID && second.has(mid) && second.other(mid) == to {
+				consider(first, second)
+			}
+		}
+	}
+	return best, found
+}
+
+// simulateRoute swaps amountIn of tokenIn through the pools in order
+func simulateRoute(path []LiquidityPool, tokenIn string, amountIn float64) (SwapRoute, bool) {
+	route := SwapRoute{AmountIn: amountIn}
+	amount, spot, kept := amountIn, amountIn, 1.0
+	for _, pool := range path {
+		out, ok := pool.swapOut(tokenIn, amount)
+		if !ok {
+			return SwapRoute{}, false
+		}
+		rate := pool.marginalRate(tokenIn)
+		hopSpot := amount * (1 - pool.Fee) * rate
+		tokenOut := pool.other(tokenIn)
+		route.Hops = append(route.Hops, models.RouteHop{
+			PoolID:      pool.ID,
+			DEX:         pool.DEX,
+			TokenIn:     tokenIn,
+			TokenOut:    tokenOut,
+			AmountIn:    amount,
+			AmountOut:   out,
+			PriceImpact: 1 - out/hopSpot,
+		})
+		spot *= (1 - pool.Fee) * rate
+		kept *= 1 - pool.Fee
+		amount, tokenIn = out, tokenOut
+	}
+	route.AmountOut = amount
+	route.FeeShare = 1 - kept
+	route.PriceImpact = 1 - amount/spot
+	return route, true
+}
+
+func (pool LiquidityPool) has(token string) bool {
+	return pool.TokenA == token || pool.TokenB == token
+}
+
+func (pool LiquidityPool) other(token string) string {
+	if pool.TokenA == token {
+		return pool.TokenB
+	}
+	return pool.TokenA
+}
+
+// reserves returns the reserves of the input and output side for a swap from tokenIn
+func (pool LiquidityPool) reserves(tokenIn string) (float64, float64) {
+	if pool.TokenA == tokenIn {
+		return pool.ReserveA, pool.ReserveB
+	}
+	return pool.ReserveB, pool.ReserveA
+}
+
+// swapOut returns the output of swapping amountIn of tokenIn, with the fee taken from the input
+func (pool LiquidityPool) swapOut(tokenIn string, amountIn float64) (float64, bool) {
+	x, y := pool.reserves(tokenIn)
+	dx := amountIn * (1 - pool.Fee)
+
+	var out float64
+	switch pool.Kind {
+	case PoolKindStableSwap:
+		d := stableSwapInvariant(pool.Amplification, x, y)
+		out = y - stableSwapBalance(pool.Amplification, d, x+dx)
+	default:
+		out = y * dx / (x + dx)
+	}
+	if out <= 0 || out >= y || math.IsNaN(out) {
+		return 0, false
+	}
+	return out, true
+}
+
+// marginalRate returns the output per unit of tokenIn for an infinitesimal swap, before fees
+func (pool LiquidityPool) marginalRate(tokenIn string) float64 {
+	x, y := pool.reserves(tokenIn)
+	if pool.Kind != PoolKindStableSwap {
+		return y / x
+	}
+	d := stableSwapInvariant(pool.Amplification, x, y)
+	dx := x * 1e-9
+	return (y - stableSwapBalance(pool.Amplification, d, x+dx)) / dx
+}
+
+// stableSwapInvariant solves the two-token stable swap invariant D by Newton's method
+func stableSwapInvariant(amp, x, y float64) float64 {
+	sum := x + y
+	ann := amp * 4
+	d := sum
+	for i := 0; i < stableSwapIterations; i++ {
+		dp := d * d / (2 * x) * d / (2 * y)
+		prev := d
+		d = (ann*sum + 2*dp) * d / ((ann-1)*d + 3*dp)
+		if math.Abs(d-prev) <= 1e-12*d {
+			break
+		}
+	}
+	return d
+}
+
+// stableSwapBalance returns the balance of one token that keeps invariant d when the other holds x
+func stableSwapBalance(amp, d, x float64) float64 {
+	ann := amp * 4
+	c := d * d / (2 * x) * d / (2 * ann)
+	b := x + d/ann
+	y := d
+	for i := 0; i < stableSwapIterations; i++ {
+		prev := y
+		y = (y*y + c) / (2*y + b - d)
+		if math.Abs(y-prev) <= 1e-12*y {
+			break
+		}
+	}
+	return y
+}
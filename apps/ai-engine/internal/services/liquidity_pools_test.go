@@ -0,0 +1,183 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestParseLiquidityPools(t *testing.T) {
+	t.Run("embedded fixture loads", func(t *testing.T) {
+		pools, err := LoadLiquidityPools("")
+		if err != nil {
+			t.Fatalf("Expected the embedded pools to load, got %v", err)
+		}
+		if pools.Len() == 0 {
+			t.Error("Expected embedded pools")
+		}
+	})
+
+	invalid := map[string]string{
+		"unknown kind":        `{"pools": [{"id": "p", "kind": "weighted", "token_a": "A", "token_b": "B", "reserve_a": 1, "reserve_b": 1}]}`,
+		"empty reserve":       `{"pools": [{"id": "p", "kind": "constant_product", "token_a": "A", "token_b": "B", "reserve_a": 0, "reserve_b": 1}]}`,
+		"fee out of range":    `{"pools": [{"id": "p", "kind": "constant_product", "token_a": "A", "token_b": "B", "reserve_a": 1, "reserve_b": 1, "fee": 1}]}`,
+		"stable without amp":  `{"pools": [{"id": "p", "kind": "stable_swap", "token_a": "A", "token_b": "B", "reserve_a": 1, "reserve_b": 1}]}`,
+		"same token twice":    `{"pools": [{"id": "p", "kind": "constant_product", "token_a": "A", "token_b": "a", "reserve_a": 1, "reserve_b": 1}]}`,
+		"malformed JSON file": `{"pools": [`,
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseLiquidityPools([]byte(data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLiquidityPools_BestRoute(t *testing.T) {
+	pools := &LiquidityPools{}
+	err := pools.Replace([]LiquidityPool{
+		{ID: "cp", DEX: "dex", Chain: "Ethereum", Kind: PoolKindConstantProduct, TokenA: "aaa", TokenB: "bbb", ReserveA: 100, ReserveB: 100},
+		{ID: "stable", DEX: "dex", Chain: "ethereum", Kind: PoolKindStableSwap, TokenA: "USDC", TokenB: "USDT", ReserveA: 1000000, ReserveB: 1000000, Fee: 0.0004, Amplification: 100},
+		{ID: "link-eth", DEX: "dex", Chain: "ethereum", Kind: PoolKindConstantProduct, TokenA: "LINK", TokenB: "ETH", ReserveA: 1000000, ReserveB: 6000, Fee: 0.003},
+		{ID: "eth-usdc", DEX: "dex", Chain: "ethereum", Kind: PoolKindConstantProduct, TokenA: "ETH", TokenB: "USDC", ReserveA: 40000, ReserveB: 100000000, Fee: 0.0005},
+		{ID: "link-usdc-thin", DEX: "dex", Chain: "ethereum", Kind: PoolKindConstantProduct, TokenA: "LINK", TokenB: "USDC", ReserveA: 10000, ReserveB: 150000, Fee: 0.003},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load pools: %v", err)
+	}
+
+	t.Run("constant product output and impact", func(t *testing.T) {
+		route, ok := pools.BestRoute("ethereum", "AAA", "BBB", 10)
+		if !ok || len(route.Hops) != 1 {
+			t.Fatalf("Expected a direct route, got %+v", route)
+		}
+		if math.Abs(route.AmountOut-1000.0/110) > 1e-9 {
+			t.Errorf("Expected 9.0909 out, got %f", route.AmountOut)
+		}
+		if math.Abs(route.PriceImpact-(1-100.0/110)) > 1e-9 {
+			t.Errorf("Expected 9.09%% impact, got %f", route.PriceImpact)
+		}
+	})
+
+	t.Run("stable swap keeps impact low near the peg", func(t *testing.T) {
+		route, ok := pools.BestRoute("ethereum", "USDC", "USDT", 100000)
+		if !ok {
+			t.Fatal("Expected a stable swap route")
+		}
+		// A constant-product pool of the same size would lose about 10%
+		if route.PriceImpact <= 0 || route.PriceImpact > 0.001 {
+			t.Errorf("Expected a small positive impact, got %f", route.PriceImpact)
+		}
+		if math.Abs(route.FeeShare-0.0004) > 1e-12 {
+			t.Errorf("Expected a 0.04%% fee, got %f", route.FeeShare)
+		}
+	})
+
+	t.Run("multi-hop route wins over a thin direct pool", func(t *testing.T) {
+		route, ok := pools.BestRoute("ethereum", "LINK", "USDC", 5000)
+		if !ok || len(route.Hops) != 2 || route.Hops[0].TokenOut != "ETH" {
+			t.Fatalf("Expected LINK -> ETH -> USDC, got %+v", route.Hops)
+		}
+		small, _ := pools.BestRoute("ethereum", "LINK", "USDC", 1)
+		if len(small.Hops) != 1 {
+			t.Errorf("Expected tiny swaps to use the direct pool, got %+v", small.Hops)
+		}
+	})
+
+	t.Run("no route across chains or for unknown tokens", func(t *testing.T) {
+		if _, ok := pools.BestRoute("arbitrum", "AAA", "BBB", 1); ok {
+			t.Error("Expected no route on another chain")
+		}
+		if _, ok := pools.BestRoute("ethereum", "AAA", "USDC", 1); ok {
+			t.Error("Expected no route between unconnected tokens")
+		}
+	})
+}
+
+func TestEnhancedAIEngine_PlanTradesWithLiquidity(t *testing.T) {
+	pools, err := LoadLiquidityPools("")
+	if err != nil {
+		t.Fatalf("Failed to load pools: %v", err)
+	}
+	engine := NewEnhancedAIEngine(WithLiquidityPools(pools))
+
+	portfolio := func(value float64, chain string) models.Portfolio {
+		return models.Portfolio{ID: "pf-pools", TotalValue: value, Positions: []models.PortfolioPosition{
+			{Token: "BTC", Weight: 0.5, Amount: value / 2 / 42000, Value: value / 2, Chain: chain},
+			{Token: "ETH", Weight: 0.5, Amount: value / 2 / 2500, Value: value / 2, Chain: chain},
+		}}
+	}
+	intoETH := []models.RebalanceAction{
+		{Type: "sell", Token: "BTC", TargetWeight: 0.3},
+		{Type: "buy", Token: "ETH", TargetWeight: 0.7},
+	}
+
+	t.Run("swaps are simulated along a route", func(t *testing.T) {
+		plan := engine.planTrades(portfolio(100000, "ethereum"), intoETH)
+		if plan == nil || len(plan.Swaps) != 1 {
+			t.Fatalf("Expected one swap, got %+v", plan)
+		}
+		swap := plan.Swaps[0]
+		if len(swap.Route) == 0 || swap.PriceImpact <= 0 {
+			t.Fatalf("Expected a simulated route with price impact, got %+v", swap)
+		}
+		if math.Abs(swap.ExpectedOut-swap.ExpectedOutUSD/2500) > 1e-9 || swap.MinAmountOut >= swap.ExpectedOut {
+			t.Errorf("Expected output at the portfolio price bounded by the tolerance, got %+v", swap)
+		}
+		if math.Abs(plan.EstimatedCostUSD-swap.EstimatedCost.TotalUSD) > 1e-9 {
+			t.Errorf("Expected the plan cost to sum its swaps, got %f", plan.EstimatedCostUSD)
+		}
+		if len(plan.Warnings) != 0 {
+			t.Errorf("Expected no warnings for a small swap, got %v", plan.Warnings)
+		}
+	})
+
+	t.Run("amounts use portfolio prices when pools are priced differently", func(t *testing.T) {
+		repriced := portfolio(100000, "ethereum")
+		repriced.Positions[1].Amount = 50000.0 / 3000
+		plan := engine.planTrades(repriced, intoETH)
+		if plan == nil || len(plan.Swaps) != 1 {
+			t.Fatalf("Expected one swap, got %+v", plan)
+		}
+		swap := plan.Swaps[0]
+		cost := swap.EstimatedCost
+		if len(swap.Route) == 0 {
+			t.Fatalf("Expected a simulated route, got %+v", swap)
+		}
+		if math.Abs(swap.ExpectedOutUSD-(swap.AmountInUSD-cost.FeeUSD-cost.SlippageUSD)) > 1e-6 {
+			t.Errorf("Expected output net of the simulated fee and impact, got %+v", swap)
+		}
+		if math.Abs(swap.ExpectedOut-swap.ExpectedOutUSD/3000) > 1e-9 {
+			t.Errorf("Expected output at the portfolio price of 3000, got %f", swap.ExpectedOut)
+		}
+		if last := swap.Route[len(swap.Route)-1]; math.Abs(swap.ExpectedOut-last.AmountOut) < 1e-6 {
+			t.Errorf("Expected output not taken from the pool price, got %f", swap.ExpectedOut)
+		}
+		if math.Abs(swap.MinAmountOut-swap.ExpectedOut*(1-engine.tradePlan.SlippageTolerance)) > 1e-9 {
+			t.Errorf("Expected min out bounded by the tolerance, got %f of %f", swap.MinAmountOut, swap.ExpectedOut)
+		}
+	})
+
+	t.Run("impact beyond the limit is flagged", func(t *testing.T) {
+		plan := engine.planTrades(portfolio(20000000, "arbitrum"), intoETH)
+		if plan == nil || len(plan.Warnings) == 0 || !strings.Contains(plan.Warnings[0], "price impact") {
+			t.Fatalf("Expected a price impact warning, got %+v", plan)
+		}
+		if plan.Swaps[0].EstimatedCost.SlippageUSD <= 0.02*plan.Swaps[0].AmountInUSD {
+			t.Errorf("Expected impact to feed the slippage cost, got %+v", plan.Swaps[0].EstimatedCost)
+		}
+	})
+
+	t.Run("unrouted swaps fall back to the cost model", func(t *testing.T) {
+		plan := engine.planTrades(portfolio(100000, "polygon"), intoETH)
+		if plan == nil || len(plan.Swaps) != 1 || plan.Swaps[0].Route != nil {
+			t.Fatalf("Expected an unrouted swap, got %+v", plan)
+		}
+		if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "no pool route") {
+			t.Errorf("Expected a missing route warning, got %v", plan.Warnings)
+		}
+	})
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// unknownEngineVersion groups records stored without engine information
+const unknownEngineVersion = "unknown"
+
+// peggedTokens are assumed to hold $1 when no price history is available
+var peggedTokens = map[string]bool{"USDC": true, "USDT": true, "DAI": true, "FRAX": true, "LUSD": true}
+
+// confidenceBucketEdges split stated confidence into calibration buckets
+var confidenceBucketEdges = []float64{0, 0.5, 0.7, 0.85, 1}
+
+// OutcomeEvaluatorConfig controls when and how recommendation outcomes are evaluated
+type OutcomeEvaluatorConfig struct {
+	Horizons    []string      // Evaluation horizons such as "1d", "7d", "30d"
+	Interval    time.Duration // How often due recommendations are evaluated
+	MaxDelay    time.Duration // How long past a horizon to wait for price data before giving up
+	MinCoverage float64       // Minimum share of portfolio weight that must have prices
+
+	CalibrationHorizon string // Horizon whose outcomes calibrate confidence; empty uses the shortest
+}
+
+// DefaultOutcomeEvaluatorConfig returns the default evaluation configuration
+func DefaultOutcomeEvaluatorConfig() OutcomeEvaluatorConfig {
+	return OutcomeEvaluatorConfig{
+		Horizons:    []string{"1d", "7d", "30d"},
+		Interval:    time.Hour,
+		MaxDelay:    48 * time.Hour,
+		MinCoverage: 0.8,
+
+		CalibrationHorizon: "7d",
+	}
+}
+
+// ParseHorizon converts an "<n>h" or "<n>d" horizon to a duration
+func ParseHorizon(horizon string) (time.Duration, error) {
+	if len(horizon) < 2 {
+		return 0, fmt.Errorf("invalid horizon %q (use e.g. 12h, 7d)", horizon)
+	}
+	n, err := strconv.Atoi(horizon[:len(horizon)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid horizon %q (use e.g. 12h, 7d)", horizon)
+	}
+	switch horizon[len(horizon)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid horizon %q (use e.g. 12h, 7d)", horizon)
+}
+
+// outcomeHorizon is a parsed evaluation horizon
+type outcomeHorizon struct {
+	name     string
+	duration time.Duration
+}
+
+// OutcomeEvaluator periodically scores stored recommendations against holding the input portfolio
+type OutcomeEvaluator struct {
+	records  store.RecommendationStore
+	prices   PriceHistory
+	config   OutcomeEvaluatorConfig
+	horizons []outcomeHorizon // Shortest first
+	logger   *slog.Logger
+	now      func() time.Time
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  sync.Once
+	done     chan struct{} // Closed when the evaluation loop exits
+
+	evalMu  sync.Mutex
+	settled time.Time // Every horizon of records created before this has an outcome
+
+	fitMu sync.Mutex
+	fits  map[string]*confidenceFit // Calibration per engine version, reset when outcomes are added
+}
+
+// NewOutcomeEvaluator creates an evaluator reading recommendations from records and prices from prices
+func NewOutcomeEvaluator(records store.RecommendationStore, prices PriceHistory, config OutcomeEvaluatorConfig) (*OutcomeEvaluator, error) {
+	if len(config.Horizons) == 0 {
+		return nil, fmt.Errorf("at least one outcome horizon is required")
+	}
+
+	horizons := make([]outcomeHorizon, 0, len(config.Horizons))
+	for _, name := range config.Horizons {
+		duration, err := ParseHorizon(name)
+		if err != nil {
+			return nil, err
+		}
+		horizons = append(horizons, outcomeHorizon{name: name, duration: duration})
+	}
+	sort.Slice(horizons, func(i, j int) bool {
+		return horizons[i].duration < horizons[j].duration
+	})
+
+	if config.CalibrationHorizon == "" {
+		config.CalibrationHorizon = horizons[0].name
+	} else if !containsFold(config.Horizons, config.CalibrationHorizon) {
+		return nil, fmt.Errorf("calibration horizon %q is not an outcome horizon", config.CalibrationHorizon)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OutcomeEvaluator{
+		records:  records,
+		prices:   prices,
+		config:   config,
+		horizons: horizons,
+		logger:   slog.Default().With("component", "outcome-evaluator"),
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start begins evaluating due recommendations every configured interval
+func (e *OutcomeEvaluator) Start() error {
+	e.started.Do(func() { go e.run() })
+	return nil
+}
+
+// run evaluates due recommendations until the evaluator stops
+func (e *OutcomeEvaluator) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if evaluated, err := e.EvaluateDue(e.ctx); err != nil && e.ctx.Err() == nil {
+			e.logger.Error("outcome evaluation failed", "error", err)
+		} else if evaluated > 0 {
+			e.logger.Info("evaluated recommendation outcomes", "count", evaluated)
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the evaluation loop and waits for a running evaluation to finish
+func (e *OutcomeEvaluator) Stop() error {
+	e.cancel()
+	e.started.Do(func() { close(e.done) }) // Never started
+	<-e.done
+	return nil
+}
+
+// EvaluateDue evaluates every recommendation horizon that has elapsed and returns how many outcomes were stored.
+// Records settled by an earlier pass, whose longest horizon and delay have passed, are not loaded again.
+func (e *OutcomeEvaluator) EvaluateDue(ctx context.Context) (int, error) {
+	e.evalMu.Lock()
+	defer e.evalMu.Unlock()
+
+	now := e.now()
+	records, err := e.records.Query(ctx, models.RecommendationQuery{
+		Kind: models.RecordKindRecommendation,
+		From: e.settled,
+		To:   now.Add(-e.horizons[0].duration),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load recommendations: %w", err)
+	}
+
+	evaluated := 0
+	defer func() {
+		if evaluated > 0 {
+			e.resetCalibration()
+		}
+	}()
+	for _, record := range records {
+		for _, horizon := range e.horizons {
+			if _, done := record.Outcomes[horizon.name]; done || now.Before(record.CreatedAt.Add(horizon.duration)) {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return evaluated, err
+			}
+
+			outcome, ok := e.evaluate(ctx, record, horizon, now)
+			if !ok {
+				continue // Retry once price data is available
+			}
+			if err := e.records.SaveOutcome(ctx, record.ID, outcome); err != nil {
+				return evaluated, fmt.Errorf("failed to save outcome of %s: %w", record.ID, err)
+			}
+			evaluated++
+		}
+	}
+
+	// A horizon past its delay is always stored, so older records are complete
+	e.settled = now.Add(-e.horizons[len(e.horizons)-1].duration - e.config.MaxDelay)
+	return evaluated, nil
+}
+
+// evaluate computes one outcome. It reports false while price data may still arrive.
+func (e *OutcomeEvaluator) evaluate(ctx context.Context, record models.RecommendationRecord, horizon outcomeHorizon, now time.Time) (models.RecommendationOutcome, bool) {
+	outcome := models.RecommendationOutcome{
+		Horizon:     horizon.name,
+		Actionable:  record.Recommendation != nil && len(record.Recommendation.Actions) > 0,
+		EvaluatedAt: now,
+	}
+
+	holdWeights, holdYields := positionWeights(record.Portfolio)
+	recommendedWeights, recommendedYields := applyRecommendation(record.Recommendation, holdWeights, holdYields)
+
+	start := record.CreatedAt
+	end := start.Add(horizon.duration)
+	returns := make(map[string]float64)
+	for _, weights := range []map[string]float64{holdWeights, recommendedWeights} {
+		for token := range weights {
+			if _, seen := returns[token]; seen {
+				continue
+			}
+			if r, ok := e.priceReturn(ctx, token, record.Market.Prices[token], start, end); ok {
+				returns[token] = r
+			}
+		}
+	}
+
+	outcome.Coverage = minFloat(coveredWeight(holdWeights, returns), coveredWeight(recommendedWeights, returns))
+	if outcome.Coverage < e.config.MinCoverage {
+		if now.Before(end.Add(e.config.MaxDelay)) {
+			return outcome, false
+		}
+		outcome.Status = models.OutcomeStatusInsufficientData
+		return outcome, true
+	}
+
+	years := horizon.duration.Hours() / (24 * 365)
+	outcome.Status = models.OutcomeStatusEvaluated
+	outcome.HoldReturn = weightedReturn(holdWeights, holdYields, returns, years)
+	outcome.RecommendedReturn = weightedReturn(recommendedWeights, recommendedYields, returns, years)
+	outcome.ExcessReturn = outcome.RecommendedReturn - outcome.HoldReturn
+	outcome.Hit = outcome.Actionable && outcome.ExcessReturn > 0
+	return outcome, true
+}
+
+// priceReturn returns a token's price return between start and end, preferring the recorded start price
+func (e *OutcomeEvaluator) priceReturn(ctx context.Context, token string, startPrice float64, start, end time.Time) (float64, bool) {
+	if startPrice <= 0 {
+		startPrice, _ = e.prices.PriceAt(ctx, token, start)
+	}
+	endPrice, _ := e.prices.PriceAt(ctx, token, end)
+
+	switch {
+	case startPrice > 0 && endPrice > 0:
+		return endPrice/startPrice - 1, true
+	case peggedTokens[token]:
+		return 0, true
+	}
+	return 0, false
+}
+
+// positionWeights returns normalized weights and weight-averaged yields by token.
+// Values are used when the portfolio carries no weights.
+func positionWeights(portfolio models.Portfolio) (map[string]float64, map[string]float64) {
+	useValue := true
+	for _, position := range portfolio.Positions {
+		if position.Weight > 0 {
+			useValue = false
+			break
+		}
+	}
+
+	weights := make(map[string]float64)
+	yields := make(map[string]float64)
+	for _, position := range portfolio.Positions {
+		weight := position.Weight
+		if useValue {
+			weight = position.Value
+		}
+		if weight <= 0 {
+			continue
+		}
+		token := strings.ToUpper(position.Token)
+		weights[token] += weight
+		yields[token] += weight * position.YieldAPY
+	}
+	for token, weight := range weights {
+		yields[token] /= weight
+	}
+	return normalizeWeights(weights), yields
+}
+
+// applyRecommendation returns the weights and yields the portfolio would have after following the actions
+func applyRecommendation(recommendation *models.RebalanceRecommendation, weights, yields map[string]float64) (map[string]float64, map[string]float64) {
+	targetWeights := make(map[string]float64, len(weights))
+	for token, weight := range weights {
+		targetWeights[token] = weight
+	}
+	targetYields := make(map[string]float64, len(yields))
+	for token, apy := range yields {
+		targetYields[token] = apy
+	}
+	if recommendation == nil {
+		return targetWeights, targetYields
+	}
+
+	for _, action := range recommendation.Actions {
+		token := strings.ToUpper(action.Token)
+		if action.Type == actionTypeMove {
+			targetYields[token] = action.TargetAPY
+			continue
+		}
+		targetWeights[token] = action.TargetWeight
+	}
+	return normalizeWeights(targetWeights), targetYields
+}
+
+// normalizeWeights scales weights to sum to one, dropping empty ones
+func normalizeWeights(weights map[string]float64) map[string]float64 {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	normalized := make(map[string]float64, len(weights))
+	if total <= 0 {
+		return normalized
+	}
+	for token, weight := range weights {
+		if weight > 0 {
+			normalized[token] = weight / total
+		}
+	}
+	return normalized
+}
+
+// coveredWeight returns the share of weight on tokens with a known return
+func coveredWeight(weights, returns map[string]float64) float64 {
+	covered := 0.0
+	for token, weight := range weights {
+		if _, ok := returns[token]; ok {
+			covered += weight
+		}
+	}
+	return covered
+}
+
+// weightedReturn combines price returns and yield accrued over years, renormalized over covered tokens
+func weightedReturn(weights, yields, returns map[string]float64, years float64) float64 {
+	total, covered := 0.0, 0.0
+	for token, weight := range weights {
+		r, ok := returns[token]
+		if !ok {
+			continue
+		}
+		total += weight * (r + yields[token]*years)
+		covered += weight
+	}
+	if covered == 0 {
+		return 0
+	}
+	return total / covered
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Scorecards aggregates evaluated outcomes per engine version and horizon.
+// Empty engineVersion or horizon match every value.
+func (e *OutcomeEvaluator) Scorecards(ctx context.Context, engineVersion, horizon string) ([]models.EngineScorecard, error) {
+	records, err := e.records.Query(ctx, models.RecommendationQuery{Kind: models.RecordKindRecommendation})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recommendations: %w", err)
+	}
+
+	type scoredOutcome struct {
+		outcome    models.RecommendationOutcome
+		confidence float64
+	}
+	type scorecardKey struct{ version, horizon string }
+	grouped := make(map[scorecardKey][]scoredOutcome)
+
+	for _, record := range records {
+		version := record.Engine.Version
+		if version == "" {
+			version = unknownEngineVersion
+		}
+		if engineVersion != "" && version != engineVersion {
+			continue
+		}
+		confidence := 0.0
+		if record.Recommendation != nil {
+			confidence = record.Recommendation.Confidence
+		}
+		for name, outcome := range record.Outcomes {
+			if outcome.Status != models.OutcomeStatusEvaluated || (horizon != "" && name != horizon) {
+				continue
+			}
+			key := scorecardKey{version, name}
+			grouped[key] = append(grouped[key], scoredOutcome{outcome, confidence})
+		}
+	}
+
+	scorecards := make([]models.EngineScorecard, 0, len(grouped))
+	for key, outcomes := range grouped {
+		scorecard := models.EngineScorecard{
+			EngineVersion: key.version,
+			Horizon:       key.horizon,
+			Evaluated:     len(outcomes),
+			Calibration:   make([]models.ConfidenceBucket, len(confidenceBucketEdges)-1),
+		}
+		for i := range scorecard.Calibration {
+			scorecard.Calibration[i].MinConfidence = confidenceBucketEdges[i]
+			scorecard.Calibration[i].MaxConfidence = confidenceBucketEdges[i+1]
+		}
+
+		var excess []float64
+		for _, scored := range outcomes {
+			if !scored.outcome.Actionable {
+				continue
+			}
+			scorecard.Actionable++
+			excess = append(excess, scored.outcome.ExcessReturn)
+			scorecard.MeanConfidence += scored.confidence
+			if scored.outcome.Hit {
+				scorecard.Hits++
+			}
+
+			bucket := &scorecard.Calibration[confidenceBucket(scored.confidence)]
+			bucket.Count++
+			bucket.MeanConfidence += scored.confidence
+			bucket.MeanExcessReturn += scored.outcome.ExcessReturn
+			if scored.outcome.Hit {
+				bucket.HitRate++
+			}
+		}
+
+		if scorecard.Actionable > 0 {
+			n := float64(scorecard.Actionable)
+			scorecard.HitRate = float64(scorecard.Hits) / n
+			scorecard.MeanConfidence /= n
+			for _, value := range excess {
+				scorecard.MeanExcessReturn += value / n
+			}
+			scorecard.MedianExcessReturn = medianOf(excess)
+		}
+		for i := range scorecard.Calibration {
+			bucket := &scorecard.Calibration[i]
+			if bucket.Count > 0 {
+				n := float64(bucket.Count)
+				bucket.MeanConfidence /= n
+				bucket.MeanExcessReturn /= n
+				bucket.HitRate /= n
+			}
+		}
+		scorecards = append(scorecards, scorecard)
+	}
+
+	sort.Slice(scorecards, func(i, j int) bool {
+		if scorecards[i].EngineVersion != scorecards[j].EngineVersion {
+			return scorecards[i].EngineVersion < scorecards[j].EngineVersion
+		}
+		return horizonDuration(scorecards[i].Horizon) < horizonDuration(scorecards[j].Horizon)
+	})
+	return scorecards, nil
+}
+
+// confidenceBucket returns the calibration bucket index of a confidence value
+func confidenceBucket(confidence float64) int {
+	for i := len(confidenceBucketEdges) - 2; i > 0; i-- {
+		if confidence >= confidenceBucketEdges[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+// horizonDuration orders horizons, including ones no longer configured
+func horizonDuration(name string) time.Duration {
+	duration, _ := ParseHorizon(name)
+	return duration
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// mockPriceHistory returns a fixed price per token before and after a switch time
+type mockPriceHistory struct {
+	switchAt time.Time
+	before   map[string]float64
+	after    map[string]float64
+}
+
+func (m *mockPriceHistory) PriceAt(ctx context.Context, token string, at time.Time) (float64, error) {
+	prices := m.before
+	if !at.Before(m.switchAt) {
+		prices = m.after
+	}
+	if price, ok := prices[token]; ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no price for %s", token)
+}
+
+func TestParseHorizon(t *testing.T) {
+	tests := map[string]time.Duration{"12h": 12 * time.Hour, "1d": 24 * time.Hour, "30d": 720 * time.Hour}
+	for input, expected := range tests {
+		if got, err := ParseHorizon(input); err != nil || got != expected {
+			t.Errorf("Expected %s for %q, got %s (%v)", expected, input, got, err)
+		}
+	}
+	for _, input := range []string{"", "d", "0d", "7w", "-1d"} {
+		if _, err := ParseHorizon(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestOutcomeEvaluator_EvaluateDue(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	records, _ := store.NewFileRecommendationStore("")
+	save := func(version string, confidence float64, portfolio models.Portfolio, actions []models.RebalanceAction) string {
+		saved, err := records.Save(ctx, models.RecommendationRecord{
+			Kind:           models.RecordKindRecommendation,
+			Owner:          "0xabc",
+			PortfolioID:    portfolio.ID,
+			Portfolio:      portfolio,
+			Recommendation: &models.RebalanceRecommendation{Confidence: confidence, Actions: actions},
+			Market:         models.MarketSnapshot{Prices: map[string]float64{"ETH": 2000}},
+			Engine:         models.EngineInfo{Version: version},
+			CreatedAt:      created,
+		})
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return saved.ID
+	}
+
+	portfolio := models.Portfolio{ID: "pf-1", Positions: []models.PortfolioPosition{
+		{Token: "ETH", Weight: 0.5},
+		{Token: "BTC", Weight: 0.5},
+	}}
+	// ETH falls 10% while BTC rises 10%, so shifting into BTC beats holding by 4%
+	good := save("1.0.0", 0.9, portfolio, []models.RebalanceAction{
+		{Type: "sell", Token: "ETH", TargetWeight: 0.3},
+		{Type: "buy", Token: "BTC", TargetWeight: 0.7},
+	})
+	bad := save("1.0.0", 0.6, portfolio, []models.RebalanceAction{
+		{Type: "buy", Token: "ETH", TargetWeight: 0.7},
+		{Type: "sell", Token: "BTC", TargetWeight: 0.3},
+	})
+	unpriced := save("0.9.0", 0.8, models.Portfolio{ID: "pf-2", Positions: []models.PortfolioPosition{
+		{Token: "OBSCURE", Weight: 1, YieldAPY: 0.1},
+	}}, nil)
+
+	prices := &mockPriceHistory{
+		switchAt: created.Add(time.Hour),
+		before:   map[string]float64{"BTC": 40000},
+		after:    map[string]float64{"ETH": 1800, "BTC": 44000},
+	}
+	config := DefaultOutcomeEvaluatorConfig()
+	config.Horizons = []string{"7d", "1d"}
+	evaluator, err := NewOutcomeEvaluator(records, prices, config)
+	if err != nil {
+		t.Fatalf("Failed to create evaluator: %v", err)
+	}
+
+	t.Run("nothing is due before the shortest horizon", func(t *testing.T) {
+		evaluator.now = func() time.Time { return created.Add(12 * time.Hour) }
+		if n, err := evaluator.EvaluateDue(ctx); err != nil || n != 0 {
+			t.Errorf("Expected no outcomes, got %d (%v)", n, err)
+		}
+	})
+
+	t.Run("elapsed horizons are evaluated against holding", func(t *testing.T) {
+		evaluator.now = func() time.Time { return created.Add(25 * time.Hour) }
+		if n, err := evaluator.EvaluateDue(ctx); err != nil || n != 2 {
+			t.Fatalf("Expected 2 outcomes, got %d (%v)", n, err)
+		}
+
+		record, _ := records.Get(ctx, "0xabc", good)
+		outcome, ok := record.Outcomes["1d"]
+		if !ok {
+			t.Fatalf("Expected a 1d outcome, got %+v", record.Outcomes)
+		}
+		if math.Abs(outcome.HoldReturn) > 1e-9 || math.Abs(outcome.RecommendedReturn-0.04) > 1e-9 {
+			t.Errorf("Expected hold 0%% and recommended 4%%, got %f and %f", outcome.HoldReturn, outcome.RecommendedReturn)
+		}
+		if !outcome.Hit || outcome.Status != models.OutcomeStatusEvaluated || outcome.Coverage != 1 {
+			t.Errorf("Expected an evaluated hit with full coverage, got %+v", outcome)
+		}
+
+		record, _ = records.Get(ctx, "0xabc", bad)
+		if record.Outcomes["1d"].Hit || record.Outcomes["1d"].ExcessReturn >= 0 {
+			t.Errorf("Expected a miss, got %+v", record.Outcomes["1d"])
+		}
+
+		record, _ = records.Get(ctx, "0xabc", unpriced)
+		if len(record.Outcomes) != 0 {
+			t.Errorf("Expected unpriced record to wait for data, got %+v", record.Outcomes)
+		}
+	})
+
+	t.Run("unpriced records give up after the maximum delay", func(t *testing.T) {
+		evaluator.now = func() time.Time { return created.Add(24*time.Hour + config.MaxDelay + time.Minute) }
+		if n, err := evaluator.EvaluateDue(ctx); err != nil || n != 1 {
+			t.Fatalf("Expected 1 outcome, got %d (%v)", n, err)
+		}
+		record, _ := records.Get(ctx, "0xabc", unpriced)
+		if record.Outcomes["1d"].Status != models.OutcomeStatusInsufficientData {
+			t.Errorf("Expected insufficient data, got %+v", record.Outcomes["1d"])
+		}
+	})
+
+	t.Run("scorecards aggregate per engine version and horizon", func(t *testing.T) {
+		evaluator.now = func() time.Time { return created.Add(8 * 24 * time.Hour) }
+		evaluator.EvaluateDue(ctx)
+
+		scorecards, err := evaluator.Scorecards(ctx, "", "")
+		if err != nil {
+			t.Fatalf("Scorecards failed: %v", err)
+		}
+		if len(scorecards) != 2 || scorecards[0].Horizon != "1d" || scorecards[1].Horizon != "7d" {
+			t.Fatalf("Expected 1d and 7d scorecards for 1.0.0, got %+v", scorecards)
+		}
+
+		scorecard := scorecards[0]
+		if scorecard.EngineVersion != "1.0.0" || scorecard.Actionable != 2 || scorecard.Hits != 1 || scorecard.HitRate != 0.5 {
+			t.Errorf("Expected 1 hit in 2 actionable recommendations, got %+v", scorecard)
+		}
+		if math.Abs(scorecard.MeanConfidence-0.75) > 1e-9 {
+			t.Errorf("Expected mean confidence 0.75, got %f", scorecard.MeanConfidence)
+		}
+		high := scorecard.Calibration[3]
+		if high.Count != 1 || high.HitRate != 1 {
+			t.Errorf("Expected the 0.85+ bucket to hold the hit, got %+v", high)
+		}
+
+		filtered, _ := evaluator.Scorecards(ctx, "1.0.0", "7d")
+		if len(filtered) != 1 || filtered[0].Horizon != "7d" {
+			t.Errorf("Expected only the 7d scorecard, got %+v", filtered)
+		}
+	})
+
+	t.Run("settled records are not loaded again", func(t *testing.T) {
+		evaluator.now = func() time.Time { return created.Add(7*24*time.Hour + config.MaxDelay + time.Minute) }
+		if n, err := evaluator.EvaluateDue(ctx); err != nil || n != 1 {
+			t.Fatalf("Expected the unpriced 7d outcome, got %d (%v)", n, err)
+		}
+
+		late := save("1.0.0", 0.9, portfolio, nil)
+		if n, err := evaluator.EvaluateDue(ctx); err != nil || n != 0 {
+			t.Errorf("Expected no outcomes before the settled time, got %d (%v)", n, err)
+		}
+		if record, _ := records.Get(ctx, "0xabc", late); len(record.Outcomes) != 0 {
+			t.Errorf("Expected the settled record not to be evaluated, got %+v", record.Outcomes)
+		}
+	})
+}
+
+func TestOutcomeEvaluator_StartStop(t *testing.T) {
+	records, _ := store.NewFileRecommendationStore("")
+	evaluator, err := NewOutcomeEvaluator(records, &mockPriceHistory{}, DefaultOutcomeEvaluatorConfig())
+	if err != nil {
+		t.Fatalf("Failed to create evaluator: %v", err)
+	}
+	evaluator.Start()
+	evaluator.Stop()
+
+	// Stopping an evaluator that never started must not block
+	idle, _ := NewOutcomeEvaluator(records, &mockPriceHistory{}, DefaultOutcomeEvaluatorConfig())
+	idle.Stop()
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// ErrInsufficientPerformanceData is returned when snapshots or prices cannot cover the requested period
+var ErrInsufficientPerformanceData = errors.New("insufficient data to attribute performance")
+
+const (
+	yearDuration        = 365 * 24 * time.Hour
+	moneyWeightedRounds = 200 // Bisection rounds when solving for the money-weighted return
+
+	// Maximum gap between a requested time and a price loaded for the period; CoinGecko
+	// serves daily prices for ranges beyond 90 days
+	periodPriceTolerance = 12 * time.Hour
+)
+
+// DefaultBenchmark returns the 60/40 BTC/ETH benchmark
+func DefaultBenchmark() models.Benchmark {
+	return models.Benchmark{Name: "btc_eth_60_40", Weights: map[string]float64{"BTC": 0.6, "ETH": 0.4}}
+}
+
+// PerformanceAnalyzer explains the returns of stored portfolios from their snapshots and price history
+type PerformanceAnalyzer struct {
+	portfolios store.PortfolioStore
+	prices     PriceHistory
+	benchmark  models.Benchmark
+	resolver   BenchmarkResolver
+	now        func() time.Time
+}
+
+// PerformanceOption configures a PerformanceAnalyzer
+type PerformanceOption func(*PerformanceAnalyzer)
+
+// WithAttributionBenchmark compares portfolios against benchmark, resolving its token weights
+// with resolver on every attribution so that named benchmarks such as market_cap stay current
+func WithAttributionBenchmark(benchmark models.Benchmark, resolver BenchmarkResolver) PerformanceOption {
+	return func(a *PerformanceAnalyzer) {
+		a.benchmark = benchmark
+		a.resolver = resolver
+	}
+}
+
+// NewPerformanceAnalyzer creates an analyzer comparing portfolios against the default benchmark
+func NewPerformanceAnalyzer(portfolios store.PortfolioStore, prices PriceHistory, opts ...PerformanceOption) *PerformanceAnalyzer {
+	a := &PerformanceAnalyzer{
+		portfolios: portfolios,
+		prices:     prices,
+		benchmark:  DefaultBenchmark(),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// holding is the quantity of a token held during a period and the APY it earned
+type holding struct {
+	quantity float64
+	apy      float64
+}
+
+// performancePeriod is the span between two snapshots, during which holdings are fixed
+type performancePeriod struct {
+	from, to time.Time
+	holdings map[string]holding
+}
+
+// periodResult holds the returns of one period
+type periodResult struct {
+	weights              map[string]float64
+	priceReturns         map[string]float64
+	income               map[string]float64 // Yield earned over the period as a share of the token's value
+	startValue           float64
+	portfolio, benchmark float64
+}
+
+// AttributePerformance explains the return of a stored portfolio between start and end.
+// Holdings change at each snapshot; value added or removed at a snapshot counts as a flow.
+func (a *PerformanceAnalyzer) AttributePerformance(ctx context.Context, owner, portfolioID string, start, end time.Time) (*models.PerformanceAttribution, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("period end %s must be after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	versions, err := a.portfolios.Versions(ctx, owner, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	benchmark, err := a.resolveBenchmark()
+	if err != nil {
+		return nil, err
+	}
+	prices, err := a.loadPrices(ctx, benchmark, versions, start, end)
+	if err != nil {
+		return nil, err
+	}
+	periods, err := a.periods(ctx, prices, versions, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]periodResult, len(periods))
+	for k, period := range periods {
+		if results[k], err = a.periodReturns(ctx, prices, benchmark, period); err != nil {
+			return nil, err
+		}
+	}
+
+	attribution := &models.PerformanceAttribution{
+		PortfolioID: portfolioID,
+		Start:       periods[0].from,
+		End:         end,
+		Periods:     len(periods),
+		StartValue:  results[0].startValue,
+		Benchmark:   benchmark,
+		Timestamp:   a.now(),
+	}
+	attribution.Tokens = linkPeriods(periods, results, attribution)
+
+	// Flows are the value of new holdings beyond what the previous holdings had grown to
+	flows := make([]float64, len(results))
+	for k := 1; k < len(results); k++ {
+		flows[k] = results[k].startValue - results[k-1].startValue*(1+results[k-1].portfolio)
+		attribution.NetFlows += flows[k]
+	}
+	last := results[len(results)-1]
+	attribution.EndValue = last.startValue * (1 + last.portfolio)
+	attribution.MoneyWeightedReturn = moneyWeightedReturn(periods, flows, attribution.StartValue, attribution.EndValue)
+	return attribution, nil
+}
+
+// resolveBenchmark returns the benchmark with the token weights it holds now
+func (a *PerformanceAnalyzer) resolveBenchmark() (models.Benchmark, error) {
+	if a.resolver == nil {
+		return a.benchmark, nil
+	}
+	weights, err := a.resolver.BenchmarkWeights(a.benchmark)
+	if err != nil {
+		return models.Benchmark{}, fmt.Errorf("%w: %v", ErrInsufficientPerformanceData, err)
+	}
+	return models.Benchmark{Name: a.benchmark.Name, Weights: weights}, nil
+}
+
+// periods splits start to end at every snapshot, starting from the snapshot held at start. When the
+// portfolio was created later, the period starts at its first snapshot.
+func (a *PerformanceAnalyzer) periods(ctx context.Context, prices periodPrices, versions []models.PortfolioVersion, start, end time.Time) ([]performancePeriod, error) {
+	first := firstVersion(versions, start)
+
+	var periods []performancePeriod
+	for i := first; i < len(versions) && versions[i].CreatedAt.Before(end); i++ {
+		from := versions[i].CreatedAt
+		if from.Before(start) {
+			from = start
+		}
+		to := end
+		if i+1 < len(versions) && versions[i+1].CreatedAt.Before(end) {
+			to = versions[i+1].CreatedAt
+		}
+		if !to.After(from) {
+			continue
+		}
+
+		holdings, err := a.holdings(ctx, prices, versions[i])
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, performancePeriod{from: from, to: to, holdings: holdings})
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("%w: no snapshots before %s", ErrInsufficientPerformanceData, end.Format(time.RFC3339))
+	}
+	return periods, nil
+}
+
+// firstVersion returns the index of the snapshot held at start, or of the first snapshot when the
+// portfolio was created later
+func firstVersion(versions []models.PortfolioVersion, start time.Time) int {
+	first := 0
+	for i, version := range versions {
+		if !version.CreatedAt.After(start) {
+			first = i
+		}
+	}
+	return first
+}
+
+// holdings returns the token quantities of a snapshot. Positions without amounts are converted
+// from their value at the snapshot's price.
+func (a *PerformanceAnalyzer) holdings(ctx context.Context, prices periodPrices, version models.PortfolioVersion) (map[string]holding, error) {
+	holdings := make(map[string]holding)
+	for _, position := range version.Portfolio.Positions {
+		token := strings.ToUpper(position.Token)
+		quantity := position.Amount
+		if quantity <= 0 && position.Value > 0 {
+			price, err := prices.at(ctx, token, version.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+			quantity = position.Value / price
+		}
+		if quantity <= 0 {
+			continue
+		}
+
+		// Yields are averaged by quantity across positions of the same token
+		h := holdings[token]
+		h.apy = (h.apy*h.quantity + position.YieldAPY*quantity) / (h.quantity + quantity)
+		h.quantity += quantity
+		holdings[token] = h
+	}
+	return holdings, nil
+}
+
+// periodReturns prices the holdings and benchmark tokens at both ends of a period
+func (a *PerformanceAnalyzer) periodReturns(ctx context.Context, prices periodPrices, benchmark models.Benchmark, period performancePeriod) (periodResult, error) {
+	result := periodResult{
+		weights:      make(map[string]float64),
+		priceReturns: make(map[string]float64),
+		income:       make(map[string]float64),
+	}
+	years := float64(period.to.Sub(period.from)) / float64(yearDuration)
+
+	tokens := make(map[string]float64)
+	for token := range period.holdings {
+		tokens[token] = 1
+	}
+	for token := range benchmark.Weights {
+		tokens[strings.ToUpper(token)] = 1
+	}
+	for _, token := range sortedTokens(tokens) {
+		startPrice, err := prices.at(ctx, token, period.from)
+		if err != nil {
+			return result, err
+		}
+		endPrice, err := prices.at(ctx, token, period.to)
+		if err != nil {
+			return result, err
+		}
+		result.priceReturns[token] = endPrice/startPrice - 1
+
+		if h, ok := period.holdings[token]; ok {
+			result.weights[token] = h.quantity * startPrice
+			result.startValue += h.quantity * startPrice
+			result.income[token] = h.apy * years
+		}
+	}
+	if result.startValue <= 0 {
+		return result, fmt.Errorf("%w: portfolio has no value at %s", ErrInsufficientPerformanceData, period.from.Format(time.RFC3339))
+	}
+
+	for token := range result.weights {
+		result.weights[token] /= result.startValue
+		result.portfolio += result.weights[token] * (result.priceReturns[token] + result.income[token])
+	}
+	for token, weight := range benchmark.Weights {
+		result.benchmark += weight * result.priceReturns[strings.ToUpper(token)]
+	}
+	return result, nil
+}
+
+// periodPrices prices tokens over an attributed period. Series loaded up front are searched
+// without fetching; without them each price is looked up in the price history.
+type periodPrices struct {
+	history PriceHistory
+	series  *TimeSeriesStore
+}
+
+// loadPrices fetches the prices of every token held or benchmarked over the period, in one
+// request per token, when the price history can load ranges
+func (a *PerformanceAnalyzer) loadPrices(ctx context.Context, benchmark models.Benchmark, versions []models.PortfolioVersion, start, end time.Time) (periodPrices, error) {
+	prices := periodPrices{history: a.prices}
+	loader, ok := a.prices.(PriceRangeHistory)
+	if !ok || len(versions) == 0 {
+		return prices, nil
+	}
+
+	first := firstVersion(versions, start)
+	from := start
+	if versions[first].CreatedAt.Before(from) {
+		from = versions[first].CreatedAt
+	}
+	tokens := make(map[string]float64)
+	for token := range benchmark.Weights {
+		tokens[strings.ToUpper(token)] = 1
+	}
+	for _, version := range versions[first:] {
+		if !version.CreatedAt.Before(end) {
+			break
+		}
+		for _, position := range version.Portfolio.Positions {
+			tokens[strings.ToUpper(position.Token)] = 1
+		}
+	}
+
+	prices.series = NewTimeSeriesStore(0)
+	for _, token := range sortedTokens(tokens) {
+		points, err := loader.PriceRange(ctx, token, from.Add(-periodPriceTolerance), end.Add(periodPriceTolerance))
+		switch {
+		case errors.Is(err, ErrTokenNotTracked):
+			// Pegged tokens fall back to $1; others have no price
+		case err != nil:
+			return prices, fmt.Errorf("failed to load %s prices: %w", token, err)
+		}
+		prices.series.AppendPoints(token, points)
+	}
+	return prices, nil
+}
+
+// at returns a token's USD price at a time, assuming $1 for pegged tokens without history
+func (p periodPrices) at(ctx context.Context, token string, at time.Time) (float64, error) {
+	if p.series != nil {
+		if point, ok := p.series.Nearest(token, at, periodPriceTolerance); ok && point.Value > 0 {
+			return point.Value, nil
+		}
+	} else if price, err := p.history.PriceAt(ctx, token, at); err == nil && price > 0 {
+		return price, nil
+	}
+	if peggedTokens[token] {
+		return 1, nil
+	}
+	return 0, fmt.Errorf("%w: no %s price at %s", ErrInsufficientPerformanceData, token, at.Format(time.RFC3339))
+}
+
+// linkPeriods compounds period returns and attributes them to tokens. Contributions in period k
+// are scaled by the portfolio's growth before k, so they sum to the time-weighted return, and
+// Brinson effects also by the benchmark's growth after k, so they sum to the active return.
+func linkPeriods(periods []performancePeriod, results []periodResult, attribution *models.PerformanceAttribution) []models.TokenPerformance {
+	benchmarkAfter := make([]float64, len(results))
+	growth := 1.0
+	for k := len(results) - 1; k >= 0; k-- {
+		benchmarkAfter[k] = growth
+		growth *= 1 + results[k].benchmark
+	}
+	attribution.BenchmarkReturn = growth - 1
+
+	total := attribution.End.Sub(attribution.Start).Seconds()
+	byToken := make(map[string]*models.TokenPerformance)
+	portfolioBefore := 1.0
+	for k, result := range results {
+		tokens := make(map[string]float64)
+		for token := range result.priceReturns {
+			tokens[token] = 1
+		}
+		share := periods[k].to.Sub(periods[k].from).Seconds() / total
+		for _, token := range sortedTokens(tokens) {
+			performance, ok := byToken[token]
+			if !ok {
+				performance = &models.TokenPerformance{Token: token, PriceReturn: 1}
+				byToken[token] = performance
+			}
+			weight, benchmarkWeight := result.weights[token], benchmarkWeightOf(attribution.Benchmark, token)
+			priceReturn, income := result.priceReturns[token], result.income[token]
+
+			performance.AverageWeight += weight * share
+			performance.BenchmarkWeight = benchmarkWeight
+			performance.PriceReturn *= 1 + priceReturn
+			performance.PriceContribution += portfolioBefore * weight * priceReturn
+			performance.IncomeContribution += portfolioBefore * weight * income
+
+			// Held tokens earn their yield on top of the benchmark's price return
+			linked := portfolioBefore * benchmarkAfter[k]
+			performance.Allocation += linked * (weight - benchmarkWeight) * (priceReturn - result.benchmark)
+			performance.Selection += linked * benchmarkWeight * income
+			performance.Interaction += linked * (weight - benchmarkWeight) * income
+		}
+		portfolioBefore *= 1 + result.portfolio
+	}
+	attribution.TimeWeightedReturn = portfolioBefore - 1
+	attribution.ActiveReturn = attribution.TimeWeightedReturn - attribution.BenchmarkReturn
+
+	tokens := make([]models.TokenPerformance, 0, len(byToken))
+	for _, performance := range byToken {
+		performance.PriceReturn--
+		performance.Contribution = performance.PriceContribution + performance.IncomeContribution
+		attribution.PriceReturn += performance.PriceContribution
+		attribution.IncomeReturn += performance.IncomeContribution
+		tokens = append(tokens, *performance)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].Contribution != tokens[j].Contribution {
+			return tokens[i].Contribution > tokens[j].Contribution
+		}
+		return tokens[i].Token < tokens[j].Token
+	})
+	return tokens
+}
+
+// benchmarkWeightOf returns the benchmark weight of a token, matching symbols case-insensitively
+func benchmarkWeightOf(benchmark models.Benchmark, token string) float64 {
+	for name, weight := range benchmark.Weights {
+		if strings.EqualFold(name, token) {
+			return weight
+		}
+	}
+	return 0
+}
+
+// moneyWeightedReturn solves for the period rate r at which the start value and flows, each
+// growing at r for the rest of the period, reach the end value. It falls back to the Modified
+// Dietz approximation when no rate in range balances the flows.
+func moneyWeightedReturn(periods []performancePeriod, flows []float64, startValue, endValue float64) float64 {
+	start, total := periods[0].from, periods[len(periods)-1].to.Sub(periods[0].from).Seconds()
+	remaining := make([]float64, len(flows))
+	for k := range flows {
+		remaining[k] = 1 - periods[k].from.Sub(start).Seconds()/total
+	}
+
+	balance := func(rate float64) float64 {
+		value := startValue * (1 + rate)
+		for k, flow := range flows {
+			value += flow * math.Pow(1+rate, remaining[k])
+		}
+		return value - endValue
+	}
+
+	low, high := -0.9999, 10.0
+	if balance(low) > 0 || balance(high) < 0 {
+		weighted := startValue
+		netFlows := 0.0
+		for k, flow := range flows {
+			weighted += flow * remaining[k]
+			netFlows += flow
+		}
+		if weighted <= 0 {
+			return 0
+		}
+		return (endValue - startValue - netFlows) / weighted
+	}
+	for i := 0; i < moneyWeightedRounds; i++ {
+		mid := (low + high) / 2
+		if balance(mid) > 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return (low + high) / 2
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+	"github.com/valkyriefinance/ai-engine/internal/store"
+)
+
+// mockPricePath prices each token with a function of time
+type mockPricePath map[string]func(time.Time) float64
+
+func (m mockPricePath) PriceAt(ctx context.Context, token string, at time.Time) (float64, error) {
+	if price, ok := m[token]; ok {
+		return price(at), nil
+	}
+	return 0, fmt.Errorf("no price for %s", token)
+}
+
+// steppedPrice returns before until at and after from then on
+func steppedPrice(at time.Time, before, after float64) func(time.Time) float64 {
+	return func(t time.Time) float64 {
+		if t.Before(at) {
+			return before
+		}
+		return after
+	}
+}
+
+// versionedStore opens a portfolio store holding one portfolio with the given snapshots
+func versionedStore(t *testing.T, owner string, versions ...models.PortfolioVersion) store.PortfolioStore {
+	t.Helper()
+	for i := range versions {
+		versions[i].Version = i + 1
+		versions[i].Portfolio.ID = "pf-perf"
+	}
+	last := versions[len(versions)-1]
+	record := map[string]interface{}{
+		"current": models.StoredPortfolio{
+			Portfolio: last.Portfolio,
+			Owner:     owner,
+			Version:   last.Version,
+			CreatedAt: versions[0].CreatedAt,
+			UpdatedAt: last.CreatedAt,
+		},
+		"versions": versions,
+	}
+	data, _ := json.Marshal(map[string]interface{}{"pf-perf": record})
+
+	path := filepath.Join(t.TempDir(), "portfolios.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write portfolio store: %v", err)
+	}
+	portfolios, err := store.NewFilePortfolioStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open portfolio store: %v", err)
+	}
+	return portfolios
+}
+
+// checkAttributionSums verifies that token contributions add up to the time-weighted return
+// and Brinson effects to the active return
+func checkAttributionSums(t *testing.T, attribution *models.PerformanceAttribution) {
+	t.Helper()
+	contributions, effects := 0.0, 0.0
+	for _, token := range attribution.Tokens {
+		contributions += token.Contribution
+		effects += token.Allocation + token.Selection + token.Interaction
+	}
+	if math.Abs(contributions-attribution.TimeWeightedReturn) > 1e-12 {
+		t.Errorf("Expected contributions summing to %f, got %f", attribution.TimeWeightedReturn, contributions)
+	}
+	if math.Abs(effects-attribution.ActiveReturn) > 1e-12 {
+		t.Errorf("Expected effects summing to %f, got %f", attribution.ActiveReturn, effects)
+	}
+}
+
+func TestPerformanceAnalyzer_AttributePerformance(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	middle := start.AddDate(0, 0, 15)
+	end := start.AddDate(0, 0, 30)
+	prices := mockPricePath{
+		"BTC": steppedPrice(middle, 100, 110),
+		"ETH": func(at time.Time) float64 {
+			if at.Before(middle) {
+				return 10
+			}
+			if at.Before(end) {
+				return 10.5
+			}
+			return 9.45
+		},
+	}
+	btcETH := models.Portfolio{Positions: []models.PortfolioPosition{
+		{Token: "BTC", Amount: 1},
+		{Token: "eth", Amount: 10},
+	}}
+
+	t.Run("single snapshot", func(t *testing.T) {
+		analyzer := NewPerformanceAnalyzer(versionedStore(t, "0xowner", models.PortfolioVersion{Portfolio: btcETH, CreatedAt: start.AddDate(0, -1, 0)}), prices)
+		attribution, err := analyzer.AttributePerformance(ctx, "0xOwner", "pf-perf", start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		// BTC +10% and ETH -5.5% at equal weights
+		if want := 0.5*0.1 + 0.5*-0.055; math.Abs(attribution.TimeWeightedReturn-want) > 1e-12 {
+			t.Errorf("Expected time-weighted return %f, got %f", want, attribution.TimeWeightedReturn)
+		}
+		if math.Abs(attribution.MoneyWeightedReturn-attribution.TimeWeightedReturn) > 1e-9 {
+			t.Errorf("Expected equal returns without flows, got %f and %f", attribution.MoneyWeightedReturn, attribution.TimeWeightedReturn)
+		}
+		if want := 0.6*0.1 + 0.4*-0.055; math.Abs(attribution.BenchmarkReturn-want) > 1e-12 {
+			t.Errorf("Expected benchmark return %f, got %f", want, attribution.BenchmarkReturn)
+		}
+		if attribution.Periods != 1 || attribution.StartValue != 200 || attribution.NetFlows != 0 {
+			t.Errorf("Expected one period from 200 without flows, got %+v", attribution)
+		}
+		if attribution.Tokens[0].Token != "BTC" || attribution.Tokens[0].AverageWeight != 0.5 {
+			t.Errorf("Expected BTC to contribute most at half the portfolio, got %+v", attribution.Tokens[0])
+		}
+		checkAttributionSums(t, attribution)
+	})
+
+	t.Run("deposit between snapshots", func(t *testing.T) {
+		doubled := models.Portfolio{Positions: []models.PortfolioPosition{
+			{Token: "BTC", Amount: 2},
+			{Token: "ETH", Amount: 10},
+		}}
+		analyzer := NewPerformanceAnalyzer(versionedStore(t, "0xowner",
+			models.PortfolioVersion{Portfolio: btcETH, CreatedAt: start},
+			models.PortfolioVersion{Portfolio: doubled, CreatedAt: middle},
+		), prices)
+		attribution, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		// First half: 200 -> 215; second half: 325 -> 314.5 after adding a BTC worth 110
+		first, second := 215.0/200-1, 314.5/325-1
+		if want := (1+first)*(1+second) - 1; math.Abs(attribution.TimeWeightedReturn-want) > 1e-12 {
+			t.Errorf("Expected time-weighted return %f, got %f", want, attribution.TimeWeightedReturn)
+		}
+		if attribution.Periods != 2 || math.Abs(attribution.NetFlows-110) > 1e-9 || math.Abs(attribution.EndValue-314.5) > 1e-9 {
+			t.Errorf("Expected a deposit of 110 ending at 314.5, got %+v", attribution)
+		}
+		// The deposit missed the first half's gain and caught the second half's loss
+		if attribution.MoneyWeightedReturn >= attribution.TimeWeightedReturn {
+			t.Errorf("Expected money-weighted below time-weighted return, got %f and %f", attribution.MoneyWeightedReturn, attribution.TimeWeightedReturn)
+		}
+		checkAttributionSums(t, attribution)
+	})
+
+	t.Run("yield on pegged tokens", func(t *testing.T) {
+		earning := models.Portfolio{Positions: []models.PortfolioPosition{
+			{Token: "BTC", Amount: 1},
+			{Token: "USDC", Value: 100, YieldAPY: 0.073},
+		}}
+		analyzer := NewPerformanceAnalyzer(versionedStore(t, "0xowner", models.PortfolioVersion{Portfolio: earning, CreatedAt: start}), prices)
+		attribution, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if want := 0.5 * 0.073 * 30 / 365; math.Abs(attribution.IncomeReturn-want) > 1e-12 {
+			t.Errorf("Expected income return %f, got %f", want, attribution.IncomeReturn)
+		}
+		checkAttributionSums(t, attribution)
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		analyzer := NewPerformanceAnalyzer(versionedStore(t, "0xowner",
+			models.PortfolioVersion{Portfolio: models.Portfolio{Positions: []models.PortfolioPosition{{Token: "DOGE", Amount: 100}}}, CreatedAt: start},
+		), prices)
+		if _, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end); !errors.Is(err, ErrInsufficientPerformanceData) {
+			t.Errorf("Expected insufficient data without prices, got %v", err)
+		}
+		if _, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start.AddDate(0, -2, 0), start); !errors.Is(err, ErrInsufficientPerformanceData) {
+			t.Errorf("Expected insufficient data before the first snapshot, got %v", err)
+		}
+		if _, err := analyzer.AttributePerformance(ctx, "0xother", "pf-perf", start, end); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Expected not found for another owner, got %v", err)
+		}
+	})
+}
+
+// TestPerformanceAnalyzer_ResolvedBenchmark tests that named benchmarks are resolved to weights
+func TestPerformanceAnalyzer_ResolvedBenchmark(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	prices := mockPricePath{
+		"BTC": steppedPrice(end, 100, 110),
+		"ETH": steppedPrice(end, 10, 9.45),
+	}
+	portfolios := versionedStore(t, "0xowner", models.PortfolioVersion{
+		Portfolio: models.Portfolio{Positions: []models.PortfolioPosition{{Token: "BTC", Amount: 1}}},
+		CreatedAt: start,
+	})
+
+	tests := []struct {
+		spec   string
+		engine *EnhancedAIEngine
+		want   float64
+	}{
+		{"btc", NewEnhancedAIEngine(), 0.1},
+		{"market_cap", NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{"BTC": 750, "ETH": 250})), 0.75*0.1 + 0.25*-0.055},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			benchmark, err := ParseBenchmark(tt.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse benchmark: %v", err)
+			}
+			analyzer := NewPerformanceAnalyzer(portfolios, prices, WithAttributionBenchmark(benchmark, tt.engine))
+			attribution, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if attribution.Benchmark.Name != tt.spec || len(attribution.Benchmark.Weights) == 0 {
+				t.Errorf("Expected resolved %s weights, got %+v", tt.spec, attribution.Benchmark)
+			}
+			if math.Abs(attribution.BenchmarkReturn-tt.want) > 1e-12 {
+				t.Errorf("Expected benchmark return %f, got %f", tt.want, attribution.BenchmarkReturn)
+			}
+			checkAttributionSums(t, attribution)
+		})
+	}
+
+	t.Run("market caps unavailable", func(t *testing.T) {
+		benchmark, _ := ParseBenchmark("market_cap")
+		engine := NewEnhancedAIEngine(WithMarketCaps(mockMarketCaps{}))
+		analyzer := NewPerformanceAnalyzer(portfolios, prices, WithAttributionBenchmark(benchmark, engine))
+		if _, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end); !errors.Is(err, ErrInsufficientPerformanceData) {
+			t.Errorf("Expected ErrInsufficientPerformanceData, got %v", err)
+		}
+	})
+}
+
+// TestPerformanceAnalyzer_PriceRanges tests that prices are loaded once per token for the whole period
+func TestPerformanceAnalyzer_PriceRanges(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	middle := start.AddDate(0, 0, 15).Add(7 * time.Hour)
+	end := start.AddDate(0, 0, 30)
+	daily := map[string]float64{"bitcoin": 40000, "ethereum": 2500}
+	requests := make(map[string]int)
+	collector := newPriceFixtureCollector(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/coins/"), "/market_chart/range")
+		price, ok := daily[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		var points [][]float64
+		for day := time.Unix(from, 0).Truncate(24 * time.Hour); !day.After(time.Unix(to, 0)); day = day.Add(24 * time.Hour) {
+			points = append(points, []float64{float64(day.UnixMilli()), price})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"prices": points})
+	})
+
+	first := models.Portfolio{Positions: []models.PortfolioPosition{
+		{Token: "BTC", Amount: 1},
+		{Token: "USDC", Value: 10000},
+	}}
+	second := models.Portfolio{Positions: []models.PortfolioPosition{
+		{Token: "BTC", Amount: 1},
+		{Token: "ETH", Value: 5000},
+	}}
+	portfolios := versionedStore(t, "0xowner",
+		models.PortfolioVersion{Portfolio: first, CreatedAt: start.Add(-5 * time.Hour)},
+		models.PortfolioVersion{Portfolio: second, CreatedAt: middle},
+	)
+
+	analyzer := NewPerformanceAnalyzer(portfolios, collector)
+	attribution, err := analyzer.AttributePerformance(ctx, "0xowner", "pf-perf", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attribution.Periods != 2 || attribution.StartValue != 50000 {
+		t.Errorf("Expected two periods from 50000, got %+v", attribution)
+	}
+	for _, path := range []string{"/coins/bitcoin/market_chart/range", "/coins/ethereum/market_chart/range"} {
+		if requests[path] != 1 {
+			t.Errorf("Expected one range request for %s, got %d", path, requests[path])
+		}
+	}
+
+	t.Run("untracked tokens are insufficient data", func(t *testing.T) {
+		untracked := versionedStore(t, "0xowner", models.PortfolioVersion{
+			Portfolio: models.Portfolio{Positions: []models.PortfolioPosition{{Token: "AAVE", Amount: 1}}},
+			CreatedAt: start,
+		})
+		before := requests["/coins/bitcoin/market_chart/range"]
+		_, err := NewPerformanceAnalyzer(untracked, collector).AttributePerformance(ctx, "0xowner", "pf-perf", start, end)
+		if !errors.Is(err, ErrInsufficientPerformanceData) {
+			t.Errorf("Expected ErrInsufficientPerformanceData, got %v", err)
+		}
+		if got := requests["/coins/bitcoin/market_chart/range"] - before; got != 0 {
+			t.Errorf("Expected the benchmark range fetched before to be reused, got %d requests", got)
+		}
+	})
+}
+
+func TestMoneyWeightedReturn(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	periods := []performancePeriod{
+		{from: start, to: start.AddDate(0, 0, 10)},
+		{from: start.AddDate(0, 0, 10), to: start.AddDate(0, 0, 20)},
+	}
+
+	// 100 grows 10% over the period; a deposit of 50 halfway grows for the remaining half
+	rate := 0.1
+	end := 100*(1+rate) + 50*math.Pow(1+rate, 0.5)
+	if got := moneyWeightedReturn(periods, []float64{0, 50}, 100, end); math.Abs(got-rate) > 1e-9 {
+		t.Errorf("Expected money-weighted return %f, got %f", rate, got)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// projectRecommendation returns the portfolio as held now and as held after following the actions
+func (e *EnhancedAIEngine) projectRecommendation(portfolio models.Portfolio, actions []models.RebalanceAction) (*models.PortfolioProjection, *models.PortfolioProjection) {
+	weights, yields := positionWeights(portfolio)
+	targetWeights, targetYields := applyRecommendation(&models.RebalanceRecommendation{Actions: actions}, weights, yields)
+
+	moves := make(map[string]models.RebalanceAction)
+	for _, action := range actions {
+		if action.Type == actionTypeMove {
+			moves[strings.ToUpper(action.Token)] = action
+		}
+	}
+
+	return e.projection(portfolio, weights, yields, nil), e.projection(portfolio, targetWeights, targetYields, moves)
+}
+
+// projection builds one position per token holding its weight of the portfolio's value.
+// Amounts use the prices implied by the original positions and are left empty for new tokens.
+func (e *EnhancedAIEngine) projection(portfolio models.Portfolio, weights, yields map[string]float64, moves map[string]models.RebalanceAction) *models.PortfolioProjection {
+	totalValue := portfolioValue(portfolio)
+
+	held := make(map[string]models.PortfolioPosition)
+	for _, position := range portfolio.Positions {
+		token := strings.ToUpper(position.Token)
+		if _, seen := held[token]; !seen {
+			held[token] = position
+		}
+	}
+	prices := impliedPrices(portfolio)
+
+	projected := &models.PortfolioProjection{
+		Portfolio: models.Portfolio{
+			ID:          portfolio.ID,
+			TotalValue:  totalValue,
+			LastUpdated: portfolio.LastUpdated,
+		},
+		Weights: make(map[string]float64, len(weights)),
+	}
+	hhi := 0.0
+	for _, token := range sortedTokens(weights) {
+		position := models.PortfolioPosition{
+			Token:    token,
+			Weight:   weights[token],
+			Value:    weights[token] * totalValue,
+			YieldAPY: yields[token],
+		}
+		if original, ok := held[token]; ok {
+			position.Token, position.Venue, position.Chain = original.Token, original.Venue, original.Chain
+		}
+		if move, ok := moves[token]; ok {
+			position.Venue, position.Chain = move.PoolID, move.Chain
+			if position.Venue == "" {
+				position.Venue = move.ToVenue
+			}
+		}
+		if price := prices[token]; price > 0 {
+			position.Amount = position.Value / price
+		}
+
+		projected.Portfolio.Positions = append(projected.Portfolio.Positions, position)
+		projected.Weights[position.Token] = position.Weight
+		projected.ExpectedReturn += position.Weight * e.positionExpectedReturn(position)
+		hhi += position.Weight * position.Weight
+	}
+	projected.Diversification = 1 - hhi
+	projected.RiskMetrics = *e.riskMetrics(projected.Portfolio, e.riskAttribution(projected.Portfolio))
+	return projected
+}
+
+// portfolioValue returns the portfolio's total value, summing positions when it is not set
+func portfolioValue(portfolio models.Portfolio) float64 {
+	if portfolio.TotalValue > 0 {
+		return portfolio.TotalValue
+	}
+	total := 0.0
+	for _, position := range portfolio.Positions {
+		total += position.Value
+	}
+	return total
+}
+
+// impliedPrices returns the USD price of each held token implied by its value and amount
+func impliedPrices(portfolio models.Portfolio) map[string]float64 {
+	prices := make(map[string]float64)
+	for _, position := range portfolio.Positions {
+		if position.Amount > 0 && position.Value > 0 {
+			prices[strings.ToUpper(position.Token)] = position.Value / position.Amount
+		}
+	}
+	return prices
+}
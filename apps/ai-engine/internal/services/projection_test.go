@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestEnhancedAIEngine_ProjectedPortfolio(t *testing.T) {
+	engine := NewEnhancedAIEngine(WithYieldStore(newTestVenueStore()))
+	portfolio := models.Portfolio{
+		ID:         "yield-portfolio",
+		TotalValue: 100000,
+		Positions: []models.PortfolioPosition{
+			{Token: "ETH", Amount: 32, Value: 80000, Weight: 0.8},
+			{Token: "USDC", Amount: 20000, Value: 20000, Weight: 0.2, Chain: "Ethereum"},
+		},
+	}
+
+	recommendation, err := engine.GetRebalanceRecommendation(context.Background(), portfolio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	current, projected := recommendation.Current, recommendation.Projected
+	if current == nil || projected == nil {
+		t.Fatal("Expected current and projected portfolios")
+	}
+
+	t.Run("current matches the input portfolio", func(t *testing.T) {
+		if current.Weights["ETH"] != 0.8 || current.Weights["USDC"] != 0.2 {
+			t.Errorf("Expected current weights 0.8/0.2, got %v", current.Weights)
+		}
+		if math.Abs(current.Diversification-0.32) > 1e-9 {
+			t.Errorf("Expected diversification 0.32, got %f", current.Diversification)
+		}
+		direct, _ := engine.CalculateRiskMetrics(context.Background(), portfolio)
+		if math.Abs(current.RiskMetrics.Volatility-direct.Volatility) > 1e-9 {
+			t.Errorf("Expected current volatility %f, got %f", direct.Volatility, current.RiskMetrics.Volatility)
+		}
+	})
+
+	t.Run("projected follows the actions", func(t *testing.T) {
+		total := 0.0
+		for _, action := range recommendation.Actions {
+			if action.Type != actionTypeMove && math.Abs(projected.Weights[action.Token]-action.TargetWeight) > 0.01 {
+				t.Errorf("Expected %s at %f, got %f", action.Token, action.TargetWeight, projected.Weights[action.Token])
+			}
+		}
+		for _, weight := range projected.Weights {
+			total += weight
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("Expected projected weights to sum to 1, got %f", total)
+		}
+		if projected.RiskMetrics.Volatility >= current.RiskMetrics.Volatility {
+			t.Errorf("Expected shifting into USDC to lower volatility, got %+v -> %+v", current.RiskMetrics, projected.RiskMetrics)
+		}
+	})
+
+	t.Run("projected positions carry venues, values and amounts", func(t *testing.T) {
+		for _, position := range projected.Portfolio.Positions {
+			if math.Abs(position.Value-position.Weight*100000) > 1e-6 {
+				t.Errorf("Expected %s value to follow its weight, got %+v", position.Token, position)
+			}
+			switch position.Token {
+			case "ETH":
+				if math.Abs(position.Amount-position.Value/2500) > 1e-9 {
+					t.Errorf("Expected ETH amount at the implied price of 2500, got %+v", position)
+				}
+			case "USDC":
+				if position.Venue != "aave-usdc" || position.YieldAPY != 0.05 {
+					t.Errorf("Expected USDC moved to aave-usdc earning 5%%, got %+v", position)
+				}
+			}
+		}
+		if projected.ExpectedReturn <= current.ExpectedReturn-0.1 {
+			t.Errorf("Expected a plausible projected return, got %f from %f", projected.ExpectedReturn, current.ExpectedReturn)
+		}
+	})
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+//go:embed data/protocol_registry.json
+var defaultProtocolRegistry []byte
+
+// Risk component names reported in a RiskBreakdown
+const (
+	RiskComponentTVLLevel      = "tvl_level"
+	RiskComponentTVLVolatility = "tvl_volatility"
+	RiskComponentProtocolAge   = "protocol_age"
+	RiskComponentAudits        = "audits"
+	RiskComponentChain         = "chain_risk"
+	RiskComponentAPYOutlier    = "apy_outlier"
+	RiskComponentExploits      = "exploits"
+)
+
+// Risk flags raised on notable findings
+const (
+	RiskFlagRecentExploit = "recent_exploit"
+	RiskFlagPastExploit   = "past_exploit"
+	RiskFlagAPYOutlier    = "apy_outlier"
+	RiskFlagUnaudited     = "unaudited"
+	RiskFlagNewProtocol   = "new_protocol"
+	RiskFlagUnknown       = "unknown_protocol"
+	RiskFlagLowTVL        = "low_tvl"
+)
+
+// ProtocolExploit records a historical security incident
+type ProtocolExploit struct {
+	Date        string  `json:"date"`
+	LossUSD     float64 `json:"loss_usd"`
+	Description string  `json:"description"`
+}
+
+// ProtocolInfo is the registry entry of a protocol
+type ProtocolInfo struct {
+	Name       string            `json:"name"`
+	LaunchDate string            `json:"launch_date"`
+	Audits     []string          `json:"audits"`
+	Exploits   []ProtocolExploit `json:"exploits,omitempty"`
+
+	launched time.Time
+	exploits []time.Time
+}
+
+// ProtocolRegistry holds static protocol and chain risk information
+type ProtocolRegistry struct {
+	Chains    map[string]float64       `json:"chains"`
+	Protocols map[string]*ProtocolInfo `json:"protocols"`
+}
+
+// LoadProtocolRegistry reads a registry file, or the embedded default when path is empty
+func LoadProtocolRegistry(path string) (*ProtocolRegistry, error) {
+	data := defaultProtocolRegistry
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read protocol registry: %w", err)
+		}
+	}
+	return ParseProtocolRegistry(data)
+}
+
+// ParseProtocolRegistry decodes and validates registry JSON
+func ParseProtocolRegistry(data []byte) (*ProtocolRegistry, error) {
+	var registry ProtocolRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse protocol registry: %w", err)
+	}
+
+	chains := make(map[string]float64, len(registry.Chains))
+	for chain, risk := range registry.Chains {
+		if risk < 0 || risk > 1 {
+			return nil, fmt.Errorf("chain %s: risk %.2f out of range [0, 1]", chain, risk)
+		}
+		chains[strings.ToLower(chain)] = risk
+	}
+	registry.Chains = chains
+
+	protocols := make(map[string]*ProtocolInfo, len(registry.Protocols))
+	for slug, info := range registry.Protocols {
+		if info == nil {
+			continue
+		}
+		if info.LaunchDate != "" {
+			launched, err := time.Parse("2006-01-02", info.LaunchDate)
+			if err != nil {
+				return nil, fmt.Errorf("protocol %s: invalid launch_date: %w", slug, err)
+			}
+			info.launched = launched
+		}
+		for _, exploit := range info.Exploits {
+			date, err := time.Parse("2006-01-02", exploit.Date)
+			if err != nil {
+				return nil, fmt.Errorf("protocol %s: invalid exploit date: %w", slug, err)
+			}
+			info.exploits = append(info.exploits, date)
+		}
+		protocols[strings.ToLower(slug)] = info
+	}
+	registry.Protocols = protocols
+
+	return &registry, nil
+}
+
+// versionSuffix matches DeFiLlama project suffixes such as "-v3"
+var versionSuffix = regexp.MustCompile(`-v\d+$`)
+
+// Protocol looks up a DeFiLlama project slug, falling back from "aave-v3" to "aave"
+func (r *ProtocolRegistry) Protocol(project string) (*ProtocolInfo, bool) {
+	slug := strings.ToLower(project)
+	candidates := []string{slug, versionSuffix.ReplaceAllString(slug, "")}
+	if i := strings.Index(slug, "-"); i > 0 {
+		candidates = append(candidates, slug[:i])
+	}
+
+	for _, candidate := range candidates {
+		if info, ok := r.Protocols[candidate]; ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// ChainRisk returns the registry risk of a chain
+func (r *ProtocolRegistry) ChainRisk(chain string) (float64, bool) {
+	risk, ok := r.Chains[strings.ToLower(chain)]
+	return risk, ok
+}
+
+// ProtocolRiskModel scores yield pools from registry data, TVL history and peer APYs
+type ProtocolRiskModel struct {
+	registry *ProtocolRegistry
+	store    *YieldStore
+	now      func() time.Time
+}
+
+// NewProtocolRiskModel creates a risk model; store may be nil when no TVL history is kept
+func NewProtocolRiskModel(registry *ProtocolRegistry, store *YieldStore) *ProtocolRiskModel {
+	return &ProtocolRiskModel{
+		registry: registry,
+		store:    store,
+		now:      time.Now,
+	}
+}
+
+// NewDefaultProtocolRiskModel loads the registry from PROTOCOL_REGISTRY_PATH or the embedded default
+func NewDefaultProtocolRiskModel(store *YieldStore) (*ProtocolRiskModel, error) {
+	registry, err := LoadProtocolRegistry(os.Getenv("PROTOCOL_REGISTRY_PATH"))
+	if err != nil {
+		return nil, err
+	}
+	return NewProtocolRiskModel(registry, store), nil
+}
+
+// ScorePools sets Risk and RiskBreakdown on every pool of a batch
+func (m *ProtocolRiskModel) ScorePools(pools []models.YieldData) {
+	peers := peerAPYStats(pools)
+	for i := range pools {
+		breakdown := m.score(pools[i], peers[peerGroupKey(pools[i])])
+		pools[i].Risk = breakdown.Score
+		pools[i].RiskBreakdown = breakdown
+	}
+}
+
+// score computes the risk breakdown of a single pool against its peer APY distribution
+func (m *ProtocolRiskModel) score(pool models.YieldData, peers apyStats) *models.RiskBreakdown {
+	breakdown := &models.RiskBreakdown{
+		Components: make([]models.RiskComponent, 0, 7),
+		Flags:      make([]string, 0),
+	}
+	add := func(name string, value, weight float64, detail string) {
+		value = clamp01(value)
+		breakdown.Components = append(breakdown.Components, models.RiskComponent{
+			Name:         name,
+			Value:        value,
+			Weight:       weight,
+			Contribution: value * weight,
+			Detail:       detail,
+		})
+		breakdown.Score += value * weight
+	}
+	flag := func(name string) {
+		breakdown.Flags = append(breakdown.Flags, name)
+	}
+
+	now := m.now()
+	info, known := m.registry.Protocol(pool.Protocol)
+	if !known {
+		flag(RiskFlagUnknown)
+	}
+
+	// TVL level: log-scaled from $1M (0.9) to $1B and above (0.05)
+	tvlRisk := 0.9
+	if pool.TVL > 0 {
+		tvlRisk = 0.9 - 0.85*(math.Log10(pool.TVL)-6)/3
+	}
+	if pool.TVL < 10000000 {
+		flag(RiskFlagLowTVL)
+	}
+	add(RiskComponentTVLLevel, math.Max(0.05, tvlRisk), 0.20, fmt.Sprintf("TVL $%.1fM", pool.TVL/1e6))
+
+	// TVL volatility: standard deviation of log changes in stored TVL history
+	volatility, samples := m.tvlVolatility(pool, now)
+	volRisk := 0.5
+	volDetail := "insufficient TVL history"
+	if samples >= 3 {
+		volRisk = volatility / 0.2 // 20% swings per observation saturate the component
+		volDetail = fmt.Sprintf("TVL change std %.1f%% over %d observations", volatility*100, samples)
+	}
+	add(RiskComponentTVLVolatility, volRisk, 0.10, volDetail)
+
+	// Protocol age
+	ageRisk := 0.7
+	ageDetail := "launch date unknown"
+	if known && !info.launched.IsZero() {
+		years := now.Sub(info.launched).Hours() / 24 / 365
+		switch {
+		case years < 0.5:
+			ageRisk = 0.9
+			flag(RiskFlagNewProtocol)
+		case years < 1:
+			ageRisk = 0.6
+		case years < 2:
+			ageRisk = 0.35
+		case years < 3:
+			ageRisk = 0.2
+		default:
+			ageRisk = 0.05
+		}
+		ageDetail = fmt.Sprintf("launched %s (%.1f years)", info.LaunchDate, years)
+	}
+	add(RiskComponentProtocolAge, ageRisk, 0.15, ageDetail)
+
+	// Audits
+	auditCount := 0
+	if known {
+		auditCount = len(info.Audits)
+	}
+	auditRisk := 0.15
+	switch auditCount {
+	case 0:
+		auditRisk = 0.8
+		flag(RiskFlagUnaudited)
+	case 1:
+		auditRisk = 0.4
+	}
+	auditDetail := "no known audits"
+	if auditCount > 0 {
+		auditDetail = fmt.Sprintf("%d audits: %s", auditCount, strings.Join(info.Audits, ", "))
+	}
+	add(RiskComponentAudits, auditRisk, 0.15, auditDetail)
+
+	// Chain risk
+	chainRisk, chainKnown := m.registry.ChainRisk(pool.Chain)
+	chainDetail := pool.Chain
+	if !chainKnown {
+		chainRisk = 0.5
+		chainDetail = fmt.Sprintf("%s not in registry", pool.Chain)
+	}
+	add(RiskComponentChain, chainRisk, 0.10, chainDetail)
+
+	// APY outlier: robust z-score against peers, with an absolute ceiling for unsustainable yields
+	outlierRisk := 0.0
+	outlierDetail := "APY in line with peers"
+	if peers.count >= 5 && peers.mad > 0 {
+		z := (pool.APY - peers.median) / (1.4826 * peers.mad)
+		if z > 0 {
+			outlierRisk = z / 6
+		}
+		outlierDetail = fmt.Sprintf("APY %.2f%% vs peer median %.2f%% (z=%.1f)", pool.APY*100, peers.median*100, z)
+	}
+	if pool.APY > 1 {
+		outlierRisk = 1
+		outlierDetail = fmt.Sprintf("APY %.0f%% is unsustainably high", pool.APY*100)
+	}
+	if outlierRisk >= 0.5 {
+		flag(RiskFlagAPYOutlier)
+	}
+	add(RiskComponentAPYOutlier, outlierRisk, 0.15, outlierDetail)
+
+	// Historical exploits
+	exploitRisk := 0.0
+	exploitDetail := "no recorded exploits"
+	if known && len(info.exploits) > 0 {
+		latest := info.exploits[0]
+		for _, date := range info.exploits[1:] {
+			if date.After(latest) {
+				latest = date
+			}
+		}
+		exploitRisk = 0.6
+		exploitDetail = fmt.Sprintf("%d exploits, latest %s", len(info.exploits), latest.Format("2006-01-02"))
+		if now.Sub(latest) < 365*24*time.Hour {
+			exploitRisk = 1
+			flag(RiskFlagRecentExploit)
+		} else {
+			flag(RiskFlagPastExploit)
+		}
+	}
+	add(RiskComponentExploits, exploitRisk, 0.15, exploitDetail)
+
+	breakdown.Score = clamp01(breakdown.Score)
+	return breakdown
+}
+
+// tvlVolatility returns the standard deviation of log TVL changes over the last 30 days
+func (m *ProtocolRiskModel) tvlVolatility(pool models.YieldData, now time.Time) (float64, int) {
+	if m.store == nil {
+		return 0, 0
+	}
+	history := m.store.TVLHistory(pool.Chain, pool.PoolID, now.Add(-30*24*time.Hour), time.Time{})
+
+	changes := make([]float64, 0, len(history))
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Value > 0 && history[i].Value > 0 {
+			changes = append(changes, math.Log(history[i].Value/history[i-1].Value))
+		}
+	}
+	if len(changes) < 2 {
+		return 0, len(changes)
+	}
+
+	mean := 0.0
+	for _, change := range changes {
+		mean += change
+	}
+	mean /= float64(len(changes))
+
+	variance := 0.0
+	for _, change := range changes {
+		variance += (change - mean) * (change - mean)
+	}
+	return math.Sqrt(variance / float64(len(changes)-1)), len(changes)
+}
+
+// apyStats summarises the APY distribution of a peer group
+type apyStats struct {
+	median float64
+	mad    float64
+	count  int
+}
+
+// peerGroupKey groups pools by their first token, so stablecoin pools are compared to stablecoin pools
+func peerGroupKey(pool models.YieldData) string {
+	token := strings.ToUpper(pool.Token)
+	if i := strings.IndexAny(token, "-/+"); i > 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// peerAPYStats computes median and median absolute deviation of APY per peer group
+func peerAPYStats(pools []models.YieldData) map[string]apyStats {
+	groups := make(map[string][]float64)
+	for _, pool := range pools {
+		key := peerGroupKey(pool)
+		groups[key] = append(groups[key], pool.APY)
+	}
+
+	stats := make(map[string]apyStats, len(groups))
+	for key, apys := range groups {
+		median := medianOf(apys)
+		deviations := make([]float64, len(apys))
+		for i, apy := range apys {
+			deviations[i] = math.Abs(apy - median)
+		}
+		stats[key] = apyStats{median: median, mad: medianOf(deviations), count: len(apys)}
+	}
+	return stats
+}
+
+// medianOf returns the median of values without modifying them
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const testProtocolRegistry = `{
+  "chains": {"Ethereum": 0.1, "bsc": 0.4},
+  "protocols": {
+    "aave": {"name": "Aave", "launch_date": "2020-01-08", "audits": ["OpenZeppelin", "Trail of Bits"]},
+    "newfarm": {"name": "New Farm", "launch_date": "2024-05-01", "audits": []},
+    "hacked": {"name": "Hacked", "launch_date": "2021-01-01", "audits": ["PeckShield"],
+      "exploits": [{"date": "2024-03-01", "loss_usd": 5000000, "description": "oracle manipulation"}]}
+  }
+}`
+
+func newTestRiskModel(t *testing.T, store *YieldStore) *ProtocolRiskModel {
+	t.Helper()
+
+	registry, err := ParseProtocolRegistry([]byte(testProtocolRegistry))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	model := NewProtocolRiskModel(registry, store)
+	model.now = func() time.Time { return time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC) }
+	return model
+}
+
+func findComponent(breakdown *models.RiskBreakdown, name string) (models.RiskComponent, bool) {
+	for _, component := range breakdown.Components {
+		if component.Name == name {
+			return component, true
+		}
+	}
+	return models.RiskComponent{}, false
+}
+
+func hasFlag(breakdown *models.RiskBreakdown, flag string) bool {
+	for _, f := range breakdown.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// TestLoadProtocolRegistry tests the embedded registry and slug fallbacks
+func TestLoadProtocolRegistry(t *testing.T) {
+	registry, err := LoadProtocolRegistry("")
+	if err != nil {
+		t.Fatalf("Expected embedded registry to load, got: %v", err)
+	}
+
+	for _, slug := range []string{"aave", "aave-v3", "uniswap-v2", "curve-dex", "compound-v3"} {
+		if _, ok := registry.Protocol(slug); !ok {
+			t.Errorf("Expected registry entry for %s", slug)
+		}
+	}
+	if _, ok := registry.Protocol("unknown-protocol"); ok {
+		t.Error("Expected no entry for unknown-protocol")
+	}
+	if _, ok := registry.ChainRisk("Ethereum"); !ok {
+		t.Error("Expected chain risk for Ethereum")
+	}
+
+	if _, err := ParseProtocolRegistry([]byte(`{"chains": {"x": 2}}`)); err == nil {
+		t.Error("Expected error for out of range chain risk")
+	}
+	if _, err := LoadProtocolRegistry("testdata/does-not-exist.json"); err == nil {
+		t.Error("Expected error for missing registry file")
+	}
+}
+
+// TestProtocolRiskModel_ScorePools tests component scoring and flags
+func TestProtocolRiskModel_ScorePools(t *testing.T) {
+	model := newTestRiskModel(t, nil)
+
+	pools := []models.YieldData{
+		{PoolID: "a", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.04, TVL: 2e9},
+		{PoolID: "b", Chain: "bsc", Protocol: "newfarm", Token: "USDC", APY: 0.9, TVL: 2e6},
+		{PoolID: "c", Chain: "Ethereum", Protocol: "hacked", Token: "USDC", APY: 0.05, TVL: 5e7},
+		{PoolID: "d", Chain: "Fantom", Protocol: "mystery", Token: "USDC", APY: 1.5, TVL: 3e6},
+		{PoolID: "e", Chain: "Ethereum", Protocol: "aave-v2", Token: "USDC", APY: 0.045, TVL: 4e8},
+		{PoolID: "f", Chain: "Ethereum", Protocol: "aave-v2", Token: "USDC-USDT", APY: 0.035, TVL: 1e8},
+	}
+	model.ScorePools(pools)
+
+	for _, pool := range pools {
+		if pool.RiskBreakdown == nil {
+			t.Fatalf("Expected breakdown for pool %s", pool.PoolID)
+		}
+		if pool.Risk < 0 || pool.Risk > 1 || pool.Risk != pool.RiskBreakdown.Score {
+			t.Errorf("Pool %s: unexpected risk %f", pool.PoolID, pool.Risk)
+		}
+
+		sum, weights := 0.0, 0.0
+		for _, component := range pool.RiskBreakdown.Components {
+			sum += component.Contribution
+			weights += component.Weight
+		}
+		if math.Abs(sum-pool.Risk) > 1e-9 {
+			t.Errorf("Pool %s: contributions sum to %f, score is %f", pool.PoolID, sum, pool.Risk)
+		}
+		if math.Abs(weights-1) > 1e-9 {
+			t.Errorf("Pool %s: weights sum to %f", pool.PoolID, weights)
+		}
+	}
+
+	established, farm, hacked, unknown := pools[0], pools[1], pools[2], pools[3]
+
+	t.Run("EstablishedIsLowestRisk", func(t *testing.T) {
+		for _, other := range pools[1:4] {
+			if established.Risk >= other.Risk {
+				t.Errorf("Expected aave risk %f below %s risk %f", established.Risk, other.Protocol, other.Risk)
+			}
+		}
+		if len(established.RiskBreakdown.Flags) != 0 {
+			t.Errorf("Expected no flags, got %v", established.RiskBreakdown.Flags)
+		}
+	})
+
+	t.Run("NewUnauditedOutlier", func(t *testing.T) {
+		for _, flag := range []string{RiskFlagNewProtocol, RiskFlagUnaudited, RiskFlagAPYOutlier, RiskFlagLowTVL} {
+			if !hasFlag(farm.RiskBreakdown, flag) {
+				t.Errorf("Expected flag %s, got %v", flag, farm.RiskBreakdown.Flags)
+			}
+		}
+		chain, _ := findComponent(farm.RiskBreakdown, RiskComponentChain)
+		if chain.Value != 0.4 {
+			t.Errorf("Expected bsc chain risk 0.4, got %f", chain.Value)
+		}
+	})
+
+	t.Run("RecentExploit", func(t *testing.T) {
+		exploits, _ := findComponent(hacked.RiskBreakdown, RiskComponentExploits)
+		if exploits.Value != 1 || !hasFlag(hacked.RiskBreakdown, RiskFlagRecentExploit) {
+			t.Errorf("Expected recent exploit to saturate component, got %+v", exploits)
+		}
+	})
+
+	t.Run("UnknownProtocol", func(t *testing.T) {
+		if !hasFlag(unknown.RiskBreakdown, RiskFlagUnknown) {
+			t.Errorf("Expected unknown protocol flag, got %v", unknown.RiskBreakdown.Flags)
+		}
+		outlier, _ := findComponent(unknown.RiskBreakdown, RiskComponentAPYOutlier)
+		if outlier.Value != 1 {
+			t.Errorf("Expected APY above 100%% to saturate outlier component, got %f", outlier.Value)
+		}
+		chain, _ := findComponent(unknown.RiskBreakdown, RiskComponentChain)
+		if chain.Value != 0.5 {
+			t.Errorf("Expected default chain risk 0.5, got %f", chain.Value)
+		}
+	})
+}
+
+// TestProtocolRiskModel_TVLVolatility tests that unstable TVL history raises risk
+func TestProtocolRiskModel_TVLVolatility(t *testing.T) {
+	store := NewYieldStore()
+	model := newTestRiskModel(t, store)
+	start := model.now().Add(-10 * 24 * time.Hour)
+
+	for day := 0; day < 10; day++ {
+		stableTVL, swingTVL := 1e8, 1e8
+		if day%2 == 1 {
+			stableTVL, swingTVL = 1.01e8, 0.6e8
+		}
+		store.Ingest([]models.YieldData{
+			{PoolID: "stable", Chain: "Ethereum", Protocol: "aave", Token: "USDC", APY: 0.04, TVL: stableTVL, Timestamp: start.Add(time.Duration(day) * 24 * time.Hour)},
+			{PoolID: "swing", Chain: "Ethereum", Protocol: "aave", Token: "USDC", APY: 0.04, TVL: swingTVL, Timestamp: start.Add(time.Duration(day) * 24 * time.Hour)},
+		})
+	}
+
+	pools := []models.YieldData{
+		{PoolID: "stable", Chain: "Ethereum", Protocol: "aave", Token: "USDC", APY: 0.04, TVL: 1e8},
+		{PoolID: "swing", Chain: "Ethereum", Protocol: "aave", Token: "USDC", APY: 0.04, TVL: 1e8},
+	}
+	model.ScorePools(pools)
+
+	stable, _ := findComponent(pools[0].RiskBreakdown, RiskComponentTVLVolatility)
+	swing, _ := findComponent(pools[1].RiskBreakdown, RiskComponentTVLVolatility)
+	if stable.Value >= 0.1 {
+		t.Errorf("Expected low volatility risk for stable pool, got %f (%s)", stable.Value, stable.Detail)
+	}
+	if swing.Value != 1 {
+		t.Errorf("Expected saturated volatility risk for swinging pool, got %f (%s)", swing.Value, swing.Detail)
+	}
+	if pools[1].Risk <= pools[0].Risk {
+		t.Errorf("Expected swinging pool to be riskier: %f <= %f", pools[1].Risk, pools[0].Risk)
+	}
+}
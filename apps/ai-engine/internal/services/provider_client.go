@@ -0,0 +1,507 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider client errors returned without contacting the upstream host
+var (
+	ErrCircuitOpen     = errors.New("circuit breaker open")
+	ErrBudgetExhausted = errors.New("request budget exhausted")
+	ErrRateLimited     = errors.New("rate limited by provider")
+)
+
+// Outcome labels recorded for every provider request
+const (
+	OutcomeSuccess         = "success"
+	OutcomeNotModified     = "not_modified"
+	OutcomeRetried         = "retried"
+	OutcomeRateLimited     = "rate_limited"
+	OutcomeServerError     = "server_error"
+	OutcomeClientError     = "client_error"
+	OutcomeNetworkError    = "network_error"
+	OutcomeCircuitOpen     = "circuit_open"
+	OutcomeBudgetExhausted = "budget_exhausted"
+)
+
+// ProviderStatusError is returned when a provider answers with a non-success status
+type ProviderStatusError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *ProviderStatusError) Error() string {
+	return fmt.Sprintf("provider %s returned status %d", e.URL, e.StatusCode)
+}
+
+// ProviderClientConfig configures retries, circuit breaking and budgets for outbound calls
+type ProviderClientConfig struct {
+	Timeout       time.Duration
+	MaxRetries    int
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration // Longest Retry-After we are willing to wait in-line
+
+	BreakerThreshold int           // Consecutive failures before the breaker opens
+	BreakerCooldown  time.Duration // Time the breaker stays open before a probe
+
+	DefaultRequestsPerMinute int
+	RequestsPerMinute        map[string]int // Per-host overrides
+
+	// Per-host share of the budget that only interactive requests may spend, so that lookups
+	// made while a caller waits are never starved by background polling and backfills
+	ReservedRequestsPerMinute map[string]int
+
+	UserAgent string
+}
+
+// DefaultProviderClientConfig returns settings suited to free-tier public APIs
+func DefaultProviderClientConfig() ProviderClientConfig {
+	return ProviderClientConfig{
+		Timeout:          15 * time.Second,
+		MaxRetries:       3,
+		BaseBackoff:      500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+		MaxRetryAfter:    30 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  60 * time.Second,
+
+		DefaultRequestsPerMinute: 60,
+		RequestsPerMinute: map[string]int{
+			"api.coingecko.com": 10, // Public tier allows roughly 10-30 calls per minute
+		},
+		ReservedRequestsPerMinute: map[string]int{
+			// Background price polls, global data and startup backfills share the other 7
+			"api.coingecko.com": 3,
+		},
+
+		UserAgent: "valkyrie-ai-engine/1.0",
+	}
+}
+
+// ProviderClient is a shared HTTP client for outbound market data providers
+type ProviderClient struct {
+	client *http.Client
+	config ProviderClientConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	cache map[string]*cachedResponse
+	rng   *rand.Rand
+
+	// Overridable for tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// hostState tracks breaker, budget and back-off state for one upstream host
+type hostState struct {
+	consecutiveFailures int
+	openUntil           time.Time
+	halfOpenProbe       bool
+
+	windowStart time.Time
+	windowUsed  int
+
+	blockedUntil time.Time // Set from Retry-After
+
+	outcomes map[string]int64
+}
+
+// cachedResponse holds a validated body for conditional requests
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// interactiveKey marks contexts of requests made while a caller waits for the response
+type interactiveKey struct{}
+
+// WithInteractivePriority marks ctx as serving a waiting caller. Its provider requests may spend
+// the reserved part of each host's budget, which background collection never touches.
+func WithInteractivePriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactiveKey{}, true)
+}
+
+// isInteractive reports whether ctx was marked by WithInteractivePriority
+func isInteractive(ctx context.Context) bool {
+	interactive, _ := ctx.Value(interactiveKey{}).(bool)
+	return interactive
+}
+
+// defaultProviderClient is shared by collectors so budgets and breakers are process-wide
+var defaultProviderClient = NewProviderClient(DefaultProviderClientConfig())
+
+// DefaultProviderClient returns the process-wide provider client
+func DefaultProviderClient() *ProviderClient {
+	return defaultProviderClient
+}
+
+// NewProviderClient creates a provider client with the given configuration
+func NewProviderClient(config ProviderClientConfig) *ProviderClient {
+	return &ProviderClient{
+		client: &http.Client{Timeout: config.Timeout},
+		config: config,
+		hosts:  make(map[string]*hostState),
+		cache:  make(map[string]*cachedResponse),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// Get fetches a URL, retrying transient failures and honouring provider rate limits
+func (c *ProviderClient) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider URL %q: %w", rawURL, err)
+	}
+	host := parsed.Host
+
+	var lastErr error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			c.record(host, OutcomeRetried)
+		}
+
+		if err := c.acquire(host, isInteractive(ctx)); err != nil {
+			return nil, err
+		}
+
+		body, retryAfter, err := c.doOnce(ctx, host, rawURL)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		if !isRetryable(err) || attempt == c.config.MaxRetries {
+			break
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			if retryAfter > c.config.MaxRetryAfter {
+				break
+			}
+			wait = retryAfter
+		}
+
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("provider request cancelled while backing off: %w", err)
+		}
+	}
+
+	return nil, lastErr
+}
+
+// GetJSON fetches a URL and decodes the JSON body into v
+func (c *ProviderClient) GetJSON(ctx context.Context, rawURL string, v interface{}) error {
+	body, err := c.Get(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// doOnce performs a single request attempt and classifies its outcome
+func (c *ProviderClient) doOnce(ctx context.Context, host, rawURL string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		c.releaseProbe(host)
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+
+	c.mu.Lock()
+	cached := c.cache[rawURL]
+	c.mu.Unlock()
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Our own cancellation says nothing about the provider's health
+			c.releaseProbe(host)
+			return nil, 0, err
+		}
+		c.recordFailure(host, OutcomeNetworkError)
+		return nil, 0, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		c.recordHealthy(host, OutcomeNotModified)
+		return cached.body, 0, nil
+
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), c.now())
+		c.block(host, retryAfter)
+		c.recordHealthy(host, OutcomeRateLimited)
+		return nil, retryAfter, &retryableError{err: fmt.Errorf("%w: %s", ErrRateLimited, host)}
+
+	case resp.StatusCode >= 500:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), c.now())
+		c.recordFailure(host, OutcomeServerError)
+		return nil, retryAfter, &retryableError{err: &ProviderStatusError{StatusCode: resp.StatusCode, URL: rawURL}}
+
+	case resp.StatusCode != http.StatusOK:
+		c.recordHealthy(host, OutcomeClientError)
+		return nil, 0, &ProviderStatusError{StatusCode: resp.StatusCode, URL: rawURL}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.recordFailure(host, OutcomeNetworkError)
+		return nil, 0, &retryableError{err: fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		c.mu.Lock()
+		c.cache[rawURL] = &cachedResponse{etag: etag, lastModified: lastModified, body: body}
+		c.mu.Unlock()
+	}
+
+	c.recordHealthy(host, OutcomeSuccess)
+	return body, 0, nil
+}
+
+// acquire checks the breaker, Retry-After block and per-minute budget for a host.
+// Background requests stop short of the share reserved for interactive ones.
+func (c *ProviderClient) acquire(host string, interactive bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.hostLocked(host)
+	now := c.now()
+
+	if now.Before(state.blockedUntil) {
+		state.outcomes[OutcomeRateLimited]++
+		return fmt.Errorf("%w: %s blocked until %s", ErrRateLimited, host, state.blockedUntil.Format(time.RFC3339))
+	}
+
+	probing := false
+	if !state.openUntil.IsZero() {
+		if now.Before(state.openUntil) || state.halfOpenProbe {
+			state.outcomes[OutcomeCircuitOpen]++
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+		// Cooldown elapsed: let a single probe through
+		probing = true
+	}
+
+	if now.Sub(state.windowStart) >= time.Minute {
+		state.windowStart = now
+		state.windowUsed = 0
+	}
+	budget := c.budgetFor(host)
+	if !interactive {
+		budget -= c.config.ReservedRequestsPerMinute[host]
+	}
+	if state.windowUsed >= budget {
+		state.outcomes[OutcomeBudgetExhausted]++
+		return fmt.Errorf("%w: %s", ErrBudgetExhausted, host)
+	}
+	state.windowUsed++
+	state.halfOpenProbe = probing
+
+	return nil
+}
+
+// budgetFor returns the per-minute request budget for a host
+func (c *ProviderClient) budgetFor(host string) int {
+	if budget, ok := c.config.RequestsPerMinute[host]; ok {
+		return budget
+	}
+	return c.config.DefaultRequestsPerMinute
+}
+
+// backoff returns an exponential delay with full jitter for the given attempt
+func (c *ProviderClient) backoff(attempt int) time.Duration {
+	ceiling := c.config.BaseBackoff << uint(attempt)
+	if ceiling <= 0 || ceiling > c.config.MaxBackoff {
+		ceiling = c.config.MaxBackoff
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(ceiling) + 1))
+}
+
+// block stops requests to a host until the Retry-After window has passed
+func (c *ProviderClient) block(host string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	until := c.now().Add(retryAfter)
+	state := c.hostLocked(host)
+	if until.After(state.blockedUntil) {
+		state.blockedUntil = until
+	}
+}
+
+// recordHealthy closes the breaker for a host that answered and records the outcome
+func (c *ProviderClient) recordHealthy(host, outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.hostLocked(host)
+	state.consecutiveFailures = 0
+	state.openUntil = time.Time{}
+	state.halfOpenProbe = false
+	state.outcomes[outcome]++
+}
+
+// recordFailure counts a breaker failure for a host and opens it past the threshold
+func (c *ProviderClient) recordFailure(host, outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.hostLocked(host)
+	state.consecutiveFailures++
+	state.outcomes[outcome]++
+
+	if state.halfOpenProbe || state.consecutiveFailures >= c.config.BreakerThreshold {
+		state.openUntil = c.now().Add(c.config.BreakerCooldown)
+		state.halfOpenProbe = false
+		log.Printf("Circuit breaker opened for %s after %d consecutive failures", host, state.consecutiveFailures)
+	}
+}
+
+// releaseProbe lets another probe through after one ended without a verdict on the host
+func (c *ProviderClient) releaseProbe(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hostLocked(host).halfOpenProbe = false
+}
+
+// record counts an outcome without touching breaker state
+func (c *ProviderClient) record(host, outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hostLocked(host).outcomes[outcome]++
+}
+
+// hostLocked returns the state for a host, creating it if needed. Caller must hold c.mu.
+func (c *ProviderClient) hostLocked(host string) *hostState {
+	state, exists := c.hosts[host]
+	if !exists {
+		state = &hostState{outcomes: make(map[string]int64)}
+		c.hosts[host] = state
+	}
+	return state
+}
+
+// Metrics returns per-host outcome counters and breaker state
+func (c *ProviderClient) Metrics() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	hosts := make(map[string]interface{}, len(c.hosts))
+	for host, state := range c.hosts {
+		outcomes := make(map[string]int64, len(state.outcomes))
+		for outcome, count := range state.outcomes {
+			outcomes[outcome] = count
+		}
+
+		breaker := "closed"
+		if !state.openUntil.IsZero() {
+			breaker = "open"
+			if !now.Before(state.openUntil) {
+				breaker = "half_open"
+			}
+		}
+
+		hosts[host] = map[string]interface{}{
+			"outcomes":             outcomes,
+			"circuit_breaker":      breaker,
+			"consecutive_failures": state.consecutiveFailures,
+			"budget_used":          state.windowUsed,
+			"budget_per_minute":    c.budgetFor(host),
+			"budget_reserved":      c.config.ReservedRequestsPerMinute[host],
+			"rate_limited":         now.Before(state.blockedUntil),
+		}
+	}
+
+	return map[string]interface{}{
+		"hosts":     hosts,
+		"timestamp": now.Format(time.RFC3339),
+	}
+}
+
+// retryableError marks errors that are worth another attempt
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
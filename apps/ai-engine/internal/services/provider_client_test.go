@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestProviderClient returns a client whose sleeps advance a fake clock instead of blocking
+func newTestProviderClient(config ProviderClientConfig) (*ProviderClient, *time.Time) {
+	client := NewProviderClient(config)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return clock }
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		clock = clock.Add(d)
+		return nil
+	}
+	return client, &clock
+}
+
+func testProviderConfig() ProviderClientConfig {
+	config := DefaultProviderClientConfig()
+	config.BaseBackoff = time.Millisecond
+	config.MaxBackoff = 10 * time.Millisecond
+	config.RequestsPerMinute = nil
+	return config
+}
+
+// TestProviderClient_RetriesServerErrors tests exponential retry on 5xx responses
+func TestProviderClient_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	client, _ := newTestProviderClient(testProviderConfig())
+
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	if err := client.GetJSON(context.Background(), srv.URL, &out); err != nil {
+		t.Fatalf("Expected success after retries, got: %v", err)
+	}
+	if !out.OK {
+		t.Error("Expected decoded body")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+// TestProviderClient_RetryAfter tests that 429 responses honour Retry-After
+func TestProviderClient_RetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, clock := newTestProviderClient(testProviderConfig())
+	start := *clock
+
+	if _, err := client.Get(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Expected success after Retry-After, got: %v", err)
+	}
+	if waited := clock.Sub(start); waited != 7*time.Second {
+		t.Errorf("Expected to wait 7s, waited %v", waited)
+	}
+
+	t.Run("RetryAfterTooLong", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		client, _ := newTestProviderClient(testProviderConfig())
+		_, err := client.Get(context.Background(), srv.URL)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got: %v", err)
+		}
+
+		// Host stays blocked without another upstream call
+		_, err = client.Get(context.Background(), srv.URL)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected host to remain blocked, got: %v", err)
+		}
+	})
+}
+
+// TestProviderClient_CircuitBreaker tests that repeated failures open the breaker
+func TestProviderClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	config := testProviderConfig()
+	config.MaxRetries = 0
+	config.BreakerThreshold = 2
+	client, clock := newTestProviderClient(config)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), srv.URL); err == nil {
+			t.Fatal("Expected error from failing provider")
+		}
+	}
+
+	_, err := client.Get(context.Background(), srv.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected open breaker to skip upstream call, got %d calls", calls)
+	}
+
+	// After cooldown a single probe is allowed through
+	*clock = clock.Add(config.BreakerCooldown)
+	if _, err := client.Get(context.Background(), srv.URL); errors.Is(err, ErrCircuitOpen) {
+		t.Error("Expected probe request after cooldown")
+	}
+	if calls != 3 {
+		t.Errorf("Expected probe to reach upstream, got %d calls", calls)
+	}
+
+	t.Run("CancelledProbe", func(t *testing.T) {
+		*clock = clock.Add(config.BreakerCooldown)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := client.Get(ctx, srv.URL); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("Expected the probe to be let through")
+		}
+
+		// The cancelled probe must not hold the breaker half-open
+		if _, err := client.Get(context.Background(), srv.URL); errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected a new probe after a cancelled one, got: %v", err)
+		}
+		if calls != 4 {
+			t.Errorf("Expected the new probe to reach upstream, got %d calls", calls)
+		}
+	})
+}
+
+// TestProviderClient_Budget tests per-minute request budgets
+func TestProviderClient_Budget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	config := testProviderConfig()
+	config.DefaultRequestsPerMinute = 2
+	client, clock := newTestProviderClient(config)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), srv.URL); err != nil {
+			t.Fatalf("Expected request %d within budget, got: %v", i, err)
+		}
+	}
+	if _, err := client.Get(context.Background(), srv.URL); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Expected ErrBudgetExhausted, got: %v", err)
+	}
+
+	*clock = clock.Add(time.Minute)
+	if _, err := client.Get(context.Background(), srv.URL); err != nil {
+		t.Errorf("Expected budget to reset after a minute, got: %v", err)
+	}
+}
+
+// TestProviderClient_ReservedBudget tests that background requests leave the reserved budget to interactive ones
+func TestProviderClient_ReservedBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	config := testProviderConfig()
+	config.RequestsPerMinute = map[string]int{host: 3}
+	config.ReservedRequestsPerMinute = map[string]int{host: 1}
+	client, _ := newTestProviderClient(config)
+	interactive := WithInteractivePriority(context.Background())
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), srv.URL); err != nil {
+			t.Fatalf("Expected background request %d within budget, got: %v", i, err)
+		}
+	}
+	if _, err := client.Get(context.Background(), srv.URL); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Expected background requests to stop short of the reserve, got: %v", err)
+	}
+	if _, err := client.Get(interactive, srv.URL); err != nil {
+		t.Fatalf("Expected an interactive request to use the reserve, got: %v", err)
+	}
+	if _, err := client.Get(interactive, srv.URL); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExhausted once the whole budget is spent, got: %v", err)
+	}
+
+	hosts := client.Metrics()["hosts"].(map[string]interface{})
+	if metrics := hosts[host].(map[string]interface{}); metrics["budget_reserved"] != 1 || metrics["budget_used"] != 3 {
+		t.Errorf("Expected 3 requests used with 1 reserved, got %+v", metrics)
+	}
+}
+
+// TestProviderClient_ConditionalRequests tests ETag revalidation
+func TestProviderClient_ConditionalRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"value":1}`))
+	}))
+	defer srv.Close()
+
+	client, _ := newTestProviderClient(testProviderConfig())
+
+	first, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("Expected cached body on 304, got %q", second)
+	}
+
+	metrics := client.Metrics()["hosts"].(map[string]interface{})
+	for _, host := range metrics {
+		outcomes := host.(map[string]interface{})["outcomes"].(map[string]int64)
+		if outcomes[OutcomeNotModified] != 1 || outcomes[OutcomeSuccess] != 1 {
+			t.Errorf("Unexpected outcome counters: %v", outcomes)
+		}
+	}
+}
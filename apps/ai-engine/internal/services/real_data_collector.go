@@ -2,10 +2,10 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,12 +16,21 @@ import (
 type RealDataCollector struct {
 	mu           sync.RWMutex
 	running      bool
-	client       *http.Client
+	provider     *ProviderClient
 	priceCache   map[string]*models.PriceData
 	marketData   *models.MarketAnalysis
+	defiData     *defiSnapshot
+	timeSeries   *TimeSeriesStore
+	sentiment    *SentimentAnalyzer
 	lastUpdate   time.Time
 	updateTicker *time.Ticker
 	stopChan     chan struct{}
+
+	coinGeckoBaseURL  string
+	defiLlamaBaseURL  string
+	trackedProtocols  []string
+	trackedChains     []string
+	backfilledHistory map[string]bool
 }
 
 // CoinGeckoPriceResponse represents the API response structure
@@ -39,36 +48,108 @@ type CoinPriceData struct {
 	LastUpdated  int64   `json:"last_updated_at"`
 }
 
-// DeFiLlamaTVLResponse represents DeFiLlama API response
+// CoinGeckoGlobalResponse represents CoinGecko's /global endpoint
+type CoinGeckoGlobalResponse struct {
+	Data struct {
+		MarketCapPercentage map[string]float64 `json:"market_cap_percentage"`
+	} `json:"data"`
+}
+
+// DeFiLlamaTVLResponse represents a TVL data point in DeFiLlama protocol history
 type DeFiLlamaTVLResponse struct {
+	Date             int64   `json:"date"`
 	TotalValueLocked float64 `json:"totalLiquidityUSD"`
 	Change24h        float64 `json:"change_1d"`
 }
 
-// NewRealDataCollector creates a new real data collector
+// DeFiLlamaChainTVL represents an entry of DeFiLlama's /v2/chains endpoint
+type DeFiLlamaChainTVL struct {
+	Name string  `json:"name"`
+	TVL  float64 `json:"tvl"`
+}
+
+// DeFiLlamaHistoricalTVL represents an entry of DeFiLlama's /v2/historicalChainTvl endpoint
+type DeFiLlamaHistoricalTVL struct {
+	Date int64   `json:"date"`
+	TVL  float64 `json:"tvl"`
+}
+
+// DeFiLlamaProtocol represents an entry of DeFiLlama's /protocols endpoint
+type DeFiLlamaProtocol struct {
+	Name     string   `json:"name"`
+	Slug     string   `json:"slug"`
+	Category string   `json:"category"`
+	Chains   []string `json:"chains"`
+	TVL      float64  `json:"tvl"`
+	Change1d float64  `json:"change_1d"`
+	Change7d float64  `json:"change_7d"`
+}
+
+// DeFiLlamaProtocolDetail represents DeFiLlama's /protocol/{slug} endpoint
+type DeFiLlamaProtocolDetail struct {
+	Name string                 `json:"name"`
+	TVL  []DeFiLlamaTVLResponse `json:"tvl"`
+}
+
+// defiSnapshot holds the latest ingested DeFi TVL figures
+type defiSnapshot struct {
+	totalTVL  float64
+	change24h float64
+	chainTVL  map[string]float64
+	protocols []models.ProtocolTVL
+	fetchedAt time.Time
+}
+
+const (
+	defaultCoinGeckoBaseURL = "https://api.coingecko.com/api/v3"
+	defaultDeFiLlamaBaseURL = "https://api.llama.fi"
+	topProtocolsCount       = 20
+	topChainsCount          = 20
+	defiHistoryInterval     = 15 * time.Minute // DeFiLlama payloads are large, so TVL is sampled at most this often
+)
+
+// defaultTrackedProtocols are protocols whose full TVL history is backfilled
+var defaultTrackedProtocols = []string{"aave", "lido", "uniswap", "curve-dex", "makerdao"}
+
+// defaultTrackedChains are chains whose TVL history is kept even outside the largest chains; they
+// are the chains the cost model prices swaps on
+var defaultTrackedChains = []string{"ethereum", "arbitrum", "optimism", "base", "polygon", "bsc"}
+
+// NewRealDataCollector creates a new real data collector using the shared provider client
 func NewRealDataCollector() *RealDataCollector {
+	return NewRealDataCollectorWithClient(DefaultProviderClient())
+}
+
+// NewRealDataCollectorWithClient creates a real data collector with a specific provider client
+func NewRealDataCollectorWithClient(provider *ProviderClient) *RealDataCollector {
+	timeSeries := NewTimeSeriesStore(0)
 	return &RealDataCollector{
-		client: &http.Client{
-			Timeout: 15 * time.Second,
-		},
-		priceCache: make(map[string]*models.PriceData),
-		stopChan:   make(chan struct{}),
+		provider:          provider,
+		priceCache:        make(map[string]*models.PriceData),
+		timeSeries:        timeSeries,
+		sentiment:         NewSentimentAnalyzer(timeSeries, provider),
+		stopChan:          make(chan struct{}),
+		coinGeckoBaseURL:  defaultCoinGeckoBaseURL,
+		defiLlamaBaseURL:  defaultDeFiLlamaBaseURL,
+		trackedProtocols:  defaultTrackedProtocols,
+		trackedChains:     defaultTrackedChains,
+		backfilledHistory: make(map[string]bool),
 	}
 }
 
 // Start begins real-time data collection
 func (r *RealDataCollector) Start() error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	if r.running {
+		r.mu.Unlock()
 		return fmt.Errorf("data collector already running")
 	}
 
 	r.running = true
 	r.updateTicker = time.NewTicker(30 * time.Second)
+	r.mu.Unlock()
 
-	// Initial data fetch
+	// Initial data fetch (takes the lock itself when updating caches)
 	if err := r.fetchAllData(); err != nil {
 		return fmt.Errorf("initial data fetch failed: %v", err)
 	}
@@ -117,49 +198,47 @@ func (r *RealDataCollector) fetchAllData() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// DeFiLlama payloads are large, so they get their own budget
+	defiCtx, defiCancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer defiCancel()
+
 	// Fetch price data from CoinGecko
 	if err := r.fetchCoinGeckoData(ctx); err != nil {
-		// Fallback to mock data if API fails
-		r.setMockPriceData()
-		fmt.Printf("CoinGecko API failed, using mock data: %v\n", err)
+		// Keep the last good prices; only fall back to mock data if we have none
+		r.mu.Lock()
+		if len(r.priceCache) == 0 {
+			r.setMockPriceData()
+		}
+		r.mu.Unlock()
+		fmt.Printf("CoinGecko API failed, keeping cached or mock data: %v\n", err)
+	}
+
+	// Sample BTC dominance for the composite sentiment index
+	if err := r.fetchGlobalData(ctx); err != nil {
+		fmt.Printf("CoinGecko global data failed, keeping the last BTC dominance: %v\n", err)
 	}
 
 	// Fetch DeFi data
-	if err := r.fetchDeFiData(ctx); err != nil {
+	if err := r.fetchDeFiData(defiCtx); err != nil {
 		r.setMockMarketData()
 		fmt.Printf("DeFi API failed, using mock data: %v\n", err)
 	}
 
+	// Fetch the published Fear & Greed index
+	if err := r.sentiment.Refresh(ctx); err != nil {
+		fmt.Printf("Fear & Greed API failed, using composite index: %v\n", err)
+	}
+
 	r.lastUpdate = time.Now()
 	return nil
 }
 
 // fetchCoinGeckoData fetches price data from CoinGecko
 func (r *RealDataCollector) fetchCoinGeckoData(ctx context.Context) error {
-	url := "https://api.coingecko.com/api/v3/simple/price?ids=bitcoin,ethereum,chainlink&vs_currencies=usd&include_24hr_change=true&include_24hr_vol=true&include_market_cap=true&include_last_updated_at=true"
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := r.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
+	url := r.coinGeckoBaseURL + "/simple/price?ids=bitcoin,ethereum,chainlink&vs_currencies=usd&include_24hr_change=true&include_24hr_vol=true&include_market_cap=true&include_last_updated_at=true"
 
 	var data CoinGeckoPriceResponse
-	if err := json.Unmarshal(body, &data); err != nil {
+	if err := r.provider.GetJSON(ctx, url, &data); err != nil {
 		return err
 	}
 
@@ -200,13 +279,187 @@ func (r *RealDataCollector) fetchCoinGeckoData(ctx context.Context) error {
 	return nil
 }
 
-// fetchDeFiData fetches DeFi protocol data
+// fetchGlobalData samples BTC's share of the total crypto market cap from CoinGecko's /global
+// endpoint into the time-series store, at most once per price history interval
+func (r *RealDataCollector) fetchGlobalData(ctx context.Context) error {
+	now := time.Now()
+	if latest, ok := r.timeSeries.Latest(SeriesBTCDominance); ok && now.Sub(latest.Timestamp) < priceHistoryInterval {
+		return nil
+	}
+
+	var global CoinGeckoGlobalResponse
+	if err := r.provider.GetJSON(ctx, r.coinGeckoBaseURL+"/global", &global); err != nil {
+		return fmt.Errorf("failed to fetch global market data: %w", err)
+	}
+	dominance := global.Data.MarketCapPercentage["btc"]
+	if dominance <= 0 || dominance >= 100 {
+		return fmt.Errorf("global market data has no BTC dominance")
+	}
+	r.timeSeries.Append(SeriesBTCDominance, now, dominance)
+	return nil
+}
+
+// fetchDeFiData ingests total, per-chain and per-protocol TVL from DeFiLlama, at most once per
+// history interval
 func (r *RealDataCollector) fetchDeFiData(ctx context.Context) error {
-	// For now, use mock data for DeFi metrics
-	r.setMockMarketData()
+	r.mu.RLock()
+	fresh := r.defiData != nil && time.Since(r.defiData.fetchedAt) < defiHistoryInterval
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	var chains []DeFiLlamaChainTVL
+	if err := r.provider.GetJSON(ctx, r.defiLlamaBaseURL+"/v2/chains", &chains); err != nil {
+		return fmt.Errorf("failed to fetch chain TVL: %w", err)
+	}
+
+	var history []DeFiLlamaHistoricalTVL
+	if err := r.provider.GetJSON(ctx, r.defiLlamaBaseURL+"/v2/historicalChainTvl", &history); err != nil {
+		return fmt.Errorf("failed to fetch historical TVL: %w", err)
+	}
+
+	var protocols []DeFiLlamaProtocol
+	if err := r.provider.GetJSON(ctx, r.defiLlamaBaseURL+"/protocols", &protocols); err != nil {
+		return fmt.Errorf("failed to fetch protocol TVL: %w", err)
+	}
+
+	now := time.Now()
+	snapshot := &defiSnapshot{
+		chainTVL:  make(map[string]float64, len(chains)),
+		fetchedAt: now,
+	}
+
+	// Per-chain TVL; the sum is the current total. History is kept only for the largest and
+	// tracked chains, as for protocols.
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].TVL > chains[j].TVL
+	})
+	trackedChains := make(map[string]bool, len(r.trackedChains))
+	for _, chain := range r.trackedChains {
+		trackedChains[strings.ToLower(chain)] = true
+	}
+	for _, chain := range chains {
+		if chain.Name == "" || chain.TVL <= 0 {
+			continue
+		}
+		if len(snapshot.chainTVL) < topChainsCount || trackedChains[strings.ToLower(chain.Name)] {
+			r.timeSeries.Append(ChainTVLSeriesKey(chain.Name), now, chain.TVL)
+		}
+		snapshot.chainTVL[chain.Name] = chain.TVL
+		snapshot.totalTVL += chain.TVL
+	}
+
+	// Daily total TVL history
+	totalPoints := make([]models.TimeSeriesPoint, 0, len(history))
+	for _, point := range history {
+		totalPoints = append(totalPoints, models.TimeSeriesPoint{
+			Timestamp: time.Unix(point.Date, 0).UTC(),
+			Value:     point.TVL,
+		})
+	}
+	r.timeSeries.AppendPoints(SeriesTotalTVL, totalPoints)
+
+	if n := len(history); n >= 2 && history[n-2].TVL > 0 {
+		snapshot.change24h = (history[n-1].TVL - history[n-2].TVL) / history[n-2].TVL * 100
+	}
+	if snapshot.totalTVL == 0 && len(history) > 0 {
+		snapshot.totalTVL = history[len(history)-1].TVL
+	}
+	r.timeSeries.Append(SeriesTotalTVLChange, now, snapshot.change24h)
+
+	// Per-protocol TVL and change, keeping history only for the largest and tracked protocols
+	// so the store stays bounded however many protocols DeFiLlama lists
+	sort.Slice(protocols, func(i, j int) bool {
+		return protocols[i].TVL > protocols[j].TVL
+	})
+	tracked := make(map[string]bool, len(r.trackedProtocols))
+	for _, slug := range r.trackedProtocols {
+		tracked[slug] = true
+	}
+	for _, protocol := range protocols {
+		if protocol.Slug == "" || protocol.TVL <= 0 {
+			continue
+		}
+		top := len(snapshot.protocols) < topProtocolsCount
+		if !top && !tracked[protocol.Slug] {
+			continue
+		}
+		r.timeSeries.Append(ProtocolTVLSeriesKey(protocol.Slug), now, protocol.TVL)
+		r.timeSeries.Append(ProtocolTVLChangeSeriesKey(protocol.Slug), now, protocol.Change1d)
+		if tracked[protocol.Slug] {
+			// One point a day, replaced by later samples, continues the backfilled daily history
+			r.timeSeries.Append(ProtocolDailyTVLSeriesKey(protocol.Slug), now.UTC().Truncate(24*time.Hour), protocol.TVL)
+		}
+
+		if top {
+			snapshot.protocols = append(snapshot.protocols, models.ProtocolTVL{
+				Name:     protocol.Name,
+				Slug:     protocol.Slug,
+				Category: protocol.Category,
+				Chains:   protocol.Chains,
+				TVL:      protocol.TVL,
+				Change1d: protocol.Change1d,
+				Change7d: protocol.Change7d,
+			})
+		}
+	}
+
+	// Backfill full history once for tracked protocols
+	for _, slug := range r.trackedProtocols {
+		if r.backfilledHistory[slug] {
+			continue
+		}
+		if err := r.backfillProtocolHistory(ctx, slug); err != nil {
+			fmt.Printf("DeFiLlama history backfill for %s failed: %v\n", slug, err)
+			continue
+		}
+		r.backfilledHistory[slug] = true
+	}
+
+	r.mu.Lock()
+	r.defiData = snapshot
+	r.mu.Unlock()
+
 	return nil
 }
 
+// backfillProtocolHistory loads the daily TVL history of a protocol into its own series, so the
+// frequent live samples cannot push it out of the bounded store
+func (r *RealDataCollector) backfillProtocolHistory(ctx context.Context, slug string) error {
+	var detail DeFiLlamaProtocolDetail
+	endpoint := r.defiLlamaBaseURL + "/protocol/" + url.PathEscape(slug)
+	if err := r.provider.GetJSON(ctx, endpoint, &detail); err != nil {
+		return err
+	}
+
+	points := make([]models.TimeSeriesPoint, 0, len(detail.TVL))
+	for _, point := range detail.TVL {
+		points = append(points, models.TimeSeriesPoint{
+			Timestamp: time.Unix(point.Date, 0).UTC(),
+			Value:     point.TotalValueLocked,
+		})
+	}
+	r.timeSeries.AppendPoints(ProtocolDailyTVLSeriesKey(slug), points)
+	return nil
+}
+
+// TimeSeries returns the store holding collected market history
+func (r *RealDataCollector) TimeSeries() *TimeSeriesStore {
+	return r.timeSeries
+}
+
+// Sentiment returns the analyzer behind the collector's Fear & Greed index
+func (r *RealDataCollector) Sentiment() *SentimentAnalyzer {
+	return r.sentiment
+}
+
+// UsePriceHistory bases the composite sentiment index on the price and volume history the engine
+// uses rather than the collector's own samples; call it before Start
+func (r *RealDataCollector) UsePriceHistory(prices *TimeSeriesStore) {
+	r.sentiment.prices = prices
+}
+
 // calculateVolatility estimates volatility from 24h change
 func (r *RealDataCollector) calculateVolatility(change24h float64) float64 {
 	// Simple volatility estimation based on price change
@@ -326,6 +579,11 @@ func (r *RealDataCollector) GetMarketAnalysis() *models.MarketAnalysis {
 	return r.marketData
 }
 
+// ProviderMetrics returns outcome counters for outbound provider requests
+func (r *RealDataCollector) ProviderMetrics() map[string]interface{} {
+	return r.provider.Metrics()
+}
+
 // IsRunning returns whether the data collector is running
 func (r *RealDataCollector) IsRunning() bool {
 	r.mu.RLock()
@@ -360,6 +618,10 @@ func (r *RealDataCollector) GetMarketIndicators() (*models.MarketIndicators, err
 		btcDominance = (btcDominance / totalMarketCap) * 100
 		ethDominance = (ethDominance / totalMarketCap) * 100
 	}
+	// The share of the cached coins only stands in until CoinGecko's global dominance is sampled
+	if latest, ok := r.timeSeries.Latest(SeriesBTCDominance); ok {
+		btcDominance = latest.Value
+	}
 
 	// Calculate average volatility
 	volatility := float64(0)
@@ -374,13 +636,29 @@ func (r *RealDataCollector) GetMarketIndicators() (*models.MarketIndicators, err
 		volatility /= float64(count)
 	}
 
-	return &models.MarketIndicators{
-		FearGreedIndex: 50.0, // Neutral default, would come from external API
+	indicators := &models.MarketIndicators{
+		FearGreedIndex: 50.0, // Neutral until prices or the published index have been collected
 		TotalMarketCap: totalMarketCap,
 		BTCDominance:   btcDominance,
 		ETHDominance:   ethDominance,
-		DeFiTVL:        250000000000, // Mock value, would come from DeFiLlama
+		DeFiTVL:        250000000000, // Fallback until DeFiLlama has been ingested
 		Volatility:     volatility,
 		Timestamp:      time.Now(),
-	}, nil
+	}
+
+	if sentiment, err := r.sentiment.MarketSentiment(); err == nil {
+		indicators.FearGreedIndex = sentiment.FearGreedIndex
+	}
+
+	if r.defiData != nil {
+		indicators.DeFiTVL = r.defiData.totalTVL
+		indicators.DeFiTVLChange24h = r.defiData.change24h
+		indicators.ChainTVL = make(map[string]float64, len(r.defiData.chainTVL))
+		for chain, tvl := range r.defiData.chainTVL {
+			indicators.ChainTVL[chain] = tvl
+		}
+		indicators.TopProtocols = append([]models.ProtocolTVL(nil), r.defiData.protocols...)
+	}
+
+	return indicators, nil
 }
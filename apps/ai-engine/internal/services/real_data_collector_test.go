@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newDeFiLlamaFixtureServer serves recorded DeFiLlama responses from testdata
+func newDeFiLlamaFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fixtures := map[string]string{
+		"/v2/chains":             "chains.json",
+		"/v2/historicalChainTvl": "historicalChainTvl.json",
+		"/protocols":             "protocols.json",
+		"/protocol/aave":         "protocol_aave.json",
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, filepath.Join("testdata", "defillama", name))
+	}))
+}
+
+func newFixtureCollector(baseURL string) *RealDataCollector {
+	config := DefaultProviderClientConfig()
+	config.BaseBackoff = time.Millisecond
+	config.MaxBackoff = time.Millisecond
+
+	collector := NewRealDataCollectorWithClient(NewProviderClient(config))
+	collector.defiLlamaBaseURL = baseURL
+	collector.trackedProtocols = []string{"aave"}
+	return collector
+}
+
+// TestRealDataCollector_FetchDeFiData tests TVL ingestion against recorded fixtures
+func TestRealDataCollector_FetchDeFiData(t *testing.T) {
+	srv := newDeFiLlamaFixtureServer(t)
+	defer srv.Close()
+
+	collector := newFixtureCollector(srv.URL)
+	if err := collector.fetchDeFiData(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	indicators, err := collector.GetMarketIndicators()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	t.Run("TotalAndChainTVL", func(t *testing.T) {
+		expectedTotal := 61234567890.12 + 8123456789.5 + 5012345678.25
+		if math.Abs(indicators.DeFiTVL-expectedTotal) > 1 {
+			t.Errorf("Expected DeFi TVL %.2f, got %.2f", expectedTotal, indicators.DeFiTVL)
+		}
+
+		if len(indicators.ChainTVL) != 3 {
+			t.Errorf("Expected 3 chains with TVL, got %d", len(indicators.ChainTVL))
+		}
+		if indicators.ChainTVL["Ethereum"] != 61234567890.12 {
+			t.Errorf("Unexpected Ethereum TVL: %f", indicators.ChainTVL["Ethereum"])
+		}
+
+		expectedChange := (74370370357.87 - 72500000000) / 72500000000 * 100
+		if math.Abs(indicators.DeFiTVLChange24h-expectedChange) > 1e-9 {
+			t.Errorf("Expected 24h change %.4f, got %.4f", expectedChange, indicators.DeFiTVLChange24h)
+		}
+	})
+
+	t.Run("Protocols", func(t *testing.T) {
+		if len(indicators.TopProtocols) != 3 {
+			t.Fatalf("Expected 3 protocols with TVL, got %d", len(indicators.TopProtocols))
+		}
+		top := indicators.TopProtocols[0]
+		if top.Slug != "lido" || top.Change1d != 1.25 || top.Change7d != 4.8 {
+			t.Errorf("Unexpected top protocol: %+v", top)
+		}
+	})
+
+	t.Run("TimeSeries", func(t *testing.T) {
+		store := collector.TimeSeries()
+
+		if n := store.Len(SeriesTotalTVL); n != 3 {
+			t.Errorf("Expected 3 total TVL points, got %d", n)
+		}
+		if n := store.Len(ProtocolDailyTVLSeriesKey("aave")); n != 3 {
+			t.Errorf("Expected 3 backfilled aave points, got %d", n)
+		}
+		if _, ok := store.Latest(ProtocolTVLChangeSeriesKey("aave-v3")); !ok {
+			t.Error("Expected change series for aave-v3")
+		}
+		if keys := store.Keys("tvl:chain:"); len(keys) != 3 {
+			t.Errorf("Expected 3 chain series, got %v", keys)
+		}
+	})
+
+	t.Run("RepeatedFetchDoesNotDuplicateHistory", func(t *testing.T) {
+		if err := collector.fetchDeFiData(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if n := collector.TimeSeries().Len(SeriesTotalTVL); n != 3 {
+			t.Errorf("Expected history to be deduplicated, got %d points", n)
+		}
+	})
+}
+
+// TestRealDataCollector_ProtocolSeriesBounded tests that protocol history is kept only for the
+// largest and tracked protocols
+func TestRealDataCollector_ProtocolSeriesBounded(t *testing.T) {
+	protocols := []DeFiLlamaProtocol{{Name: "Aave", Slug: "aave", TVL: 1}}
+	for i := 0; i < topProtocolsCount+10; i++ {
+		protocols = append(protocols, DeFiLlamaProtocol{Name: fmt.Sprintf("P%d", i), Slug: fmt.Sprintf("p%d", i), TVL: float64(1000 + i)})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/protocols":
+			json.NewEncoder(w).Encode(protocols)
+		case "/protocol/aave":
+			http.ServeFile(w, r, filepath.Join("testdata", "defillama", "protocol_aave.json"))
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer srv.Close()
+
+	collector := newFixtureCollector(srv.URL)
+	if err := collector.fetchDeFiData(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	store := collector.TimeSeries()
+	if keys := store.Keys("tvl_change_1d:protocol:"); len(keys) != topProtocolsCount+1 {
+		t.Errorf("Expected %d protocol series, got %d", topProtocolsCount+1, len(keys))
+	}
+	if _, ok := store.Latest(ProtocolTVLChangeSeriesKey("aave")); !ok {
+		t.Error("Expected series for the tracked protocol")
+	}
+	if _, ok := store.Latest(ProtocolTVLSeriesKey("p0")); ok {
+		t.Error("Expected no series for a small untracked protocol")
+	}
+	if latest, ok := store.Latest(ProtocolDailyTVLSeriesKey("aave")); !ok || latest.Value != 1 || store.Len(ProtocolDailyTVLSeriesKey("aave")) != 4 {
+		t.Errorf("Expected today's sample to extend the daily history, got %+v of %d points", latest, store.Len(ProtocolDailyTVLSeriesKey("aave")))
+	}
+}
+
+// TestRealDataCollector_ChainSeriesSampled tests that chain history is kept only for the largest
+// and tracked chains and that DeFiLlama is polled at most once per history interval
+func TestRealDataCollector_ChainSeriesSampled(t *testing.T) {
+	chains := []DeFiLlamaChainTVL{{Name: "Arbitrum", TVL: 1}}
+	for i := 0; i < topChainsCount+10; i++ {
+		chains = append(chains, DeFiLlamaChainTVL{Name: fmt.Sprintf("C%d", i), TVL: float64(1000 + i)})
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v2/chains" {
+			json.NewEncoder(w).Encode(chains)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	collector := newFixtureCollector(srv.URL)
+	collector.trackedProtocols = nil
+	for i := 0; i < 2; i++ {
+		if err := collector.fetchDeFiData(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected one poll of 3 requests within the interval, got %d", requests)
+	}
+	store := collector.TimeSeries()
+	if keys := store.Keys("tvl:chain:"); len(keys) != topChainsCount+1 {
+		t.Errorf("Expected %d chain series, got %d", topChainsCount+1, len(keys))
+	}
+	if _, ok := store.Latest(ChainTVLSeriesKey("Arbitrum")); !ok {
+		t.Error("Expected series for the tracked chain")
+	}
+	if _, ok := store.Latest(ChainTVLSeriesKey("C0")); ok {
+		t.Error("Expected no series for a small untracked chain")
+	}
+	if indicators, _ := collector.GetMarketIndicators(); len(indicators.ChainTVL) != len(chains) {
+		t.Errorf("Expected every chain in the indicators, got %d", len(indicators.ChainTVL))
+	}
+}
+
+// TestRealDataCollector_BackfilledHistoryKept tests that live samples past the store's cap do not
+// evict the backfilled daily history
+func TestRealDataCollector_BackfilledHistoryKept(t *testing.T) {
+	srv := newDeFiLlamaFixtureServer(t)
+	defer srv.Close()
+
+	collector := newFixtureCollector(srv.URL)
+	if err := collector.fetchDeFiData(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	store := collector.TimeSeries()
+	start := time.Now()
+	for i := 0; i <= store.maxPoints; i++ {
+		store.Append(ProtocolTVLSeriesKey("aave"), start.Add(time.Duration(i)*30*time.Second), 1e10)
+	}
+
+	if n := store.Len(ProtocolTVLSeriesKey("aave")); n != store.maxPoints {
+		t.Errorf("Expected live samples capped at %d, got %d", store.maxPoints, n)
+	}
+	daily := store.Range(ProtocolDailyTVLSeriesKey("aave"), time.Time{}, time.Time{})
+	if len(daily) != 3 || daily[0].Timestamp.Unix() != 1704067200 {
+		t.Errorf("Expected the 3 backfilled aave points to remain, got %+v", daily)
+	}
+}
+
+// TestRealDataCollector_FetchDeFiDataError tests that upstream failures are surfaced
+func TestRealDataCollector_FetchDeFiDataError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	collector := newFixtureCollector(srv.URL)
+	if err := collector.fetchDeFiData(context.Background()); err == nil {
+		t.Fatal("Expected error when DeFiLlama is unavailable")
+	}
+
+	indicators, _ := collector.GetMarketIndicators()
+	if indicators.DeFiTVL <= 0 {
+		t.Errorf("Expected fallback DeFi TVL, got %f", indicators.DeFiTVL)
+	}
+}
+
+// TestRealDataCollector_FearGreedIndex tests that market indicators report the collected sentiment
+func TestRealDataCollector_FearGreedIndex(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/global" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"market_cap_percentage": {"btc": 52.3, "eth": 17.1}}}`))
+	}))
+	defer srv.Close()
+
+	collector := newFixtureCollector("")
+	collector.coinGeckoBaseURL = srv.URL
+	collector.mu.Lock()
+	collector.setMockPriceData()
+	collector.mu.Unlock()
+
+	indicators, _ := collector.GetMarketIndicators()
+	if indicators.BTCDominance <= 0 || indicators.BTCDominance == 52.3 {
+		t.Errorf("Expected BTC's share of the cached coins before sampling, got %f", indicators.BTCDominance)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := collector.fetchGlobalData(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	store := collector.TimeSeries()
+	if dominance, ok := store.Latest(SeriesBTCDominance); !ok || dominance.Value != 52.3 {
+		t.Errorf("Expected BTC dominance of 52.3 from CoinGecko, got %+v", dominance)
+	}
+	if requests != 1 {
+		t.Errorf("Expected one request within the sampling interval, got %d", requests)
+	}
+	if _, ok := store.Latest(PriceSeriesKey("BTC")); ok {
+		t.Error("Expected no BTC price series of the collector's own")
+	}
+
+	indicators, _ = collector.GetMarketIndicators()
+	if indicators.BTCDominance != 52.3 {
+		t.Errorf("Expected the sampled BTC dominance of 52.3, got %f", indicators.BTCDominance)
+	}
+	if indicators.FearGreedIndex != 50 {
+		t.Errorf("Expected a neutral index from a single sample, got %f", indicators.FearGreedIndex)
+	}
+
+	store.Append(SeriesFearGreed, time.Now(), 23)
+	indicators, _ = collector.GetMarketIndicators()
+	if indicators.FearGreedIndex != 23 {
+		t.Errorf("Expected the published index, got %f", indicators.FearGreedIndex)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// Trigger rules reported on actions
+const (
+	ruleAbsoluteBand   = "absolute_band"
+	ruleRelativeBand   = "relative_band"
+	ruleVolatilityBand = "volatility_band"
+	ruleCalendar       = "calendar"
+)
+
+// policyYieldVenue is the trigger mode of venue moves, which follow the yield venue policy
+const policyYieldVenue = "yield_venue"
+
+// Default review cadences
+const (
+	defaultReviewCadence   = "1d"
+	defaultCalendarCadence = "30d"
+)
+
+// DefaultRebalancePolicy returns the policy used when a request names none: a 2% absolute band reviewed daily
+func DefaultRebalancePolicy() models.RebalancePolicy {
+	return models.RebalancePolicy{
+		Mode:    models.PolicyModeThreshold,
+		Band:    models.DriftBand{Absolute: rebalanceBand},
+		Cadence: defaultReviewCadence,
+	}
+}
+
+// ValidateRebalancePolicy checks that a requested policy can be applied
+func ValidateRebalancePolicy(policy models.RebalancePolicy) error {
+	switch policy.Mode {
+	case "", models.PolicyModeThreshold, models.PolicyModeCalendar, models.PolicyModeVolatility:
+	default:
+		return fmt.Errorf("unknown mode %q (use threshold, calendar or volatility)", policy.Mode)
+	}
+	if policy.Cadence != "" {
+		if _, err := ParseHorizon(policy.Cadence); err != nil {
+			return fmt.Errorf("cadence: %w", err)
+		}
+	}
+	bands := map[string]models.DriftBand{"band": policy.Band}
+	for token, band := range policy.TokenBands {
+		bands["token_bands."+token] = band
+	}
+	for name, band := range bands {
+		if band.Absolute < 0 || band.Absolute >= 1 || band.Relative < 0 {
+			return fmt.Errorf("%s: limits must be within [0, 1) for absolute and non-negative for relative", name)
+		}
+	}
+	if policy.VolatilityMultiplier < 0 {
+		return fmt.Errorf("volatility_multiplier must not be negative")
+	}
+	if policy.NoTradeZone < 0 || policy.NoTradeZone >= 1 {
+		return fmt.Errorf("no_trade_zone must be within [0, 1)")
+	}
+	return nil
+}
+
+// policySchedule is a validated policy with defaults filled in
+type policySchedule struct {
+	models.RebalancePolicy
+	cadence time.Duration
+}
+
+// newPolicySchedule validates a policy and fills in the defaults of its mode
+func newPolicySchedule(policy *models.RebalancePolicy) (policySchedule, error) {
+	if policy == nil {
+		defaults := DefaultRebalancePolicy()
+		policy = &defaults
+	}
+	if err := ValidateRebalancePolicy(*policy); err != nil {
+		return policySchedule{}, fmt.Errorf("invalid rebalance policy: %w", err)
+	}
+
+	schedule := policySchedule{RebalancePolicy: *policy}
+	if schedule.Mode == "" {
+		schedule.Mode = models.PolicyModeThreshold
+	}
+	if schedule.Cadence == "" {
+		schedule.Cadence = defaultReviewCadence
+		if schedule.Mode == models.PolicyModeCalendar {
+			schedule.Cadence = defaultCalendarCadence
+		}
+	}
+	if schedule.Mode == models.PolicyModeThreshold && schedule.Band == (models.DriftBand{}) {
+		schedule.Band.Absolute = rebalanceBand
+	}
+	if schedule.Mode == models.PolicyModeVolatility && schedule.VolatilityMultiplier == 0 {
+		schedule.VolatilityMultiplier = 1
+	}
+	schedule.cadence, _ = ParseHorizon(schedule.Cadence) // Validated above
+	return schedule, nil
+}
+
+// due reports whether the policy allows rebalancing trades at now
+func (s policySchedule) due(now time.Time) bool {
+	if s.Mode != models.PolicyModeCalendar || s.LastRebalanced.IsZero() {
+		return true
+	}
+	return !now.Before(s.LastRebalanced.Add(s.cadence))
+}
+
+// status reports the policy's mode, whether trades were allowed and when to review next
+func (s policySchedule) status(now time.Time) *models.PolicyStatus {
+	status := &models.PolicyStatus{Mode: s.Mode, Due: s.due(now), NextReview: now.Add(s.cadence)}
+	if !status.Due {
+		status.NextReview = s.LastRebalanced.Add(s.cadence)
+	}
+	return status
+}
+
+// band returns the drift band of a token, with per-token overrides taking precedence
+func (s policySchedule) band(token string) models.DriftBand {
+	for name, band := range s.TokenBands {
+		if strings.EqualFold(name, token) {
+			return band
+		}
+	}
+	return s.Band
+}
+
+// policyTrigger decides whether a token drifting from target to current is rebalanced, and why
+func (e *EnhancedAIEngine) policyTrigger(s policySchedule, token string, current, target float64) (*models.ActionTrigger, bool) {
+	drift := math.Abs(current - target)
+	if drift == 0 {
+		return nil, false
+	}
+
+	switch s.Mode {
+	case models.PolicyModeCalendar:
+		if drift <= s.NoTradeZone {
+			return nil, false
+		}
+		return &models.ActionTrigger{Mode: s.Mode, Rule: ruleCalendar, Drift: drift, Band: s.NoTradeZone}, true
+
+	case models.PolicyModeVolatility:
+		// A token's weight drifts roughly with its volatility over the review interval
+		years := s.cadence.Hours() / (24 * 365)
+		band := s.VolatilityMultiplier * e.getTokenRisk(strings.ToUpper(token)) * math.Sqrt(years)
+		if drift <= band {
+			return nil, false
+		}
+		return &models.ActionTrigger{Mode: s.Mode, Rule: ruleVolatilityBand, Drift: drift, Band: band}, true
+	}
+
+	band := s.band(token)
+	if band.Absolute > 0 && drift > band.Absolute {
+		return &models.ActionTrigger{Mode: s.Mode, Rule: ruleAbsoluteBand, Drift: drift, Band: band.Absolute}, true
+	}
+	if band.Relative > 0 {
+		relative := 1.0 // A token targeted at zero is entirely off target
+		if target > 0 {
+			relative = drift / target
+		}
+		if relative > band.Relative {
+			return &models.ActionTrigger{Mode: s.Mode, Rule: ruleRelativeBand, Drift: relative, Band: band.Relative}, true
+		}
+	}
+	return nil, false
+}
+
+// tradeTarget returns the weight a triggered trade moves to, stopping at the edge of the no-trade zone
+func (s policySchedule) tradeTarget(current, target float64) float64 {
+	if s.NoTradeZone <= 0 || math.Abs(current-target) <= s.NoTradeZone {
+		return target
+	}
+	if current > target {
+		return target + s.NoTradeZone
+	}
+	return target - s.NoTradeZone
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestValidateRebalancePolicy(t *testing.T) {
+	invalid := map[string]models.RebalancePolicy{
+		"unknown mode":          {Mode: "monthly"},
+		"malformed cadence":     {Cadence: "soon"},
+		"absolute band too big": {Band: models.DriftBand{Absolute: 1}},
+		"negative token band":   {TokenBands: map[string]models.DriftBand{"ETH": {Relative: -0.1}}},
+		"negative multiplier":   {Mode: models.PolicyModeVolatility, VolatilityMultiplier: -1},
+		"no-trade zone too big": {NoTradeZone: 1},
+	}
+	for name, policy := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateRebalancePolicy(policy); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if err := ValidateRebalancePolicy(DefaultRebalancePolicy()); err != nil {
+		t.Errorf("Expected the default policy to be valid, got %v", err)
+	}
+}
+
+func TestEnhancedAIEngine_PolicyTrigger(t *testing.T) {
+	engine := NewEnhancedAIEngine()
+	schedule := func(policy models.RebalancePolicy) policySchedule {
+		s, err := newPolicySchedule(&policy)
+		if err != nil {
+			t.Fatalf("Failed to build schedule: %v", err)
+		}
+		return s
+	}
+
+	t.Run("threshold absolute band", func(t *testing.T) {
+		s := schedule(models.RebalancePolicy{Mode: models.PolicyModeThreshold})
+		if _, ok := engine.policyTrigger(s, "ETH", 0.31, 0.3); ok {
+			t.Error("Expected a 1% drift to stay within the default 2% band")
+		}
+		trigger, ok := engine.policyTrigger(s, "ETH", 0.35, 0.3)
+		if !ok || trigger.Rule != ruleAbsoluteBand || math.Abs(trigger.Band-0.02) > 1e-12 {
+			t.Errorf("Expected the absolute band to trigger, got %+v", trigger)
+		}
+	})
+
+	t.Run("threshold relative band and token overrides", func(t *testing.T) {
+		s := schedule(models.RebalancePolicy{
+			Band:       models.DriftBand{Relative: 0.25},
+			TokenBands: map[string]models.DriftBand{"eth": {Absolute: 0.1}},
+		})
+		trigger, ok := engine.policyTrigger(s, "BTC", 0.13, 0.1)
+		if !ok || trigger.Rule != ruleRelativeBand || math.Abs(trigger.Drift-0.3) > 1e-9 {
+			t.Errorf("Expected a 30%% relative drift to trigger, got %+v", trigger)
+		}
+		if _, ok := engine.policyTrigger(s, "ETH", 0.35, 0.3); ok {
+			t.Error("Expected the ETH override to tolerate a 5% drift")
+		}
+		if trigger, ok := engine.policyTrigger(s, "BTC", 0.05, 0); !ok || trigger.Drift != 1 {
+			t.Errorf("Expected a zero target to count as full relative drift, got %+v", trigger)
+		}
+	})
+
+	t.Run("volatility band scales with risk and cadence", func(t *testing.T) {
+		daily := schedule(models.RebalancePolicy{Mode: models.PolicyModeVolatility})
+		// BTC at 30% volatility drifts about 1.6% a day
+		if _, ok := engine.policyTrigger(daily, "BTC", 0.41, 0.4); ok {
+			t.Error("Expected a 1% drift within the daily volatility band")
+		}
+		trigger, ok := engine.policyTrigger(daily, "BTC", 0.43, 0.4)
+		if !ok || trigger.Rule != ruleVolatilityBand || math.Abs(trigger.Band-0.3*math.Sqrt(1.0/365)) > 1e-9 {
+			t.Errorf("Expected the volatility band to trigger, got %+v", trigger)
+		}
+		monthly := schedule(models.RebalancePolicy{Mode: models.PolicyModeVolatility, Cadence: "30d"})
+		if _, ok := engine.policyTrigger(monthly, "BTC", 0.43, 0.4); ok {
+			t.Error("Expected a longer cadence to widen the band")
+		}
+	})
+
+	t.Run("no-trade zone stops trades at its edge", func(t *testing.T) {
+		s := schedule(models.RebalancePolicy{Mode: models.PolicyModeCalendar, NoTradeZone: 0.05})
+		if _, ok := engine.policyTrigger(s, "ETH", 0.33, 0.3); ok {
+			t.Error("Expected drift inside the no-trade zone to be tolerated")
+		}
+		if _, ok := engine.policyTrigger(s, "ETH", 0.4, 0.3); !ok {
+			t.Error("Expected drift beyond the no-trade zone to trigger")
+		}
+		if target := s.tradeTarget(0.4, 0.3); math.Abs(target-0.35) > 1e-12 {
+			t.Errorf("Expected a target of 0.35, got %f", target)
+		}
+		if target := s.tradeTarget(0.1, 0.3); math.Abs(target-0.25) > 1e-12 {
+			t.Errorf("Expected a target of 0.25, got %f", target)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_RecommendWithPolicy(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	engine := NewEnhancedAIEngine()
+	engine.now = func() time.Time { return now }
+
+	portfolio := models.Portfolio{ID: "pf-policy", TotalValue: 100000, Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 0.9, Amount: 2, Value: 90000, Chain: "ethereum"},
+		{Token: "ETH", Weight: 0.1, Amount: 4, Value: 10000, Chain: "ethereum"},
+	}}
+
+	t.Run("calendar policy waits for its next review", func(t *testing.T) {
+		last := now.Add(-10 * 24 * time.Hour)
+		policy := &models.RebalancePolicy{Mode: models.PolicyModeCalendar, LastRebalanced: last}
+		recommendation, err := engine.RecommendWithOptions(context.Background(), portfolio, models.RecommendationOptions{Policy: policy})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(recommendation.Actions) != 0 {
+			t.Errorf("Expected no trades before the review date, got %+v", recommendation.Actions)
+		}
+		status := recommendation.Policy
+		if status == nil || status.Due || !status.NextReview.Equal(last.Add(30*24*time.Hour)) {
+			t.Errorf("Expected the next review 30 days after the last, got %+v", status)
+		}
+	})
+
+	t.Run("calendar policy trades once due", func(t *testing.T) {
+		policy := &models.RebalancePolicy{Mode: models.PolicyModeCalendar, LastRebalanced: now.Add(-31 * 24 * time.Hour)}
+		recommendation, err := engine.RecommendWithOptions(context.Background(), portfolio, models.RecommendationOptions{Policy: policy})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !recommendation.Policy.Due || !recommendation.Policy.NextReview.Equal(now.Add(30*24*time.Hour)) {
+			t.Errorf("Expected a due policy reviewed again in 30 days, got %+v", recommendation.Policy)
+		}
+		if len(recommendation.Actions) == 0 {
+			t.Fatal("Expected trades once the policy is due")
+		}
+		for _, action := range recommendation.Actions {
+			if action.Trigger == nil {
+				t.Errorf("Expected a trigger on %s", action.Token)
+			}
+		}
+	})
+
+	t.Run("default policy is a threshold band", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(context.Background(), portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Policy == nil || recommendation.Policy.Mode != models.PolicyModeThreshold {
+			t.Errorf("Expected the threshold policy, got %+v", recommendation.Policy)
+		}
+	})
+
+	t.Run("invalid policy is rejected", func(t *testing.T) {
+		policy := &models.RebalancePolicy{Mode: "weekly"}
+		if _, err := engine.RecommendWithOptions(context.Background(), portfolio, models.RecommendationOptions{Policy: policy}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// RegimeParameters are the optimizer settings used in a market regime
+type RegimeParameters struct {
+	Strategy     string  `json:"strategy"`      // Allocation strategy for requests that name none
+	RiskAversion float64 `json:"risk_aversion"` // Black-Litterman risk aversion behind implied returns
+}
+
+// RegimeConfig sets how the market regime is classified and the optimizer parameters of each regime
+type RegimeConfig struct {
+	FastWindow      int     `json:"fast_window"`      // Days in the fast moving average and volatility window
+	SlowWindow      int     `json:"slow_window"`      // Days in the slow moving average; fewer daily returns leave the regime unknown
+	HighVolatility  float64 `json:"high_volatility"`  // Annualized fast-window volatility that marks high_vol
+	VolatilityRatio float64 `json:"volatility_ratio"` // Fast-window over lookback volatility that marks high_vol
+
+	// Parameters per regime; regimes without an entry keep the engine's defaults
+	Parameters map[string]RegimeParameters `json:"parameters"`
+}
+
+// DefaultRegimeConfig compares 7 and 21 day averages and moves to risk parity when the market
+// turns down and to inverse volatility when it turns volatile
+func DefaultRegimeConfig() RegimeConfig {
+	return RegimeConfig{
+		FastWindow:      7,
+		SlowWindow:      21,
+		HighVolatility:  0.8,
+		VolatilityRatio: 2,
+		Parameters: map[string]RegimeParameters{
+			models.RegimeRiskOn:  {Strategy: models.StrategyRiskAdjusted, RiskAversion: 2.5},
+			models.RegimeRiskOff: {Strategy: models.StrategyRiskParity, RiskAversion: 4},
+			models.RegimeHighVol: {Strategy: models.StrategyInverseVolatility, RiskAversion: 6},
+		},
+	}
+}
+
+// WithRegimeConfig overrides regime classification and the optimizer parameters of each regime
+func WithRegimeConfig(config RegimeConfig) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.regime = config
+	}
+}
+
+// detectRegime classifies the market from the daily returns of the engine's benchmark. Volatility
+// well above its threshold or its own recent level marks high_vol; otherwise the moving-average
+// structure of the benchmark index decides between risk_on and risk_off.
+func (e *EnhancedAIEngine) detectRegime() *models.MarketRegime {
+	regime := &models.MarketRegime{
+		Regime:    models.RegimeUnknown,
+		Benchmark: e.benchmark.Name,
+		Timestamp: e.now(),
+	}
+	if regime.Benchmark == "" {
+		regime.Benchmark = customBenchmarkName
+	}
+
+	weights, err := e.benchmarkWeights(e.benchmark)
+	if err != nil {
+		regime.Reason = fmt.Sprintf("benchmark unavailable: %v", err)
+		return regime
+	}
+	priced := make(map[string]float64)
+	for token := range weights {
+		if !peggedTokens[token] {
+			priced[token] = 1
+		}
+	}
+	if len(priced) == 0 {
+		regime.Reason = "benchmark holds only pegged tokens"
+		return regime
+	}
+
+	closes, aligned := e.alignedDays(priced)
+	returns, days := dailyReturns(closes, aligned)
+	regime.Observations = days
+	if days < e.regime.SlowWindow || days < e.regime.FastWindow || e.regime.FastWindow < 2 {
+		regime.Reason = fmt.Sprintf("%d daily returns of price history, %d needed", days, e.regime.SlowWindow)
+		return regime
+	}
+	// Returns across a gap are dropped, so averages over it would join non-adjacent days
+	window := aligned[len(aligned)-e.regime.SlowWindow-1:]
+	for i := 1; i < len(window); i++ {
+		if gap := window[i] - window[i-1]; gap != 1 {
+			regime.Reason = fmt.Sprintf("price history misses %d days within the %d-day window", gap-1, e.regime.SlowWindow)
+			return regime
+		}
+	}
+
+	index := make([]float64, days)
+	for token, series := range returns {
+		for t, logReturn := range series {
+			index[t] += weights[token] * (math.Exp(logReturn) - 1)
+		}
+	}
+	levels := indexLevels(index)
+	latest := levels[len(levels)-1]
+	fast, slow := movingAverage(levels, e.regime.FastWindow), movingAverage(levels, e.regime.SlowWindow)
+	regime.FastAverage, regime.SlowAverage = fast/latest, slow/latest
+	regime.Trend = averageTrend(latest, fast, slow)
+	regime.Volatility = realizedVolatility(index[days-e.regime.FastWindow:])
+	regime.LongVolatility = realizedVolatility(index)
+
+	switch {
+	case regime.Volatility >= e.regime.HighVolatility:
+		regime.Regime = models.RegimeHighVol
+		regime.Reason = fmt.Sprintf("%d-day volatility of %.0f%% is above %.0f%%", e.regime.FastWindow, regime.Volatility*100, e.regime.HighVolatility*100)
+	case regime.LongVolatility > 0 && regime.Volatility >= e.regime.VolatilityRatio*regime.LongVolatility:
+		regime.Regime = models.RegimeHighVol
+		regime.Reason = fmt.Sprintf("%d-day volatility is %.1fx its %d-day level", e.regime.FastWindow, regime.Volatility/regime.LongVolatility, days)
+	case regime.Trend == models.TrendUp:
+		regime.Regime = models.RegimeRiskOn
+		regime.Reason = fmt.Sprintf("index above its rising %d- and %d-day averages", e.regime.FastWindow, e.regime.SlowWindow)
+	case regime.Trend == models.TrendDown:
+		regime.Regime = models.RegimeRiskOff
+		regime.Reason = fmt.Sprintf("index below its falling %d- and %d-day averages", e.regime.FastWindow, e.regime.SlowWindow)
+	case latest >= slow:
+		regime.Regime = models.RegimeRiskOn
+		regime.Reason = fmt.Sprintf("mixed averages with the index above its %d-day average", e.regime.SlowWindow)
+	default:
+		regime.Regime = models.RegimeRiskOff
+		regime.Reason = fmt.Sprintf("mixed averages with the index below its %d-day average", e.regime.SlowWindow)
+	}
+	return regime
+}
+
+// regimeParameters returns the optimizer parameters configured for a regime
+func (e *EnhancedAIEngine) regimeParameters(regime *models.MarketRegime) (RegimeParameters, bool) {
+	parameters, ok := e.regime.Parameters[regime.Regime]
+	return parameters, ok
+}
+
+// tokenTrend labels a token bullish or bearish from the moving-average structure of its daily
+// prices, and neutral when the averages are mixed or history is too short
+func (e *EnhancedAIEngine) tokenTrend(token string) string {
+	returns, days := e.alignedDailyReturns(map[string]float64{token: 1})
+	if days < e.regime.SlowWindow || days < e.regime.FastWindow || e.regime.FastWindow < 1 {
+		return "neutral"
+	}
+
+	simple := make([]float64, days)
+	for t, logReturn := range returns[token] {
+		simple[t] = math.Exp(logReturn) - 1
+	}
+	levels := indexLevels(simple)
+	latest := levels[len(levels)-1]
+	switch averageTrend(latest, movingAverage(levels, e.regime.FastWindow), movingAverage(levels, e.regime.SlowWindow)) {
+	case models.TrendUp:
+		return "bullish"
+	case models.TrendDown:
+		return "bearish"
+	}
+	return "neutral"
+}
+
+// indexLevels compounds simple returns into index levels starting at 1
+func indexLevels(returns []float64) []float64 {
+	levels := make([]float64, len(returns)+1)
+	levels[0] = 1
+	for t, r := range returns {
+		levels[t+1] = levels[t] * (1 + r)
+	}
+	return levels
+}
+
+// movingAverage returns the mean of the last window levels
+func movingAverage(levels []float64, window int) float64 {
+	if window > len(levels) {
+		window = len(levels)
+	}
+	sum := 0.0
+	for _, level := range levels[len(levels)-window:] {
+		sum += level
+	}
+	return sum / float64(window)
+}
+
+// averageTrend compares the latest level with its fast and slow moving averages
+func averageTrend(latest, fast, slow float64) string {
+	switch {
+	case latest > fast && fast > slow:
+		return models.TrendUp
+	case latest < fast && fast < slow:
+		return models.TrendDown
+	}
+	return models.TrendMixed
+}
+
+// realizedVolatility annualizes the sample standard deviation of daily returns
+func realizedVolatility(returns []float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r / float64(len(returns))
+	}
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1) * 365)
+}
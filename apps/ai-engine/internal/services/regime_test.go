@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// regimeEngine returns an engine whose BTC and ETH history follows daily returns, oldest first
+func regimeEngine(now time.Time, returns func(day int) float64) *EnhancedAIEngine {
+	const days = 29
+	history := NewTimeSeriesStore(1000)
+	price := 100.0
+	for day := 0; day <= days; day++ {
+		if day > 0 {
+			price *= 1 + returns(day)
+		}
+		at := now.Add(-time.Duration(days-day) * 24 * time.Hour)
+		history.Append(PriceSeriesKey("BTC"), at, 40000*price)
+		history.Append(PriceSeriesKey("ETH"), at, 2500*price)
+	}
+	engine := NewEnhancedAIEngine(WithPriceHistory(history))
+	engine.now = func() time.Time { return now }
+	return engine
+}
+
+// trending returns a daily return of drift with a small alternating wobble
+func trending(drift float64) func(int) float64 {
+	return func(day int) float64 {
+		return drift + 0.002*math.Pow(-1, float64(day))
+	}
+}
+
+func TestEnhancedAIEngine_DetectRegime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		returns func(int) float64
+		regime  string
+		trend   string
+	}{
+		{"calm uptrend", trending(0.005), models.RegimeRiskOn, models.TrendUp},
+		{"calm downtrend", trending(-0.005), models.RegimeRiskOff, models.TrendDown},
+		{"large daily swings", func(day int) float64 { return 0.08 * math.Pow(-1, float64(day)) }, models.RegimeHighVol, ""},
+		{"volatility spike", func(day int) float64 {
+			if day > 22 {
+				return 0.03 * math.Pow(-1, float64(day))
+			}
+			return trending(0.003)(day)
+		}, models.RegimeHighVol, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regime := regimeEngine(now, test.returns).detectRegime()
+			if regime.Regime != test.regime {
+				t.Errorf("Expected regime %s, got %+v", test.regime, regime)
+			}
+			if test.trend != "" && regime.Trend != test.trend {
+				t.Errorf("Expected trend %s, got %s", test.trend, regime.Trend)
+			}
+			if regime.Observations < 21 || regime.Reason == "" || regime.Benchmark != DefaultBenchmark().Name {
+				t.Errorf("Expected a reasoned regime over the default benchmark, got %+v", regime)
+			}
+		})
+	}
+
+	t.Run("gaps inside the slow window", func(t *testing.T) {
+		gapped := func(missing int) *EnhancedAIEngine {
+			history := NewTimeSeriesStore(1000)
+			for day := 0; day <= 29; day++ {
+				if day != missing {
+					at := now.Add(-time.Duration(29-day) * 24 * time.Hour)
+					history.Append(PriceSeriesKey("BTC"), at, 40000*math.Pow(1.005, float64(day)))
+					history.Append(PriceSeriesKey("ETH"), at, 2500*math.Pow(1.005, float64(day)))
+				}
+			}
+			engine := NewEnhancedAIEngine(WithPriceHistory(history))
+			engine.now = func() time.Time { return now }
+			return engine
+		}
+
+		if regime := gapped(20).detectRegime(); regime.Regime != models.RegimeUnknown || regime.Observations < 21 {
+			t.Errorf("Expected an unknown regime despite enough returns, got %+v", regime)
+		}
+		if regime := gapped(3).detectRegime(); regime.Regime != models.RegimeRiskOn {
+			t.Errorf("Expected a gap before the window to leave the regime known, got %+v", regime)
+		}
+	})
+
+	t.Run("unknown without history", func(t *testing.T) {
+		regime := NewEnhancedAIEngine().detectRegime()
+		if regime.Regime != models.RegimeUnknown || regime.Reason == "" {
+			t.Errorf("Expected an unknown regime with a reason, got %+v", regime)
+		}
+	})
+
+	t.Run("token trend", func(t *testing.T) {
+		engine := regimeEngine(now, trending(-0.005))
+		if trend := engine.tokenTrend("ETH"); trend != "bearish" {
+			t.Errorf("Expected ETH bearish, got %s", trend)
+		}
+		if trend := engine.tokenTrend("LINK"); trend != "neutral" {
+			t.Errorf("Expected LINK neutral without history, got %s", trend)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_RegimeParameters(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	portfolio := models.Portfolio{ID: "pf-regime", TotalValue: 100000, Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 0.5, Value: 50000},
+		{Token: "ETH", Weight: 0.5, Value: 50000},
+	}}
+	engine := regimeEngine(now, trending(-0.005))
+
+	t.Run("regime strategy applies by default", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Regime == nil || recommendation.Regime.Regime != models.RegimeRiskOff {
+			t.Fatalf("Expected a risk-off regime, got %+v", recommendation.Regime)
+		}
+		if recommendation.Allocation.Strategy != models.StrategyRiskParity {
+			t.Errorf("Expected the risk-off strategy, got %s", recommendation.Allocation.Strategy)
+		}
+	})
+
+	t.Run("requested strategy wins", func(t *testing.T) {
+		recommendation, err := engine.RecommendWithOptions(ctx, portfolio, models.RecommendationOptions{Strategy: models.StrategyEqualWeight})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Allocation.Strategy != models.StrategyEqualWeight {
+			t.Errorf("Expected the requested strategy, got %s", recommendation.Allocation.Strategy)
+		}
+	})
+
+	t.Run("regimes without parameters keep the defaults", func(t *testing.T) {
+		config := DefaultRegimeConfig()
+		config.Parameters = nil
+		WithRegimeConfig(config)(engine)
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recommendation.Allocation.Strategy != models.StrategyRiskAdjusted {
+			t.Errorf("Expected the default strategy, got %s", recommendation.Allocation.Strategy)
+		}
+	})
+
+	t.Run("market analysis reports the regime", func(t *testing.T) {
+		analysis, err := engine.GetMarketAnalysis(ctx, []string{"ETH"}, "1d")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if analysis.Regime == nil || analysis.Regime.Regime != models.RegimeRiskOff || analysis.TokenAnalysis[0].Trend != "bearish" {
+			t.Errorf("Expected a risk-off regime with ETH bearish, got %+v and %+v", analysis.Regime, analysis.TokenAnalysis[0])
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	jacobiMaxSweeps = 100
+	jacobiTolerance = 1e-22 // Sum of squared off-diagonal entries treated as zero
+)
+
+// RiskAttribution breaks the risk of a portfolio down by token
+func (e *EnhancedAIEngine) RiskAttribution(ctx context.Context, portfolio models.Portfolio) (*models.RiskAttribution, error) {
+	if len(portfolio.Positions) == 0 {
+		return nil, fmt.Errorf("failed to attribute risk: portfolio %s has no positions", portfolio.ID)
+	}
+	return e.riskAttribution(portfolio), nil
+}
+
+// riskAttribution splits portfolio volatility into Euler components w_i (Σw)_i / σ, which sum
+// to σ, and attributes the parametric daily VaR in the same proportions
+func (e *EnhancedAIEngine) riskAttribution(portfolio models.Portfolio) *models.RiskAttribution {
+	weights, _ := positionWeights(portfolio)
+	tokens := sortedTokens(weights)
+	covariance, source := e.covariance(tokens, weights)
+
+	w := make([]float64, len(tokens))
+	for i, token := range tokens {
+		w[i] = weights[token]
+	}
+	marginal := make([]float64, len(tokens))
+	variance := 0.0
+	for i := range tokens {
+		for j := range tokens {
+			marginal[i] += covariance[i][j] * w[j]
+		}
+		variance += w[i] * marginal[i]
+	}
+	volatility := math.Sqrt(math.Max(variance, 0))
+
+	// Parametric 95% VaR over one day, as in calculateVaR
+	varScale := -1.645 * math.Sqrt(1.0/365.0)
+	attribution := &models.RiskAttribution{
+		PortfolioID: portfolio.ID,
+		Covariance:  source,
+		Volatility:  volatility,
+		VaR95:       varScale * volatility,
+		Timestamp:   time.Now(),
+	}
+
+	weightedVolatility := 0.0
+	for i, token := range tokens {
+		tokenVolatility := math.Sqrt(math.Max(covariance[i][i], 0))
+		weightedVolatility += w[i] * tokenVolatility
+
+		position := models.PositionRisk{Token: token, Weight: w[i], Volatility: tokenVolatility}
+		if volatility > 0 {
+			position.MarginalRisk = marginal[i] / volatility
+			position.ComponentRisk = w[i] * position.MarginalRisk
+			position.RiskShare = position.ComponentRisk / volatility
+			position.ComponentVaR95 = varScale * position.ComponentRisk
+			position.VaRContribution = position.RiskShare // Parametric VaR scales with volatility
+		}
+		attribution.Positions = append(attribution.Positions, position)
+	}
+
+	if volatility > 0 {
+		attribution.DiversificationRatio = weightedVolatility / volatility
+		attribution.EffectiveBets = effectiveBets(covariance, w, variance)
+	}
+	return attribution
+}
+
+// effectiveBets returns the exponential of the entropy of the variance shares of the portfolio's
+// principal components (Meucci, 2009): 1 when all risk comes from one source and n when it is
+// spread evenly over n uncorrelated sources
+func effectiveBets(covariance [][]float64, weights []float64, variance float64) float64 {
+	values, vectors := symmetricEigen(covariance)
+	entropy := 0.0
+	for k, value := range values {
+		exposure := 0.0
+		for i, w := range weights {
+			exposure += vectors[i][k] * w
+		}
+		if share := exposure * exposure * math.Max(value, 0) / variance; share > 0 {
+			entropy -= share * math.Log(share)
+		}
+	}
+	return math.Exp(entropy)
+}
+
+// symmetricEigen returns the eigenvalues of a symmetric matrix and the eigenvectors as columns,
+// using cyclic Jacobi rotations
+func symmetricEigen(matrix [][]float64) ([]float64, [][]float64) {
+	n := len(matrix)
+	a := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range matrix {
+		a[i] = append([]float64{}, matrix[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		off := 0.0
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < jacobiTolerance {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// Rotate by the angle that zeroes a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					kp, kq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*kp-s*kq, s*kp+c*kq
+				}
+				for k := 0; k < n; k++ {
+					pk, qk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*pk-s*qk, s*pk+c*qk
+				}
+				for k := 0; k < n; k++ {
+					kp, kq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*kp-s*kq, s*kp+c*kq
+				}
+			}
+		}
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return values, v
+}
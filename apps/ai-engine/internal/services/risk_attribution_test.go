@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestEnhancedAIEngine_RiskAttribution(t *testing.T) {
+	ctx := context.Background()
+	engine := NewEnhancedAIEngine()
+	portfolio := models.Portfolio{ID: "pf-risk", Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 0.5, Value: 50000},
+		{Token: "ETH", Weight: 0.3, Value: 30000},
+		{Token: "USDC", Weight: 0.2, Value: 20000},
+	}}
+
+	attribution, err := engine.RiskAttribution(ctx, portfolio)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(attribution.Positions) != 3 || attribution.Covariance != covarianceAssumed {
+		t.Fatalf("Expected 3 positions on assumed covariance, got %+v", attribution)
+	}
+
+	t.Run("components sum to the portfolio totals", func(t *testing.T) {
+		risk, share, varTotal := 0.0, 0.0, 0.0
+		for _, position := range attribution.Positions {
+			risk += position.ComponentRisk
+			share += position.RiskShare
+			varTotal += position.ComponentVaR95
+			if math.Abs(position.ComponentRisk-position.Weight*position.MarginalRisk) > 1e-12 {
+				t.Errorf("Expected component risk to be weight times marginal risk for %s", position.Token)
+			}
+		}
+		if math.Abs(risk-attribution.Volatility) > 1e-12 || math.Abs(share-1) > 1e-12 || math.Abs(varTotal-attribution.VaR95) > 1e-12 {
+			t.Errorf("Expected components to sum to %f, 1 and %f, got %f, %f and %f", attribution.Volatility, attribution.VaR95, risk, share, varTotal)
+		}
+		if attribution.VaR95 >= 0 {
+			t.Errorf("Expected VaR as a negative loss, got %f", attribution.VaR95)
+		}
+	})
+
+	t.Run("the most volatile large holding drives risk", func(t *testing.T) {
+		shares := make(map[string]float64)
+		for _, position := range attribution.Positions {
+			shares[position.Token] = position.RiskShare
+		}
+		if shares["BTC"] <= shares["ETH"] || shares["ETH"] <= shares["USDC"] || shares["BTC"] <= 0.5 {
+			t.Errorf("Expected BTC to dominate risk beyond its weight, got %v", shares)
+		}
+	})
+
+	t.Run("diversification measures", func(t *testing.T) {
+		if attribution.DiversificationRatio <= 1 {
+			t.Errorf("Expected a diversification ratio above 1, got %f", attribution.DiversificationRatio)
+		}
+		if attribution.EffectiveBets < 1 || attribution.EffectiveBets > 3 {
+			t.Errorf("Expected between 1 and 3 effective bets, got %f", attribution.EffectiveBets)
+		}
+
+		single, _ := engine.RiskAttribution(ctx, models.Portfolio{Positions: []models.PortfolioPosition{{Token: "ETH", Weight: 1}}})
+		if math.Abs(single.DiversificationRatio-1) > 1e-12 || math.Abs(single.EffectiveBets-1) > 1e-9 {
+			t.Errorf("Expected a single holding to be one undiversified bet, got %+v", single)
+		}
+	})
+
+	t.Run("empty portfolio", func(t *testing.T) {
+		if _, err := engine.RiskAttribution(ctx, models.Portfolio{ID: "empty"}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("risk metrics include the attribution", func(t *testing.T) {
+		metrics, err := engine.CalculateRiskMetrics(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if metrics.Attribution == nil || len(metrics.Attribution.Positions) != 3 {
+			t.Fatalf("Expected an attribution, got %+v", metrics.Attribution)
+		}
+		if metrics.Volatility != metrics.Attribution.Volatility || math.Abs(metrics.VaR95-metrics.Attribution.VaR95) > 1e-12 {
+			t.Errorf("Expected volatility and VaR from the attribution's covariance, got %f/%f vs %f/%f",
+				metrics.Volatility, metrics.VaR95, metrics.Attribution.Volatility, metrics.Attribution.VaR95)
+		}
+	})
+}
+
+func TestSymmetricEigen(t *testing.T) {
+	matrix := [][]float64{
+		{4, 1, 0.5},
+		{1, 3, 0.2},
+		{0.5, 0.2, 1},
+	}
+	values, vectors := symmetricEigen(matrix)
+	for k, value := range values {
+		for i := range matrix {
+			product := 0.0
+			for j := range matrix {
+				product += matrix[i][j] * vectors[j][k]
+			}
+			if math.Abs(product-value*vectors[i][k]) > 1e-9 {
+				t.Errorf("Expected eigenpair %d to satisfy Av = λv, got %f vs %f", k, product, value*vectors[i][k])
+			}
+		}
+	}
+	if sum := values[0] + values[1] + values[2]; math.Abs(sum-8) > 1e-9 {
+		t.Errorf("Expected eigenvalues summing to the trace 8, got %f", sum)
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+const (
+	defaultFearGreedBaseURL  = "https://api.alternative.me"
+	fearGreedRefreshInterval = time.Hour      // alternative.me publishes one value a day
+	fearGreedMaxAge          = 48 * time.Hour // Older published values give way to the composite
+	sentimentLookback        = 30 * 24 * time.Hour
+	sentimentHistoryDays     = 30
+	sentimentFastDays        = 7 // Days in the short volatility window
+)
+
+// Composite index weights, following alternative.me's emphasis on volatility and momentum
+const (
+	volatilityWeight = 0.3
+	momentumWeight   = 0.3
+	volumeWeight     = 0.2
+	dominanceWeight  = 0.2
+)
+
+// ErrInsufficientSentimentData is returned when there is neither a recent published index nor
+// enough collected market data for the composite
+var ErrInsufficientSentimentData = errors.New("insufficient data for market sentiment")
+
+// FearGreedResponse represents alternative.me's /fng/ endpoint
+type FearGreedResponse struct {
+	Data []FearGreedValue `json:"data"`
+}
+
+// FearGreedValue is one daily value of the Crypto Fear & Greed index; numbers arrive as strings
+type FearGreedValue struct {
+	Value          string `json:"value"`
+	Classification string `json:"value_classification"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// SentimentAnalyzer reports the market Fear & Greed index. It ingests alternative.me's published
+// index into a time-series store and computes a local composite from the BTC dominance series in
+// the same store and the BTC price and volume series of the price history.
+type SentimentAnalyzer struct {
+	history  *TimeSeriesStore
+	prices   *TimeSeriesStore // BTC price and volume history; the history store unless shared
+	provider *ProviderClient
+	baseURL  string
+
+	mu          sync.Mutex
+	lastRefresh time.Time
+
+	now func() time.Time // Overridable for tests
+}
+
+// NewSentimentAnalyzer creates an analyzer over history; a nil provider disables the published index
+func NewSentimentAnalyzer(history *TimeSeriesStore, provider *ProviderClient) *SentimentAnalyzer {
+	return &SentimentAnalyzer{
+		history:  history,
+		prices:   history,
+		provider: provider,
+		baseURL:  defaultFearGreedBaseURL,
+		now:      time.Now,
+	}
+}
+
+// WithSentiment lets market analysis report the Fear & Greed index and its breakdown
+func WithSentiment(sentiment SentimentProvider) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.sentiment = sentiment
+	}
+}
+
+// Refresh ingests the last month of the published index, at most once per refresh interval after
+// a successful fetch
+func (s *SentimentAnalyzer) Refresh(ctx context.Context) error {
+	if s.provider == nil {
+		return nil
+	}
+	s.mu.Lock()
+	now := s.now()
+	fresh := !s.lastRefresh.IsZero() && now.Sub(s.lastRefresh) < fearGreedRefreshInterval
+	s.mu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	var response FearGreedResponse
+	url := fmt.Sprintf("%s/fng/?limit=%d", s.baseURL, sentimentHistoryDays)
+	if err := s.provider.GetJSON(ctx, url, &response); err != nil {
+		return fmt.Errorf("failed to fetch fear & greed index: %w", err)
+	}
+
+	points := make([]models.TimeSeriesPoint, 0, len(response.Data))
+	for _, entry := range response.Data {
+		value, err := strconv.ParseFloat(entry.Value, 64)
+		if err != nil || value < 0 || value > 100 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, models.TimeSeriesPoint{Timestamp: time.Unix(timestamp, 0).UTC(), Value: value})
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("fear & greed response has no valid values")
+	}
+	s.history.AppendPoints(SeriesFearGreed, points)
+
+	s.mu.Lock()
+	s.lastRefresh = now
+	s.mu.Unlock()
+	return nil
+}
+
+// MarketSentiment reports the published index while a recent value has been ingested and the
+// composite otherwise. The composite breakdown is included whenever market data allows.
+func (s *SentimentAnalyzer) MarketSentiment() (*models.MarketSentiment, error) {
+	now := s.now()
+	components := s.components(now)
+	sentiment := &models.MarketSentiment{Components: components}
+
+	if latest, ok := s.history.Latest(SeriesFearGreed); ok && now.Sub(latest.Timestamp) <= fearGreedMaxAge {
+		sentiment.FearGreedIndex = latest.Value
+		sentiment.Source = models.SentimentSourceAlternativeMe
+		sentiment.History = s.history.Range(SeriesFearGreed, now.Add(-sentimentLookback), now)
+	} else if index, ok := compositeIndex(components); ok {
+		sentiment.FearGreedIndex = index
+		sentiment.Source = models.SentimentSourceComposite
+		sentiment.History = s.compositeHistory(now)
+	} else {
+		return nil, fmt.Errorf("%w: no published index since %s and under %d days of BTC prices",
+			ErrInsufficientSentimentData, now.Add(-fearGreedMaxAge).Format(time.RFC3339), sentimentFastDays+1)
+	}
+
+	setSentimentDistribution(sentiment)
+	return sentiment, nil
+}
+
+// components scores the market data up to at. Rising volatility and BTC dominance signal fear;
+// prices above their average and heavy volume in a rising market signal greed.
+func (s *SentimentAnalyzer) components(at time.Time) []models.SentimentComponent {
+	var components []models.SentimentComponent
+	days := int(sentimentLookback / (24 * time.Hour))
+
+	prices := dailyCloses(s.prices, PriceSeriesKey("BTC"), at)
+	deviation := 0.0
+	if len(prices) > sentimentFastDays {
+		returns := make([]float64, len(prices)-1)
+		for t := range returns {
+			returns[t] = prices[t+1]/prices[t] - 1
+		}
+		fast, long := realizedVolatility(returns[len(returns)-sentimentFastDays:]), realizedVolatility(returns)
+		if long > 0 {
+			ratio := fast / long
+			components = append(components, models.SentimentComponent{
+				Name:   "volatility",
+				Score:  sentimentScore(50 * (2 - ratio)),
+				Weight: volatilityWeight,
+				Value:  fast,
+				Detail: fmt.Sprintf("BTC %d-day volatility of %.0f%% is %.2fx its %d-day level", sentimentFastDays, fast*100, ratio, days),
+			})
+		}
+
+		deviation = prices[len(prices)-1]/mean(prices) - 1
+		components = append(components, models.SentimentComponent{
+			Name:   "momentum",
+			Score:  sentimentScore(50 + 500*deviation), // 10% from the average is an extreme
+			Weight: momentumWeight,
+			Value:  deviation,
+			Detail: fmt.Sprintf("BTC is %.1f%% %s its %d-day average", math.Abs(deviation)*100, aboveOrBelow(deviation), days),
+		})
+	}
+
+	volumes := dailyCloses(s.prices, VolumeSeriesKey("BTC"), at)
+	if len(prices) > sentimentFastDays && len(volumes) > sentimentFastDays {
+		if average := mean(volumes[:len(volumes)-1]); average > 0 {
+			ratio := volumes[len(volumes)-1] / average
+			direction, market := 1.0, "rising"
+			if deviation < 0 {
+				direction, market = -1.0, "falling"
+			}
+			components = append(components, models.SentimentComponent{
+				Name:   "volume",
+				Score:  sentimentScore(50 + 50*direction*math.Max(-1, math.Min(1, ratio-1))),
+				Weight: volumeWeight,
+				Value:  ratio,
+				Detail: fmt.Sprintf("BTC 24h volume is %.2fx its %d-day average in a %s market", ratio, days, market),
+			})
+		}
+	}
+
+	dominance := dailyCloses(s.history, SeriesBTCDominance, at)
+	if len(dominance) > 1 {
+		change := dominance[len(dominance)-1] - dominance[0]
+		components = append(components, models.SentimentComponent{
+			Name:   "btc_dominance",
+			Score:  sentimentScore(50 - 10*change), // A 5 point move is an extreme
+			Weight: dominanceWeight,
+			Value:  dominance[len(dominance)-1],
+			Detail: fmt.Sprintf("BTC dominance moved %+.1f points over %d days", change, len(dominance)-1),
+		})
+	}
+
+	total := 0.0
+	for _, component := range components {
+		total += component.Weight
+	}
+	for i := range components {
+		components[i].Weight /= total
+	}
+	return components
+}
+
+// compositeHistory recomputes the composite index at the end of each recent day
+func (s *SentimentAnalyzer) compositeHistory(now time.Time) []models.TimeSeriesPoint {
+	var history []models.TimeSeriesPoint
+	for day := sentimentHistoryDays - 1; day >= 0; day-- {
+		at := now.Add(-time.Duration(day) * 24 * time.Hour)
+		if index, ok := compositeIndex(s.components(at)); ok {
+			history = append(history, models.TimeSeriesPoint{Timestamp: at, Value: index})
+		}
+	}
+	return history
+}
+
+// analyzeMarketSentiment reports the engine's sentiment provider, or a neutral placeholder
+// when none is configured or it has too little data
+func (e *EnhancedAIEngine) analyzeMarketSentiment() models.MarketSentiment {
+	if e.sentiment != nil {
+		sentiment, err := e.sentiment.MarketSentiment()
+		if err == nil {
+			return *sentiment
+		}
+		e.logger.Error("market sentiment unavailable, reporting neutral", "error", err)
+	}
+
+	sentiment := models.MarketSentiment{FearGreedIndex: 50, Source: models.SentimentSourceDefault}
+	setSentimentDistribution(&sentiment)
+	return sentiment
+}
+
+// compositeIndex is the weighted score of the components
+func compositeIndex(components []models.SentimentComponent) (float64, bool) {
+	if len(components) == 0 {
+		return 0, false
+	}
+	index := 0.0
+	for _, component := range components {
+		index += component.Weight * component.Score
+	}
+	return index, true
+}
+
+// setSentimentDistribution classifies the index and splits sentiment into bullish, bearish and
+// neutral shares summing to 100; the neutral share shrinks toward either extreme
+func setSentimentDistribution(sentiment *models.MarketSentiment) {
+	index := sentimentScore(sentiment.FearGreedIndex)
+	sentiment.Classification = sentimentClassification(index)
+	sentiment.NeutralSentiment = 40 - 0.8*math.Abs(index-50)
+	sentiment.BullishSentiment = (100 - sentiment.NeutralSentiment) * index / 100
+	sentiment.BearishSentiment = 100 - sentiment.NeutralSentiment - sentiment.BullishSentiment
+}
+
+// sentimentClassification labels an index using alternative.me's bands
+func sentimentClassification(index float64) string {
+	switch rounded := math.Round(index); {
+	case rounded < 25:
+		return models.SentimentExtremeFear
+	case rounded < 47:
+		return models.SentimentFear
+	case rounded < 55:
+		return models.SentimentNeutral
+	case rounded < 76:
+		return models.SentimentGreed
+	}
+	return models.SentimentExtremeGreed
+}
+
+// sentimentScore clamps a score to the 0-100 index range
+func sentimentScore(score float64) float64 {
+	return math.Max(0, math.Min(100, score))
+}
+
+// dailyCloses returns the last positive value of each day in the lookback before at, oldest first
+func dailyCloses(history *TimeSeriesStore, key string, at time.Time) []float64 {
+	var closes []float64
+	lastDay := int64(math.MinInt64)
+	for _, point := range history.Range(key, at.Add(-sentimentLookback), at) {
+		if point.Value <= 0 {
+			continue
+		}
+		if day := point.Timestamp.Unix() / 86400; day != lastDay {
+			closes = append(closes, point.Value)
+			lastDay = day
+			continue
+		}
+		closes[len(closes)-1] = point.Value
+	}
+	return closes
+}
+
+// aboveOrBelow describes the sign of a deviation
+func aboveOrBelow(deviation float64) string {
+	if deviation < 0 {
+		return "below"
+	}
+	return "above"
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// sentimentHistory returns 30 days of daily BTC prices, volumes and dominance, oldest first
+func sentimentHistory(now time.Time, price, volume, dominance func(day int) float64) *TimeSeriesStore {
+	const days = 30
+	history := NewTimeSeriesStore(1000)
+	for day := 0; day < days; day++ {
+		at := now.Add(-time.Duration(days-1-day) * 24 * time.Hour)
+		history.Append(PriceSeriesKey("BTC"), at, price(day))
+		history.Append(VolumeSeriesKey("BTC"), at, volume(day))
+		history.Append(SeriesBTCDominance, at, dominance(day))
+	}
+	return history
+}
+
+// newTestSentimentAnalyzer returns an analyzer over history whose clock is fixed at now
+func newTestSentimentAnalyzer(now time.Time, history *TimeSeriesStore, provider *ProviderClient) *SentimentAnalyzer {
+	analyzer := NewSentimentAnalyzer(history, provider)
+	analyzer.now = func() time.Time { return now }
+	return analyzer
+}
+
+// compounding returns a price path growing by drift a day with a small alternating wobble
+func compounding(drift float64) func(int) float64 {
+	return func(day int) float64 {
+		return 40000 * math.Pow(1+drift, float64(day)) * (1 + 0.002*math.Pow(-1, float64(day)))
+	}
+}
+
+// linear returns a series moving by slope a day from start
+func linear(start, slope float64) func(int) float64 {
+	return func(day int) float64 {
+		return start + slope*float64(day)
+	}
+}
+
+func TestSentimentAnalyzer_Composite(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("rising market with falling dominance", func(t *testing.T) {
+		history := sentimentHistory(now, compounding(0.01), linear(20e9, 1e9), linear(55, -0.1))
+		sentiment, err := newTestSentimentAnalyzer(now, history, nil).MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if sentiment.Source != models.SentimentSourceComposite || sentiment.FearGreedIndex <= 55 {
+			t.Errorf("Expected a composite greed reading, got %+v", sentiment)
+		}
+		if sentiment.Classification != sentimentClassification(sentiment.FearGreedIndex) {
+			t.Errorf("Expected classification of %f, got %s", sentiment.FearGreedIndex, sentiment.Classification)
+		}
+
+		names, weights := map[string]bool{}, 0.0
+		for _, component := range sentiment.Components {
+			names[component.Name] = true
+			weights += component.Weight
+			if component.Score < 0 || component.Score > 100 || component.Detail == "" {
+				t.Errorf("Expected a described score between 0 and 100, got %+v", component)
+			}
+		}
+		if len(names) != 4 || !names["volatility"] || !names["momentum"] || !names["volume"] || !names["btc_dominance"] {
+			t.Errorf("Expected four components, got %+v", sentiment.Components)
+		}
+		if math.Abs(weights-1) > 1e-9 {
+			t.Errorf("Expected weights summing to 1, got %f", weights)
+		}
+
+		if len(sentiment.History) < 2 {
+			t.Fatalf("Expected composite history, got %v", sentiment.History)
+		}
+		if latest := sentiment.History[len(sentiment.History)-1]; !latest.Timestamp.Equal(now) || math.Abs(latest.Value-sentiment.FearGreedIndex) > 1e-9 {
+			t.Errorf("Expected history to end at the current index, got %+v", latest)
+		}
+	})
+
+	t.Run("falling market with rising dominance", func(t *testing.T) {
+		history := sentimentHistory(now, compounding(-0.01), linear(20e9, 1e9), linear(50, 0.2))
+		sentiment, err := newTestSentimentAnalyzer(now, history, nil).MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if sentiment.FearGreedIndex >= 45 || sentiment.BearishSentiment <= sentiment.BullishSentiment {
+			t.Errorf("Expected a bearish fear reading, got %+v", sentiment)
+		}
+	})
+
+	t.Run("partial data reweights components", func(t *testing.T) {
+		history := NewTimeSeriesStore(1000)
+		history.Append(SeriesBTCDominance, now.Add(-48*time.Hour), 50)
+		history.Append(SeriesBTCDominance, now, 52)
+		sentiment, err := newTestSentimentAnalyzer(now, history, nil).MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(sentiment.Components) != 1 || sentiment.Components[0].Weight != 1 || math.Abs(sentiment.FearGreedIndex-30) > 1e-9 {
+			t.Errorf("Expected dominance alone to score 30, got %+v", sentiment)
+		}
+	})
+
+	t.Run("prices from the shared price history", func(t *testing.T) {
+		collector := NewRealDataCollectorWithClient(nil)
+		collector.UsePriceHistory(sentimentHistory(now, compounding(0.01), linear(20e9, 1e9), linear(55, -0.1)))
+		collector.sentiment.now = func() time.Time { return now }
+		sentiment, err := collector.Sentiment().MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		// Dominance is only read from the collector's own store, which has none
+		if len(sentiment.Components) != 3 || sentiment.FearGreedIndex <= 55 {
+			t.Errorf("Expected a greed reading from BTC prices and volumes, got %+v", sentiment)
+		}
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		_, err := newTestSentimentAnalyzer(now, NewTimeSeriesStore(0), nil).MarketSentiment()
+		if !errors.Is(err, ErrInsufficientSentimentData) {
+			t.Errorf("Expected ErrInsufficientSentimentData, got %v", err)
+		}
+	})
+}
+
+func TestSentimentAnalyzer_PublishedIndex(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/fng/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[
+			{"value":"72","value_classification":"Greed","timestamp":"%d"},
+			{"value":"65","value_classification":"Greed","timestamp":"%d"},
+			{"value":"n/a","value_classification":"","timestamp":"%d"}
+		]}`, now.Add(-time.Hour).Unix(), now.Add(-25*time.Hour).Unix(), now.Add(-49*time.Hour).Unix())
+	}))
+	defer srv.Close()
+
+	config := DefaultProviderClientConfig()
+	config.BaseBackoff = time.Millisecond
+	config.MaxBackoff = time.Millisecond
+	history := sentimentHistory(now, compounding(-0.01), linear(20e9, 1e9), linear(50, 0.2))
+	analyzer := newTestSentimentAnalyzer(now, history, NewProviderClient(config))
+	analyzer.baseURL = srv.URL
+
+	t.Run("published value wins", func(t *testing.T) {
+		if err := analyzer.Refresh(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		sentiment, err := analyzer.MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if sentiment.Source != models.SentimentSourceAlternativeMe || sentiment.FearGreedIndex != 72 || sentiment.Classification != models.SentimentGreed {
+			t.Errorf("Expected the published greed reading of 72, got %+v", sentiment)
+		}
+		if len(sentiment.History) != 2 || sentiment.History[0].Value != 65 {
+			t.Errorf("Expected two published values, got %v", sentiment.History)
+		}
+		if len(sentiment.Components) != 4 {
+			t.Errorf("Expected the composite breakdown alongside, got %+v", sentiment.Components)
+		}
+	})
+
+	t.Run("refreshes once per interval", func(t *testing.T) {
+		if err := analyzer.Refresh(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected 1 request, got %d", requests)
+		}
+	})
+
+	t.Run("stale published value falls back to the composite", func(t *testing.T) {
+		analyzer.now = func() time.Time { return now.Add(72 * time.Hour) }
+		sentiment, err := analyzer.MarketSentiment()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if sentiment.Source != models.SentimentSourceComposite {
+			t.Errorf("Expected the composite, got %s", sentiment.Source)
+		}
+	})
+
+	t.Run("provider errors", func(t *testing.T) {
+		failing := newTestSentimentAnalyzer(now, NewTimeSeriesStore(0), NewProviderClient(config))
+		failing.baseURL = srv.URL + "/missing"
+		if err := failing.Refresh(context.Background()); err == nil {
+			t.Error("Expected an error")
+		}
+
+		// A failed fetch does not hold off the next attempt
+		failing.baseURL = srv.URL
+		if err := failing.Refresh(context.Background()); err != nil {
+			t.Errorf("Expected a retry to succeed, got %v", err)
+		}
+		if _, ok := failing.history.Latest(SeriesFearGreed); !ok {
+			t.Error("Expected the retried index to be ingested")
+		}
+	})
+}
+
+func TestSentimentClassification(t *testing.T) {
+	tests := map[float64]string{
+		0:    models.SentimentExtremeFear,
+		24.4: models.SentimentExtremeFear,
+		25:   models.SentimentFear,
+		46:   models.SentimentFear,
+		50:   models.SentimentNeutral,
+		55:   models.SentimentGreed,
+		75:   models.SentimentGreed,
+		76:   models.SentimentExtremeGreed,
+		100:  models.SentimentExtremeGreed,
+	}
+	for index, want := range tests {
+		if got := sentimentClassification(index); got != want {
+			t.Errorf("Expected %s for %.1f, got %s", want, index, got)
+		}
+	}
+}
+
+func TestEnhancedAIEngine_MarketSentiment(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("sentiment provider", func(t *testing.T) {
+		history := sentimentHistory(now, compounding(0.01), linear(20e9, 1e9), linear(55, -0.1))
+		engine := NewEnhancedAIEngine(WithSentiment(newTestSentimentAnalyzer(now, history, nil)))
+		analysis, err := engine.GetMarketAnalysis(ctx, []string{"BTC"}, "1d")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if analysis.Sentiment.Source != models.SentimentSourceComposite || len(analysis.Sentiment.Components) == 0 {
+			t.Errorf("Expected the composite sentiment, got %+v", analysis.Sentiment)
+		}
+	})
+
+	t.Run("neutral without data", func(t *testing.T) {
+		engines := map[string]*EnhancedAIEngine{
+			"no provider": NewEnhancedAIEngine(),
+			"no data":     NewEnhancedAIEngine(WithSentiment(newTestSentimentAnalyzer(now, NewTimeSeriesStore(0), nil))),
+		}
+		for name, engine := range engines {
+			sentiment := engine.analyzeMarketSentiment()
+			if sentiment.FearGreedIndex != 50 || sentiment.Source != models.SentimentSourceDefault || sentiment.Classification != models.SentimentNeutral {
+				t.Errorf("Expected a neutral default with %s, got %+v", name, sentiment)
+			}
+			if total := sentiment.BullishSentiment + sentiment.BearishSentiment + sentiment.NeutralSentiment; math.Abs(total-100) > 1e-9 {
+				t.Errorf("Expected shares summing to 100 with %s, got %f", name, total)
+			}
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// Series key prefixes used by the collectors
+const (
+	SeriesTotalTVL          = "tvl:total"
+	SeriesTotalTVLChange    = "tvl_change_1d:total"
+	seriesChainTVLPrefix    = "tvl:chain:"
+	seriesProtocolTVLPrefix = "tvl:protocol:"
+	seriesProtocolDayPrefix = "tvl_daily:protocol:"
+	seriesProtocolChgPrefix = "tvl_change_1d:protocol:"
+	seriesPricePrefix       = "price:"
+	seriesVolumePrefix      = "volume_24h:"
+	SeriesBTCDominance      = "dominance:btc"
+	SeriesFearGreed         = "fear_greed"
+)
+
+// ChainTVLSeriesKey returns the series key for a chain's TVL
+func ChainTVLSeriesKey(chain string) string {
+	return seriesChainTVLPrefix + strings.ToLower(chain)
+}
+
+// ProtocolTVLSeriesKey returns the series key for a protocol's TVL
+func ProtocolTVLSeriesKey(slug string) string {
+	return seriesProtocolTVLPrefix + strings.ToLower(slug)
+}
+
+// ProtocolDailyTVLSeriesKey returns the series key for a protocol's daily TVL history
+func ProtocolDailyTVLSeriesKey(slug string) string {
+	return seriesProtocolDayPrefix + strings.ToLower(slug)
+}
+
+// ProtocolTVLChangeSeriesKey returns the series key for a protocol's 1d TVL change
+func ProtocolTVLChangeSeriesKey(slug string) string {
+	return seriesProtocolChgPrefix + strings.ToLower(slug)
+}
+
+// PriceSeriesKey returns the series key for a token's USD price
+func PriceSeriesKey(token string) string {
+	return seriesPricePrefix + strings.ToUpper(token)
+}
+
+// VolumeSeriesKey returns the series key for a token's 24h USD trading volume
+func VolumeSeriesKey(token string) string {
+	return seriesVolumePrefix + strings.ToUpper(token)
+}
+
+// TimeSeriesStore is an in-memory, bounded store of timestamped values keyed by series name
+type TimeSeriesStore struct {
+	mu        sync.RWMutex
+	series    map[string][]models.TimeSeriesPoint
+	maxPoints int
+}
+
+// NewTimeSeriesStore creates a store keeping at most maxPoints per series
+func NewTimeSeriesStore(maxPoints int) *TimeSeriesStore {
+	if maxPoints <= 0 {
+		maxPoints = 10000
+	}
+	return &TimeSeriesStore{
+		series:    make(map[string][]models.TimeSeriesPoint),
+		maxPoints: maxPoints,
+	}
+}
+
+// Append adds a point to a series, replacing any point with the same timestamp
+func (s *TimeSeriesStore) Append(key string, timestamp time.Time, value float64) {
+	s.AppendPoints(key, []models.TimeSeriesPoint{{Timestamp: timestamp, Value: value}})
+}
+
+// AppendPoints adds several points to a series, keeping it sorted and bounded
+func (s *TimeSeriesStore) AppendPoints(key string, points []models.TimeSeriesPoint) {
+	if len(points) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing := s.series[key]
+	for _, point := range points {
+		idx := sort.Search(len(existing), func(i int) bool {
+			return !existing[i].Timestamp.Before(point.Timestamp)
+		})
+
+		switch {
+		case idx < len(existing) && existing[idx].Timestamp.Equal(point.Timestamp):
+			existing[idx] = point
+		case idx == len(existing):
+			existing = append(existing, point)
+		default:
+			existing = append(existing, models.TimeSeriesPoint{})
+			copy(existing[idx+1:], existing[idx:])
+			existing[idx] = point
+		}
+	}
+
+	if len(existing) > s.maxPoints {
+		existing = append([]models.TimeSeriesPoint(nil), existing[len(existing)-s.maxPoints:]...)
+	}
+	s.series[key] = existing
+}
+
+// Range returns the points of a series within [from, to]; zero bounds are open
+func (s *TimeSeriesStore) Range(key string, from, to time.Time) []models.TimeSeriesPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := s.series[key]
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(points), func(i int) bool {
+			return !points[i].Timestamp.Before(from)
+		})
+	}
+	end := len(points)
+	if !to.IsZero() {
+		end = sort.Search(len(points), func(i int) bool {
+			return points[i].Timestamp.After(to)
+		})
+	}
+	if start >= end {
+		return nil
+	}
+
+	result := make([]models.TimeSeriesPoint, end-start)
+	copy(result, points[start:end])
+	return result
+}
+
+// Latest returns the most recent point of a series
+func (s *TimeSeriesStore) Latest(key string) (models.TimeSeriesPoint, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := s.series[key]
+	if len(points) == 0 {
+		return models.TimeSeriesPoint{}, false
+	}
+	return points[len(points)-1], true
+}
+
+// Nearest returns the point of a series closest to at, if one lies within tolerance
+func (s *TimeSeriesStore) Nearest(key string, at time.Time, tolerance time.Duration) (models.TimeSeriesPoint, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := s.series[key]
+	idx := sort.Search(len(points), func(i int) bool {
+		return !points[i].Timestamp.Before(at)
+	})
+
+	var best models.TimeSeriesPoint
+	bestGap := time.Duration(-1)
+	for _, i := range []int{idx - 1, idx} {
+		if i < 0 || i >= len(points) {
+			continue
+		}
+		gap := points[i].Timestamp.Sub(at)
+		if gap < 0 {
+			gap = -gap
+		}
+		if bestGap < 0 || gap < bestGap {
+			best, bestGap = points[i], gap
+		}
+	}
+	if bestGap < 0 || bestGap > tolerance {
+		return models.TimeSeriesPoint{}, false
+	}
+	return best, true
+}
+
+// Len returns the number of points stored for a series
+func (s *TimeSeriesStore) Len(key string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.series[key])
+}
+
+// Keys returns the sorted series keys starting with prefix
+func (s *TimeSeriesStore) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []string
+	for key := range s.series {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
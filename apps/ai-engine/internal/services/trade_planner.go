@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// TradePlanConfig controls how recommended trades are turned into swaps
+type TradePlanConfig struct {
+	MinTradeUSD       float64  // Swaps smaller than this are skipped
+	SlippageTolerance float64  // Fraction the output may fall below the expected amount
+	QuoteTokens       []string // Stablecoins that may hold sale proceeds, in order of preference
+	MaxPriceImpact    float64  // Simulated price impact above which a swap is flagged
+}
+
+// DefaultTradePlanConfig returns the default trade planning settings
+func DefaultTradePlanConfig() TradePlanConfig {
+	return TradePlanConfig{
+		MinTradeUSD:       10,
+		SlippageTolerance: 0.005,
+		QuoteTokens:       []string{"USDC", "USDT", "DAI"},
+		MaxPriceImpact:    0.02,
+	}
+}
+
+// WithTradePlanConfig overrides the default trade planning settings
+func WithTradePlanConfig(config TradePlanConfig) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.tradePlan = config
+	}
+}
+
+// WithLiquidityPools lets the engine simulate planned swaps against pool reserves
+func WithLiquidityPools(pools *LiquidityPools) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.pools = pools
+	}
+}
+
+// hopGasFactor is the extra gas of each additional hop of a routed swap, relative to a single swap
+const hopGasFactor = 0.5
+
+// tradeLeg is the USD amount of one token still to be sold or bought
+type tradeLeg struct {
+	token string
+	chain string
+	usd   float64
+}
+
+// planTrades turns the buy, sell and rebalance actions into ordered swaps. Sells are netted
+// against buys on the same chain so proceeds swap straight into the bought token; what remains
+// is sold into the quote stablecoin first, and buys are then funded from it up to the cash
+// the portfolio holds. It returns nil when there is nothing to trade or the portfolio has no value.
+func (e *EnhancedAIEngine) planTrades(portfolio models.Portfolio, actions []models.RebalanceAction) *models.TradePlan {
+	totalValue := portfolioValue(portfolio)
+	if totalValue <= 0 || len(tradeTargets(actions)) == 0 {
+		return nil
+	}
+
+	weights, yields := positionWeights(portfolio)
+	target, _ := applyRecommendation(&models.RebalanceRecommendation{Actions: actions}, weights, yields)
+	quote := e.quoteToken(weights)
+	chains := positionChains(portfolio)
+	prices := e.tokenPrices(portfolio, target)
+
+	var sells, buys []tradeLeg
+	for _, token := range sortedTokens(weights, target) {
+		if token == quote {
+			continue // The quote balance follows from the other trades
+		}
+		leg := tradeLeg{token: token, chain: tokenChain(chains, token), usd: (target[token] - weights[token]) * totalValue}
+		switch {
+		case leg.usd < 0:
+			leg.usd = -leg.usd
+			sells = append(sells, leg)
+		case leg.usd > 0:
+			buys = append(buys, leg)
+		}
+	}
+	sortLegs(sells)
+	sortLegs(buys)
+
+	plan := &models.TradePlan{QuoteToken: quote}
+	add := func(from, to, chain string, usd float64) (models.Swap, bool) {
+		if usd < e.tradePlan.MinTradeUSD {
+			plan.SkippedUSD += usd
+			return models.Swap{}, false
+		}
+		swap := e.planSwap(from, to, chain, usd, prices)
+		swap.Step = len(plan.Swaps) + 1
+		plan.Swaps = append(plan.Swaps, swap)
+		plan.EstimatedCostUSD += swap.EstimatedCost.TotalUSD
+		switch {
+		case e.pools != nil && swap.Route == nil:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("swap %d: no pool route for %s to %s on %s, cost is estimated", swap.Step, from, to, swap.Chain))
+		case swap.PriceImpact > e.tradePlan.MaxPriceImpact:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("swap %d: price impact %.2f%% exceeds %.2f%%", swap.Step, swap.PriceImpact*100, e.tradePlan.MaxPriceImpact*100))
+		}
+		return swap, true
+	}
+
+	// Net sells against buys on the same chain
+	type pairing struct {
+		from, to tradeLeg
+		usd      float64
+	}
+	var direct []pairing
+	for i := range sells {
+		for j := range buys {
+			if sells[i].usd <= 0 {
+				break
+			}
+			if buys[j].usd <= 0 || !strings.EqualFold(sells[i].chain, buys[j].chain) {
+				continue
+			}
+			usd := math.Min(sells[i].usd, buys[j].usd)
+			sells[i].usd -= usd
+			buys[j].usd -= usd
+			direct = append(direct, pairing{from: sells[i], to: buys[j], usd: usd})
+		}
+	}
+
+	// Sell the remainder into the quote token before any buy needs the cash
+	cash := e.quoteBalance(portfolio, quote, weights[quote]*totalValue)
+	for _, sell := range sells {
+		if swap, ok := add(sell.token, quote, sell.chain, sell.usd); ok {
+			cash += swap.ExpectedOutUSD
+		}
+	}
+	for _, pair := range direct {
+		add(pair.from.token, pair.to.token, pair.from.chain, pair.usd)
+	}
+
+	// Fund the remaining buys from cash, scaling them down when it runs short
+	needed := 0.0
+	for _, buy := range buys {
+		needed += buy.usd
+	}
+	scale := 1.0
+	if needed > cash {
+		scale = math.Max(cash, 0) / needed
+		plan.ShortfallUSD = needed - math.Max(cash, 0)
+	}
+	for _, buy := range buys {
+		add(quote, buy.token, buy.chain, buy.usd*scale)
+	}
+	return plan
+}
+
+// planSwap prices a swap of usd from one token to another, bounding its output by the slippage tolerance.
+// Simulated routes give the output in units straight from the pools.
+func (e *EnhancedAIEngine) planSwap(from, to, chain string, usd float64, prices map[string]float64) models.Swap {
+	cost, route := e.estimateSwap(chain, from, to, usd, prices)
+	swap := models.Swap{
+		FromToken:      from,
+		ToToken:        to,
+		Chain:          cost.Chain,
+		AmountInUSD:    usd,
+		ExpectedOutUSD: usd - cost.FeeUSD - cost.SlippageUSD, // Gas is paid in the native token
+		EstimatedCost:  &cost,
+	}
+	if price := prices[from]; price > 0 {
+		swap.AmountIn = usd / price
+	}
+	if price := prices[to]; price > 0 {
+		swap.ExpectedOut = swap.ExpectedOutUSD / price
+	}
+	if route != nil {
+		// Only the simulated fee and impact fractions are used; amounts stay at portfolio prices
+		swap.Route = route.Hops
+		swap.PriceImpact = route.PriceImpact
+	}
+	swap.MinAmountOut = swap.ExpectedOut * (1 - e.tradePlan.SlippageTolerance)
+	return swap
+}
+
+// estimateSwap costs a swap of usd from one token to another. With pool reserves and a price
+// for the input token the swap is simulated along its best route; otherwise the cost model's
+// liquidity estimates are used and no route is returned.
+func (e *EnhancedAIEngine) estimateSwap(chain, from, to string, usd float64, prices map[string]float64) (models.TradeCost, *SwapRoute) {
+	cost := e.costModel.SwapCost(chain, e.costModel.thinnerToken(from, to), usd)
+	if e.pools == nil || prices[from] <= 0 {
+		return cost, nil
+	}
+	route, ok := e.pools.BestRoute(chain, from, to, usd/prices[from])
+	if !ok {
+		return cost, nil
+	}
+
+	cost.GasUSD = e.costModel.gas(chain) * (1 + hopGasFactor*float64(len(route.Hops)-1))
+	cost.FeeUSD = usd * route.FeeShare
+	cost.SlippageUSD = (usd - cost.FeeUSD) * route.PriceImpact
+	cost.TotalUSD = cost.GasUSD + cost.FeeUSD + cost.SlippageUSD
+	return cost, &route
+}
+
+// quoteToken picks the configured stablecoin the portfolio holds most of, or the first one
+func (e *EnhancedAIEngine) quoteToken(weights map[string]float64) string {
+	quote, held := "", 0.0
+	for _, token := range e.tradePlan.QuoteTokens {
+		token = strings.ToUpper(token)
+		if quote == "" {
+			quote = token
+		}
+		if weights[token] > held {
+			quote, held = token, weights[token]
+		}
+	}
+	if quote == "" {
+		return "USDC"
+	}
+	return quote
+}
+
+// quoteBalance returns the USD held in the quote token, falling back to its weighted value
+func (e *EnhancedAIEngine) quoteBalance(portfolio models.Portfolio, quote string, weighted float64) float64 {
+	balance, valued := 0.0, false
+	for _, position := range portfolio.Positions {
+		if position.Value > 0 {
+			valued = true
+			if strings.EqualFold(position.Token, quote) {
+				balance += position.Value
+			}
+		}
+	}
+	if !valued {
+		return weighted
+	}
+	return balance
+}
+
+// tokenPrices returns USD prices for held and target tokens from the positions, then the
+// latest stored price, with stablecoins at their peg. Tokens without a price are omitted.
+func (e *EnhancedAIEngine) tokenPrices(portfolio models.Portfolio, target map[string]float64) map[string]float64 {
+	prices := impliedPrices(portfolio)
+	for token := range target {
+		if prices[token] > 0 {
+			continue
+		}
+		if peggedTokens[token] {
+			prices[token] = 1
+			continue
+		}
+		if e.priceHistory != nil {
+			if latest, ok := e.priceHistory.Latest(PriceSeriesKey(token)); ok && latest.Value > 0 {
+				prices[token] = latest.Value
+			}
+		}
+	}
+	for _, token := range e.tradePlan.QuoteTokens {
+		if token = strings.ToUpper(token); prices[token] == 0 && peggedTokens[token] {
+			prices[token] = 1
+		}
+	}
+	return prices
+}
+
+// sortLegs orders legs largest first, breaking ties by token
+func sortLegs(legs []tradeLeg) {
+	sort.SliceStable(legs, func(i, j int) bool {
+		if legs[i].usd != legs[j].usd {
+			return legs[i].usd > legs[j].usd
+		}
+		return legs[i].token < legs[j].token
+	})
+}
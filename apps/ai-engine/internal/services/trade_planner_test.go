@@ -0,0 +1,126 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func TestEnhancedAIEngine_PlanTrades(t *testing.T) {
+	// BTC at 40000 and ETH at 2000
+	portfolio := func(btcChain, ethChain string) models.Portfolio {
+		return models.Portfolio{ID: "pf-plan", TotalValue: 100000, Positions: []models.PortfolioPosition{
+			{Token: "BTC", Weight: 0.5, Amount: 1.25, Value: 50000, Chain: btcChain},
+			{Token: "ETH", Weight: 0.5, Amount: 25, Value: 50000, Chain: ethChain},
+		}}
+	}
+	intoETH := []models.RebalanceAction{
+		{Type: "sell", Token: "BTC", TargetWeight: 0.3},
+		{Type: "buy", Token: "ETH", TargetWeight: 0.7},
+	}
+
+	t.Run("sells are netted against buys on the same chain", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		plan := engine.planTrades(portfolio("ethereum", "ethereum"), intoETH)
+		if plan == nil || len(plan.Swaps) != 1 {
+			t.Fatalf("Expected a single direct swap, got %+v", plan)
+		}
+		swap := plan.Swaps[0]
+		if swap.FromToken != "BTC" || swap.ToToken != "ETH" || math.Abs(swap.AmountInUSD-20000) > 1e-6 {
+			t.Errorf("Expected $20000 of BTC swapped into ETH, got %+v", swap)
+		}
+		if math.Abs(swap.AmountIn-0.5) > 1e-9 {
+			t.Errorf("Expected 0.5 BTC in, got %f", swap.AmountIn)
+		}
+		if math.Abs(swap.ExpectedOut-swap.ExpectedOutUSD/2000) > 1e-9 || swap.ExpectedOutUSD >= 20000 {
+			t.Errorf("Expected ETH out net of costs at 2000, got %+v", swap)
+		}
+		if math.Abs(swap.MinAmountOut-swap.ExpectedOut*0.995) > 1e-9 {
+			t.Errorf("Expected min out within 0.5%% of expected, got %f of %f", swap.MinAmountOut, swap.ExpectedOut)
+		}
+	})
+
+	t.Run("sells settle into the quote token before buys across chains", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		plan := engine.planTrades(portfolio("ethereum", "arbitrum"), intoETH)
+		if plan == nil || len(plan.Swaps) != 2 {
+			t.Fatalf("Expected a sell and a buy, got %+v", plan)
+		}
+		sell, buy := plan.Swaps[0], plan.Swaps[1]
+		if sell.Step != 1 || sell.FromToken != "BTC" || sell.ToToken != plan.QuoteToken {
+			t.Errorf("Expected BTC sold into %s first, got %+v", plan.QuoteToken, sell)
+		}
+		if buy.Step != 2 || buy.FromToken != plan.QuoteToken || buy.ToToken != "ETH" || buy.Chain != "arbitrum" {
+			t.Errorf("Expected ETH bought on arbitrum second, got %+v", buy)
+		}
+		// Proceeds net of fees cannot cover the whole buy
+		if math.Abs(buy.AmountInUSD-sell.ExpectedOutUSD) > 1e-6 || math.Abs(plan.ShortfallUSD-(20000-sell.ExpectedOutUSD)) > 1e-6 {
+			t.Errorf("Expected the buy limited to sale proceeds, got %+v", plan)
+		}
+	})
+
+	t.Run("held stablecoins fund buys", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		withCash := models.Portfolio{ID: "pf-cash", Positions: []models.PortfolioPosition{
+			{Token: "ETH", Weight: 0.5, Amount: 25, Value: 50000},
+			{Token: "USDT", Weight: 0.5, Amount: 50000, Value: 50000},
+		}}
+		plan := engine.planTrades(withCash, []models.RebalanceAction{
+			{Type: "buy", Token: "ETH", TargetWeight: 0.8},
+			{Type: "sell", Token: "USDT", TargetWeight: 0.2},
+		})
+		if plan == nil || plan.QuoteToken != "USDT" || len(plan.Swaps) != 1 {
+			t.Fatalf("Expected one buy funded from USDT, got %+v", plan)
+		}
+		if swap := plan.Swaps[0]; swap.FromToken != "USDT" || math.Abs(swap.AmountInUSD-30000) > 1e-6 || math.Abs(swap.AmountIn-30000) > 1e-6 {
+			t.Errorf("Expected 30000 USDT swapped into ETH, got %+v", swap)
+		}
+		if plan.ShortfallUSD != 0 {
+			t.Errorf("Expected no shortfall, got %f", plan.ShortfallUSD)
+		}
+	})
+
+	t.Run("trades below the minimum size are skipped", func(t *testing.T) {
+		config := DefaultTradePlanConfig()
+		config.MinTradeUSD = 50000
+		engine := NewEnhancedAIEngine(WithTradePlanConfig(config))
+		plan := engine.planTrades(portfolio("ethereum", "ethereum"), intoETH)
+		if plan == nil || len(plan.Swaps) != 0 || math.Abs(plan.SkippedUSD-20000) > 1e-6 {
+			t.Errorf("Expected the $20000 swap skipped, got %+v", plan)
+		}
+	})
+
+	t.Run("no plan without trades or value", func(t *testing.T) {
+		engine := NewEnhancedAIEngine()
+		if plan := engine.planTrades(portfolio("ethereum", "ethereum"), nil); plan != nil {
+			t.Errorf("Expected no plan without actions, got %+v", plan)
+		}
+		unvalued := models.Portfolio{Positions: []models.PortfolioPosition{{Token: "BTC", Weight: 0.5}, {Token: "ETH", Weight: 0.5}}}
+		if plan := engine.planTrades(unvalued, intoETH); plan != nil {
+			t.Errorf("Expected no plan without a portfolio value, got %+v", plan)
+		}
+	})
+}
+
+func TestEnhancedAIEngine_GenerateRebalanceActionsAmount(t *testing.T) {
+	engine := NewEnhancedAIEngine()
+	portfolio := models.Portfolio{TotalValue: 100000, Positions: []models.PortfolioPosition{
+		{Token: "BTC", Weight: 1, Value: 100000},
+		{Token: "ETH", Weight: 0, Value: 0},
+	}}
+
+	schedule, _ := newPolicySchedule(nil)
+	actions := engine.generateRebalanceActions(portfolio, map[string]float64{"BTC": 0.6, "ETH": 0.4}, schedule)
+	for _, action := range actions {
+		if math.IsInf(action.Amount, 0) || math.IsNaN(action.Amount) {
+			t.Fatalf("Expected a finite amount for %s, got %f", action.Token, action.Amount)
+		}
+		if math.Abs(action.Amount-40000) > 1e-6 {
+			t.Errorf("Expected %s to trade $40000, got %f", action.Token, action.Amount)
+		}
+	}
+	if len(actions) != 2 {
+		t.Errorf("Expected 2 actions, got %d", len(actions))
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// YieldForecastConfig tunes the smoothing and mean-reversion model
+type YieldForecastConfig struct {
+	SmoothingAlpha   float64 // Exponential smoothing weight of the newest observation
+	ReversionSpeed   float64 // Daily mean-reversion rate toward the protocol average
+	MinObservations  int     // History length at which data quality stops discounting confidence
+	FallbackDailyVol float64 // Relative daily APY volatility assumed without history
+	MaxPredictions   int
+}
+
+// DefaultYieldForecastConfig returns the default forecasting parameters
+func DefaultYieldForecastConfig() YieldForecastConfig {
+	return YieldForecastConfig{
+		SmoothingAlpha:   0.3,
+		ReversionSpeed:   0.05, // Half-life of roughly two weeks
+		MinObservations:  30,
+		FallbackDailyVol: 0.05,
+		MaxPredictions:   50,
+	}
+}
+
+// YieldForecaster predicts pool APYs from stored APY history
+type YieldForecaster struct {
+	store  *YieldStore
+	config YieldForecastConfig
+}
+
+// NewYieldForecaster creates a forecaster over the given yield store
+func NewYieldForecaster(store *YieldStore) *YieldForecaster {
+	return &YieldForecaster{
+		store:  store,
+		config: DefaultYieldForecastConfig(),
+	}
+}
+
+// ParsePredictionPeriod converts "1d", "7d" or "30d" to a number of days
+func ParsePredictionPeriod(period string) (float64, error) {
+	switch period {
+	case "1d":
+		return 1, nil
+	case "7d":
+		return 7, nil
+	case "30d":
+		return 30, nil
+	default:
+		return 0, fmt.Errorf("unsupported prediction period %q (use 1d, 7d or 30d)", period)
+	}
+}
+
+// PredictYields forecasts APYs for pools matching the requested protocols and tokens
+func (f *YieldForecaster) PredictYields(ctx context.Context, protocols, tokens []string, period string) (*models.YieldPredictionResponse, error) {
+	horizon, err := ParsePredictionPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := f.matchingPools(protocols, tokens)
+	protocolAverages := f.protocolAverages(pools)
+
+	predictions := make([]models.YieldPrediction, 0, len(pools))
+	for _, pool := range pools {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("yield prediction cancelled: %w", err)
+		}
+
+		history := f.store.APYHistory(pool.Chain, pool.PoolID, time.Time{}, time.Time{})
+		prediction := f.forecast(pool.YieldData, history, protocolAverages[strings.ToLower(pool.Protocol)], horizon)
+		prediction.Timeframe = period
+		predictions = append(predictions, prediction)
+	}
+
+	return &models.YieldPredictionResponse{
+		Predictions: predictions,
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// matchingPools returns the largest stored pools matching any protocol and any token
+func (f *YieldForecaster) matchingPools(protocols, tokens []string) []models.YieldOpportunity {
+	candidates := f.store.Query(YieldFilter{SortBy: YieldSortTVL})
+
+	matched := make([]models.YieldOpportunity, 0)
+	for _, pool := range candidates {
+		if len(protocols) > 0 && !containsFold(protocols, pool.Protocol) {
+			continue
+		}
+		if len(tokens) > 0 {
+			found := false
+			for _, token := range tokens {
+				if poolHasToken(pool.Token, token) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		matched = append(matched, pool)
+		if len(matched) >= f.config.MaxPredictions {
+			break
+		}
+	}
+	return matched
+}
+
+// protocolAverages computes the TVL-weighted mean APY of every protocol in the store
+func (f *YieldForecaster) protocolAverages(pools []models.YieldOpportunity) map[string]float64 {
+	averages := make(map[string]float64)
+	for _, pool := range pools {
+		key := strings.ToLower(pool.Protocol)
+		if _, done := averages[key]; done {
+			continue
+		}
+
+		weighted, totalTVL := 0.0, 0.0
+		for _, peer := range f.store.Query(YieldFilter{Protocol: pool.Protocol}) {
+			weighted += peer.APY * peer.TVL
+			totalTVL += peer.TVL
+		}
+		if totalTVL > 0 {
+			averages[key] = weighted / totalTVL
+		} else {
+			averages[key] = pool.APY
+		}
+	}
+	return averages
+}
+
+// forecast applies exponential smoothing then Ornstein-Uhlenbeck style reversion to the protocol mean
+func (f *YieldForecaster) forecast(pool models.YieldData, history []models.TimeSeriesPoint, protocolMean, horizonDays float64) models.YieldPrediction {
+	level := pool.APY
+	if len(history) > 0 {
+		level = history[0].Value
+		for _, point := range history[1:] {
+			level = f.config.SmoothingAlpha*point.Value + (1-f.config.SmoothingAlpha)*level
+		}
+	}
+
+	kappa := f.config.ReversionSpeed
+	decay := math.Exp(-kappa * horizonDays)
+	predicted := protocolMean + (level-protocolMean)*decay
+
+	// Forecast variance of a mean-reverting process over the horizon
+	dailyVol := f.dailyVolatility(pool.APY, history)
+	variance := dailyVol * dailyVol * (1 - math.Exp(-2*kappa*horizonDays)) / (2 * kappa)
+	halfWidth := 1.96 * math.Sqrt(variance)
+
+	lower := math.Max(0, predicted-halfWidth)
+	upper := predicted + halfWidth
+
+	// Narrow intervals and long histories earn higher confidence
+	relativeWidth := (upper - lower) / math.Max(predicted, 0.01)
+	dataQuality := 0.5 + 0.5*math.Min(1, float64(len(history))/float64(f.config.MinObservations))
+	confidence := clamp01(dataQuality / (1 + relativeWidth))
+
+	return models.YieldPrediction{
+		Protocol:     pool.Protocol,
+		Token:        pool.Token,
+		Chain:        pool.Chain,
+		PoolID:       pool.PoolID,
+		CurrentAPY:   pool.APY,
+		PredictedAPY: math.Max(0, predicted),
+		LowerBound:   lower,
+		UpperBound:   upper,
+		Confidence:   confidence,
+		Observations: len(history),
+	}
+}
+
+// dailyVolatility estimates the standard deviation of daily APY changes from history
+func (f *YieldForecaster) dailyVolatility(currentAPY float64, history []models.TimeSeriesPoint) float64 {
+	fallback := currentAPY * f.config.FallbackDailyVol
+	if len(history) < 3 {
+		return fallback
+	}
+
+	// Scale per-observation variance of changes to a daily rate
+	sumSq, sumDays := 0.0, 0.0
+	for i := 1; i < len(history); i++ {
+		days := history[i].Timestamp.Sub(history[i-1].Timestamp).Hours() / 24
+		if days <= 0 {
+			continue
+		}
+		change := history[i].Value - history[i-1].Value
+		sumSq += change * change
+		sumDays += days
+	}
+	if sumDays == 0 {
+		return fallback
+	}
+
+	vol := math.Sqrt(sumSq / sumDays)
+	if vol == 0 {
+		return fallback
+	}
+	return vol
+}
+
+// containsFold reports whether values contains target, ignoring case
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// clamp01 limits v to the [0, 1] range
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// createForecastStore builds a store where one aave pool yields well above its peers
+func createForecastStore() *YieldStore {
+	store := NewYieldStore()
+	start := time.Now().Add(-40 * 24 * time.Hour)
+
+	for day := 0; day < 40; day++ {
+		ts := start.Add(time.Duration(day) * 24 * time.Hour)
+		wiggle := 0.002 * float64(day%3-1)
+		store.Ingest([]models.YieldData{
+			{PoolID: "hot", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.12 + wiggle, TVL: 100e6, Risk: 0.2, Timestamp: ts},
+			{PoolID: "calm", Chain: "Ethereum", Protocol: "aave-v3", Token: "DAI", APY: 0.04 + wiggle, TVL: 300e6, Risk: 0.2, Timestamp: ts},
+			{PoolID: "other", Chain: "Arbitrum", Protocol: "gmx", Token: "WETH", APY: 0.15, TVL: 50e6, Risk: 0.6, Timestamp: ts},
+		})
+	}
+	return store
+}
+
+// TestYieldForecaster_PredictYields tests smoothing, mean reversion and intervals
+func TestYieldForecaster_PredictYields(t *testing.T) {
+	forecaster := NewYieldForecaster(createForecastStore())
+	ctx := context.Background()
+
+	predictHot := func(period string) models.YieldPrediction {
+		t.Helper()
+		response, err := forecaster.PredictYields(ctx, []string{"aave-v3"}, []string{"USDC"}, period)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(response.Predictions) != 1 {
+			t.Fatalf("Expected 1 prediction, got %d", len(response.Predictions))
+		}
+		return response.Predictions[0]
+	}
+
+	day := predictHot("1d")
+	month := predictHot("30d")
+
+	t.Run("MeanReversion", func(t *testing.T) {
+		// TVL-weighted aave mean is 0.06, so the hot pool should drift down over time
+		if !(month.PredictedAPY < day.PredictedAPY && day.PredictedAPY < day.CurrentAPY+0.01) {
+			t.Errorf("Expected reversion toward protocol mean: 1d=%f 30d=%f current=%f",
+				day.PredictedAPY, month.PredictedAPY, day.CurrentAPY)
+		}
+		if month.PredictedAPY < 0.06 {
+			t.Errorf("Expected 30d prediction to stay above protocol mean, got %f", month.PredictedAPY)
+		}
+	})
+
+	t.Run("ConfidenceIntervals", func(t *testing.T) {
+		for _, p := range []models.YieldPrediction{day, month} {
+			if p.LowerBound > p.PredictedAPY || p.UpperBound < p.PredictedAPY {
+				t.Errorf("Prediction %f outside interval [%f, %f]", p.PredictedAPY, p.LowerBound, p.UpperBound)
+			}
+			if p.Confidence <= 0 || p.Confidence > 1 {
+				t.Errorf("Expected confidence in (0, 1], got %f", p.Confidence)
+			}
+		}
+		if month.UpperBound-month.LowerBound <= day.UpperBound-day.LowerBound {
+			t.Error("Expected wider interval for longer horizon")
+		}
+		if month.Confidence >= day.Confidence {
+			t.Error("Expected lower confidence for longer horizon")
+		}
+	})
+
+	t.Run("AllPools", func(t *testing.T) {
+		response, err := forecaster.PredictYields(ctx, nil, nil, "7d")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(response.Predictions) != 3 {
+			t.Errorf("Expected 3 predictions, got %d", len(response.Predictions))
+		}
+	})
+
+	t.Run("InvalidPeriod", func(t *testing.T) {
+		if _, err := forecaster.PredictYields(ctx, nil, nil, "1y"); err == nil {
+			t.Error("Expected error for unsupported period")
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// Yield sort orders supported by YieldStore.Query
+const (
+	YieldSortRiskAdjustedAPY = "risk_adjusted_apy"
+	YieldSortAPY             = "apy"
+	YieldSortTVL             = "tvl"
+)
+
+// APY and TVL history bounds per pool. Thousands of pools are tracked, so instead of keeping
+// every ingested snapshot (formerly up to 2000 per pool, a few days at the ingest rate) history
+// is sampled at most every 30 minutes and keeps 15 days per pool.
+const (
+	yieldHistoryInterval = 30 * time.Minute
+	yieldHistoryPoints   = 720
+)
+
+// YieldFilter narrows a yield query; zero values disable a filter
+type YieldFilter struct {
+	Chain    string
+	Protocol string
+	Token    string
+	MinTVL   float64
+	MaxRisk  float64
+	SortBy   string
+	Limit    int
+}
+
+// YieldStore keeps the latest snapshot of each yield pool and its APY history
+type YieldStore struct {
+	mu              sync.RWMutex
+	pools           map[string]*yieldPoolRecord
+	history         *TimeSeriesStore
+	historyInterval time.Duration // Minimum spacing between history points of a pool
+	lastIngest      time.Time
+}
+
+// yieldPoolRecord is the stored state of one pool
+type yieldPoolRecord struct {
+	latest    models.YieldData
+	firstSeen time.Time
+	snapshots int
+}
+
+// NewYieldStore creates an empty yield store
+func NewYieldStore() *YieldStore {
+	return &YieldStore{
+		pools:           make(map[string]*yieldPoolRecord),
+		history:         NewTimeSeriesStore(yieldHistoryPoints),
+		historyInterval: yieldHistoryInterval,
+	}
+}
+
+// yieldPoolKey identifies a pool by chain and pool id, falling back to protocol and token
+func yieldPoolKey(data models.YieldData) string {
+	id := data.PoolID
+	if id == "" {
+		id = data.Protocol + "/" + data.Token
+	}
+	return strings.ToLower(data.Chain) + ":" + strings.ToLower(id)
+}
+
+// apySeriesKey returns the time-series key of a pool's APY history
+func apySeriesKey(poolKey string) string {
+	return "apy:" + poolKey
+}
+
+// poolTVLSeriesKey returns the time-series key of a pool's TVL history
+func poolTVLSeriesKey(poolKey string) string {
+	return "pool_tvl:" + poolKey
+}
+
+// Ingest stores a batch of pool snapshots, deduplicating by pool id and chain
+func (s *YieldStore) Ingest(data []models.YieldData) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	seen := make(map[string]bool, len(data))
+
+	for _, pool := range data {
+		if pool.Timestamp.IsZero() {
+			pool.Timestamp = now
+		}
+
+		key := yieldPoolKey(pool)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		record, exists := s.pools[key]
+		if !exists {
+			record = &yieldPoolRecord{firstSeen: pool.Timestamp}
+			s.pools[key] = record
+		}
+		record.latest = pool
+		record.snapshots++
+
+		last, hasHistory := s.history.Latest(apySeriesKey(key))
+		if !hasHistory || pool.Timestamp.Sub(last.Timestamp) >= s.historyInterval {
+			s.history.Append(apySeriesKey(key), pool.Timestamp, pool.APY)
+			s.history.Append(poolTVLSeriesKey(key), pool.Timestamp, pool.TVL)
+		}
+	}
+
+	s.lastIngest = now
+	return len(seen)
+}
+
+// Query returns stored pools matching the filter, sorted as requested
+func (s *YieldStore) Query(filter YieldFilter) []models.YieldOpportunity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	results := make([]models.YieldOpportunity, 0)
+
+	for key, record := range s.pools {
+		pool := record.latest
+
+		if filter.Chain != "" && !strings.EqualFold(pool.Chain, filter.Chain) {
+			continue
+		}
+		if filter.Protocol != "" && !strings.EqualFold(pool.Protocol, filter.Protocol) {
+			continue
+		}
+		if filter.Token != "" && !poolHasToken(pool.Token, filter.Token) {
+			continue
+		}
+		if filter.MinTVL > 0 && pool.TVL < filter.MinTVL {
+			continue
+		}
+		if filter.MaxRisk > 0 && pool.Risk > filter.MaxRisk {
+			continue
+		}
+
+		results = append(results, models.YieldOpportunity{
+			YieldData:       pool,
+			RiskAdjustedAPY: riskAdjustedAPY(pool),
+			APY7dAvg:        averageValue(s.history.Range(apySeriesKey(key), weekAgo, time.Time{}), pool.APY),
+			FirstSeen:       record.firstSeen,
+			Snapshots:       record.snapshots,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		switch filter.SortBy {
+		case YieldSortAPY:
+			return results[i].APY > results[j].APY
+		case YieldSortTVL:
+			return results[i].TVL > results[j].TVL
+		default:
+			return results[i].RiskAdjustedAPY > results[j].RiskAdjustedAPY
+		}
+	})
+
+	if filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+	return results
+}
+
+// Pool returns the latest snapshot of a pool
+func (s *YieldStore) Pool(chain, poolID string) (models.YieldData, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, exists := s.pools[yieldPoolKey(models.YieldData{Chain: chain, PoolID: poolID})]
+	if !exists {
+		return models.YieldData{}, false
+	}
+	return record.latest, true
+}
+
+// APYHistory returns the APY history of a pool within [from, to], sampled at most every 30 minutes
+func (s *YieldStore) APYHistory(chain, poolID string, from, to time.Time) []models.TimeSeriesPoint {
+	key := yieldPoolKey(models.YieldData{Chain: chain, PoolID: poolID})
+	return s.history.Range(apySeriesKey(key), from, to)
+}
+
+// TVLHistory returns the TVL history of a pool within [from, to]
+func (s *YieldStore) TVLHistory(chain, poolID string, from, to time.Time) []models.TimeSeriesPoint {
+	key := yieldPoolKey(models.YieldData{Chain: chain, PoolID: poolID})
+	return s.history.Range(poolTVLSeriesKey(key), from, to)
+}
+
+// Len returns the number of distinct pools stored
+func (s *YieldStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.pools)
+}
+
+// LastIngest returns when the store was last updated
+func (s *YieldStore) LastIngest() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastIngest
+}
+
+// riskAdjustedAPY discounts a pool's APY by its risk score
+func riskAdjustedAPY(pool models.YieldData) float64 {
+	return pool.APY * (1 - pool.Risk)
+}
+
+// poolHasToken reports whether a pool symbol such as "USDC-WETH" contains token
+func poolHasToken(symbol, token string) bool {
+	for _, part := range strings.FieldsFunc(symbol, func(r rune) bool { return r == '-' || r == '/' || r == '+' }) {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return strings.EqualFold(symbol, token)
+}
+
+// averageValue returns the mean of points, or fallback when there are none
+func averageValue(points []models.TimeSeriesPoint, fallback float64) float64 {
+	if len(points) == 0 {
+		return fallback
+	}
+	sum := 0.0
+	for _, point := range points {
+		sum += point.Value
+	}
+	return sum / float64(len(points))
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func createTestYieldData() []models.YieldData {
+	return []models.YieldData{
+		{PoolID: "pool-1", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.05, TVL: 500e6, Risk: 0.2},
+		{PoolID: "pool-2", Chain: "Ethereum", Protocol: "curve-dex", Token: "USDC-USDT", APY: 0.08, TVL: 80e6, Risk: 0.4},
+		{PoolID: "pool-3", Chain: "Arbitrum", Protocol: "gmx", Token: "WETH", APY: 0.20, TVL: 5e6, Risk: 0.8},
+		{PoolID: "pool-1", Chain: "Arbitrum", Protocol: "aave-v3", Token: "USDC", APY: 0.06, TVL: 90e6, Risk: 0.25},
+	}
+}
+
+// TestYieldStore_Ingest tests deduplication by pool id and chain
+func TestYieldStore_Ingest(t *testing.T) {
+	store := NewYieldStore()
+
+	data := createTestYieldData()
+	data = append(data, data[0]) // Duplicate within a batch
+
+	if stored := store.Ingest(data); stored != 4 {
+		t.Errorf("Expected 4 distinct pools stored, got %d", stored)
+	}
+	if store.Len() != 4 {
+		t.Errorf("Expected 4 pools, got %d", store.Len())
+	}
+
+	// A later snapshot replaces the latest value and extends APY history
+	later := time.Now().Add(time.Hour)
+	store.Ingest([]models.YieldData{
+		{PoolID: "pool-1", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.07, TVL: 510e6, Risk: 0.2, Timestamp: later},
+	})
+
+	pool, ok := store.Pool("ethereum", "pool-1")
+	if !ok {
+		t.Fatal("Expected pool to be found")
+	}
+	if pool.APY != 0.07 {
+		t.Errorf("Expected latest APY 0.07, got %f", pool.APY)
+	}
+	if history := store.APYHistory("Ethereum", "pool-1", time.Time{}, time.Time{}); len(history) != 2 {
+		t.Errorf("Expected 2 APY history points, got %d", len(history))
+	}
+}
+
+// TestYieldStore_HistorySpacing tests that pool history is sampled at the history interval and bounded
+func TestYieldStore_HistorySpacing(t *testing.T) {
+	store := NewYieldStore()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Snapshots every 10 minutes for two hours keep one point per 30 minutes
+	for i := 0; i <= 12; i++ {
+		store.Ingest([]models.YieldData{
+			{PoolID: "pool-1", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.05, TVL: 500e6, Timestamp: start.Add(time.Duration(i) * 10 * time.Minute)},
+		})
+	}
+	history := store.APYHistory("Ethereum", "pool-1", time.Time{}, time.Time{})
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 APY points 30 minutes apart, got %d", len(history))
+	}
+	for i := 1; i < len(history); i++ {
+		if gap := history[i].Timestamp.Sub(history[i-1].Timestamp); gap != yieldHistoryInterval {
+			t.Errorf("Expected points %s apart, got %s", yieldHistoryInterval, gap)
+		}
+	}
+	if tvl := store.TVLHistory("Ethereum", "pool-1", time.Time{}, time.Time{}); len(tvl) != len(history) {
+		t.Errorf("Expected TVL sampled with APY, got %d points", len(tvl))
+	}
+
+	// History keeps the newest 15 days
+	for i := 0; i < yieldHistoryPoints+10; i++ {
+		store.Ingest([]models.YieldData{
+			{PoolID: "pool-2", Chain: "Ethereum", Protocol: "aave-v3", Token: "DAI", APY: 0.04, TVL: 300e6, Timestamp: start.Add(time.Duration(i) * yieldHistoryInterval)},
+		})
+	}
+	history = store.APYHistory("Ethereum", "pool-2", time.Time{}, time.Time{})
+	if len(history) != yieldHistoryPoints || history[0].Timestamp != start.Add(10*yieldHistoryInterval) {
+		t.Errorf("Expected the newest %d points, got %d from %s", yieldHistoryPoints, len(history), history[0].Timestamp)
+	}
+}
+
+// TestYieldStore_Query tests filtering and sorting
+func TestYieldStore_Query(t *testing.T) {
+	store := NewYieldStore()
+	store.Ingest(createTestYieldData())
+
+	t.Run("SortByRiskAdjustedAPY", func(t *testing.T) {
+		results := store.Query(YieldFilter{})
+		if len(results) != 4 {
+			t.Fatalf("Expected 4 results, got %d", len(results))
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].RiskAdjustedAPY > results[i-1].RiskAdjustedAPY {
+				t.Errorf("Results not sorted by risk-adjusted APY at %d", i)
+			}
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   YieldFilter
+			expected int
+		}{
+			{"Chain", YieldFilter{Chain: "arbitrum"}, 2},
+			{"Protocol", YieldFilter{Protocol: "aave-v3"}, 2},
+			{"Token", YieldFilter{Token: "usdt"}, 1},
+			{"MinTVL", YieldFilter{MinTVL: 85e6}, 2},
+			{"MaxRisk", YieldFilter{MaxRisk: 0.3}, 2},
+			{"Combined", YieldFilter{Token: "USDC", MaxRisk: 0.5, Chain: "Ethereum"}, 2},
+			{"Limit", YieldFilter{Limit: 1}, 1},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if results := store.Query(tt.filter); len(results) != tt.expected {
+					t.Errorf("Expected %d results, got %d", tt.expected, len(results))
+				}
+			})
+		}
+	})
+
+	t.Run("SortByTVL", func(t *testing.T) {
+		results := store.Query(YieldFilter{SortBy: YieldSortTVL})
+		if results[0].PoolID != "pool-1" || results[0].Chain != "Ethereum" {
+			t.Errorf("Expected largest pool first, got %+v", results[0].YieldData)
+		}
+	})
+}
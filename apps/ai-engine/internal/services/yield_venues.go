@@ -0,0 +1,185 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// actionTypeMove moves a holding between yield venues without changing its weight
+const actionTypeMove = "move"
+
+// YieldVenuePolicy limits which pools the engine may recommend moving funds into
+type YieldVenuePolicy struct {
+	MinAPYImprovement float64 // Minimum risk-adjusted APY gain, as a decimal, to justify a move
+	MaxPoolRisk       float64 // Maximum protocol risk score (0-1) of a target pool
+	MinPoolTVL        float64 // Minimum target pool TVL in USD
+}
+
+// DefaultYieldVenuePolicy returns the default venue selection policy
+func DefaultYieldVenuePolicy() YieldVenuePolicy {
+	return YieldVenuePolicy{
+		MinAPYImprovement: 0.01,
+		MaxPoolRisk:       0.5,
+		MinPoolTVL:        10000000,
+	}
+}
+
+// WithYieldStore lets the engine use stored yield pools for expected returns and venue moves
+func WithYieldStore(store *YieldStore) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.yieldStore = store
+	}
+}
+
+// WithYieldVenuePolicy overrides the default venue selection policy
+func WithYieldVenuePolicy(policy YieldVenuePolicy) EngineOption {
+	return func(e *EnhancedAIEngine) {
+		e.yieldPolicy = policy
+	}
+}
+
+// positionExpectedReturn combines the token's price return with the yield it currently earns
+func (e *EnhancedAIEngine) positionExpectedReturn(position models.PortfolioPosition) float64 {
+	return e.getTokenExpectedReturn(position.Token) + position.YieldAPY
+}
+
+// achievableYield returns the higher of the current yield and the best eligible venue's risk-adjusted APY
+func (e *EnhancedAIEngine) achievableYield(position models.PortfolioPosition) float64 {
+	current := e.currentRiskAdjustedYield(position)
+	if venue, ok := e.bestYieldVenue(position); ok && venue.RiskAdjustedAPY > current {
+		return venue.RiskAdjustedAPY
+	}
+	return current
+}
+
+// currentRiskAdjustedYield discounts the position's yield by the risk of its current venue when known
+func (e *EnhancedAIEngine) currentRiskAdjustedYield(position models.PortfolioPosition) float64 {
+	if pool, ok := e.currentVenue(position); ok {
+		return position.YieldAPY * (1 - pool.Risk)
+	}
+	return position.YieldAPY
+}
+
+// currentVenue looks up the stored pool a position is deployed in, by pool id or protocol
+func (e *EnhancedAIEngine) currentVenue(position models.PortfolioPosition) (models.YieldOpportunity, bool) {
+	if e.yieldStore == nil || position.Venue == "" {
+		return models.YieldOpportunity{}, false
+	}
+
+	for _, pool := range e.yieldStore.Query(YieldFilter{Token: position.Token, Chain: position.Chain}) {
+		if strings.EqualFold(pool.PoolID, position.Venue) || strings.EqualFold(pool.Protocol, position.Venue) {
+			return pool, true
+		}
+	}
+	return models.YieldOpportunity{}, false
+}
+
+// bestYieldVenue returns the single-asset pool with the highest risk-adjusted APY allowed by the policy
+func (e *EnhancedAIEngine) bestYieldVenue(position models.PortfolioPosition) (models.YieldOpportunity, bool) {
+	if e.yieldStore == nil {
+		return models.YieldOpportunity{}, false
+	}
+
+	// Candidates are sorted by risk-adjusted APY; stay on the position's chain to avoid bridging
+	candidates := e.yieldStore.Query(YieldFilter{
+		Token:   position.Token,
+		Chain:   position.Chain,
+		MinTVL:  e.yieldPolicy.MinPoolTVL,
+		MaxRisk: e.yieldPolicy.MaxPoolRisk,
+	})
+	for _, pool := range candidates {
+		// Multi-asset pools carry impermanent loss and change the token exposure
+		if strings.EqualFold(pool.Token, position.Token) {
+			return pool, true
+		}
+	}
+	return models.YieldOpportunity{}, false
+}
+
+// appendYieldMoveActions adds venue moves for positions that can earn materially more elsewhere
+func (e *EnhancedAIEngine) appendYieldMoveActions(actions []models.RebalanceAction, portfolio models.Portfolio, optimalAllocations map[string]float64) []models.RebalanceAction {
+	if e.yieldStore == nil {
+		return actions
+	}
+
+	totalValue := portfolioValue(portfolio)
+
+	for _, position := range portfolio.Positions {
+		venue, ok := e.bestYieldVenue(position)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(venue.PoolID, position.Venue) || strings.EqualFold(venue.Protocol, position.Venue) {
+			continue // Already deployed in the best venue
+		}
+
+		improvement := venue.RiskAdjustedAPY - e.currentRiskAdjustedYield(position)
+		if improvement < e.yieldPolicy.MinAPYImprovement {
+			continue
+		}
+
+		// Move what the position will hold after rebalancing
+		targetWeight, exists := optimalAllocations[position.Token]
+		if !exists {
+			targetWeight = position.Weight
+		}
+		amount := math.Min(position.Value, targetWeight*totalValue)
+		if amount <= 0 {
+			continue
+		}
+
+		// Gas for the withdrawal and deposit must be earned back within the benefit horizon
+		cost := e.costModel.MoveCost(venue.Chain)
+		cost.ExpectedBenefit = improvement * amount * e.costModel.BenefitHorizon
+		if cost.ExpectedBenefit <= cost.TotalUSD {
+			continue
+		}
+
+		fromVenue := position.Venue
+		if fromVenue == "" {
+			fromVenue = "idle"
+		}
+
+		actions = append(actions, models.RebalanceAction{
+			Type:          actionTypeMove,
+			Token:         position.Token,
+			Amount:        amount,
+			TargetWeight:  targetWeight,
+			Priority:      int(improvement * 100),
+			FromVenue:     fromVenue,
+			ToVenue:       venue.Protocol,
+			Chain:         venue.Chain,
+			PoolID:        venue.PoolID,
+			CurrentAPY:    position.YieldAPY,
+			TargetAPY:     venue.APY,
+			RiskScore:     venue.Risk,
+			RiskBreakdown: venue.RiskBreakdown,
+			EstimatedCost: &cost,
+			Trigger: &models.ActionTrigger{
+				Mode:  policyYieldVenue,
+				Rule:  ruleMinAPYImprovement,
+				Drift: improvement,
+				Band:  e.yieldPolicy.MinAPYImprovement,
+			},
+		})
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Priority > actions[j].Priority
+	})
+	return actions
+}
+
+// countActions counts actions of the given type
+func countActions(actions []models.RebalanceAction, actionType string) int {
+	count := 0
+	for _, action := range actions {
+		if action.Type == actionType {
+			count++
+		}
+	}
+	return count
+}
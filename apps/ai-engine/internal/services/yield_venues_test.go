@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func newTestVenueStore() *YieldStore {
+	store := NewYieldStore()
+	store.Ingest([]models.YieldData{
+		{PoolID: "aave-usdc", Chain: "Ethereum", Protocol: "aave-v3", Token: "USDC", APY: 0.05, TVL: 5e8, Risk: 0.1, Timestamp: time.Now()},
+		{PoolID: "farm-usdc", Chain: "Ethereum", Protocol: "newfarm", Token: "USDC", APY: 0.40, TVL: 2e7, Risk: 0.8, Timestamp: time.Now()},
+		{PoolID: "curve-lp", Chain: "Ethereum", Protocol: "curve-dex", Token: "USDC-USDT", APY: 0.09, TVL: 3e8, Risk: 0.15, Timestamp: time.Now()},
+		{PoolID: "tiny-usdc", Chain: "Ethereum", Protocol: "compound-v3", Token: "USDC", APY: 0.08, TVL: 2e6, Risk: 0.2, Timestamp: time.Now()},
+		{PoolID: "arb-usdc", Chain: "Arbitrum", Protocol: "aave-v3", Token: "USDC", APY: 0.07, TVL: 1e8, Risk: 0.2, Timestamp: time.Now()},
+	})
+	return store
+}
+
+func findAction(actions []models.RebalanceAction, actionType, token string) (models.RebalanceAction, bool) {
+	for _, action := range actions {
+		if action.Type == actionType && action.Token == token {
+			return action, true
+		}
+	}
+	return models.RebalanceAction{}, false
+}
+
+// TestEnhancedAIEngine_YieldAwareReturns tests that position yield adds to expected return
+func TestEnhancedAIEngine_YieldAwareReturns(t *testing.T) {
+	engine := NewEnhancedAIEngine()
+
+	idle := createTestPortfolio()
+	for i := range idle.Positions {
+		idle.Positions[i].YieldAPY = 0
+	}
+	earning := createTestPortfolio()
+
+	idleReturn := engine.analyzePortfolio(idle).ExpectedReturn
+	earningReturn := engine.analyzePortfolio(earning).ExpectedReturn
+
+	// Weighted yield of the test portfolio: 0.6*0.05 + 0.3*0.08 + 0.1*0.12
+	expectedDiff := 0.066
+	if math.Abs(earningReturn-idleReturn-expectedDiff) > 1e-9 {
+		t.Errorf("Expected yield to add %.3f to expected return, got %.3f", expectedDiff, earningReturn-idleReturn)
+	}
+}
+
+// TestEnhancedAIEngine_YieldMoveActions tests venue move recommendations
+func TestEnhancedAIEngine_YieldMoveActions(t *testing.T) {
+	ctx := context.Background()
+	engine := NewEnhancedAIEngine(WithYieldStore(newTestVenueStore()))
+
+	portfolio := models.Portfolio{
+		ID:         "yield-portfolio",
+		TotalValue: 100000,
+		Positions: []models.PortfolioPosition{
+			{Token: "ETH", Amount: 20, Value: 50000, Weight: 0.5},
+			{Token: "USDC", Amount: 50000, Value: 50000, Weight: 0.5, Chain: "Ethereum"},
+		},
+	}
+
+	t.Run("IdleUSDCMovesToLendingPool", func(t *testing.T) {
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		move, ok := findAction(recommendation.Actions, actionTypeMove, "USDC")
+		if !ok {
+			t.Fatalf("Expected a move action for USDC, got %+v", recommendation.Actions)
+		}
+		// The risky farm, the LP pool, the small pool and the other chain are all excluded
+		if move.PoolID != "aave-usdc" || move.ToVenue != "aave-v3" || move.FromVenue != "idle" {
+			t.Errorf("Expected move from idle to aave-usdc, got %+v", move)
+		}
+		if move.RiskScore != 0.1 || move.TargetAPY != 0.05 {
+			t.Errorf("Expected pool risk and APY to be attached, got %+v", move)
+		}
+		if move.Amount <= 0 || move.Amount > 50000 {
+			t.Errorf("Expected move amount within position value, got %f", move.Amount)
+		}
+		if _, ok := findAction(recommendation.Actions, actionTypeMove, "ETH"); ok {
+			t.Error("Expected no move action for ETH without a venue")
+		}
+	})
+
+	t.Run("AlreadyInBestVenue", func(t *testing.T) {
+		deployed := portfolio
+		deployed.Positions = []models.PortfolioPosition{
+			portfolio.Positions[0],
+			{Token: "USDC", Amount: 50000, Value: 50000, Weight: 0.5, Chain: "Ethereum", Venue: "aave-usdc", YieldAPY: 0.05},
+		}
+
+		recommendation, err := engine.GetRebalanceRecommendation(ctx, deployed)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, ok := findAction(recommendation.Actions, actionTypeMove, "USDC"); ok {
+			t.Error("Expected no move when already in the best venue")
+		}
+	})
+
+	t.Run("PolicyAllowsRiskierPools", func(t *testing.T) {
+		permissive := NewEnhancedAIEngine(
+			WithYieldStore(newTestVenueStore()),
+			WithYieldVenuePolicy(YieldVenuePolicy{MinAPYImprovement: 0.01, MaxPoolRisk: 1, MinPoolTVL: 1e6}),
+		)
+
+		venue, ok := permissive.bestYieldVenue(portfolio.Positions[1])
+		if !ok || venue.PoolID != "farm-usdc" {
+			t.Errorf("Expected farm-usdc as best risk-adjusted venue, got %+v", venue)
+		}
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		recommendation, err := NewEnhancedAIEngine().GetRebalanceRecommendation(ctx, portfolio)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if countActions(recommendation.Actions, actionTypeMove) != 0 {
+			t.Error("Expected no move actions without a yield store")
+		}
+	})
+}
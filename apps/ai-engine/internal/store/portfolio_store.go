@@ -0,0 +1,315 @@
+// Package store provides persistence for user-owned engine data
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// Errors returned by stores
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+)
+
+// PortfolioStore persists portfolios per owner with a snapshot on every write.
+// Portfolios of other owners behave as if they do not exist.
+type PortfolioStore interface {
+	// Create stores a new portfolio, generating an ID when portfolio.ID is empty
+	Create(ctx context.Context, owner, name string, portfolio models.Portfolio) (*models.StoredPortfolio, error)
+
+	// Get returns the current version of a portfolio
+	Get(ctx context.Context, owner, id string) (*models.StoredPortfolio, error)
+
+	// List returns the owner's portfolios, most recently updated first
+	List(ctx context.Context, owner string) ([]models.StoredPortfolio, error)
+
+	// Update replaces a portfolio, recording a new version
+	Update(ctx context.Context, owner, id, name string, portfolio models.Portfolio) (*models.StoredPortfolio, error)
+
+	// Delete removes a portfolio and its versions
+	Delete(ctx context.Context, owner, id string) error
+
+	// Versions returns every snapshot of a portfolio, oldest first
+	Versions(ctx context.Context, owner, id string) ([]models.PortfolioVersion, error)
+
+	// Version returns a single snapshot
+	Version(ctx context.Context, owner, id string, version int) (*models.PortfolioVersion, error)
+}
+
+// Ensure the file store implements the interface
+var _ PortfolioStore = (*FilePortfolioStore)(nil)
+
+// filePortfolioRecord is the persisted state of one portfolio
+type filePortfolioRecord struct {
+	Current  models.StoredPortfolio    `json:"current"`
+	Versions []models.PortfolioVersion `json:"versions"`
+}
+
+// FilePortfolioStore keeps portfolios in memory and persists them to a JSON file
+type FilePortfolioStore struct {
+	mu         sync.RWMutex
+	path       string
+	portfolios map[string]*filePortfolioRecord // Keyed by portfolio ID
+	now        func() time.Time
+}
+
+// NewFilePortfolioStore opens the store at path, creating it on first write.
+// An empty path keeps portfolios in memory only.
+func NewFilePortfolioStore(path string) (*FilePortfolioStore, error) {
+	s := &FilePortfolioStore{
+		path:       path,
+		portfolios: make(map[string]*filePortfolioRecord),
+		now:        time.Now,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read portfolio store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.portfolios); err != nil {
+		return nil, fmt.Errorf("failed to parse portfolio store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// normalizeOwner makes wallet addresses case-insensitive
+func normalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimSpace(owner))
+}
+
+// newID generates a random identifier with the given prefix
+func newID(prefix string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+// Create stores a new portfolio, generating an ID when portfolio.ID is empty
+func (s *FilePortfolioStore) Create(ctx context.Context, owner, name string, portfolio models.Portfolio) (*models.StoredPortfolio, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if portfolio.ID == "" {
+		id, err := newID("pf_")
+		if err != nil {
+			return nil, err
+		}
+		portfolio.ID = id
+	}
+	if _, exists := s.portfolios[portfolio.ID]; exists {
+		return nil, fmt.Errorf("portfolio %s: %w", portfolio.ID, ErrConflict)
+	}
+
+	now := s.now()
+	portfolio.LastUpdated = now
+	record := &filePortfolioRecord{
+		Current: models.StoredPortfolio{
+			Portfolio: portfolio,
+			Owner:     normalizeOwner(owner),
+			Name:      name,
+			Version:   1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Versions: []models.PortfolioVersion{{Version: 1, Portfolio: portfolio, Name: name, CreatedAt: now}},
+	}
+
+	s.portfolios[portfolio.ID] = record
+	if err := s.persistLocked(); err != nil {
+		delete(s.portfolios, portfolio.ID)
+		return nil, err
+	}
+
+	stored := record.Current
+	return &stored, nil
+}
+
+// Get returns the current version of a portfolio
+func (s *FilePortfolioStore) Get(ctx context.Context, owner, id string) (*models.StoredPortfolio, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.recordLocked(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	stored := record.Current
+	return &stored, nil
+}
+
+// List returns the owner's portfolios, most recently updated first
+func (s *FilePortfolioStore) List(ctx context.Context, owner string) ([]models.StoredPortfolio, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owner = normalizeOwner(owner)
+	portfolios := make([]models.StoredPortfolio, 0)
+	for _, record := range s.portfolios {
+		if record.Current.Owner == owner {
+			portfolios = append(portfolios, record.Current)
+		}
+	}
+
+	sort.Slice(portfolios, func(i, j int) bool {
+		return portfolios[i].UpdatedAt.After(portfolios[j].UpdatedAt)
+	})
+	return portfolios, nil
+}
+
+// Update replaces a portfolio, recording a new version
+func (s *FilePortfolioStore) Update(ctx context.Context, owner, id, name string, portfolio models.Portfolio) (*models.StoredPortfolio, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.recordLocked(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := *record
+
+	now := s.now()
+	portfolio.ID = id
+	portfolio.LastUpdated = now
+	if name == "" {
+		name = record.Current.Name
+	}
+
+	record.Current.Portfolio = portfolio
+	record.Current.Name = name
+	record.Current.Version++
+	record.Current.UpdatedAt = now
+	record.Versions = append(record.Versions, models.PortfolioVersion{
+		Version:   record.Current.Version,
+		Portfolio: portfolio,
+		Name:      name,
+		CreatedAt: now,
+	})
+
+	if err := s.persistLocked(); err != nil {
+		*record = previous
+		return nil, err
+	}
+
+	stored := record.Current
+	return &stored, nil
+}
+
+// Delete removes a portfolio and its versions
+func (s *FilePortfolioStore) Delete(ctx context.Context, owner, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.recordLocked(owner, id)
+	if err != nil {
+		return err
+	}
+
+	delete(s.portfolios, id)
+	if err := s.persistLocked(); err != nil {
+		s.portfolios[id] = record
+		return err
+	}
+	return nil
+}
+
+// Versions returns every snapshot of a portfolio, oldest first
+func (s *FilePortfolioStore) Versions(ctx context.Context, owner, id string) ([]models.PortfolioVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.recordLocked(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.PortfolioVersion(nil), record.Versions...), nil
+}
+
+// Version returns a single snapshot
+func (s *FilePortfolioStore) Version(ctx context.Context, owner, id string, version int) (*models.PortfolioVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.recordLocked(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range record.Versions {
+		if snapshot.Version == version {
+			return &snapshot, nil
+		}
+	}
+	return nil, fmt.Errorf("portfolio %s version %d: %w", id, version, ErrNotFound)
+}
+
+// recordLocked returns the portfolio if it belongs to owner; callers hold s.mu
+func (s *FilePortfolioStore) recordLocked(owner, id string) (*filePortfolioRecord, error) {
+	record, exists := s.portfolios[id]
+	if !exists || record.Current.Owner != normalizeOwner(owner) {
+		return nil, fmt.Errorf("portfolio %s: %w", id, ErrNotFound)
+	}
+	return record, nil
+}
+
+// persistLocked atomically rewrites the store file; callers hold s.mu for writing
+func (s *FilePortfolioStore) persistLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.portfolios, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode portfolio store: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close store file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+	return nil
+}
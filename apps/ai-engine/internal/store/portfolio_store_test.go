@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func testPortfolio(id string, weight float64) models.Portfolio {
+	return models.Portfolio{
+		ID: id,
+		Positions: []models.PortfolioPosition{
+			{Token: "ETH", Weight: weight, Value: 1000 * weight},
+			{Token: "USDC", Weight: 1 - weight, Value: 1000 * (1 - weight)},
+		},
+		TotalValue: 1000,
+	}
+}
+
+func TestFilePortfolioStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFilePortfolioStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	t.Run("create generates an ID", func(t *testing.T) {
+		stored, err := s.Create(ctx, "0xABC", "main", testPortfolio("", 0.5))
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if stored.ID == "" {
+			t.Error("Expected a generated portfolio ID")
+		}
+		if stored.Owner != "0xabc" {
+			t.Errorf("Expected normalized owner 0xabc, got %s", stored.Owner)
+		}
+		if stored.Version != 1 {
+			t.Errorf("Expected version 1, got %d", stored.Version)
+		}
+	})
+
+	t.Run("create rejects duplicate IDs", func(t *testing.T) {
+		if _, err := s.Create(ctx, "0xabc", "", testPortfolio("dup", 0.5)); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		_, err := s.Create(ctx, "0xother", "", testPortfolio("dup", 0.5))
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("update records versions", func(t *testing.T) {
+		if _, err := s.Create(ctx, "0xabc", "versioned", testPortfolio("pf-1", 0.5)); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		updated, err := s.Update(ctx, "0xabc", "pf-1", "", testPortfolio("", 0.8))
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.Version != 2 || updated.ID != "pf-1" || updated.Name != "versioned" {
+			t.Errorf("Expected version 2 of pf-1 keeping its name, got %+v", updated)
+		}
+
+		versions, err := s.Versions(ctx, "0xabc", "pf-1")
+		if err != nil {
+			t.Fatalf("Versions failed: %v", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("Expected 2 versions, got %d", len(versions))
+		}
+		if versions[0].Portfolio.Positions[0].Weight != 0.5 || versions[1].Portfolio.Positions[0].Weight != 0.8 {
+			t.Errorf("Expected snapshots with weights 0.5 and 0.8, got %+v", versions)
+		}
+
+		first, err := s.Version(ctx, "0xabc", "pf-1", 1)
+		if err != nil {
+			t.Fatalf("Version failed: %v", err)
+		}
+		if first.Portfolio.Positions[0].Weight != 0.5 {
+			t.Errorf("Expected version 1 weight 0.5, got %f", first.Portfolio.Positions[0].Weight)
+		}
+		if _, err := s.Version(ctx, "0xabc", "pf-1", 3); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for missing version, got %v", err)
+		}
+	})
+
+	t.Run("other owners cannot see portfolios", func(t *testing.T) {
+		if _, err := s.Get(ctx, "0xother", "pf-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound on Get, got %v", err)
+		}
+		if _, err := s.Update(ctx, "0xother", "pf-1", "", testPortfolio("", 0.1)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound on Update, got %v", err)
+		}
+		if err := s.Delete(ctx, "0xother", "pf-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound on Delete, got %v", err)
+		}
+
+		list, err := s.List(ctx, "0xother")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 0 {
+			t.Errorf("Expected no portfolios for 0xother, got %+v", list)
+		}
+	})
+
+	t.Run("delete removes the portfolio", func(t *testing.T) {
+		if err := s.Delete(ctx, "0xabc", "pf-1"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := s.Get(ctx, "0xabc", "pf-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+		if _, err := s.Versions(ctx, "0xabc", "pf-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected versions to be deleted, got %v", err)
+		}
+	})
+}
+
+func TestFilePortfolioStore_ListOrder(t *testing.T) {
+	ctx := context.Background()
+	s, _ := NewFilePortfolioStore("")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.Create(ctx, "0xabc", "", testPortfolio("older", 0.5))
+	now = now.Add(time.Hour)
+	s.Create(ctx, "0xabc", "", testPortfolio("newer", 0.5))
+	now = now.Add(time.Hour)
+	s.Update(ctx, "0xabc", "older", "", testPortfolio("", 0.6))
+
+	list, err := s.List(ctx, "0xabc")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "older" || list[1].ID != "newer" {
+		t.Errorf("Expected most recently updated first, got %+v", list)
+	}
+}
+
+func TestFilePortfolioStore_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "portfolios.json")
+
+	s, err := NewFilePortfolioStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if _, err := s.Create(ctx, "0xabc", "saved", testPortfolio("pf-1", 0.5)); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := s.Update(ctx, "0xabc", "pf-1", "", testPortfolio("", 0.7)); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	reopened, err := NewFilePortfolioStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	stored, err := reopened.Get(ctx, "0xabc", "pf-1")
+	if err != nil {
+		t.Fatalf("Get after reopen failed: %v", err)
+	}
+	if stored.Version != 2 || stored.Name != "saved" || stored.Positions[0].Weight != 0.7 {
+		t.Errorf("Expected persisted version 2, got %+v", stored)
+	}
+
+	versions, err := reopened.Versions(ctx, "0xabc", "pf-1")
+	if err != nil || len(versions) != 2 {
+		t.Errorf("Expected 2 persisted versions, got %d (%v)", len(versions), err)
+	}
+}
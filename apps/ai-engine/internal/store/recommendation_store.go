@@ -0,0 +1,399 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+// maxRecordLine bounds a single JSON line when loading the recommendation log
+const maxRecordLine = 16 << 20
+
+// DefaultRecommendationRetention keeps records well past the longest default outcome horizon
+const DefaultRecommendationRetention = 180 * 24 * time.Hour
+
+// RecommendationStore keeps an append-only audit trail of engine results
+type RecommendationStore interface {
+	// Save stores a record, assigning its ID and creation time when unset
+	Save(ctx context.Context, record models.RecommendationRecord) (*models.RecommendationRecord, error)
+
+	// Get returns a single record owned by owner
+	Get(ctx context.Context, owner, id string) (*models.RecommendationRecord, error)
+
+	// Query returns matching records, newest first. An empty owner matches every owner.
+	Query(ctx context.Context, query models.RecommendationQuery) ([]models.RecommendationRecord, error)
+
+	// SaveOutcome attaches a realized outcome to a record, replacing one for the same horizon
+	SaveOutcome(ctx context.Context, recordID string, outcome models.RecommendationOutcome) error
+}
+
+// Ensure the file store implements the interface
+var _ RecommendationStore = (*FileRecommendationStore)(nil)
+
+// outcomeEntry is a log line attaching an outcome to an earlier record
+type outcomeEntry struct {
+	RecordID string                        `json:"record_id"`
+	Outcome  *models.RecommendationOutcome `json:"outcome"`
+}
+
+// FileRecommendationStore indexes records in memory and appends them to a JSON Lines file.
+// Outcomes are appended as separate lines so records are never rewritten.
+type FileRecommendationStore struct {
+	mu        sync.RWMutex
+	path      string
+	file      *os.File
+	records   []models.RecommendationRecord // Ordered by CreatedAt, oldest first
+	byID      map[string]int
+	now       func() time.Time
+	retention time.Duration // Age after which records are dropped; zero keeps every record
+	expired   int           // Records dropped since the log was last compacted
+}
+
+// RecommendationStoreOption configures a FileRecommendationStore
+type RecommendationStoreOption func(*FileRecommendationStore)
+
+// WithRetention drops records older than maxAge, compacting the log once half of it has expired
+func WithRetention(maxAge time.Duration) RecommendationStoreOption {
+	return func(s *FileRecommendationStore) {
+		s.retention = maxAge
+	}
+}
+
+// NewFileRecommendationStore opens the log at path, replaying existing records.
+// An empty path keeps records in memory only.
+func NewFileRecommendationStore(path string, opts ...RecommendationStoreOption) (*FileRecommendationStore, error) {
+	s := &FileRecommendationStore{
+		path: path,
+		byID: make(map[string]int),
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+	s.expireLocked()
+	if s.expired > 0 {
+		if err := s.compactLocked(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recommendation log: %w", err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// load replays the log. A malformed final line is a torn write and is truncated
+// so that later appends start on a fresh line.
+func (s *FileRecommendationStore) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read recommendation log: %w", err)
+	}
+
+	offset := 0
+	for line := 1; offset < len(data); line++ {
+		end := bytes.IndexByte(data[offset:], '\n')
+		last := end < 0
+		if last {
+			end = len(data) - offset
+		}
+		if end > maxRecordLine {
+			return fmt.Errorf("recommendation log %s line %d exceeds %d bytes", path, line, maxRecordLine)
+		}
+
+		raw := data[offset : offset+end]
+		if len(bytes.TrimSpace(raw)) > 0 {
+			if err := s.replayLocked(raw); err != nil {
+				if last {
+					break
+				}
+				return fmt.Errorf("failed to parse recommendation log %s line %d: %w", path, line, err)
+			}
+		}
+		offset += end + 1
+	}
+
+	switch {
+	case offset < len(data):
+		if err := os.Truncate(path, int64(offset)); err != nil {
+			return fmt.Errorf("failed to truncate torn recommendation log: %w", err)
+		}
+	case offset > len(data):
+		// The final record is complete but lost its newline
+		return appendNewline(path)
+	}
+	return nil
+}
+
+// replayLocked applies one log line, either a record or an outcome entry
+func (s *FileRecommendationStore) replayLocked(raw []byte) error {
+	var entry outcomeEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return err
+	}
+	if entry.Outcome != nil {
+		s.applyOutcomeLocked(entry.RecordID, *entry.Outcome)
+		return nil
+	}
+
+	var record models.RecommendationRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return err
+	}
+	s.insertLocked(record)
+	return nil
+}
+
+// appendNewline terminates the last line of the file at path
+func appendNewline(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recommendation log: %w", err)
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to repair recommendation log: %w", err)
+	}
+	return file.Close()
+}
+
+// Save stores a record, assigning its ID and creation time when unset
+func (s *FileRecommendationStore) Save(ctx context.Context, record models.RecommendationRecord) (*models.RecommendationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record.ID == "" {
+		id, err := newID("rec_")
+		if err != nil {
+			return nil, err
+		}
+		record.ID = id
+	}
+	if _, exists := s.byID[record.ID]; exists {
+		return nil, fmt.Errorf("recommendation %s: %w", record.ID, ErrConflict)
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = s.now()
+	}
+	record.Owner = normalizeOwner(record.Owner)
+
+	if s.file != nil {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode recommendation: %w", err)
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return nil, fmt.Errorf("failed to append recommendation: %w", err)
+		}
+	}
+
+	s.insertLocked(record)
+	s.expireLocked()
+	if s.expired > 0 && s.expired >= len(s.records) {
+		if err := s.compactLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+// expireLocked drops records older than the retention period; callers hold s.mu for writing
+func (s *FileRecommendationStore) expireLocked() {
+	if s.retention <= 0 {
+		return
+	}
+	cutoff := s.now().Add(-s.retention)
+	n := sort.Search(len(s.records), func(i int) bool {
+		return !s.records[i].CreatedAt.Before(cutoff)
+	})
+	if n == 0 {
+		return
+	}
+
+	for _, record := range s.records[:n] {
+		delete(s.byID, record.ID)
+	}
+	s.records = append([]models.RecommendationRecord(nil), s.records[n:]...)
+	for i, record := range s.records {
+		s.byID[record.ID] = i
+	}
+	s.expired += n
+}
+
+// compactLocked rewrites the log with the retained records, their outcomes inline,
+// and reopens it for appending; callers hold s.mu for writing
+func (s *FileRecommendationStore) compactLocked() error {
+	s.expired = 0
+	if s.path == "" {
+		return nil
+	}
+
+	var data bytes.Buffer
+	for _, record := range s.records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode recommendation: %w", err)
+		}
+		data.Write(append(line, '\n'))
+	}
+
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return fmt.Errorf("failed to close recommendation log: %w", err)
+		}
+		s.file = nil
+	}
+	if err := writeFileAtomic(s.path, data.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact recommendation log: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recommendation log: %w", err)
+	}
+	s.file = file
+	return nil
+}
+
+// insertLocked adds a record keeping creation order; callers hold s.mu for writing
+func (s *FileRecommendationStore) insertLocked(record models.RecommendationRecord) {
+	i := sort.Search(len(s.records), func(i int) bool {
+		return s.records[i].CreatedAt.After(record.CreatedAt)
+	})
+	if i == len(s.records) {
+		s.records = append(s.records, record)
+		s.byID[record.ID] = i
+		return
+	}
+
+	s.records = append(s.records, models.RecommendationRecord{})
+	copy(s.records[i+1:], s.records[i:])
+	s.records[i] = record
+	for j := i; j < len(s.records); j++ {
+		s.byID[s.records[j].ID] = j
+	}
+}
+
+// Get returns a single record owned by owner
+func (s *FileRecommendationStore) Get(ctx context.Context, owner, id string) (*models.RecommendationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, exists := s.byID[id]
+	if !exists || s.records[i].Owner != normalizeOwner(owner) {
+		return nil, fmt.Errorf("recommendation %s: %w", id, ErrNotFound)
+	}
+	record := s.records[i]
+	return &record, nil
+}
+
+// Query returns matching records, newest first. An empty owner matches every owner.
+func (s *FileRecommendationStore) Query(ctx context.Context, query models.RecommendationQuery) ([]models.RecommendationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owner := normalizeOwner(query.Owner)
+	records := make([]models.RecommendationRecord, 0)
+	for i := len(s.records) - 1; i >= 0; i-- {
+		record := s.records[i]
+		if !query.To.IsZero() && !record.CreatedAt.Before(query.To) {
+			continue
+		}
+		if !query.From.IsZero() && record.CreatedAt.Before(query.From) {
+			break
+		}
+		if owner != "" && record.Owner != owner {
+			continue
+		}
+		if query.PortfolioID != "" && record.PortfolioID != query.PortfolioID {
+			continue
+		}
+		if query.Kind != "" && record.Kind != query.Kind {
+			continue
+		}
+
+		records = append(records, record)
+		if query.Limit > 0 && len(records) == query.Limit {
+			break
+		}
+	}
+	return records, nil
+}
+
+// SaveOutcome attaches a realized outcome to a record, replacing one for the same horizon
+func (s *FileRecommendationStore) SaveOutcome(ctx context.Context, recordID string, outcome models.RecommendationOutcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.byID[recordID]; !exists {
+		return fmt.Errorf("recommendation %s: %w", recordID, ErrNotFound)
+	}
+
+	if s.file != nil {
+		line, err := json.Marshal(outcomeEntry{RecordID: recordID, Outcome: &outcome})
+		if err != nil {
+			return fmt.Errorf("failed to encode outcome: %w", err)
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to append outcome: %w", err)
+		}
+	}
+
+	s.applyOutcomeLocked(recordID, outcome)
+	return nil
+}
+
+// applyOutcomeLocked sets an outcome on a record; outcomes of unknown records are ignored.
+// The map is copied so records already returned to callers never change underneath them.
+func (s *FileRecommendationStore) applyOutcomeLocked(recordID string, outcome models.RecommendationOutcome) {
+	i, exists := s.byID[recordID]
+	if !exists {
+		return
+	}
+
+	outcomes := make(map[string]models.RecommendationOutcome, len(s.records[i].Outcomes)+1)
+	for horizon, existing := range s.records[i].Outcomes {
+		outcomes[horizon] = existing
+	}
+	outcomes[outcome.Horizon] = outcome
+	s.records[i].Outcomes = outcomes
+}
+
+// Close closes the underlying log file
+func (s *FileRecommendationStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
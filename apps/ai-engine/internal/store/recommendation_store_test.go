@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valkyriefinance/ai-engine/internal/models"
+)
+
+func testRecord(owner, portfolioID, kind string, createdAt time.Time) models.RecommendationRecord {
+	return models.RecommendationRecord{
+		Kind:        kind,
+		Owner:       owner,
+		PortfolioID: portfolioID,
+		Portfolio:   testPortfolio(portfolioID, 0.5),
+		Recommendation: &models.RebalanceRecommendation{
+			PortfolioID: portfolioID,
+			Actions:     []models.RebalanceAction{{Type: "sell", Token: "ETH"}},
+		},
+		Engine:    models.EngineInfo{Version: "1.0.0", ConfigHash: "abc"},
+		CreatedAt: createdAt,
+	}
+}
+
+func TestFileRecommendationStore_Query(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileRecommendationStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, record := range []models.RecommendationRecord{
+		testRecord("0xABC", "pf-1", models.RecordKindRecommendation, base.Add(2*time.Hour)),
+		testRecord("0xabc", "pf-1", models.RecordKindRiskReport, base),
+		testRecord("0xabc", "pf-2", models.RecordKindRecommendation, base.Add(time.Hour)),
+		testRecord("0xother", "pf-3", models.RecordKindRecommendation, base.Add(3*time.Hour)),
+	} {
+		if _, err := s.Save(ctx, record); err != nil {
+			t.Fatalf("Save %d failed: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    models.RecommendationQuery
+		expected []string // Portfolio IDs, newest first
+	}{
+		{"by owner", models.RecommendationQuery{Owner: "0xabc"}, []string{"pf-1", "pf-2", "pf-1"}},
+		{"by portfolio", models.RecommendationQuery{Owner: "0xabc", PortfolioID: "pf-1"}, []string{"pf-1", "pf-1"}},
+		{"by kind", models.RecommendationQuery{Owner: "0xabc", Kind: models.RecordKindRiskReport}, []string{"pf-1"}},
+		{"by time range", models.RecommendationQuery{Owner: "0xabc", From: base.Add(time.Hour), To: base.Add(2 * time.Hour)}, []string{"pf-2"}},
+		{"with limit", models.RecommendationQuery{Owner: "0xabc", Limit: 1}, []string{"pf-1"}},
+		{"all owners", models.RecommendationQuery{}, []string{"pf-3", "pf-1", "pf-2", "pf-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := s.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(records) != len(tt.expected) {
+				t.Fatalf("Expected %d records, got %d", len(tt.expected), len(records))
+			}
+			for i, record := range records {
+				if record.PortfolioID != tt.expected[i] {
+					t.Errorf("Expected record %d for %s, got %s", i, tt.expected[i], record.PortfolioID)
+				}
+			}
+		})
+	}
+
+	t.Run("get enforces ownership", func(t *testing.T) {
+		records, _ := s.Query(ctx, models.RecommendationQuery{Owner: "0xabc", Limit: 1})
+		if _, err := s.Get(ctx, "0xABC", records[0].ID); err != nil {
+			t.Errorf("Expected owner to read record, got %v", err)
+		}
+		if _, err := s.Get(ctx, "0xother", records[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another owner, got %v", err)
+		}
+	})
+}
+
+func TestFileRecommendationStore_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "recommendations.jsonl")
+
+	s, err := NewFileRecommendationStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	saved, err := s.Save(ctx, testRecord("0xabc", "pf-1", models.RecordKindRecommendation, time.Time{}))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved.ID == "" || saved.CreatedAt.IsZero() {
+		t.Errorf("Expected ID and creation time to be assigned, got %+v", saved)
+	}
+	s.Close()
+
+	// Simulate a write torn by a crash
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	file.WriteString(`{"id":"rec_torn","kind":`)
+	file.Close()
+
+	reopened, err := NewFileRecommendationStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer reopened.Close()
+
+	record, err := reopened.Get(ctx, "0xabc", saved.ID)
+	if err != nil {
+		t.Fatalf("Get after reopen failed: %v", err)
+	}
+	if record.Engine.Version != "1.0.0" || len(record.Recommendation.Actions) != 1 {
+		t.Errorf("Expected the persisted record, got %+v", record)
+	}
+
+	t.Run("rejects corruption before the last line", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "corrupt.jsonl")
+		os.WriteFile(corrupt, []byte("not json\n{}\n"), 0o644)
+		if _, err := NewFileRecommendationStore(corrupt); err == nil {
+			t.Error("Expected an error for a corrupt log")
+		}
+	})
+}
+
+func TestFileRecommendationStore_Retention(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "recommendations.jsonl")
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewFileRecommendationStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	old, _ := s.Save(ctx, testRecord("0xabc", "pf-1", models.RecordKindRecommendation, now.Add(-48*time.Hour)))
+	recent, _ := s.Save(ctx, testRecord("0xabc", "pf-1", models.RecordKindRecommendation, now.Add(-time.Hour)))
+	if err := s.SaveOutcome(ctx, recent.ID, models.RecommendationOutcome{Horizon: "1h", Status: models.OutcomeStatusEvaluated}); err != nil {
+		t.Fatalf("SaveOutcome failed: %v", err)
+	}
+	s.Close()
+
+	reopened, err := NewFileRecommendationStore(path, WithRetention(24*time.Hour), func(s *FileRecommendationStore) { s.now = func() time.Time { return now } })
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if _, err := reopened.Get(ctx, "0xabc", old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the expired record dropped, got %v", err)
+	}
+	record, err := reopened.Get(ctx, "0xabc", recent.ID)
+	if err != nil || record.Outcomes["1h"].Status != models.OutcomeStatusEvaluated {
+		t.Errorf("Expected the recent record with its outcome, got %+v (%v)", record, err)
+	}
+
+	// Expiring as time passes compacts the log once most of it is stale
+	now = now.Add(24 * time.Hour)
+	if _, err := reopened.Save(ctx, testRecord("0xabc", "pf-1", models.RecordKindRecommendation, time.Time{})); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened.Close()
+
+	data, _ := os.ReadFile(path)
+	if lines := len(strings.Split(strings.TrimSpace(string(data)), "\n")); lines != 1 {
+		t.Errorf("Expected the compacted log to hold 1 record, got %d lines", lines)
+	}
+	records, _ := NewFileRecommendationStore(path)
+	if all, _ := records.Query(ctx, models.RecommendationQuery{}); len(all) != 1 || all[0].CreatedAt != now {
+		t.Errorf("Expected only the newest record after compaction, got %+v", all)
+	}
+}
@@ -28,6 +28,15 @@
 //	SENTRY_DSN            - Sentry DSN for error tracking
 //	ENVIRONMENT           - Environment name (development/staging/production)
 //	RELEASE_VERSION       - Application version for tracking
+//	PORTFOLIO_STORE_PATH  - Portfolio store file (default: data/portfolios.json)
+//	RECOMMENDATION_STORE_PATH - Recommendation audit log (default: data/recommendations.jsonl)
+//	RECOMMENDATION_RETENTION - Age after which audit records are dropped (default: 180d)
+//	SESSION_SECRET        - Key verifying signed wallet sessions; wallet-scoped endpoints are disabled without it
+//	OUTCOME_HORIZONS      - Recommendation evaluation horizons (default: 1d,7d,30d)
+//	CALIBRATION_HORIZON   - Outcome horizon used to calibrate confidence (default: 7d)
+//	LIQUIDITY_POOLS_PATH  - DEX pool reserves used to simulate swaps (default: none, swaps use the cost model)
+//	HOUSE_VIEWS_PATH      - Black-Litterman house views applied to every recommendation (default: none)
+//	BENCHMARK             - Benchmark for beta, relative risk and performance attribution: btc, eth, market_cap or weights like BTC:0.6,ETH:0.4 (default: BTC:0.6,ETH:0.4)
 //
 // Example Usage:
 //
@@ -49,6 +58,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -57,6 +67,7 @@ import (
 	"github.com/valkyriefinance/ai-engine/internal/monitoring"
 	"github.com/valkyriefinance/ai-engine/internal/server"
 	"github.com/valkyriefinance/ai-engine/internal/services"
+	"github.com/valkyriefinance/ai-engine/internal/store"
 )
 
 // main is the entry point for the AI Engine service.
@@ -108,7 +119,13 @@ func main() {
 	perfMonitor := services.NewPerformanceMonitor()
 
 		// Initialize data collector with real market data
-	var dataCollector services.MarketDataCollector = services.NewRealDataCollector()
+	realDataCollector := services.NewRealDataCollector()
+	var dataCollector services.MarketDataCollector = realDataCollector
+
+	// Initialize feed collector for yield pools and streamed prices; its price history also
+	// drives the composite sentiment index
+	feedCollector := services.NewDataCollector()
+	realDataCollector.UsePriceHistory(feedCollector.PriceHistory())
 
 	// Initialize health checker
 	healthChecker := health.NewHealthChecker(perfMonitor, dataCollector)
@@ -146,11 +163,130 @@ func main() {
 		}
 	}()
 
+	// Start feed collector
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer log.Println("Yield collector stopped")
+
+		if err := feedCollector.Start(); err != nil {
+			log.Printf("Failed to start feed collector: %v", err)
+			monitoring.CaptureError(err, map[string]string{
+				"component":  "feed_collector",
+				"error_type": "startup_failure",
+			}, nil)
+			return
+		}
+
+		<-ctx.Done()
+		if err := feedCollector.Stop(); err != nil {
+			log.Printf("Error stopping feed collector: %v", err)
+		}
+	}()
+
+	// Open the portfolio registry
+	portfolioStore, err := store.NewFilePortfolioStore(storePath("PORTFOLIO_STORE_PATH", "data/portfolios.json"))
+	if err != nil {
+		log.Fatalf("Failed to open portfolio store: %v", err)
+	}
+
+	// Open the recommendation audit trail, dropping records past the retention period
+	retention := store.DefaultRecommendationRetention
+	if value := os.Getenv("RECOMMENDATION_RETENTION"); value != "" {
+		if retention, err = services.ParseHorizon(value); err != nil {
+			log.Fatalf("Invalid RECOMMENDATION_RETENTION: %v", err)
+		}
+	}
+	recommendationStore, err := store.NewFileRecommendationStore(storePath("RECOMMENDATION_STORE_PATH", "data/recommendations.jsonl"), store.WithRetention(retention))
+	if err != nil {
+		log.Fatalf("Failed to open recommendation store: %v", err)
+	}
+	defer recommendationStore.Close()
+
+	// Score past recommendations once their horizons elapse
+	evaluatorConfig := services.DefaultOutcomeEvaluatorConfig()
+	if horizons := os.Getenv("OUTCOME_HORIZONS"); horizons != "" {
+		evaluatorConfig.Horizons = strings.Split(horizons, ",")
+		evaluatorConfig.CalibrationHorizon = "" // Shortest configured horizon
+	}
+	if horizon := os.Getenv("CALIBRATION_HORIZON"); horizon != "" {
+		evaluatorConfig.CalibrationHorizon = horizon
+	}
+	outcomeEvaluator, err := services.NewOutcomeEvaluator(recommendationStore, feedCollector, evaluatorConfig)
+	if err != nil {
+		log.Fatalf("Invalid outcome evaluator configuration: %v", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer log.Println("Outcome evaluator stopped")
+
+		if err := outcomeEvaluator.Start(); err != nil {
+			log.Printf("Failed to start outcome evaluator: %v", err)
+			return
+		}
+
+		<-ctx.Done()
+		if err := outcomeEvaluator.Stop(); err != nil {
+			log.Printf("Error stopping outcome evaluator: %v", err)
+		}
+	}()
+
+	// Load pool reserves for simulating planned swaps; without them swaps use the cost model
+	var liquidityPools *services.LiquidityPools
+	if path := os.Getenv("LIQUIDITY_POOLS_PATH"); path != "" {
+		if liquidityPools, err = services.LoadLiquidityPools(path); err != nil {
+			log.Fatalf("Failed to load liquidity pools: %v", err)
+		}
+	}
+
+	// Load house views blended into market-implied returns
+	houseViews, err := services.LoadMarketViews(os.Getenv("HOUSE_VIEWS_PATH"))
+	if err != nil {
+		log.Fatalf("Failed to load house views: %v", err)
+	}
+	blackLitterman := services.DefaultBlackLittermanConfig()
+	blackLitterman.Views = houseViews
+
+	// Choose the benchmark behind beta, relative risk and performance attribution
+	benchmark := services.DefaultBenchmark()
+	if spec := os.Getenv("BENCHMARK"); spec != "" {
+		if benchmark, err = services.ParseBenchmark(spec); err != nil {
+			log.Fatalf("Invalid benchmark: %v", err)
+		}
+	}
+
 	// Initialize AI engine
-	var aiEngine services.AIEngine = services.NewEnhancedAIEngine()
+	aiEngine := services.NewEnhancedAIEngine(
+		services.WithYieldStore(feedCollector.YieldStore()),
+		services.WithPriceHistory(feedCollector.PriceHistory()),
+		services.WithConfidenceCalibrator(outcomeEvaluator),
+		services.WithLiquidityPools(liquidityPools),
+		services.WithBlackLitterman(blackLitterman),
+		services.WithMarketCaps(feedCollector),
+		services.WithBenchmark(benchmark),
+		services.WithSentiment(realDataCollector.Sentiment()),
+	)
+
+	// Wallet-scoped endpoints trust only sessions signed with this key
+	sessionSecret := os.Getenv("SESSION_SECRET")
+	if sessionSecret == "" {
+		log.Println("SESSION_SECRET not set; portfolio, recommendation history and portfolio stream endpoints are disabled")
+	}
 
 	// Create HTTP server with enhanced monitoring
-	httpServer := server.NewSimpleHTTPServer(aiEngine, dataCollector)
+	httpServer := server.NewSimpleHTTPServer(aiEngine, dataCollector,
+		server.WithYieldStore(feedCollector.YieldStore()),
+		server.WithYieldPredictor(services.NewYieldForecaster(feedCollector.YieldStore())),
+		server.WithPriceStreamer(feedCollector),
+		server.WithPortfolioStore(portfolioStore),
+		server.WithRecommendationStore(recommendationStore),
+		server.WithScorecardProvider(outcomeEvaluator),
+		server.WithPerformanceAttributor(services.NewPerformanceAnalyzer(portfolioStore, feedCollector,
+			services.WithAttributionBenchmark(benchmark, aiEngine))),
+		server.WithSessionSecret([]byte(sessionSecret)),
+	)
 
 	// Start HTTP server in a goroutine
 	wg.Add(1)
@@ -183,6 +319,17 @@ func main() {
 	log.Printf("  GET  http://localhost:%d/health", port)
 	log.Printf("  POST http://localhost:%d/api/optimize-portfolio", port)
 	log.Printf("  GET  http://localhost:%d/api/market-indicators", port)
+	log.Printf("  POST http://localhost:%d/api/risk-attribution", port)
+	log.Printf("  POST http://localhost:%d/api/benchmark-comparison", port)
+	log.Printf("  GET  http://localhost:%d/api/yields", port)
+	log.Printf("  POST http://localhost:%d/api/predict-yields", port)
+	log.Printf("  GET  http://localhost:%d/api/stream/prices", port)
+	log.Printf("  GET  http://localhost:%d/api/stream/recommendations", port)
+	log.Printf("  GET  http://localhost:%d/api/portfolios", port)
+	log.Printf("  POST http://localhost:%d/api/portfolios", port)
+	log.Printf("  GET  http://localhost:%d/api/portfolios/{id}/performance", port)
+	log.Printf("  GET  http://localhost:%d/api/recommendations", port)
+	log.Printf("  GET  http://localhost:%d/api/scorecard", port)
 
 	monitoring.CaptureMessage("AI Engine startup completed",
 		monitoring.LevelInfo,
@@ -243,3 +390,11 @@ func getVersion() string {
 	}
 	return "1.0.0"
 }
+
+// storePath gets a store file location from environment or default
+func storePath(envVar, fallback string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	return fallback
+}